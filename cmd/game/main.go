@@ -2,8 +2,11 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"os"
 	"runtime"
+	"time"
 
 	"nightmare/internal/logger"
 	"nightmare/pkg/config"
@@ -22,20 +25,197 @@ func main() {
 
 	// Чтение конфигурации
 	configPath := flag.String("config", "config.yaml", "Path to configuration file")
+	seedCode := flag.String("seed", "", "World seed code (e.g. 0DX4-7KQ9-MZ2B1) or number; overrides the config")
+	verifyDeterminism := flag.Bool("verify-determinism", false, "Generate the world twice, simulate it at different frame rates and compare hashes")
+	verifyTicks := flag.Uint64("verify-ticks", 3000, "Number of world ticks to simulate for -verify-determinism")
+	recordPath := flag.String("record", "", "Record the input of every tick to a replay file")
+	replayPath := flag.String("replay", "", "Play a replay file instead of live input")
+	headless := flag.Bool("headless", false, "Run without a window: check a -replay, or soak test a -bot or -script")
+	botName := flag.String("bot", "", "Let a bot play instead of live input (wander)")
+	scriptPath := flag.String("script", "", "Play an input script instead of live input")
+	soakDuration := flag.Duration("soak", time.Hour, "Game time to soak test a -bot or -script with -headless")
+	objectivesPath := flag.String("objectives", "", "Save the world's objectives and how far the -headless run got to a YAML file")
 	flag.Parse()
 
+	if *headless && *replayPath == "" && *botName == "" && *scriptPath == "" {
+		log.Fatalf("-headless needs -replay, -bot or -script")
+	}
+
+	// Воспроизведение без окна: сверка состояния с записью (регрессионные тесты по баг-репортам)
+	if *headless && *replayPath != "" {
+		replay, err := engine.LoadReplay(*replayPath)
+		if err != nil {
+			log.Fatalf("Failed to load replay: %v", err)
+		}
+		hash, err := engine.VerifyReplay(replay)
+		if err != nil {
+			logger.Errorf("Replay check failed: %v", err)
+			os.Exit(1)
+		}
+		if replay.Complete {
+			logger.Infof("Replay check passed: state %016x after %d ticks", hash, len(replay.Frames))
+		} else {
+			logger.Warnf("Replay was cut off, nothing to compare: state %016x after %d ticks", hash, len(replay.Frames))
+		}
+		return
+	}
+
 	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Запись несет свой конфиг и сид: мир должен совпасть с записанным
+	var replay *engine.Replay
+	if *replayPath != "" {
+		replay, err = engine.LoadReplay(*replayPath)
+		if err != nil {
+			log.Fatalf("Failed to load replay: %v", err)
+		}
+		cfg = replay.Config
+	}
+
+	// Сид из командной строки имеет приоритет над конфигурацией
+	if *seedCode != "" && replay == nil {
+		seed, err := config.ParseSeed(*seedCode)
+		if err != nil {
+			log.Fatalf("Invalid seed: %v", err)
+		}
+		// 0 в конфиге означает случайный мир, поэтому явно задать его нельзя
+		if seed == 0 {
+			log.Fatalf("Invalid seed %q: 0 means a random world, leave -seed out to get one", *seedCode)
+		}
+		cfg.Procedural.Seed = seed
+	}
+
+	// Проверка детерминизма мира без запуска окна
+	if *verifyDeterminism {
+		hash, err := engine.VerifyDeterminism(cfg.Procedural, *verifyTicks)
+		if err != nil {
+			logger.Errorf("Determinism check failed: %v", err)
+			os.Exit(1)
+		}
+		logger.Infof("Determinism check passed: world hash %016x after %d ticks", hash, *verifyTicks)
+		return
+	}
+
+	// Долгий прогон бота или сценария без окна: игрок не должен провалиться
+	// сквозь землю, уйти за край карты или застрять
+	if *headless {
+		os.Exit(soak(cfg, *botName, *scriptPath, *soakDuration, *recordPath, *objectivesPath, logger))
+	}
+
 	// Инициализация игрового движка
 	game, err := engine.NewEngine(cfg, logger)
 	if err != nil {
 		log.Fatalf("Failed to initialize game engine: %v", err)
 	}
 
+	if replay != nil {
+		game.PlayReplay(replay)
+	} else if *botName != "" || *scriptPath != "" {
+		source, err := inputDriver(*botName, *scriptPath, game.Seed())
+		if err != nil {
+			log.Fatalf("Failed to start input driver: %v", err)
+		}
+		game.DriveWith(source)
+	}
+	if *recordPath != "" {
+		if err := game.RecordTo(*recordPath); err != nil {
+			log.Fatalf("Failed to start recording: %v", err)
+		}
+	}
+
 	// Запуск игрового цикла
 	logger.Info("Engine initialized, starting game loop...")
 	game.Run()
 }
+
+// inputDriver создает бота или сценарий, который играет вместо игрока
+func inputDriver(botName, scriptPath string, seed int64) (engine.InputSource, error) {
+	if scriptPath != "" {
+		script, err := engine.LoadScript(scriptPath)
+		if err != nil {
+			return nil, err
+		}
+		return script, nil
+	}
+
+	switch botName {
+	case "wander":
+		return engine.NewWanderBot(seed), nil
+	default:
+		return nil, fmt.Errorf("unknown bot %q (available: wander)", botName)
+	}
+}
+
+// soak гоняет бота или сценарий без окна и возвращает код выхода.
+// Прогон можно записать (-record), чтобы разобрать сбой через -headless -replay
+func soak(cfg *config.Config, botName, scriptPath string, duration time.Duration, recordPath, objectivesPath string, logger *logger.Logger) int {
+	sim, err := engine.NewSimulation(cfg)
+	if err != nil {
+		logger.Errorf("Failed to create simulation: %v", err)
+		return 1
+	}
+	sim.Start()
+	for _, err := range sim.ModErrors() {
+		logger.Warnf("Skipping interactions: %v", err)
+	}
+
+	source, err := inputDriver(botName, scriptPath, sim.Seed())
+	if err != nil {
+		logger.Errorf("Failed to start input driver: %v", err)
+		return 1
+	}
+
+	var recorder *engine.ReplayRecorder
+	if recordPath != "" {
+		// Бот играет за нового игрока: профиля страха у него нет
+		recorder, err = engine.NewReplayRecorder(recordPath, cfg, sim.Seed(), nil)
+		if err != nil {
+			logger.Errorf("Failed to start recording: %v", err)
+			return 1
+		}
+	}
+
+	ticks := uint64(duration.Seconds() * engine.SimulationTickRate)
+	logger.Infof("Soak test: seed %d, %v of game time (%d ticks)", sim.Seed(), duration, ticks)
+	report, soakErr := engine.RunSoak(sim, source, ticks, recorder)
+
+	if recorder != nil {
+		if err := recorder.Close(sim.StateHash()); err != nil {
+			logger.Errorf("Failed to save replay: %v", err)
+		} else {
+			logger.Infof("Soak run recorded to %s; replay it with -headless -replay %s", recordPath, recordPath)
+		}
+	}
+
+	if objectives := sim.Objectives(); objectives != nil {
+		logger.Infof("Objectives: %.0f%% done, missing person: %s", objectives.Progress()*100, objectives.Missing)
+		for _, line := range objectives.Lines() {
+			logger.Infof("  %s", line)
+		}
+		if objectivesPath != "" {
+			if err := saveObjectives(objectives, objectivesPath); err != nil {
+				logger.Errorf("Failed to save objectives: %v", err)
+			}
+		}
+	}
+
+	if soakErr != nil {
+		logger.Errorf("Soak test failed after %d ticks: %v", report.Ticks, soakErr)
+		return 1
+	}
+	logger.Infof("Soak test passed: %d ticks, walked %.0f m, died %d times, ended at (%.1f, %.1f, %.1f)",
+		report.Ticks, report.Distance, report.Deaths, report.Position.X, report.Position.Y, report.Position.Z)
+	return 0
+}
+
+// saveObjectives сохраняет цели мира в YAML
+func saveObjectives(objectives *engine.Objectives, path string) error {
+	data, err := engine.EncodeObjectives(objectives)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
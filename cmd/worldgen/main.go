@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"nightmare/pkg/config"
+	"nightmare/pkg/engine"
+)
+
+func main() {
+	// Параметры командной строки
+	configPath := flag.String("config", "config.yaml", "Path to configuration file")
+	seedCode := flag.String("seed", "", "World seed code or number (random if empty and the config seed is 0)")
+	count := flag.Int("count", 1, "Number of worlds to generate; consecutive seeds are used after the first one")
+	outDir := flag.String("out", ".", "Directory for the generated PNG maps")
+	scale := flag.Int("scale", 4, "Pixels per terrain cell")
+	size := flag.Int("size", 0, "Terrain size override (0 = use the config)")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		// Генератор карт работает и без файла конфигурации
+		log.Printf("Using default configuration: %v", err)
+	}
+
+	if *size > 0 {
+		cfg.Procedural.TerrainSize = *size
+	}
+
+	seed := cfg.Procedural.Seed
+	if *seedCode != "" {
+		seed, err = config.ParseSeed(*seedCode)
+		if err != nil {
+			log.Fatalf("Invalid seed: %v", err)
+		}
+		// 0 означает случайный мир, поэтому явно задать его нельзя
+		if seed == 0 {
+			log.Fatalf("Invalid seed %q: 0 means a random world, leave -seed out to get one", *seedCode)
+		}
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		log.Fatalf("Failed to create output directory: %v", err)
+	}
+
+	for i := 0; i < *count; i++ {
+		worldCfg := cfg.Procedural
+		worldCfg.Seed = seed + int64(i)
+
+		if err := generateMap(worldCfg, *outDir, *scale); err != nil {
+			log.Fatalf("Failed to generate world %s: %v", config.FormatSeed(worldCfg.Seed), err)
+		}
+	}
+}
+
+// generateMap генерирует мир без окна и сохраняет его карту в PNG
+func generateMap(cfg config.ProceduralConfig, outDir string, scale int) error {
+	generator, err := engine.NewProceduralGenerator(cfg)
+	if err != nil {
+		return err
+	}
+
+	generator.GenerateInitialWorld()
+	scene := generator.GetCurrentScene()
+
+	code := config.FormatSeed(cfg.Seed)
+	path := filepath.Join(outDir, "world_"+code+".png")
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := png.Encode(file, renderWorldMap(scene, scale)); err != nil {
+		return err
+	}
+
+	printSummary(scene, code, path)
+	return nil
+}
+
+// printSummary выводит краткое описание мира, чтобы выбирать сиды без просмотра карт
+func printSummary(scene *engine.ProceduralScene, code, path string) {
+	objects := make(map[string]int)
+	for _, obj := range scene.Objects {
+		objects[obj.Type]++
+	}
+
+	structures := make(map[string]int)
+	for _, structure := range scene.Structures {
+		structures[structure.Type]++
+	}
+
+	fmt.Printf("seed %s (%d) -> %s\n", code, scene.Seed, path)
+	fmt.Printf("  objects:    %s\n", formatCounts(objects))
+	fmt.Printf("  structures: %s\n", formatCounts(structures))
+	fmt.Printf("  paths:      %d\n", len(scene.Terrain.Paths))
+	fmt.Printf("  caves:      %d\n", len(scene.Caves))
+}
+
+// formatCounts форматирует счетчики в стабильном порядке
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := ""
+	for i, k := range keys {
+		if i > 0 {
+			result += ", "
+		}
+		result += fmt.Sprintf("%s=%d", k, counts[k])
+	}
+	return result
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"nightmare/pkg/engine"
+)
+
+// Цвета материалов ландшафта (HeightMap.Materials)
+var materialColors = map[int]color.RGBA{
+	1: {30, 50, 90, 255},    // Вода
+	2: {70, 60, 40, 255},    // Земля
+	3: {110, 110, 110, 255}, // Камень
+	4: {230, 230, 235, 255}, // Снег
+}
+
+// Цвета регионов (HeightMap.Regions), смешиваются с цветом материала
+var regionColors = map[string]color.RGBA{
+	"clearing":      {120, 140, 70, 255},
+	"low_forest":    {40, 80, 40, 255},
+	"dark_forest":   {20, 50, 25, 255},
+	"dense_forest":  {10, 35, 15, 255},
+	"swamp":         {60, 70, 40, 255},
+	"swamp_pit":     {45, 50, 30, 255},
+	"pond":          {40, 70, 110, 255},
+	"rocky_hills":   {120, 100, 80, 255},
+	"mountains":     {140, 140, 140, 255},
+	"mountain_peak": {240, 240, 240, 255},
+	"path":          {160, 130, 90, 255},
+	"ravine":        {60, 40, 30, 255},
+}
+
+// Цвета объектов на карте
+var (
+	pathColor      = color.RGBA{200, 170, 110, 255}
+	caveColor      = color.RGBA{15, 10, 20, 255}
+	entranceColor  = color.RGBA{255, 60, 40, 255}
+	treeColor      = color.RGBA{70, 190, 60, 255}
+	rockColor      = color.RGBA{170, 170, 160, 255}
+	strangeColor   = color.RGBA{220, 0, 200, 255}
+	structureColor = color.RGBA{255, 170, 0, 255}
+	otherColor     = color.RGBA{255, 255, 255, 255}
+)
+
+// renderWorldMap рисует карту мира сверху: материалы и регионы с отмывкой рельефа,
+// тропинки, пещеры и позиции объектов
+func renderWorldMap(scene *engine.ProceduralScene, scale int) *image.RGBA {
+	terrain := scene.Terrain
+	if scale < 1 {
+		scale = 1
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, terrain.Width*scale, terrain.Height*scale))
+
+	// Ландшафт
+	for z := 0; z < terrain.Height; z++ {
+		for x := 0; x < terrain.Width; x++ {
+			base, ok := materialColors[terrain.Materials[z][x]]
+			if !ok {
+				base = materialColors[2]
+			}
+			if region, ok := regionColors[terrain.Regions[z][x]]; ok {
+				base = blend(base, region, 0.5)
+			}
+
+			shade := 0.6 + 0.4*terrain.Data[z][x]
+			shade *= hillshade(terrain, x, z)
+
+			fillCell(img, x, z, scale, scaleColor(base, shade))
+		}
+	}
+
+	// Тропинки
+	for _, path := range terrain.Paths {
+		for _, point := range path {
+			fillCell(img, point[0], point[1], scale, pathColor)
+		}
+	}
+
+	// Пещеры: туннель по оси и вход. Мировые координаты -> координаты сетки (world = grid - size/2)
+	toMap := func(x, z float64) (float64, float64) {
+		return (x + float64(terrain.Width)/2) * float64(scale), (z + float64(terrain.Height)/2) * float64(scale)
+	}
+	for _, cave := range scene.Caves {
+		for i := 0; i+1 < len(cave.Path); i++ {
+			a, b := cave.Path[i], cave.Path[i+1]
+			for t := 0.0; t <= 1.0; t += 0.05 {
+				px, pz := toMap(a.X+(b.X-a.X)*t, a.Z+(b.Z-a.Z)*t)
+				radius := (cave.Radii[i] + (cave.Radii[i+1]-cave.Radii[i])*t) * 0.6 * float64(scale)
+				fillCircle(img, px, pz, radius, caveColor)
+			}
+		}
+
+		px, pz := toMap(cave.Entrance.X, cave.Entrance.Z)
+		fillCircle(img, px, pz, math.Max(2, float64(scale)), entranceColor)
+	}
+
+	// Объекты
+	for _, obj := range scene.Objects {
+		px, pz := toMap(obj.Position.X, obj.Position.Z)
+		radius := math.Max(1, math.Max(obj.Scale.X, obj.Scale.Z)*0.5*float64(scale))
+
+		objColor := otherColor
+		switch {
+		case obj.StructureID != 0:
+			objColor = structureColor
+		case obj.Type == "tree":
+			objColor = treeColor
+		case obj.Type == "rock":
+			objColor = rockColor
+		case obj.Type == "strange":
+			objColor = strangeColor
+		}
+
+		fillCircle(img, px, pz, radius, objColor)
+	}
+
+	return img
+}
+
+// hillshade возвращает освещенность клетки от света с северо-запада
+func hillshade(terrain *engine.HeightMap, x, z int) float64 {
+	x0, x1 := max(x-1, 0), min(x+1, terrain.Width-1)
+	z0, z1 := max(z-1, 0), min(z+1, terrain.Height-1)
+
+	dx := (terrain.Data[z][x1] - terrain.Data[z][x0]) * 20.0
+	dz := (terrain.Data[z1][x] - terrain.Data[z0][x]) * 20.0
+
+	// Склоны, обращенные к свету, светлее
+	light := 1.0 - (dx+dz)*0.15
+	return math.Max(0.5, math.Min(1.3, light))
+}
+
+func fillCell(img *image.RGBA, x, z, scale int, c color.RGBA) {
+	for py := z * scale; py < (z+1)*scale; py++ {
+		for px := x * scale; px < (x+1)*scale; px++ {
+			img.SetRGBA(px, py, c)
+		}
+	}
+}
+
+func fillCircle(img *image.RGBA, cx, cy, radius float64, c color.RGBA) {
+	bounds := img.Bounds()
+	for py := int(cy - radius); py <= int(cy+radius); py++ {
+		for px := int(cx - radius); px <= int(cx+radius); px++ {
+			if !(image.Point{X: px, Y: py}).In(bounds) {
+				continue
+			}
+			dx := float64(px) + 0.5 - cx
+			dy := float64(py) + 0.5 - cy
+			if dx*dx+dy*dy <= radius*radius {
+				img.SetRGBA(px, py, c)
+			}
+		}
+	}
+}
+
+func blend(a, b color.RGBA, t float64) color.RGBA {
+	return color.RGBA{
+		R: uint8(float64(a.R)*(1-t) + float64(b.R)*t),
+		G: uint8(float64(a.G)*(1-t) + float64(b.G)*t),
+		B: uint8(float64(a.B)*(1-t) + float64(b.B)*t),
+		A: 255,
+	}
+}
+
+func scaleColor(c color.RGBA, k float64) color.RGBA {
+	clampByte := func(v float64) uint8 {
+		return uint8(math.Max(0, math.Min(255, v)))
+	}
+
+	return color.RGBA{
+		R: clampByte(float64(c.R) * k),
+		G: clampByte(float64(c.G) * k),
+		B: clampByte(float64(c.B) * k),
+		A: 255,
+	}
+}
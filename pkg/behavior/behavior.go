@@ -0,0 +1,143 @@
+// Package behavior implements behavior trees for world entities: creatures,
+// ambient animals, haunted objects. Trees are built from Go or from YAML
+// definitions (for mod data); their conditions and actions are plain
+// functions registered by name in a Registry.
+package behavior
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Status is the result of ticking a node
+type Status int
+
+const (
+	Invalid Status = iota // Not ticked yet
+	Success
+	Failure
+	Running // Needs more ticks to finish
+)
+
+var statusNames = [...]string{"invalid", "success", "failure", "running"}
+
+func (s Status) String() string {
+	if s < 0 || int(s) >= len(statusNames) {
+		return "unknown"
+	}
+	return statusNames[s]
+}
+
+// Node is a node of a behavior tree. Composites and decorators tick their
+// children through Context.Run so the tree can trace statuses and the
+// active path
+type Node interface {
+	// Tick advances the node by one tree tick
+	Tick(ctx *Context) Status
+	// Reset aborts a running node, the next tick starts it over
+	Reset()
+	// Label names the node in debug dumps
+	Label() string
+	// Children returns the child nodes, nil for leaves
+	Children() []Node
+}
+
+// Context is passed to every node on a tick
+type Context struct {
+	Subject    interface{} // The entity the tree decides for
+	Blackboard Blackboard  // Memory shared by the nodes of the tree
+	DeltaTime  float64     // Seconds since the previous tick
+	Time       float64     // Seconds the tree has been ticked for
+	Rand       *rand.Rand  // Randomness of the tree, seeded when it is built
+
+	tree     *Tree
+	stack    []Node
+	recorded bool // A node deeper than the current one was ticked
+}
+
+// Run ticks a child node, recording its status for Tree.Dump and
+// Tree.ActivePath
+func (ctx *Context) Run(node Node) Status {
+	ctx.stack = append(ctx.stack, node)
+	ctx.recorded = false
+	status := node.Tick(ctx)
+	ctx.tree.record(ctx.stack, status, !ctx.recorded)
+	ctx.recorded = true
+	ctx.stack = ctx.stack[:len(ctx.stack)-1]
+	return status
+}
+
+// Blackboard is the memory of a tree: nodes leave values for each other,
+// the game reads and writes it from outside
+type Blackboard map[string]interface{}
+
+// Float returns a number, or fallback if the key is missing or not a number
+func (bb Blackboard) Float(key string, fallback float64) float64 {
+	if value, ok := toFloat(bb[key]); ok {
+		return value
+	}
+	return fallback
+}
+
+// Bool returns a flag, or false if the key is missing or not a flag
+func (bb Blackboard) Bool(key string) bool {
+	value, _ := bb[key].(bool)
+	return value
+}
+
+// String returns a string, or "" if the key is missing or not a string
+func (bb Blackboard) String(key string) string {
+	value, _ := bb[key].(string)
+	return value
+}
+
+// Params are the settings of a condition or action node, e.g. the radius
+// of "player_within". In YAML they come from the node's params section
+type Params map[string]interface{}
+
+// Float returns a number parameter, or fallback if it is missing
+func (p Params) Float(name string, fallback float64) float64 {
+	if value, ok := toFloat(p[name]); ok {
+		return value
+	}
+	return fallback
+}
+
+// String returns a string parameter, or fallback if it is missing
+func (p Params) String(name, fallback string) string {
+	if value, ok := p[name].(string); ok {
+		return value
+	}
+	return fallback
+}
+
+// Bool returns a flag parameter, or fallback if it is missing
+func (p Params) Bool(name string, fallback bool) bool {
+	if value, ok := p[name].(bool); ok {
+		return value
+	}
+	return fallback
+}
+
+// toFloat converts numbers decoded from YAML (int or float64) to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	}
+	return 0, false
+}
+
+// label formats a node label with an optional custom name
+func label(kind, name string) string {
+	if name == "" {
+		return kind
+	}
+	return fmt.Sprintf("%s %s", kind, name)
+}
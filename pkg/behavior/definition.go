@@ -0,0 +1,153 @@
+package behavior
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Definition describes a tree in YAML, e.g.:
+//
+//	name: whisperer
+//	root:
+//	  type: selector
+//	  children:
+//	    - type: sequence
+//	      children:
+//	        - {type: condition, name: player_within, params: {radius: 6}}
+//	        - {type: action, name: play_sound, params: {sound: ambient}}
+//	        - {type: wait, seconds: 10}
+//	    - {type: wait, seconds: 1}
+//
+// A definition is a template: Build makes a new tree instance from it
+type Definition struct {
+	Name string         `yaml:"name"`
+	Root NodeDefinition `yaml:"root"`
+}
+
+// NodeDefinition describes one node. Type is one of: sequence, selector,
+// parallel, inverter, succeeder, repeat, until_failure, cooldown, timeout,
+// wait, condition, action
+type NodeDefinition struct {
+	Type     string           `yaml:"type"`
+	Name     string           `yaml:"name"`     // Registered name of a condition or action; a label for other nodes
+	Params   Params           `yaml:"params"`   // Settings of a condition or action
+	Children []NodeDefinition `yaml:"children"` // Sequence, selector, parallel
+	Child    *NodeDefinition  `yaml:"child"`    // Decorators
+	Seconds  float64          `yaml:"seconds"`  // Wait, cooldown, timeout
+	Times    int              `yaml:"times"`    // Repeat: 0 - forever
+	Required int              `yaml:"required"` // Parallel: children that must succeed, 0 - all
+}
+
+// ParseDefinition reads a tree definition from YAML
+func ParseDefinition(data []byte) (*Definition, error) {
+	def := &Definition{}
+	if err := yaml.UnmarshalStrict(data, def); err != nil {
+		return nil, fmt.Errorf("error parsing behavior tree: %v", err)
+	}
+	return def, nil
+}
+
+// LoadDefinition reads a tree definition from a YAML file
+func LoadDefinition(path string) (*Definition, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read behavior tree: %v", err)
+	}
+	def, err := ParseDefinition(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return def, nil
+}
+
+// Build creates a tree instance. Unknown node types, conditions and actions
+// are reported with the path to the node
+func (def *Definition) Build(registry *Registry, seed int64) (*Tree, error) {
+	root, err := def.Root.build(registry, "root")
+	if err != nil {
+		return nil, fmt.Errorf("behavior tree %q: %v", def.Name, err)
+	}
+	return NewTree(def.Name, root, seed), nil
+}
+
+// Validate checks that the definition builds with the registry
+func (def *Definition) Validate(registry *Registry) error {
+	_, err := def.Build(registry, 0)
+	return err
+}
+
+// build creates the node and its children
+func (nd *NodeDefinition) build(registry *Registry, path string) (Node, error) {
+	switch nd.Type {
+	case "condition":
+		node, err := registry.Condition(nd.Name, nd.Params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return node, nil
+
+	case "action":
+		node, err := registry.Action(nd.Name, nd.Params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return node, nil
+
+	case "wait":
+		if nd.Seconds <= 0 {
+			return nil, fmt.Errorf("%s: wait needs seconds", path)
+		}
+		return Wait(nd.Seconds), nil
+
+	case "sequence", "selector", "parallel":
+		if len(nd.Children) == 0 {
+			return nil, fmt.Errorf("%s: %s has no children", path, nd.Type)
+		}
+		children := make([]Node, len(nd.Children))
+		for i := range nd.Children {
+			child, err := nd.Children[i].build(registry, fmt.Sprintf("%s > %s[%d]", path, nd.Type, i))
+			if err != nil {
+				return nil, err
+			}
+			children[i] = child
+		}
+		switch nd.Type {
+		case "sequence":
+			return Sequence(nd.Name, children...), nil
+		case "selector":
+			return Selector(nd.Name, children...), nil
+		default:
+			return Parallel(nd.Name, nd.Required, children...), nil
+		}
+
+	case "inverter", "succeeder", "repeat", "until_failure", "cooldown", "timeout":
+		if nd.Child == nil {
+			return nil, fmt.Errorf("%s: %s has no child", path, nd.Type)
+		}
+		if (nd.Type == "cooldown" || nd.Type == "timeout") && nd.Seconds <= 0 {
+			return nil, fmt.Errorf("%s: %s needs seconds", path, nd.Type)
+		}
+		child, err := nd.Child.build(registry, path+" > "+nd.Type)
+		if err != nil {
+			return nil, err
+		}
+		switch nd.Type {
+		case "inverter":
+			return Inverter(nd.Name, child), nil
+		case "succeeder":
+			return Succeeder(nd.Name, child), nil
+		case "repeat":
+			return Repeat(nd.Name, nd.Times, child), nil
+		case "until_failure":
+			return UntilFailure(nd.Name, child), nil
+		case "cooldown":
+			return Cooldown(nd.Name, nd.Seconds, child), nil
+		default:
+			return Timeout(nd.Name, nd.Seconds, child), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: unknown node type %q", path, nd.Type)
+}
@@ -0,0 +1,125 @@
+package behavior
+
+import (
+	"strings"
+	"testing"
+)
+
+const guardTree = `
+name: guard
+root:
+  type: selector
+  children:
+    - type: sequence
+      name: alarm
+      children:
+        - {type: condition, name: blackboard, params: {key: noise, above: 0.5}}
+        - {type: action, name: set, params: {key: alarmed, value: true}}
+        - {type: wait, seconds: 0.15}
+    - type: cooldown
+      seconds: 5
+      child:
+        type: inverter
+        child: {type: condition, name: blackboard, params: {key: alarmed}}
+`
+
+func TestDefinitionBuild(t *testing.T) {
+	def, err := ParseDefinition([]byte(guardTree))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	tree, err := def.Build(NewRegistry(), 1)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if tree.Name != "guard" {
+		t.Errorf("tree name %q, want guard", tree.Name)
+	}
+
+	// Quiet: the alarm branch fails, the inverted check succeeds
+	if got := tree.Tick(nil, 0.1); got != Success {
+		t.Fatalf("quiet tick: got %v, want success", got)
+	}
+
+	// Noise: the alarm is raised and the guard waits
+	tree.Blackboard["noise"] = 0.8
+	if got := tree.Tick(nil, 0.1); got != Running {
+		t.Fatalf("noisy tick: got %v, want running", got)
+	}
+	if !tree.Blackboard.Bool("alarmed") {
+		t.Errorf("alarm not raised, blackboard %v", tree.Blackboard)
+	}
+	if path := tree.ActivePathString(); path != "selector > sequence alarm > wait 0.15s" {
+		t.Errorf("active path %q", path)
+	}
+}
+
+func TestDefinitionErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+		want string // Part of the error message
+	}{
+		{
+			name: "unknown node type",
+			yaml: "name: bad\nroot: {type: loop}",
+			want: `root: unknown node type "loop"`,
+		},
+		{
+			name: "unknown condition",
+			yaml: "name: bad\nroot: {type: condition, name: player_within}",
+			want: `unknown condition "player_within"`,
+		},
+		{
+			name: "missing action name",
+			yaml: "name: bad\nroot: {type: action}",
+			want: `unknown action ""`,
+		},
+		{
+			name: "missing children",
+			yaml: "name: bad\nroot: {type: sequence}",
+			want: "sequence has no children",
+		},
+		{
+			name: "missing child",
+			yaml: "name: bad\nroot: {type: inverter}",
+			want: "inverter has no child",
+		},
+		{
+			name: "missing wait seconds",
+			yaml: "name: bad\nroot: {type: selector, children: [{type: wait}]}",
+			want: "root > selector[0]: wait needs seconds",
+		},
+		{
+			name: "missing cooldown seconds",
+			yaml: "name: bad\nroot: {type: cooldown, child: {type: wait, seconds: 1}}",
+			want: "cooldown needs seconds",
+		},
+		{
+			name: "nested error path",
+			yaml: "name: bad\nroot: {type: sequence, children: [{type: wait, seconds: 1}, {type: timeout, seconds: 1, child: {type: jump}}]}",
+			want: `root > sequence[1] > timeout: unknown node type "jump"`,
+		},
+		{
+			name: "unknown field",
+			yaml: "name: bad\nroot: {type: wait, secs: 1}",
+			want: "error parsing behavior tree",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			def, err := ParseDefinition([]byte(tc.yaml))
+			if err == nil {
+				err = def.Validate(NewRegistry())
+			}
+			if err == nil {
+				t.Fatalf("no error, want %q", tc.want)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error %q, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package behavior
+
+import "strconv"
+
+// composite holds the children of sequences, selectors and parallels
+type composite struct {
+	name     string
+	children []Node
+	current  int // Child that was running on the previous tick
+}
+
+func (c *composite) Children() []Node {
+	return c.children
+}
+
+func (c *composite) Reset() {
+	c.current = 0
+	for _, child := range c.children {
+		child.Reset()
+	}
+}
+
+// sequence runs children in order while they succeed
+type sequence struct{ composite }
+
+// Sequence succeeds when all children succeed one after another and fails
+// on the first failing child. A running child is resumed on the next tick
+func Sequence(name string, children ...Node) Node {
+	return &sequence{composite{name: name, children: children}}
+}
+
+func (s *sequence) Label() string {
+	return label("sequence", s.name)
+}
+
+func (s *sequence) Tick(ctx *Context) Status {
+	for s.current < len(s.children) {
+		switch status := ctx.Run(s.children[s.current]); status {
+		case Success:
+			s.current++
+		case Running:
+			return Running
+		default:
+			s.current = 0
+			return status
+		}
+	}
+	s.current = 0
+	return Success
+}
+
+// selector tries children in order until one succeeds
+type selector struct{ composite }
+
+// Selector succeeds on the first succeeding child and fails when all
+// children fail. A running child is resumed on the next tick
+func Selector(name string, children ...Node) Node {
+	return &selector{composite{name: name, children: children}}
+}
+
+func (s *selector) Label() string {
+	return label("selector", s.name)
+}
+
+func (s *selector) Tick(ctx *Context) Status {
+	for s.current < len(s.children) {
+		switch status := ctx.Run(s.children[s.current]); status {
+		case Failure:
+			s.current++
+		case Running:
+			return Running
+		default:
+			s.current = 0
+			return status
+		}
+	}
+	s.current = 0
+	return Failure
+}
+
+// parallel ticks all children on every tick
+type parallel struct {
+	composite
+	required int
+	done     []Status // Results of children that already finished
+}
+
+// Parallel ticks all unfinished children on every tick. It succeeds once
+// required children have succeeded (0 means all of them) and fails as soon
+// as that becomes impossible; the children still running are then reset
+func Parallel(name string, required int, children ...Node) Node {
+	if required <= 0 || required > len(children) {
+		required = len(children)
+	}
+	return &parallel{
+		composite: composite{name: name, children: children},
+		required:  required,
+		done:      make([]Status, len(children)),
+	}
+}
+
+func (p *parallel) Label() string {
+	return label("parallel", p.name)
+}
+
+func (p *parallel) Tick(ctx *Context) Status {
+	succeeded, failed := 0, 0
+	for i, child := range p.children {
+		if p.done[i] == Invalid {
+			if status := ctx.Run(child); status != Running {
+				p.done[i] = status
+			}
+		}
+		switch p.done[i] {
+		case Success:
+			succeeded++
+		case Failure:
+			failed++
+		}
+	}
+
+	switch {
+	case succeeded >= p.required:
+		p.Reset()
+		return Success
+	case len(p.children)-failed < p.required:
+		p.Reset()
+		return Failure
+	}
+	return Running
+}
+
+func (p *parallel) Reset() {
+	p.composite.Reset()
+	for i := range p.done {
+		p.done[i] = Invalid
+	}
+}
+
+// decorator wraps a single child
+type decorator struct {
+	kind  string
+	name  string
+	child Node
+}
+
+func (d *decorator) Label() string {
+	return label(d.kind, d.name)
+}
+
+func (d *decorator) Children() []Node {
+	return []Node{d.child}
+}
+
+func (d *decorator) Reset() {
+	d.child.Reset()
+}
+
+// inverter swaps success and failure
+type inverter struct{ decorator }
+
+// Inverter turns the child's success into failure and the other way round
+func Inverter(name string, child Node) Node {
+	return &inverter{decorator{kind: "inverter", name: name, child: child}}
+}
+
+func (i *inverter) Tick(ctx *Context) Status {
+	switch status := ctx.Run(i.child); status {
+	case Success:
+		return Failure
+	case Failure:
+		return Success
+	default:
+		return status
+	}
+}
+
+// succeeder ignores the child's failure
+type succeeder struct{ decorator }
+
+// Succeeder succeeds whenever the child finishes, e.g. for optional steps
+// of a sequence
+func Succeeder(name string, child Node) Node {
+	return &succeeder{decorator{kind: "succeeder", name: name, child: child}}
+}
+
+func (s *succeeder) Tick(ctx *Context) Status {
+	if ctx.Run(s.child) == Running {
+		return Running
+	}
+	return Success
+}
+
+// repeat runs the child several times
+type repeat struct {
+	decorator
+	times int
+	count int
+}
+
+// Repeat runs the child until it has succeeded times times (0 - forever)
+// and fails as soon as the child fails. Each tick runs the child at most once
+func Repeat(name string, times int, child Node) Node {
+	return &repeat{decorator: decorator{kind: "repeat", name: name, child: child}, times: times}
+}
+
+func (r *repeat) Tick(ctx *Context) Status {
+	switch ctx.Run(r.child) {
+	case Running:
+		return Running
+	case Failure:
+		r.count = 0
+		return Failure
+	}
+
+	r.count++
+	if r.times > 0 && r.count >= r.times {
+		r.count = 0
+		return Success
+	}
+	return Running
+}
+
+func (r *repeat) Reset() {
+	r.count = 0
+	r.decorator.Reset()
+}
+
+// untilFailure runs the child until it fails
+type untilFailure struct{ decorator }
+
+// UntilFailure reruns the child until it fails, then succeeds: "keep doing
+// this while you can"
+func UntilFailure(name string, child Node) Node {
+	return &untilFailure{decorator{kind: "until_failure", name: name, child: child}}
+}
+
+func (u *untilFailure) Tick(ctx *Context) Status {
+	if ctx.Run(u.child) == Failure {
+		return Success
+	}
+	return Running
+}
+
+// cooldown keeps the child from running again too soon
+type cooldown struct {
+	decorator
+	seconds float64
+	readyAt float64
+	running bool
+}
+
+// Cooldown fails without ticking the child for seconds after the child
+// succeeded, so an event doesn't repeat every tick
+func Cooldown(name string, seconds float64, child Node) Node {
+	return &cooldown{decorator: decorator{kind: "cooldown", name: name, child: child}, seconds: seconds}
+}
+
+func (c *cooldown) Tick(ctx *Context) Status {
+	if !c.running && ctx.Time < c.readyAt {
+		return Failure
+	}
+
+	status := ctx.Run(c.child)
+	c.running = status == Running
+	if status == Success {
+		c.readyAt = ctx.Time + c.seconds
+	}
+	return status
+}
+
+func (c *cooldown) Reset() {
+	c.running = false
+	c.decorator.Reset()
+}
+
+// timeout limits how long the child may run
+type timeout struct {
+	decorator
+	seconds float64
+	started float64
+	running bool
+}
+
+// Timeout fails and resets the child if it keeps running for longer than seconds
+func Timeout(name string, seconds float64, child Node) Node {
+	return &timeout{decorator: decorator{kind: "timeout", name: name, child: child}, seconds: seconds}
+}
+
+func (t *timeout) Tick(ctx *Context) Status {
+	if !t.running {
+		t.started = ctx.Time
+	}
+	if ctx.Time-t.started >= t.seconds {
+		t.Reset()
+		return Failure
+	}
+
+	status := ctx.Run(t.child)
+	t.running = status == Running
+	return status
+}
+
+func (t *timeout) Reset() {
+	t.running = false
+	t.decorator.Reset()
+}
+
+// ConditionFunc checks something about the world; params come from the node
+type ConditionFunc func(ctx *Context, params Params) bool
+
+// ActionFunc does something and reports how it went. Actions that take
+// several ticks return Running and keep their progress in the blackboard
+type ActionFunc func(ctx *Context, params Params) Status
+
+// leaf is a condition or action node
+type leaf struct {
+	kind   string
+	name   string
+	params Params
+}
+
+func (l *leaf) Label() string {
+	return label(l.kind, l.name)
+}
+
+func (l *leaf) Children() []Node {
+	return nil
+}
+
+func (l *leaf) Reset() {}
+
+// condition succeeds while its function holds
+type condition struct {
+	leaf
+	check ConditionFunc
+}
+
+// Condition wraps a check in a node that succeeds or fails
+func Condition(name string, params Params, check ConditionFunc) Node {
+	return &condition{leaf: leaf{kind: "condition", name: name, params: params}, check: check}
+}
+
+func (c *condition) Tick(ctx *Context) Status {
+	if c.check(ctx, c.params) {
+		return Success
+	}
+	return Failure
+}
+
+// action runs its function
+type action struct {
+	leaf
+	run ActionFunc
+}
+
+// Action wraps a function in a node
+func Action(name string, params Params, run ActionFunc) Node {
+	return &action{leaf: leaf{kind: "action", name: name, params: params}, run: run}
+}
+
+func (a *action) Tick(ctx *Context) Status {
+	return a.run(ctx, a.params)
+}
+
+// wait is a pause
+type wait struct {
+	leaf
+	seconds float64
+	until   float64
+	running bool
+}
+
+// Wait runs for seconds, then succeeds
+func Wait(seconds float64) Node {
+	return &wait{leaf: leaf{kind: "wait"}, seconds: seconds}
+}
+
+func (w *wait) Label() string {
+	return label("wait", formatSeconds(w.seconds))
+}
+
+func (w *wait) Tick(ctx *Context) Status {
+	if !w.running {
+		w.running = true
+		w.until = ctx.Time + w.seconds
+	}
+	if ctx.Time < w.until {
+		return Running
+	}
+	w.running = false
+	return Success
+}
+
+func (w *wait) Reset() {
+	w.running = false
+}
+
+// formatSeconds prints a duration for labels: "2s", "0.5s"
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'g', -1, 64) + "s"
+}
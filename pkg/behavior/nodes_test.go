@@ -0,0 +1,259 @@
+package behavior
+
+import "testing"
+
+// stub is a leaf that returns the given statuses one per tick (the last
+// one repeats) and counts its ticks and resets
+type stub struct {
+	statuses []Status
+	ticks    int
+	resets   int
+}
+
+func (s *stub) Tick(ctx *Context) Status {
+	status := s.statuses[min(s.ticks, len(s.statuses)-1)]
+	s.ticks++
+	return status
+}
+
+func (s *stub) Reset()           { s.resets++ }
+func (s *stub) Label() string    { return "stub" }
+func (s *stub) Children() []Node { return nil }
+
+func TestNodes(t *testing.T) {
+	S, F, R := Success, Failure, Running
+
+	cases := []struct {
+		name     string
+		children [][]Status // Statuses of each child stub, one per tick
+		root     func(children []Node) Node
+		want     []Status // Status of the tree on each tick
+		ticks    []int    // Ticks of each child after all tree ticks
+	}{
+		{
+			name:     "sequence succeeds",
+			children: [][]Status{{S}, {S}},
+			root:     func(c []Node) Node { return Sequence("", c...) },
+			want:     []Status{S},
+			ticks:    []int{1, 1},
+		},
+		{
+			name:     "sequence stops on failure",
+			children: [][]Status{{F}, {S}},
+			root:     func(c []Node) Node { return Sequence("", c...) },
+			want:     []Status{F},
+			ticks:    []int{1, 0},
+		},
+		{
+			name:     "sequence resumes running child",
+			children: [][]Status{{S}, {R, S}},
+			root:     func(c []Node) Node { return Sequence("", c...) },
+			want:     []Status{R, S},
+			ticks:    []int{1, 2},
+		},
+		{
+			name:     "sequence restarts after failure",
+			children: [][]Status{{S}, {F, S}},
+			root:     func(c []Node) Node { return Sequence("", c...) },
+			want:     []Status{F, S},
+			ticks:    []int{2, 2},
+		},
+		{
+			name:     "selector stops on success",
+			children: [][]Status{{S}, {S}},
+			root:     func(c []Node) Node { return Selector("", c...) },
+			want:     []Status{S},
+			ticks:    []int{1, 0},
+		},
+		{
+			name:     "selector fails when all fail",
+			children: [][]Status{{F}, {F}},
+			root:     func(c []Node) Node { return Selector("", c...) },
+			want:     []Status{F},
+			ticks:    []int{1, 1},
+		},
+		{
+			name:     "selector resumes running child",
+			children: [][]Status{{F}, {R, F}},
+			root:     func(c []Node) Node { return Selector("", c...) },
+			want:     []Status{R, F},
+			ticks:    []int{1, 2},
+		},
+		{
+			name:     "parallel waits for all",
+			children: [][]Status{{S}, {R, S}},
+			root:     func(c []Node) Node { return Parallel("", 0, c...) },
+			want:     []Status{R, S},
+			ticks:    []int{1, 2},
+		},
+		{
+			name:     "parallel fails when all can't succeed",
+			children: [][]Status{{R}, {F}},
+			root:     func(c []Node) Node { return Parallel("", 0, c...) },
+			want:     []Status{F},
+			ticks:    []int{1, 1},
+		},
+		{
+			name:     "parallel succeeds with required children",
+			children: [][]Status{{R}, {S}},
+			root:     func(c []Node) Node { return Parallel("", 1, c...) },
+			want:     []Status{S},
+			ticks:    []int{1, 1},
+		},
+		{
+			name:     "parallel keeps running while required can succeed",
+			children: [][]Status{{R, R, S}, {F}},
+			root:     func(c []Node) Node { return Parallel("", 1, c...) },
+			want:     []Status{R, R, S},
+			ticks:    []int{3, 1},
+		},
+		{
+			name:     "inverter",
+			children: [][]Status{{S, F, R}},
+			root:     func(c []Node) Node { return Inverter("", c[0]) },
+			want:     []Status{F, S, R},
+		},
+		{
+			name:     "succeeder",
+			children: [][]Status{{S, F, R}},
+			root:     func(c []Node) Node { return Succeeder("", c[0]) },
+			want:     []Status{S, S, R},
+		},
+		{
+			name:     "repeat counts successes",
+			children: [][]Status{{S, R, S, S}},
+			root:     func(c []Node) Node { return Repeat("", 3, c[0]) },
+			want:     []Status{R, R, R, S},
+		},
+		{
+			name:     "repeat fails with child",
+			children: [][]Status{{S, F}},
+			root:     func(c []Node) Node { return Repeat("", 3, c[0]) },
+			want:     []Status{R, F},
+		},
+		{
+			name:     "until failure",
+			children: [][]Status{{S, R, F}},
+			root:     func(c []Node) Node { return UntilFailure("", c[0]) },
+			want:     []Status{R, R, S},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stubs := make([]*stub, len(tc.children))
+			nodes := make([]Node, len(tc.children))
+			for i, statuses := range tc.children {
+				stubs[i] = &stub{statuses: statuses}
+				nodes[i] = stubs[i]
+			}
+
+			tree := NewTree(tc.name, tc.root(nodes), 1)
+			for tick, want := range tc.want {
+				if got := tree.Tick(nil, 0.1); got != want {
+					t.Fatalf("tick %d: got %v, want %v", tick, got, want)
+				}
+			}
+			for i, want := range tc.ticks {
+				if stubs[i].ticks != want {
+					t.Errorf("child %d ticked %d times, want %d", i, stubs[i].ticks, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	child := &stub{statuses: []Status{Success}}
+	tree := NewTree("cooldown", Cooldown("", 1, child), 1)
+
+	want := []Status{Success, Failure, Failure, Failure, Success}
+	for tick, status := range want {
+		if got := tree.Tick(nil, 0.3); got != status {
+			t.Fatalf("tick %d: got %v, want %v", tick, got, status)
+		}
+	}
+	if child.ticks != 2 {
+		t.Errorf("child ticked %d times during cooldown, want 2", child.ticks)
+	}
+}
+
+func TestTimeoutResetsChild(t *testing.T) {
+	child := &stub{statuses: []Status{Running}}
+	tree := NewTree("timeout", Timeout("", 0.25, child), 1)
+
+	want := []Status{Running, Running, Running, Failure, Running}
+	for tick, status := range want {
+		if got := tree.Tick(nil, 0.1); got != status {
+			t.Fatalf("tick %d: got %v, want %v", tick, got, status)
+		}
+	}
+	if child.resets != 1 {
+		t.Errorf("child reset %d times, want 1", child.resets)
+	}
+}
+
+func TestParallelResetsRunningChildren(t *testing.T) {
+	running := &stub{statuses: []Status{Running}}
+	failing := &stub{statuses: []Status{Failure}}
+	tree := NewTree("parallel", Parallel("", 0, running, failing), 1)
+
+	if got := tree.Tick(nil, 0.1); got != Failure {
+		t.Fatalf("got %v, want failure", got)
+	}
+	if running.resets != 1 {
+		t.Errorf("running child reset %d times, want 1", running.resets)
+	}
+}
+
+func TestReset(t *testing.T) {
+	cases := []struct {
+		name string
+		root func() Node
+		want []Status // Statuses after Tree.Reset
+	}{
+		{
+			name: "wait starts over",
+			root: func() Node { return Wait(0.15) },
+			want: []Status{Running, Running, Success},
+		},
+		{
+			name: "repeat forgets its count",
+			root: func() Node { return Repeat("", 3, &stub{statuses: []Status{Success}}) },
+			want: []Status{Running, Running, Success},
+		},
+		{
+			name: "sequence starts from the first child",
+			root: func() Node {
+				return Sequence("", &stub{statuses: []Status{Success}}, Wait(0.15))
+			},
+			want: []Status{Running, Running, Success},
+		},
+		{
+			name: "cooldown keeps cooling down",
+			root: func() Node {
+				return Cooldown("", 10, &stub{statuses: []Status{Running, Success, Success}})
+			},
+			// The child succeeded before the reset: still cooling down
+			want: []Status{Failure},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tree := NewTree(tc.name, tc.root(), 1)
+			tree.Tick(nil, 0.1)
+			tree.Tick(nil, 0.1)
+			tree.Reset()
+			if tree.Status() != Invalid {
+				t.Errorf("status after reset: %v", tree.Status())
+			}
+
+			for tick, want := range tc.want {
+				if got := tree.Tick(nil, 0.1); got != want {
+					t.Fatalf("tick %d after reset: got %v, want %v", tick, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package behavior
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Registry holds the conditions and actions trees can refer to by name.
+// The game registers what its entities can sense and do; trees from mod
+// data combine them
+type Registry struct {
+	conditions map[string]ConditionFunc
+	actions    map[string]ActionFunc
+}
+
+// NewRegistry creates a registry with the built-in conditions and actions:
+//
+//	condition blackboard  key, and one of equals / above / below (without
+//	                      them - the key is set and not false)
+//	condition chance      per_second: average successes per second (a rate,
+//	                      the chance per tick is 1-exp(-rate*dt)), or
+//	                      probability: 0..1 chance on every tick
+//	action    set         key, value: writes the blackboard
+//	action    clear       key: removes the key from the blackboard
+func NewRegistry() *Registry {
+	r := &Registry{
+		conditions: make(map[string]ConditionFunc),
+		actions:    make(map[string]ActionFunc),
+	}
+	r.RegisterCondition("blackboard", checkBlackboard)
+	r.RegisterCondition("chance", func(ctx *Context, params Params) bool {
+		// A one-off check, e.g. on entering a branch that runs for one tick
+		if probability, ok := toFloat(params["probability"]); ok {
+			return ctx.Rand.Float64() < probability
+		}
+		// Per tick from the rate, so the chance doesn't depend on the tick rate
+		perTick := 1 - math.Exp(-math.Max(0, params.Float("per_second", 0))*ctx.DeltaTime)
+		return ctx.Rand.Float64() < perTick
+	})
+	r.RegisterAction("set", func(ctx *Context, params Params) Status {
+		ctx.Blackboard[params.String("key", "")] = params["value"]
+		return Success
+	})
+	r.RegisterAction("clear", func(ctx *Context, params Params) Status {
+		delete(ctx.Blackboard, params.String("key", ""))
+		return Success
+	})
+	return r
+}
+
+// RegisterCondition adds a condition, replacing one with the same name
+func (r *Registry) RegisterCondition(name string, check ConditionFunc) {
+	r.conditions[name] = check
+}
+
+// RegisterAction adds an action, replacing one with the same name
+func (r *Registry) RegisterAction(name string, run ActionFunc) {
+	r.actions[name] = run
+}
+
+// Condition returns a condition node for the registered check
+func (r *Registry) Condition(name string, params Params) (Node, error) {
+	check, ok := r.conditions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown condition %q (available: %v)", name, sortedNames(r.conditions))
+	}
+	return Condition(name, params, check), nil
+}
+
+// Action returns an action node for the registered function
+func (r *Registry) Action(name string, params Params) (Node, error) {
+	run, ok := r.actions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown action %q (available: %v)", name, sortedNames(r.actions))
+	}
+	return Action(name, params, run), nil
+}
+
+// checkBlackboard compares a blackboard value with the node's params
+func checkBlackboard(ctx *Context, params Params) bool {
+	key := params.String("key", "")
+	value, ok := ctx.Blackboard[key]
+	if !ok {
+		return false
+	}
+
+	if expected, ok := params["equals"]; ok {
+		if number, isNumber := toFloat(expected); isNumber {
+			actual, ok := toFloat(value)
+			return ok && actual == number
+		}
+		return value == expected
+	}
+
+	number, isNumber := toFloat(value)
+	if above, ok := toFloat(params["above"]); ok && !(isNumber && number > above) {
+		return false
+	}
+	if below, ok := toFloat(params["below"]); ok && !(isNumber && number < below) {
+		return false
+	}
+	return value != false
+}
+
+// sortedNames lists the names of a registry map for error messages
+func sortedNames[F any](m map[string]F) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,47 @@
+package behavior
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChance(t *testing.T) {
+	const ticks = 20000
+
+	cases := []struct {
+		name      string
+		params    Params
+		deltaTime float64
+		want      float64 // Expected share of ticks that succeed
+	}{
+		{"never", Params{"per_second": 0}, 0.1, 0},
+		{"rate", Params{"per_second": 2}, 0.01, 1 - math.Exp(-0.02)},
+		{"high rate is not certain", Params{"per_second": 30}, 1.0 / 60, 1 - math.Exp(-0.5)},
+		{"probability", Params{"probability": 0.4}, 0.01, 0.4},
+		{"certain", Params{"probability": 1}, 0.01, 1},
+	}
+
+	registry := NewRegistry()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node, err := registry.Condition("chance", tc.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tree := NewTree(tc.name, node, 1)
+			succeeded := 0
+			for i := 0; i < ticks; i++ {
+				if tree.Tick(nil, tc.deltaTime) == Success {
+					succeeded++
+				}
+			}
+
+			share := float64(succeeded) / ticks
+			tolerance := 4 * math.Sqrt(tc.want*(1-tc.want)/ticks)
+			if math.Abs(share-tc.want) > tolerance {
+				t.Errorf("succeeded on %.4f of ticks, want %.4f", share, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package behavior
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// Tree is an instance of a behavior tree with its own blackboard and node
+// state. Every entity needs its own instance: build one per entity from a
+// Definition or from Go constructors
+type Tree struct {
+	Name       string
+	Blackboard Blackboard
+
+	root   Node
+	rng    *rand.Rand
+	time   float64
+	status Status
+
+	// Statuses of the nodes ticked on the last tick, for debugging
+	ticked     map[Node]Status
+	activePath []Node
+	lastPath   []Node
+}
+
+// NewTree creates a tree around the root node. The seed makes the tree's
+// randomness (Context.Rand) reproducible
+func NewTree(name string, root Node, seed int64) *Tree {
+	return &Tree{
+		Name:       name,
+		Blackboard: make(Blackboard),
+		root:       root,
+		rng:        rand.New(rand.NewSource(seed)),
+		ticked:     make(map[Node]Status),
+	}
+}
+
+// Tick advances the tree by deltaTime seconds, deciding for subject
+func (t *Tree) Tick(subject interface{}, deltaTime float64) Status {
+	t.time += deltaTime
+	for node := range t.ticked {
+		delete(t.ticked, node)
+	}
+	t.activePath, t.lastPath = t.activePath[:0], t.lastPath[:0]
+
+	ctx := &Context{
+		Subject:    subject,
+		Blackboard: t.Blackboard,
+		DeltaTime:  deltaTime,
+		Time:       t.time,
+		Rand:       t.rng,
+		tree:       t,
+	}
+	t.status = ctx.Run(t.root)
+	return t.status
+}
+
+// Reset aborts everything running; the blackboard is kept
+func (t *Tree) Reset() {
+	t.root.Reset()
+	t.status = Invalid
+}
+
+// Status returns the result of the last tick
+func (t *Tree) Status() Status {
+	return t.status
+}
+
+// record remembers the status of the node on top of the stack. The active
+// path leads to the first running node that ticked no children (a leaf,
+// or e.g. a cooldown that is not ready), or else to the last such node
+func (t *Tree) record(stack []Node, status Status, deepest bool) {
+	t.ticked[stack[len(stack)-1]] = status
+	if !deepest {
+		return
+	}
+
+	t.lastPath = append(t.lastPath[:0], stack...)
+	if status == Running && len(t.activePath) == 0 {
+		t.activePath = append(t.activePath, stack...)
+	}
+}
+
+// ActivePath returns the labels of the nodes from the root to the node
+// that decided the last tick: the running leaf, or else the last node ticked
+func (t *Tree) ActivePath() []string {
+	path := t.activePath
+	if len(path) == 0 {
+		path = t.lastPath
+	}
+
+	labels := make([]string, len(path))
+	for i, node := range path {
+		labels[i] = node.Label()
+	}
+	return labels
+}
+
+// ActivePathString returns the active path joined with " > "
+func (t *Tree) ActivePathString() string {
+	return strings.Join(t.ActivePath(), " > ")
+}
+
+// Dump prints the tree with the status of every node ticked on the last
+// tick; the active path is marked with "*"
+func (t *Tree) Dump() string {
+	active := make(map[Node]bool)
+	path := t.activePath
+	if len(path) == 0 {
+		path = t.lastPath
+	}
+	for _, node := range path {
+		active[node] = true
+	}
+
+	var sb strings.Builder
+	sb.WriteString(t.Name + " [" + t.status.String() + "]\n")
+	t.dumpNode(&sb, t.root, 1, active)
+	return sb.String()
+}
+
+// dumpNode prints a node and its children
+func (t *Tree) dumpNode(sb *strings.Builder, node Node, depth int, active map[Node]bool) {
+	marker := "  "
+	if active[node] {
+		marker = "* "
+	}
+	sb.WriteString(strings.Repeat("  ", depth-1) + marker + node.Label())
+	if status, ok := t.ticked[node]; ok {
+		sb.WriteString(" [" + status.String() + "]")
+	}
+	sb.WriteString("\n")
+
+	for _, child := range node.Children() {
+		t.dumpNode(sb, child, depth+1, active)
+	}
+}
@@ -15,7 +15,12 @@ type Config struct {
 	Renderer   RendererConfig   `yaml:"renderer"`
 	Procedural ProceduralConfig `yaml:"procedural"`
 	AI         AIConfig         `yaml:"ai"`
+	Sanity     SanityConfig     `yaml:"sanity"`
+	Objectives ObjectivesConfig `yaml:"objectives"`
 	Mods       ModsConfig       `yaml:"mods"`
+	Camera     CameraConfig     `yaml:"camera"`
+	Controls   ControlsConfig   `yaml:"controls"`
+	Gamepad    GamepadConfig    `yaml:"gamepad"`
 }
 
 // GraphicsConfig contains graphics-related configuration
@@ -59,16 +64,36 @@ type ProceduralConfig struct {
 	TreeDensity float64 `yaml:"tree_density"`
 	RockDensity float64 `yaml:"rock_density"`
 	DetailLevel int     `yaml:"detail_level"`
-	Seed        int64   `yaml:"seed"` // Optional: 0 means random
+	Seed        int64   `yaml:"seed"`       // Optional: 0 means random
+	Caves       bool    `yaml:"caves"`      // Carve caves with a 3D density field into mountain slopes
+	CaveCount   int     `yaml:"cave_count"` // Maximum number of caves
+
+	GazeAwareEvolution bool    `yaml:"gaze_aware_evolution"` // Objects move, appear, vanish and turn only while the player isn't looking
+	GazeSafeDistance   float64 `yaml:"gaze_safe_distance"`   // ...and only this far from the player
 }
 
 // AIConfig contains AI-related configuration
 type AIConfig struct {
 	Enabled          bool    `yaml:"enabled"`
-	Difficulty       float64 `yaml:"difficulty"` // 0.0-1.0
-	AdaptationRate   float64 `yaml:"adaptation_rate"`
-	MicEnabled       bool    `yaml:"mic_enabled"`
-	BehaviorAnalysis bool    `yaml:"behavior_analysis"`
+	Difficulty       float64 `yaml:"difficulty"`        // 0.0-1.0
+	AdaptationRate   float64 `yaml:"adaptation_rate"`   // How fast the AI director follows the player's stress (per second)
+	MicEnabled       bool    `yaml:"mic_enabled"`       // Let the AI director hear the player's voice through the microphone
+	BehaviorAnalysis bool    `yaml:"behavior_analysis"` // Learn how the player reacts to scares and build a fear profile
+	ProfilePath      string  `yaml:"profile_path"`      // Where the fear profile is kept between sessions
+}
+
+// SanityConfig contains settings of the player's sanity
+type SanityConfig struct {
+	Enabled        bool    `yaml:"enabled"`
+	DrainRate      float64 `yaml:"drain_rate"`     // Multiplier for how fast darkness, strange objects, scares and isolation wear sanity down
+	RestoreRate    float64 `yaml:"restore_rate"`   // Multiplier for how fast clearings and light restore it
+	Hallucinations bool    `yaml:"hallucinations"` // Show objects, figures and footsteps that exist only for the player
+}
+
+// ObjectivesConfig contains settings of the goals generated for each world
+type ObjectivesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Notes   int  `yaml:"notes"` // How many notes to find near structures
 }
 
 // ModsConfig contains mod-related configuration
@@ -78,6 +103,29 @@ type ModsConfig struct {
 	EnabledMods []string `yaml:"enabled_mods"`
 }
 
+// CameraConfig contains mouse look and camera motion settings
+type CameraConfig struct {
+	MouseSensitivity float64 `yaml:"mouse_sensitivity"` // Multiplier for mouse look speed
+	InvertY          bool    `yaml:"invert_y"`          // Moving the mouse up looks down
+	CaptureCursor    bool    `yaml:"capture_cursor"`    // Hide and lock the cursor for mouse look
+	KeyTurnSpeed     float64 `yaml:"key_turn_speed"`    // Arrow key and right stick turning speed (radians per second)
+	MaxPitch         float64 `yaml:"max_pitch"`         // How far up and down the player can look (degrees)
+	HeadBob          float64 `yaml:"head_bob"`          // Head bob strength, 0 disables
+	FearShake        float64 `yaml:"fear_shake"`        // Camera shake strength at high fear, 0 disables
+}
+
+// ControlsConfig maps action names (move_forward, jump, ...) to one or more
+// bindings ("w", "left_shift", "mouse_right", "gamepad_a"). Actions that are
+// not listed keep their default bindings
+type ControlsConfig map[string][]string
+
+// GamepadConfig contains gamepad settings
+type GamepadConfig struct {
+	Enabled  bool    `yaml:"enabled"`  // Read the first connected gamepad
+	Deadzone float64 `yaml:"deadzone"` // Stick deflection ignored around the center (0..1)
+	Rumble   bool    `yaml:"rumble"`   // Let scares and hard landings rumble the gamepad where supported
+}
+
 // MetadataConfig represents the hierarchical configuration for metadata
 type MetadataConfig struct {
 	Atmosphere struct {
@@ -139,6 +187,11 @@ func DefaultConfig() *Config {
 			RockDensity: 3.0,
 			DetailLevel: 3,
 			Seed:        0, // Random seed
+			Caves:       true,
+			CaveCount:   3,
+
+			GazeAwareEvolution: true,
+			GazeSafeDistance:   15,
 		},
 		AI: AIConfig{
 			Enabled:          true,
@@ -146,41 +199,56 @@ func DefaultConfig() *Config {
 			AdaptationRate:   0.2,
 			MicEnabled:       true,
 			BehaviorAnalysis: true,
+			ProfilePath:      "fear_profile.yaml",
+		},
+		Sanity: SanityConfig{
+			Enabled:        true,
+			DrainRate:      1.0,
+			RestoreRate:    1.0,
+			Hallucinations: true,
+		},
+		Objectives: ObjectivesConfig{
+			Enabled: true,
+			Notes:   5,
 		},
 		Mods: ModsConfig{
 			Enabled:     true,
 			ModsFolder:  "mods",
 			EnabledMods: []string{},
 		},
+		Camera: CameraConfig{
+			MouseSensitivity: 1.0,
+			InvertY:          false,
+			CaptureCursor:    true,
+			KeyTurnSpeed:     2.0,
+			MaxPitch:         80.0,
+			HeadBob:          1.0,
+			FearShake:        1.0,
+		},
+		Gamepad: GamepadConfig{
+			Enabled:  true,
+			Deadzone: 0.2,
+			Rumble:   true,
+		},
 	}
 }
 
 // LoadConfig loads the configuration from a file
 func LoadConfig(filePath string) (*Config, error) {
-	// Create default config
-	config := DefaultConfig()
-
 	// Read file
 	data, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		return config, fmt.Errorf("config file not found, using defaults: %v", err)
+		return DefaultConfig(), fmt.Errorf("config file not found, using defaults: %v", err)
 	}
 
-	// Parse YAML
-	err = yaml.Unmarshal(data, config)
-	if err != nil {
-		return config, fmt.Errorf("error parsing config: %v", err)
-	}
-
-	return config, nil
+	return Decode(data)
 }
 
 // SaveConfig saves the configuration to a file
 func SaveConfig(config *Config, filePath string) error {
-	// Convert to YAML
-	data, err := yaml.Marshal(config)
+	data, err := Encode(config)
 	if err != nil {
-		return fmt.Errorf("error serializing config: %v", err)
+		return err
 	}
 
 	// Write file
@@ -192,6 +260,27 @@ func SaveConfig(config *Config, filePath string) error {
 	return nil
 }
 
+// Encode serializes the configuration to YAML
+func Encode(config *Config) ([]byte, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing config: %v", err)
+	}
+	return data, nil
+}
+
+// Decode parses YAML over the default configuration, so missing settings keep their defaults
+func Decode(data []byte) (*Config, error) {
+	config := DefaultConfig()
+
+	err := yaml.Unmarshal(data, config)
+	if err != nil {
+		return config, fmt.Errorf("error parsing config: %v", err)
+	}
+
+	return config, nil
+}
+
 // FlattenMetadata converts hierarchical metadata to a flat map
 func FlattenMetadata(metadata *MetadataConfig) map[string]float64 {
 	result := make(map[string]float64)
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// seedAlphabet - алфавит Crockford base32: без I, L, O и U, чтобы коды было легко диктовать
+const seedAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// seedCodeLength - количество символов base32 для 64-битного сида
+const seedCodeLength = 13
+
+// FormatSeed converts a world seed to a shareable code like "0DX4-7KQ9-MZ2B1"
+func FormatSeed(seed int64) string {
+	value := uint64(seed)
+
+	var digits [seedCodeLength]byte
+	for i := seedCodeLength - 1; i >= 0; i-- {
+		digits[i] = seedAlphabet[value&31]
+		value >>= 5
+	}
+
+	return string(digits[0:4]) + "-" + string(digits[4:8]) + "-" + string(digits[8:])
+}
+
+// ParseSeed converts a seed code produced by FormatSeed back to the world seed.
+// Codes are case-insensitive, dashes and spaces are ignored, and the commonly
+// confused letters I/L and O are read as 1 and 0. Plain decimal numbers are
+// accepted as raw seeds, except 13-digit ones: those are codes typed without
+// dashes. A leading sign applies to both forms
+func ParseSeed(code string) (int64, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return 0, fmt.Errorf("empty seed code")
+	}
+
+	// Знак снимается до выбора формата, иначе "-42" читается как код base32
+	digits, negative := code, false
+	if digits[0] == '-' || digits[0] == '+' {
+		digits, negative = strings.TrimSpace(digits[1:]), digits[0] == '-'
+	}
+
+	// Обычное число без дефисов и букв - это "сырой" сид, если оно не может
+	// быть кодом, набранным без дефисов
+	if !strings.ContainsAny(digits, "-") && len(digits) != seedCodeLength {
+		if seed, err := strconv.ParseUint(digits, 10, 64); err == nil {
+			switch {
+			case negative && seed <= 1<<63:
+				return int64(-seed), nil
+			case !negative && seed <= math.MaxInt64:
+				return int64(seed), nil
+			}
+			return 0, fmt.Errorf("invalid seed code %q: value out of range", code)
+		} else if errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("invalid seed code %q: value out of range", code)
+		}
+	}
+
+	value, err := parseSeedCode(digits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid seed code %q: %w", code, err)
+	}
+
+	if negative {
+		return -int64(value), nil
+	}
+	return int64(value), nil
+}
+
+// parseSeedCode разбирает код base32 без знака
+func parseSeedCode(code string) (uint64, error) {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(code))
+	if len(normalized) == 0 || len(normalized) > seedCodeLength {
+		return 0, fmt.Errorf("expected up to %d characters", seedCodeLength)
+	}
+
+	var value uint64
+	for i, r := range normalized {
+		switch r {
+		case 'I', 'L':
+			r = '1'
+		case 'O':
+			r = '0'
+		}
+
+		digit := strings.IndexRune(seedAlphabet, r)
+		if digit < 0 {
+			return 0, fmt.Errorf("unexpected character %q", r)
+		}
+
+		// Первый символ из 13 несет только 4 старших бита
+		if i == 0 && len(normalized) == seedCodeLength && digit > 15 {
+			return 0, fmt.Errorf("value out of range")
+		}
+
+		value = value<<5 | uint64(digit)
+	}
+
+	return value, nil
+}
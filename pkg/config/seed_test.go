@@ -0,0 +1,68 @@
+package config
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseSeedRoundTrip(t *testing.T) {
+	seeds := []int64{0, 1, 42, -1, -42, math.MaxInt64, math.MinInt64}
+
+	for _, seed := range seeds {
+		code := FormatSeed(seed)
+		got, err := ParseSeed(code)
+		if err != nil {
+			t.Errorf("ParseSeed(%q): %v", code, err)
+			continue
+		}
+		if got != seed {
+			t.Errorf("ParseSeed(FormatSeed(%d)) = %d (code %q)", seed, got, code)
+		}
+	}
+}
+
+func TestParseSeed(t *testing.T) {
+	cases := []struct {
+		code    string
+		want    int64
+		wantErr bool
+	}{
+		{code: "42", want: 42},
+		{code: "-42", want: -42},
+		{code: "+42", want: 42},
+		{code: " -42 ", want: -42},
+		{code: "9223372036854775807", want: math.MaxInt64},
+		{code: "-9223372036854775808", want: math.MinInt64},
+		{code: "0000-0000-0001A", want: 42},
+		{code: "oooo-oooo-ooo1a", want: 42},
+		{code: "-0000-0000-0001A", want: -42},
+		{code: "0000000000010", want: 32},
+		{code: "0000-0000-00010", want: 32},
+		{code: "000000000010", want: 10},
+		{code: "9223372036854775808", wantErr: true},
+		{code: "-9223372036854775809", wantErr: true},
+		{code: "", wantErr: true},
+		{code: "-", wantErr: true},
+		{code: "0000-0000-000U", wantErr: true},
+		{code: "12#4", wantErr: true},
+		{code: "ZZZZ-ZZZZ-ZZZZZ", wantErr: true},
+		{code: "0000-0000-0000-00", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		got, err := ParseSeed(tc.code)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseSeed(%q) = %d, want error", tc.code, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSeed(%q): %v", tc.code, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseSeed(%q) = %d, want %d", tc.code, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// Action - именованное игровое действие, к которому привязываются клавиши,
+// кнопки мыши и геймпада (секция controls в config.yaml)
+type Action string
+
+// Игровые действия
+const (
+	ActionMoveForward          Action = "move_forward"
+	ActionMoveBackward         Action = "move_backward"
+	ActionMoveLeft             Action = "move_left"
+	ActionMoveRight            Action = "move_right"
+	ActionTurnLeft             Action = "turn_left"
+	ActionTurnRight            Action = "turn_right"
+	ActionLookUp               Action = "look_up"
+	ActionLookDown             Action = "look_down"
+	ActionSprint               Action = "sprint"
+	ActionCrouch               Action = "crouch"
+	ActionJump                 Action = "jump"
+	ActionInteract             Action = "interact"
+	ActionTogglePostProcessing Action = "toggle_post_processing"
+	ActionPixelSizeDown        Action = "pixel_size_down"
+	ActionPixelSizeUp          Action = "pixel_size_up"
+	ActionToggleMute           Action = "toggle_mute"
+	ActionReleaseCursor        Action = "release_cursor"
+	ActionDumpBehaviors        Action = "dump_behaviors"
+	ActionQuit                 Action = "quit"
+)
+
+// DefaultBindings - раскладка по умолчанию. Имена клавиш обозначают физическое
+// положение клавиши на американской QWERTY, поэтому на AZERTY "w a s d" - это ZQSD
+var DefaultBindings = map[Action][]string{
+	ActionMoveForward:          {"w", "gamepad_left_stick_up"},
+	ActionMoveBackward:         {"s", "gamepad_left_stick_down"},
+	ActionMoveLeft:             {"a", "gamepad_left_stick_left"},
+	ActionMoveRight:            {"d", "gamepad_left_stick_right"},
+	ActionTurnLeft:             {"left", "gamepad_right_stick_left"},
+	ActionTurnRight:            {"right", "gamepad_right_stick_right"},
+	ActionLookUp:               {"up", "gamepad_right_stick_up"},
+	ActionLookDown:             {"down", "gamepad_right_stick_down"},
+	ActionSprint:               {"left_shift", "gamepad_left_trigger"},
+	ActionCrouch:               {"left_control", "c", "gamepad_b"},
+	ActionJump:                 {"space", "gamepad_a"},
+	ActionInteract:             {"e", "gamepad_x"},
+	ActionTogglePostProcessing: {"p"},
+	ActionPixelSizeDown:        {"equal", "kp_add"},
+	ActionPixelSizeUp:          {"minus", "kp_subtract"},
+	ActionToggleMute:           {"m"},
+	ActionReleaseCursor:        {"tab"},
+	ActionDumpBehaviors:        {"f3"},
+	ActionQuit:                 {"escape"},
+}
+
+// bindingKind - источник ввода привязки
+type bindingKind int
+
+const (
+	bindingKey bindingKind = iota
+	bindingMouseButton
+	bindingGamepadButton
+	bindingGamepadAxis
+)
+
+// analogPressThreshold - насколько отклонен стик или нажат курок, чтобы
+// действие считалось нажатым (ActionDown, ActionPressed)
+const analogPressThreshold = 0.5
+
+// Binding - одна привязка действия к клавише, кнопке мыши или геймпада
+type Binding struct {
+	Name          string
+	kind          bindingKind
+	key           glfw.Key
+	mouseButton   glfw.MouseButton
+	gamepadButton glfw.GamepadButton
+	gamepadAxis   glfw.GamepadAxis
+	axisSign      float64 // Направление стика: -1 или 1 (у курков 1)
+}
+
+// bindingNames сопоставляет имена из конфига с кодами GLFW
+var bindingNames = buildBindingNames()
+
+func buildBindingNames() map[string]Binding {
+	names := make(map[string]Binding)
+	key := func(name string, k glfw.Key) {
+		names[name] = Binding{Name: name, kind: bindingKey, key: k}
+	}
+
+	for i := 0; i < 26; i++ {
+		key(string(rune('a'+i)), glfw.KeyA+glfw.Key(i))
+	}
+	for i := 0; i < 10; i++ {
+		key(fmt.Sprintf("%d", i), glfw.Key0+glfw.Key(i))
+		key(fmt.Sprintf("kp_%d", i), glfw.KeyKP0+glfw.Key(i))
+	}
+	for i := 0; i < 12; i++ {
+		key(fmt.Sprintf("f%d", i+1), glfw.KeyF1+glfw.Key(i))
+	}
+
+	for name, k := range map[string]glfw.Key{
+		"space": glfw.KeySpace, "escape": glfw.KeyEscape, "enter": glfw.KeyEnter,
+		"tab": glfw.KeyTab, "backspace": glfw.KeyBackspace,
+		"insert": glfw.KeyInsert, "delete": glfw.KeyDelete,
+		"home": glfw.KeyHome, "end": glfw.KeyEnd,
+		"page_up": glfw.KeyPageUp, "page_down": glfw.KeyPageDown,
+		"up": glfw.KeyUp, "down": glfw.KeyDown, "left": glfw.KeyLeft, "right": glfw.KeyRight,
+		"left_shift": glfw.KeyLeftShift, "right_shift": glfw.KeyRightShift,
+		"left_control": glfw.KeyLeftControl, "right_control": glfw.KeyRightControl,
+		"left_alt": glfw.KeyLeftAlt, "right_alt": glfw.KeyRightAlt,
+		"minus": glfw.KeyMinus, "equal": glfw.KeyEqual,
+		"comma": glfw.KeyComma, "period": glfw.KeyPeriod, "slash": glfw.KeySlash,
+		"semicolon": glfw.KeySemicolon, "apostrophe": glfw.KeyApostrophe,
+		"left_bracket": glfw.KeyLeftBracket, "right_bracket": glfw.KeyRightBracket,
+		"backslash": glfw.KeyBackslash, "grave": glfw.KeyGraveAccent,
+		"kp_add": glfw.KeyKPAdd, "kp_subtract": glfw.KeyKPSubtract,
+		"kp_multiply": glfw.KeyKPMultiply, "kp_divide": glfw.KeyKPDivide,
+		"kp_decimal": glfw.KeyKPDecimal, "kp_enter": glfw.KeyKPEnter,
+	} {
+		key(name, k)
+	}
+
+	for name, b := range map[string]glfw.MouseButton{
+		"mouse_left": glfw.MouseButtonLeft, "mouse_right": glfw.MouseButtonRight,
+		"mouse_middle": glfw.MouseButtonMiddle,
+		"mouse_4":      glfw.MouseButton4, "mouse_5": glfw.MouseButton5,
+	} {
+		names[name] = Binding{Name: name, kind: bindingMouseButton, mouseButton: b}
+	}
+
+	for name, b := range map[string]glfw.GamepadButton{
+		"gamepad_a": glfw.ButtonA, "gamepad_b": glfw.ButtonB,
+		"gamepad_x": glfw.ButtonX, "gamepad_y": glfw.ButtonY,
+		"gamepad_left_bumper": glfw.ButtonLeftBumper, "gamepad_right_bumper": glfw.ButtonRightBumper,
+		"gamepad_back": glfw.ButtonBack, "gamepad_start": glfw.ButtonStart, "gamepad_guide": glfw.ButtonGuide,
+		"gamepad_left_thumb": glfw.ButtonLeftThumb, "gamepad_right_thumb": glfw.ButtonRightThumb,
+		"gamepad_dpad_up": glfw.ButtonDpadUp, "gamepad_dpad_right": glfw.ButtonDpadRight,
+		"gamepad_dpad_down": glfw.ButtonDpadDown, "gamepad_dpad_left": glfw.ButtonDpadLeft,
+	} {
+		names[name] = Binding{Name: name, kind: bindingGamepadButton, gamepadButton: b}
+	}
+
+	axis := func(name string, a glfw.GamepadAxis, sign float64) {
+		names[name] = Binding{Name: name, kind: bindingGamepadAxis, gamepadAxis: a, axisSign: sign}
+	}
+	axis("gamepad_left_trigger", glfw.AxisLeftTrigger, 1)
+	axis("gamepad_right_trigger", glfw.AxisRightTrigger, 1)
+	// Ось Y стиков GLFW направлена вниз
+	axis("gamepad_left_stick_up", glfw.AxisLeftY, -1)
+	axis("gamepad_left_stick_down", glfw.AxisLeftY, 1)
+	axis("gamepad_left_stick_left", glfw.AxisLeftX, -1)
+	axis("gamepad_left_stick_right", glfw.AxisLeftX, 1)
+	axis("gamepad_right_stick_up", glfw.AxisRightY, -1)
+	axis("gamepad_right_stick_down", glfw.AxisRightY, 1)
+	axis("gamepad_right_stick_left", glfw.AxisRightX, -1)
+	axis("gamepad_right_stick_right", glfw.AxisRightX, 1)
+
+	return names
+}
+
+// ParseBinding разбирает имя привязки из конфига ("w", "left_shift", "mouse_right", "gamepad_a")
+func ParseBinding(name string) (Binding, error) {
+	binding, ok := bindingNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Binding{}, fmt.Errorf("unknown binding %q", name)
+	}
+	return binding, nil
+}
+
+// ActionMap хранит привязки действий. У действия может быть несколько привязок,
+// оно активно, если активна любая из них
+type ActionMap struct {
+	bindings map[Action][]Binding
+}
+
+// NewActionMap строит карту действий из DefaultBindings, заменяя привязки
+// действий, перечисленных в controls. Неизвестные действия и клавиши
+// пропускаются и возвращаются ошибкой, остальная карта остается рабочей
+func NewActionMap(controls map[string][]string) (*ActionMap, error) {
+	am := &ActionMap{bindings: make(map[Action][]Binding)}
+	var problems []string
+
+	for action, names := range DefaultBindings {
+		am.bind(action, names, &problems)
+	}
+
+	// Сортируем для стабильного порядка сообщений об ошибках
+	actions := make([]string, 0, len(controls))
+	for action := range controls {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	for _, name := range actions {
+		action := Action(name)
+		if _, known := DefaultBindings[action]; !known {
+			problems = append(problems, fmt.Sprintf("unknown action %q", name))
+			continue
+		}
+		am.bind(action, controls[name], &problems)
+	}
+
+	if len(problems) > 0 {
+		return am, fmt.Errorf("controls: %s", strings.Join(problems, "; "))
+	}
+	return am, nil
+}
+
+// bind заменяет привязки действия
+func (am *ActionMap) bind(action Action, names []string, problems *[]string) {
+	bindings := make([]Binding, 0, len(names))
+	for _, name := range names {
+		binding, err := ParseBinding(name)
+		if err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s: %v", action, err))
+			continue
+		}
+		bindings = append(bindings, binding)
+	}
+	am.bindings[action] = bindings
+}
+
+// Bindings возвращает привязки действия
+func (am *ActionMap) Bindings(action Action) []Binding {
+	return am.bindings[action]
+}
+
+// Keys возвращает все клавиши, используемые привязками (их опрашивает InputHandler)
+func (am *ActionMap) Keys() []glfw.Key {
+	seen := make(map[glfw.Key]bool)
+	var keys []glfw.Key
+	for _, bindings := range am.bindings {
+		for _, b := range bindings {
+			if b.kind == bindingKey && !seen[b.key] {
+				seen[b.key] = true
+				keys = append(keys, b.key)
+			}
+		}
+	}
+	return keys
+}
@@ -19,6 +19,7 @@ const (
 	sampleRate      = 44100
 	framesPerBuffer = 1024
 	numChannels     = 2
+	micFullScale    = 0.2 // Mean microphone amplitude treated as shouting
 )
 
 // AudioEngine handles all audio operations
@@ -38,6 +39,7 @@ type AudioEngine struct {
 	activeSounds    map[string]*Sound
 	micEnabled      bool
 	micAnalyzer     *MicrophoneAnalyzer
+	voiceDistortion float32              // 0..1, насколько искажается голос игрока (растет с потерей рассудка)
 	lastEffectTimes map[string]time.Time // Для контроля частоты эффектов
 	effectCooldowns map[string]float64   // Минимальный интервал между эффектами
 	logger          *logger.Logger
@@ -311,7 +313,10 @@ func (ae *AudioEngine) microphoneCallback(in []float32) {
 	avgVolume := sum / float32(len(in))
 
 	// Update analyzer
+	ae.masterMutex.Lock()
 	ae.micAnalyzer.LastVolume = avgVolume
+	distortion := ae.voiceDistortion
+	ae.masterMutex.Unlock()
 
 	// Check if the user is speaking
 	if avgVolume > ae.micAnalyzer.VolumeThreshold {
@@ -349,10 +354,11 @@ func (ae *AudioEngine) microphoneCallback(in []float32) {
 			pitchShiftFactors := []float32{0.7, 0.8, 1.2, 1.5}
 			pitchShiftWeights := []float32{0.3, 0.2, 0.25, 0.15}
 
+			// Чем меньше рассудка, тем громче чужие голоса в хоре
 			for j, factor := range pitchShiftFactors {
 				index := int(float32(i)*factor) % len(in)
 				if index < len(in) {
-					processedSample += in[index] * pitchShiftWeights[j]
+					processedSample += in[index] * pitchShiftWeights[j] * (1 + distortion)
 				}
 			}
 
@@ -372,7 +378,7 @@ func (ae *AudioEngine) microphoneCallback(in []float32) {
 			}
 
 			// Модуляция амплитуды для эффекта "плавающего" звука
-			modulationDepth := 0.3
+			modulationDepth := 0.3 + 0.5*float64(distortion)
 			modulationRate := 3.0 // Гц
 			modulationTime := float64(time.Now().UnixNano()) / 1e9
 			modulation := 1.0 + modulationDepth*math.Sin(2.0*math.Pi*modulationRate*modulationTime)
@@ -450,6 +456,8 @@ func NewAudioEngine(config config.AudioConfig) (*AudioEngine, error) {
 	engine.effectCooldowns["footstep"] = 0.5 // Полсекунды между шагами
 	engine.effectCooldowns["interact"] = 1.0 // 1 секунда между звуками взаимодействия
 	engine.effectCooldowns["voice"] = 15.0   // 15 секунд между голосами
+	engine.effectCooldowns["whisper"] = 8.0  // 8 секунд между шепотами
+	engine.effectCooldowns["phantom"] = 0.4  // Шаги, которых нет, - свой интервал, чтобы их не глушили настоящие
 
 	// Try to initialize microphone
 	if engine.micEnabled {
@@ -579,7 +587,8 @@ func (ae *AudioEngine) Update(deltaTime float64) {
 	// Проверка микрофона и генерация эффектов
 	if ae.micEnabled && ae.micAnalyzer != nil && ae.micAnalyzer.HasSpokenRecently {
 		// Возможность генерации отклика на речь игрока
-		if ae.CanPlayEffect("voice") && rand.Float64() < 0.1*deltaTime { // Вероятность зависит от deltaTime
+		// Вероятность зависит от deltaTime; без рассудка голоса отвечают чаще
+		if ae.CanPlayEffect("voice") && rand.Float64() < 0.1*(1+3*float64(ae.voiceDistortion))*deltaTime {
 			responseMeta := map[string]float64{
 				"atmosphere.fear":      0.7,
 				"atmosphere.tension":   0.8,
@@ -844,6 +853,44 @@ func (ae *AudioEngine) PlayProceduralSound(id string, volume, pan float32, metad
 	ae.PlaySound(id, samples, volume, pan, false, metadata)
 }
 
+// PlayFootstep проигрывает шаг игрока. Звучание зависит от поверхности:
+// surface.hardness и conditions.wetness в metadata
+func (ae *AudioEngine) PlayFootstep(volume, pan float32, metadata map[string]float64) {
+	ae.playPattern("footstep", "footstep", AudioPatternFootstep, 0.3, volume, pan, metadata)
+}
+
+// PlayPhantomFootstep проигрывает шаг, которого нет: его слышит только игрок
+func (ae *AudioEngine) PlayPhantomFootstep(volume, pan float32, metadata map[string]float64) {
+	ae.playPattern("phantom", "phantom_footstep", AudioPatternFootstep, 0.3, volume, pan, metadata)
+}
+
+// PlayWhisper проигрывает шепот, который слышит только игрок
+func (ae *AudioEngine) PlayWhisper(volume, pan float32, metadata map[string]float64) {
+	ae.playPattern("whisper", "whisper", AudioPatternWhisper, 2.0, volume, pan, metadata)
+}
+
+// playPattern генерирует звук по шаблону длиной seconds и проигрывает его
+// под именем id, если эффект effect не на паузе между повторами
+func (ae *AudioEngine) playPattern(effect, id string, pattern AudioPattern, seconds float64, volume, pan float32, metadata map[string]float64) {
+	if !ae.isRunning || !ae.CanPlayEffect(effect) {
+		return
+	}
+	ae.lastEffectTimes[effect] = time.Now()
+
+	generator := NewProceduralAudioGenerator(sampleRate)
+	samples := generator.GenerateAudio(pattern, seconds, metadata, time.Now().UnixNano())
+
+	ae.PlaySound(id, samples, volume, pan, false, metadata)
+}
+
+// SetVoiceDistortion задает, насколько искажать голос игрока из микрофона (0..1)
+func (ae *AudioEngine) SetVoiceDistortion(amount float64) {
+	ae.masterMutex.Lock()
+	defer ae.masterMutex.Unlock()
+
+	ae.voiceDistortion = float32(math.Max(0, math.Min(1, amount)))
+}
+
 // generateProceduralSound generates a procedural sound based on metadata
 func (ae *AudioEngine) generateProceduralSound(seed int64, metadata map[string]float64) []float32 {
 	// Create a local noise generator with the given seed
@@ -1314,6 +1361,18 @@ func (ae *AudioEngine) SetVolume(volume float32) {
 	ae.masterVolume = float32(math.Max(0.0, math.Min(1.0, float64(volume))))
 }
 
+// MicLevel returns the loudness of the microphone input from 0 (silence)
+// to 1 (shouting), or 0 if the microphone is off
+func (ae *AudioEngine) MicLevel() float64 {
+	ae.masterMutex.Lock()
+	defer ae.masterMutex.Unlock()
+
+	if !ae.micEnabled || ae.micAnalyzer == nil {
+		return 0
+	}
+	return math.Min(1, float64(ae.micAnalyzer.LastVolume)/micFullScale)
+}
+
 // Shutdown shuts down the audio engine
 func (ae *AudioEngine) Shutdown() {
 	ae.masterMutex.Lock()
@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"math"
+
+	"nightmare/internal/util"
+)
+
+// Настройки анализа поведения
+const (
+	reactionWindow   = 3.0 // Секунд после события, за которые видна реакция игрока
+	reactionDistance = 1.5 // На сколько метров игрок должен отойти от источника или подойти к нему
+	reactionFreeze   = 1.0 // Замер: игрок шел, а за окно реакции сместился меньше чем на столько, м
+	reactionMoving   = 1.0 // Игрок считается идущим быстрее этого, м/с
+	reactionSpeedUp  = 1.3 // Бегство: игрок ускорился хотя бы во столько раз или побежал
+
+	turnAroundAngle = 2.5 // Поворот за секунду больше этого (рад) - игрок обернулся
+
+	gazeCone    = 0.6  // Полуугол, в котором игрок смотрит на предмет, рад
+	gazeRange   = 20.0 // Странные объекты дальше этого не считаются
+	darkLight   = 0.3  // Освещенность ниже этого - темнота
+	gazeSamples = 4    // Через сколько тиков проверять, на что игрок смотрит
+)
+
+// pendingReaction - событие, на которое игрок еще реагирует
+type pendingReaction struct {
+	kind     DirectorEventKind
+	source   Vector3
+	start    Vector3 // Где был игрок в момент события
+	speed    float64 // С какой скоростью он шел, м/с
+	elapsed  float64
+	sprinted bool // Побежал за время реакции
+}
+
+// BehaviorAnalyzer следит за игроком: куда он смотрит, как часто оборачивается,
+// сколько времени проводит в темноте и как реагирует на события директора
+// (замирает, убегает, идет посмотреть), и пополняет этим профиль страха.
+// Анализ - часть симуляции: профиль при воспроизведении записи совпадает
+type BehaviorAnalyzer struct {
+	profile *FearProfile
+
+	yaws      [SimulationTickRate]float64 // Направление взгляда за последнюю секунду
+	yawIndex  int
+	yawFilled bool
+
+	lastPosition Vector3
+	speed        float64 // Горизонтальная скорость игрока, м/с
+	started      bool
+	gazeTick     int
+	pending      []pendingReaction
+}
+
+// newBehaviorAnalyzer начинает новую сессию профиля
+func newBehaviorAnalyzer(profile *FearProfile) *BehaviorAnalyzer {
+	ba := &BehaviorAnalyzer{profile: profile}
+	ba.profile.Sessions++
+	return ba
+}
+
+// Profile возвращает профиль страха, который пополняет анализатор
+func (ba *BehaviorAnalyzer) Profile() *FearProfile {
+	return ba.profile
+}
+
+// Update наблюдает за игроком на одном тике
+func (ba *BehaviorAnalyzer) Update(sim *Simulation, deltaTime float64) {
+	player := sim.Player()
+	profile := ba.profile
+
+	if ba.started {
+		ba.speed = horizontalDistance(player.Position, ba.lastPosition) / deltaTime
+	}
+	ba.lastPosition = player.Position
+	ba.started = true
+
+	profile.PlayTime += deltaTime
+	if sim.procedural.GetCurrentScene().LightAt(player.Position) < darkLight {
+		profile.DarkTime += deltaTime
+	} else {
+		profile.LitTime += deltaTime
+	}
+
+	// Средние за все время игры
+	weight := deltaTime / profile.PlayTime
+	pitch := math.Asin(util.Clamp(player.Direction.Y, -1, 1))
+	profile.LookPitch += (pitch - profile.LookPitch) * weight
+
+	ba.gazeTick++
+	if ba.gazeTick >= gazeSamples {
+		ba.gazeTick = 0
+		gaze := 0.0
+		if ba.looksAtThreat(sim) {
+			gaze = 1
+		}
+		profile.ThreatGaze += (gaze - profile.ThreatGaze) * weight * gazeSamples
+	}
+
+	ba.watchTurns(player)
+	ba.watchReactions(player, deltaTime)
+}
+
+// Notice начинает следить за реакцией игрока на событие директора.
+// Анализатор уже видел игрока на этом тике и знает, где тот стоит
+func (ba *BehaviorAnalyzer) Notice(kind DirectorEventKind, source Vector3) {
+	ba.pending = append(ba.pending, pendingReaction{
+		kind:   kind,
+		source: source,
+		start:  ba.lastPosition,
+		speed:  ba.speed,
+	})
+}
+
+// watchTurns замечает, что игрок резко обернулся
+func (ba *BehaviorAnalyzer) watchTurns(player *Player) {
+	yaw := math.Atan2(player.Direction.X, player.Direction.Z)
+	oldest := ba.yaws[ba.yawIndex]
+	if ba.yawFilled && math.Abs(wrapAngle(yaw-oldest)) > turnAroundAngle {
+		ba.profile.TurnArounds++
+		// Один поворот не считается дважды: история начинается заново
+		ba.yawFilled = false
+		ba.yawIndex = 0
+	}
+
+	ba.yaws[ba.yawIndex] = yaw
+	ba.yawIndex++
+	if ba.yawIndex == len(ba.yaws) {
+		ba.yawIndex = 0
+		ba.yawFilled = true
+	}
+}
+
+// watchReactions ведет реакции на недавние события и записывает в профиль
+// те, окно которых закончилось
+func (ba *BehaviorAnalyzer) watchReactions(player *Player, deltaTime float64) {
+	kept := ba.pending[:0]
+	for _, pr := range ba.pending {
+		pr.elapsed += deltaTime
+		pr.sprinted = pr.sprinted || player.IsSprinting
+		if pr.elapsed < reactionWindow {
+			kept = append(kept, pr)
+			continue
+		}
+		ba.profile.recordReaction(pr.kind, pr.classify(player.Position))
+	}
+	ba.pending = kept
+}
+
+// classify определяет реакцию по тому, куда игрок сместился за окно реакции.
+// Для помех источник - сам игрок, и от него можно только убежать или замереть
+func (pr *pendingReaction) classify(position Vector3) string {
+	moved := horizontalDistance(position, pr.start)
+	away := horizontalDistance(position, pr.source) - horizontalDistance(pr.start, pr.source)
+	speed := moved / pr.elapsed
+
+	switch {
+	case pr.speed > reactionMoving && moved < reactionFreeze:
+		return ReactionFreeze
+	case away >= reactionDistance && (pr.sprinted || speed > pr.speed*reactionSpeedUp):
+		return ReactionFlee
+	case away <= -reactionDistance:
+		return ReactionApproach
+	default:
+		return ReactionIgnore
+	}
+}
+
+// looksAtThreat сообщает, что игрок смотрит на сталкера или странный объект
+func (ba *BehaviorAnalyzer) looksAtThreat(sim *Simulation) bool {
+	player := sim.Player()
+	inView := func(target Vector3) bool {
+		to := horizontalDirection(target.Sub(player.Position))
+		return to.Dot(horizontalDirection(player.Direction)) > math.Cos(gazeCone)
+	}
+
+	// Сталкер, который видит игрока, виден и ему
+	if stalker := sim.stalker; stalker != nil && stalker.SeesPlayer() && inView(stalker.Position()) {
+		return true
+	}
+
+	found := false
+	sim.procedural.GetCurrentScene().Index.QueryRadius(player.Position, gazeRange, func(obj *ProceduralObject) bool {
+		if obj.Type == "strange" && inView(obj.Position) {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// hash добавляет наблюдения в хэш состояния симуляции
+func (ba *BehaviorAnalyzer) hash(wh *worldHasher) {
+	profile := ba.profile
+	wh.float(profile.DarkTime)
+	wh.int(int64(profile.TurnArounds))
+	wh.float(profile.ThreatGaze)
+	for kind := range directorEventNames {
+		wh.float(profile.Effect(DirectorEventKind(kind)))
+	}
+	wh.int(int64(len(ba.pending)))
+}
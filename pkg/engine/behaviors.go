@@ -0,0 +1,297 @@
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"nightmare/internal/logger"
+	"nightmare/pkg/behavior"
+	"nightmare/pkg/config"
+)
+
+// Behavior tree settings
+const (
+	behaviorRadius     = 30.0 // Default distance within which attached trees think, m
+	behaviorsFolder    = "behaviors"
+	behaviorSeedStride = 7919 // Spreads the seeds of trees attached to neighbouring objects
+)
+
+// BehaviorFile is a behavior tree from mod data: the tree and what it is
+// attached to. Without attach the tree runs once for the whole world
+type BehaviorFile struct {
+	behavior.Definition `yaml:",inline"`
+	Attach              string  `yaml:"attach"` // Scene object type that gets its own copy of the tree, e.g. strange
+	Radius              float64 `yaml:"radius"` // Attached trees think only this close to the player, m
+}
+
+// attachedTree is a tree instance of one scene object
+type attachedTree struct {
+	tree   *behavior.Tree
+	object *ProceduralObject
+	seen   bool
+}
+
+// BehaviorSystem ticks behavior trees of the world and of scene objects
+// (haunted objects, creatures) on every engine update
+type BehaviorSystem struct {
+	registry *behavior.Registry
+	logger   *logger.Logger
+	seed     int64
+
+	world    []*behavior.Tree
+	attached []*BehaviorFile
+	objects  map[*BehaviorFile]map[int]*attachedTree
+	paths    map[*behavior.Tree]string // Last logged active path of every tree
+}
+
+// NewBehaviorSystem creates a system with a registry of built-in
+// conditions and actions; the engine adds its own
+func NewBehaviorSystem(log *logger.Logger, seed int64) *BehaviorSystem {
+	return &BehaviorSystem{
+		registry: behavior.NewRegistry(),
+		logger:   log,
+		seed:     seed,
+		objects:  make(map[*BehaviorFile]map[int]*attachedTree),
+		paths:    make(map[*behavior.Tree]string),
+	}
+}
+
+// Registry returns the registry trees are built with. Register conditions
+// and actions before adding trees that use them
+func (bs *BehaviorSystem) Registry() *behavior.Registry {
+	return bs.registry
+}
+
+// AddWorldTree runs a tree built in Go once for the whole world
+func (bs *BehaviorSystem) AddWorldTree(tree *behavior.Tree) {
+	bs.world = append(bs.world, tree)
+}
+
+// Add checks a tree file and runs it: for the world, or for every scene
+// object of the attached type near the player
+func (bs *BehaviorSystem) Add(file *BehaviorFile) error {
+	if file.Attach == "" {
+		tree, err := file.Build(bs.registry, deriveSeed(bs.seed, "behavior."+file.Name))
+		if err != nil {
+			return err
+		}
+		bs.AddWorldTree(tree)
+		return nil
+	}
+
+	if err := file.Validate(bs.registry); err != nil {
+		return err
+	}
+	if file.Radius <= 0 {
+		file.Radius = behaviorRadius
+	}
+	bs.attached = append(bs.attached, file)
+	bs.objects[file] = make(map[int]*attachedTree)
+	return nil
+}
+
+// LoadMods adds the trees of enabled mods: every *.yaml in
+// <mods_folder>/<mod>/behaviors. A broken file is skipped with a warning
+func (bs *BehaviorSystem) LoadMods(mods config.ModsConfig) {
+	if !mods.Enabled {
+		return
+	}
+
+	for _, mod := range mods.EnabledMods {
+		paths, _ := filepath.Glob(filepath.Join(mods.ModsFolder, mod, behaviorsFolder, "*.yaml"))
+		sort.Strings(paths)
+		for _, path := range paths {
+			file, err := LoadBehaviorFile(path)
+			if err == nil {
+				err = bs.Add(file)
+			}
+			if err != nil {
+				bs.logger.Warnf("Mod %s: skipping behavior tree: %v", mod, err)
+				continue
+			}
+			bs.logger.Infof("Mod %s: behavior tree %q loaded", mod, file.Name)
+		}
+	}
+}
+
+// LoadBehaviorFile reads a behavior tree file
+func LoadBehaviorFile(path string) (*BehaviorFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read behavior tree: %v", err)
+	}
+
+	file := &BehaviorFile{}
+	if err := yaml.UnmarshalStrict(data, file); err != nil {
+		return nil, fmt.Errorf("error parsing behavior tree %s: %v", path, err)
+	}
+	if file.Name == "" {
+		file.Name = filepath.Base(path)
+	}
+	return file, nil
+}
+
+// Update ticks the world trees and the trees of objects near the player.
+// Objects that went out of range forget what they were doing
+func (bs *BehaviorSystem) Update(scene *ProceduralScene, player Vector3, deltaTime float64) {
+	for _, tree := range bs.world {
+		tree.Tick(nil, deltaTime)
+		bs.tracePath(tree, "world")
+	}
+
+	for _, file := range bs.attached {
+		instances := bs.objects[file]
+		for _, instance := range instances {
+			instance.seen = false
+		}
+
+		scene.Index.QueryRadius(player, file.Radius, func(obj *ProceduralObject) bool {
+			if obj.Type != file.Attach {
+				return true
+			}
+			instance, ok := instances[obj.ID]
+			if !ok {
+				// The definition was validated in Add, so it builds
+				tree, _ := file.Build(bs.registry, deriveSeed(bs.seed, "behavior."+file.Name)+int64(obj.ID)*behaviorSeedStride)
+				instance = &attachedTree{tree: tree, object: obj}
+				instances[obj.ID] = instance
+			}
+			instance.seen = true
+			return true
+		})
+
+		// Ticked in ID order, so the trees don't depend on the map order
+		ids := make([]int, 0, len(instances))
+		for id, instance := range instances {
+			if !instance.seen {
+				delete(bs.paths, instance.tree)
+				delete(instances, id)
+				continue
+			}
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			instance := instances[id]
+			instance.tree.Tick(instance.object, deltaTime)
+			bs.tracePath(instance.tree, fmt.Sprintf("%s #%d", instance.object.Type, id))
+		}
+	}
+}
+
+// tracePath logs the active path of a tree when it changes
+func (bs *BehaviorSystem) tracePath(tree *behavior.Tree, owner string) {
+	path := tree.ActivePathString()
+	if bs.paths[tree] == path {
+		return
+	}
+	bs.paths[tree] = path
+	bs.logger.Debugf("Behavior %s (%s): %s", tree.Name, owner, path)
+}
+
+// Dump prints every running tree with the statuses of its last tick
+func (bs *BehaviorSystem) Dump() string {
+	dump := ""
+	for _, tree := range bs.world {
+		dump += tree.Dump()
+	}
+	for _, file := range bs.attached {
+		ids := make([]int, 0, len(bs.objects[file]))
+		for id := range bs.objects[file] {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			instance := bs.objects[file][id]
+			dump += fmt.Sprintf("%s #%d: %s", instance.object.Type, id, instance.tree.Dump())
+		}
+	}
+	if dump == "" {
+		return "no behavior trees are running\n"
+	}
+	return dump
+}
+
+// registerEngineBehaviors adds what trees can sense and do in the game.
+// The subject of an attached tree is its *ProceduralObject; world trees
+// have none
+//
+//	condition player_within   radius: the player is this close to the object, m
+//	condition player_facing   angle: the player looks at the object within this angle, degrees
+//	condition night           it is night or evening
+//	condition player_in_cave  the player is inside a cave
+//	condition fear_above      level: fear around the player is above this (0..1)
+//	condition director_phase  phase: build-up, peak or relief
+//	action    play_sound      sound (ambient, scare, interact...), volume: panned from the object
+//	action    glitch          strength, duration: distorts the picture
+//	action    rumble          strength, duration: shakes the gamepad
+//	action    log             message: writes to the debug log
+func (e *Engine) registerEngineBehaviors(registry *behavior.Registry) {
+	player := func() *Player { return e.physics.GetPlayer() }
+	subject := func(ctx *behavior.Context) (*ProceduralObject, bool) {
+		obj, ok := ctx.Subject.(*ProceduralObject)
+		return obj, ok
+	}
+
+	registry.RegisterCondition("player_within", func(ctx *behavior.Context, params behavior.Params) bool {
+		obj, ok := subject(ctx)
+		return ok && Vector3Distance(obj.Position, player().Position) < params.Float("radius", 5)
+	})
+	registry.RegisterCondition("player_facing", func(ctx *behavior.Context, params behavior.Params) bool {
+		obj, ok := subject(ctx)
+		if !ok {
+			return false
+		}
+		to := horizontalDirection(obj.Position.Sub(player().Position))
+		return to.Dot(horizontalDirection(player().Direction)) > math.Cos(params.Float("angle", 30)*math.Pi/180)
+	})
+	registry.RegisterCondition("night", func(ctx *behavior.Context, params behavior.Params) bool {
+		timeOfDay := e.procedural.GetCurrentScene().TimeOfDay
+		return timeOfDay < 0.25 || timeOfDay > 0.75
+	})
+	registry.RegisterCondition("player_in_cave", func(ctx *behavior.Context, params behavior.Params) bool {
+		return e.procedural.GetCurrentScene().CaveEnclosureAt(player().Position) > 0.5
+	})
+	registry.RegisterCondition("fear_above", func(ctx *behavior.Context, params behavior.Params) bool {
+		return e.fear > params.Float("level", 0.5)
+	})
+	registry.RegisterCondition("director_phase", func(ctx *behavior.Context, params behavior.Params) bool {
+		director := e.sim.Director()
+		return director != nil && director.Phase().String() == params.String("phase", "")
+	})
+
+	registry.RegisterAction("play_sound", func(ctx *behavior.Context, params behavior.Params) behavior.Status {
+		sound := params.String("sound", "ambient")
+		if !e.audioEngine.CanPlayEffect(sound) {
+			return behavior.Failure
+		}
+		pan := float32(0)
+		if obj, ok := subject(ctx); ok {
+			toObject := horizontalDirection(obj.Position.Sub(player().Position))
+			pan = float32(math.Max(-1, math.Min(1, toObject.Dot(e.raytracer.GetCameraRight()))))
+		}
+		soundMeta := map[string]float64{
+			"atmosphere.fear":    params.Float("fear", 0.5),
+			"atmosphere.ominous": params.Float("ominous", 0.5),
+		}
+		e.audioEngine.PlayProceduralSound(sound, float32(params.Float("volume", 0.5)), pan, soundMeta)
+		return behavior.Success
+	})
+	registry.RegisterAction("glitch", func(ctx *behavior.Context, params behavior.Params) behavior.Status {
+		e.renderer.ApplyGlitchEffect(float32(params.Float("strength", 0.5)), float32(params.Float("duration", 0.3)))
+		return behavior.Success
+	})
+	registry.RegisterAction("rumble", func(ctx *behavior.Context, params behavior.Params) behavior.Status {
+		e.rumble(params.Float("strength", 0.5), params.Float("duration", 0.3))
+		return behavior.Success
+	})
+	registry.RegisterAction("log", func(ctx *behavior.Context, params behavior.Params) behavior.Status {
+		e.logger.Debugf("Behavior: %s", params.String("message", ""))
+		return behavior.Success
+	})
+}
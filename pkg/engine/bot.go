@@ -0,0 +1,279 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Настройки ботов
+const (
+	botTurnSpeed      = 4.0 // Скорость поворота, рад/с
+	botArriveDistance = 0.6 // Точка пути считается пройденной на таком расстоянии
+	botLookAround     = 0.8 // Скорость осмотра на месте, рад/с
+
+	botJumpAfter   = 1.5 // Секунд без продвижения, после которых бот пробует перепрыгнуть препятствие
+	botReplanAfter = 3.0 // Секунд без продвижения, после которых бот ищет другой путь
+	botProgress    = 0.3 // Приближение к точке пути (м), которое считается продвижением
+
+	wanderMinDistance = 8.0  // Ближайшая случайная цель блуждания, м
+	wanderMaxDistance = 40.0 // Самая дальняя
+	wanderMapMargin   = 4.0  // Отступ целей от края карты
+	wanderAttempts    = 8    // Сколько целей пробовать, прежде чем выбираться напролом
+	wanderBreakout    = 2.0  // Секунд попытки выбраться напролом, если пути никуда нет
+
+	breakoutDirections = 16  // Сколько направлений осмотреть, выбираясь напролом
+	breakoutProbe      = 1.5 // На каком расстоянии искать пологую землю, м
+	breakoutSafeProbe  = 6.0 // Насколько вперед земля пологая в лучших направлениях (не скатиться в яму), м
+)
+
+// botSteering ведет взгляд бота и выдает кадры движения к точке.
+// Бот поворачивается с ограниченной скоростью, как человек, и идет вперед
+// тем медленнее, чем сильнее отвернут от цели
+type botSteering struct {
+	yaw, pitch  float64
+	initialized bool
+}
+
+// init берет начальный взгляд из направления игрока
+func (bs *botSteering) init(player *Player) {
+	if bs.initialized {
+		return
+	}
+	bs.yaw = math.Atan2(player.Direction.X, player.Direction.Z)
+	bs.pitch = 0
+	bs.initialized = true
+}
+
+// turnTowards поворачивает взгляд к углу yaw и возвращает оставшееся отклонение
+func (bs *botSteering) turnTowards(yaw, deltaTime float64) float64 {
+	diff := wrapAngle(yaw - bs.yaw)
+	step := botTurnSpeed * deltaTime
+	bs.yaw = wrapAngle(bs.yaw + math.Max(-step, math.Min(step, diff)))
+	return wrapAngle(yaw - bs.yaw)
+}
+
+// walkTowards возвращает кадр движения к точке target
+func (bs *botSteering) walkTowards(player *Player, target Vector3, deltaTime float64) InputFrame {
+	dx := target.X - player.Position.X
+	dz := target.Z - player.Position.Z
+	remaining := bs.turnTowards(math.Atan2(dx, dz), deltaTime)
+
+	return InputFrame{
+		Forward: math.Max(0, math.Cos(remaining)),
+		Yaw:     bs.yaw,
+		Pitch:   bs.pitch,
+	}
+}
+
+// idle возвращает кадр без движения с текущим взглядом
+func (bs *botSteering) idle() InputFrame {
+	return InputFrame{Yaw: bs.yaw, Pitch: bs.pitch}
+}
+
+// wrapAngle приводит угол к -Pi..Pi
+func wrapAngle(angle float64) float64 {
+	angle = math.Mod(angle+math.Pi, 2*math.Pi)
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	return angle - math.Pi
+}
+
+// horizontalDistance возвращает расстояние между точками в плоскости XZ
+func horizontalDistance(a, b Vector3) float64 {
+	return math.Hypot(a.X-b.X, a.Z-b.Z)
+}
+
+// botPathFollower ведет бота по точкам пути и замечает, что он застрял
+type botPathFollower struct {
+	path     []Vector3
+	waypoint int
+	best     float64 // Ближайшее расстояние до текущей точки пути
+	stalled  float64 // Секунд без продвижения
+	jumped   bool    // Уже пробовал перепрыгнуть на этой точке
+}
+
+// setPath начинает движение по новому пути
+func (pf *botPathFollower) setPath(path []Vector3) {
+	pf.path = path
+	pf.waypoint = 0
+	pf.best = math.Inf(1)
+	pf.stalled = 0
+	pf.jumped = false
+}
+
+// done сообщает, что путь пройден (или его нет)
+func (pf *botPathFollower) done() bool {
+	return pf.waypoint >= len(pf.path)
+}
+
+// step возвращает кадр движения по пути. stuck = true, если бот долго не
+// продвигается к точке пути и путь стоит искать заново
+func (pf *botPathFollower) step(steer *botSteering, player *Player, deltaTime float64) (frame InputFrame, stuck bool) {
+	for !pf.done() && horizontalDistance(player.Position, pf.path[pf.waypoint]) < botArriveDistance {
+		pf.waypoint++
+		pf.best = math.Inf(1)
+		pf.stalled = 0
+		pf.jumped = false
+	}
+	if pf.done() {
+		return steer.idle(), false
+	}
+
+	target := pf.path[pf.waypoint]
+	frame = steer.walkTowards(player, target, deltaTime)
+
+	distance := horizontalDistance(player.Position, target)
+	if distance < pf.best-botProgress {
+		pf.best = distance
+		pf.stalled = 0
+	} else {
+		pf.stalled += deltaTime
+	}
+
+	if pf.stalled > botJumpAfter && !pf.jumped {
+		frame.Jump = true
+		pf.jumped = true
+	}
+	return frame, pf.stalled > botReplanAfter
+}
+
+// WanderBot исследует мир: выбирает случайные достижимые точки, идет к ним
+// по найденному пути, иногда бежит и осматривается на месте. Реализует InputSource.
+// Случайность бота детерминирована сидом мира, поэтому прогон воспроизводим
+type WanderBot struct {
+	rng      *rand.Rand
+	steer    botSteering
+	follow   botPathFollower
+	pause    float64 // Секунд осмотра на месте до выбора новой цели
+	breakout float64 // Секунд попытки выбраться напролом
+	sprint   bool    // Бежать к текущей цели
+	stuckAt  Vector3 // Где бот застрял в последний раз
+	wasStuck bool
+
+	// Узлы пути, до которых бот не смог дойти: поиск пути считает их
+	// проходимыми, а физика нет. Бот обходит их в следующих путях
+	avoid map[NavCell]bool
+}
+
+// NewWanderBot создает бота блуждания для мира с данным сидом
+func NewWanderBot(seed int64) *WanderBot {
+	return &WanderBot{
+		rng:   newStream(seed, streamWanderBot),
+		avoid: make(map[NavCell]bool),
+	}
+}
+
+// NextFrame реализует InputSource. Бот блуждает бесконечно
+func (wb *WanderBot) NextFrame(sim *Simulation) (InputFrame, bool) {
+	player := sim.Player()
+	wb.steer.init(player)
+	dt := sim.tickDuration
+
+	if wb.pause > 0 {
+		wb.pause -= dt
+		wb.steer.yaw = wrapAngle(wb.steer.yaw + botLookAround*dt)
+		return wb.steer.idle().Quantize(), true
+	}
+
+	if wb.breakout > 0 {
+		wb.breakout -= dt
+		frame := wb.steer.idle()
+		frame.Forward = 1
+		frame.Jump = wb.rng.Float64() < dt
+		return frame.Quantize(), true
+	}
+
+	if wb.follow.done() && !wb.chooseTarget(sim) {
+		// Пути никуда нет: как человек, пробуем выбраться напролом в случайную
+		// сторону. Если игрок правда заперт, мониторинг прогона заметит застревание
+		wb.steer.yaw = wb.breakoutYaw(sim)
+		wb.breakout = wanderBreakout
+		return wb.steer.idle().Quantize(), true
+	}
+
+	frame, stuck := wb.follow.step(&wb.steer, player, dt)
+	if stuck {
+		wb.avoid[NavCellOf(wb.follow.path[wb.follow.waypoint])] = true
+		wb.follow.setPath(nil)
+
+		// Застрял снова на том же месте: соседние узлы тоже не пускают
+		// (низкий свод, уступ), выбираемся напролом с прыжками
+		if wb.wasStuck && horizontalDistance(player.Position, wb.stuckAt) < botArriveDistance {
+			wb.steer.yaw = wb.breakoutYaw(sim)
+			wb.breakout = wanderBreakout
+		}
+		wb.stuckAt = player.Position
+		wb.wasStuck = true
+	} else if wb.follow.done() {
+		wb.pause = 0.5 + wb.rng.Float64()*2.5
+	}
+
+	frame.Sprint = wb.sprint
+	return frame.Quantize(), true
+}
+
+// breakoutYaw выбирает направление, чтобы выбраться напролом: случайное из
+// тех, где рядом пологая земля, а если таких нет - к центру карты
+// (за краем карты игрок падает в пустоту). Направления, где земля пологая
+// и дальше, предпочтительнее: напролом легко скатиться в яму, из которой не выбраться
+func (wb *WanderBot) breakoutYaw(sim *Simulation) float64 {
+	terrain := sim.Procedural().GetCurrentScene().Terrain
+	position := sim.Player().Position
+	halfW := float64(terrain.Width)/2 - wanderMapMargin
+	halfH := float64(terrain.Height)/2 - wanderMapMargin
+
+	// Куда игрок может добежать за попытку
+	reach := sim.Player().MoveSpeed * sim.Player().SprintModifier * wanderBreakout
+
+	var candidates, safe []float64
+	offset := wb.rng.Float64() * 2 * math.Pi
+	for i := 0; i < breakoutDirections; i++ {
+		yaw := offset + float64(i)*2*math.Pi/breakoutDirections
+		dx, dz := math.Sin(yaw), math.Cos(yaw)
+		if math.Abs(position.X+dx*reach) > halfW || math.Abs(position.Z+dz*reach) > halfH {
+			continue
+		}
+		if terrain.NormalAt(position.X+dx*breakoutProbe, position.Z+dz*breakoutProbe).Y >= maxWalkableNormalY {
+			candidates = append(candidates, yaw)
+			if gentleSlope(terrain, position.X, position.Z, position.X+dx*breakoutSafeProbe, position.Z+dz*breakoutSafeProbe) {
+				safe = append(safe, yaw)
+			}
+		}
+	}
+
+	if len(safe) > 0 {
+		return wrapAngle(safe[wb.rng.Intn(len(safe))])
+	}
+	if len(candidates) > 0 {
+		return wrapAngle(candidates[wb.rng.Intn(len(candidates))])
+	}
+	return wrapAngle(math.Atan2(-position.X, -position.Z) + (wb.rng.Float64()-0.5)*math.Pi/2)
+}
+
+// chooseTarget выбирает случайную достижимую цель и путь к ней
+func (wb *WanderBot) chooseTarget(sim *Simulation) bool {
+	terrain := sim.Procedural().GetCurrentScene().Terrain
+	player := sim.Player()
+	halfW := float64(terrain.Width)/2 - wanderMapMargin
+	halfH := float64(terrain.Height)/2 - wanderMapMargin
+
+	for attempt := 0; attempt < wanderAttempts; attempt++ {
+		angle := wb.rng.Float64() * 2 * math.Pi
+		distance := wanderMinDistance + wb.rng.Float64()*(wanderMaxDistance-wanderMinDistance)
+		target := Vector3{
+			X: math.Max(-halfW, math.Min(halfW, player.Position.X+math.Sin(angle)*distance)),
+			Z: math.Max(-halfH, math.Min(halfH, player.Position.Z+math.Cos(angle)*distance)),
+		}
+		if !sim.Physics().CanStandAt(target.X, target.Z) {
+			continue
+		}
+
+		if path := sim.Navigation().FindPath(NavQuery{From: player.Position, To: target, Avoid: wb.avoid}); path != nil {
+			wb.follow.setPath(path)
+			wb.sprint = wb.rng.Float64() < 0.3
+			return true
+		}
+	}
+	return false
+}
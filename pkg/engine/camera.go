@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"math"
+
+	"nightmare/pkg/config"
+)
+
+// Camera motion tuning
+const (
+	mouseRadiansPerPixel = 0.0025 // Mouse look speed at sensitivity 1.0
+
+	headBobHeight = 0.035 // Vertical head bob at walking pace (m)
+	headBobSway   = 0.02  // Sideways sway (m)
+
+	fearShakeThreshold = 0.5   // Fear below this doesn't shake the camera
+	fearShakeAngle     = 0.015 // Shake amplitude at full fear (radians)
+	fearSmoothing      = 1.5   // How fast the shake follows fear changes (1/s)
+)
+
+// CameraController owns the look angles of the player. Mouse and keyboard
+// turn it, and it feeds Player.Direction and the raytracer camera.
+// Head bob and fear shake only affect the rendered view, not the simulation
+type CameraController struct {
+	Yaw   float64 // Radians, 0 looks along +Z, grows turning right
+	Pitch float64 // Radians, positive looks up
+
+	settings config.CameraConfig
+	maxPitch float64
+
+	bobPhase  float64 // Stride cycle, radians
+	bobAmount float64 // 0..1, fades in and out with movement
+	fear      float64 // Smoothed fear level driving the shake
+	time      float64 // Seconds of camera motion, drives the shake
+}
+
+// NewCameraController creates a camera controller with the given settings
+func NewCameraController(settings config.CameraConfig) *CameraController {
+	maxPitch := settings.MaxPitch * math.Pi / 180.0
+	if maxPitch <= 0 || maxPitch > math.Pi/2-0.01 {
+		maxPitch = math.Pi/2 - 0.01 // Never flip over the top
+	}
+
+	return &CameraController{
+		settings: settings,
+		maxPitch: maxPitch,
+	}
+}
+
+// Turn rotates the camera by the given angles (radians) and clamps the pitch
+func (cc *CameraController) Turn(yawDelta, pitchDelta float64) {
+	cc.Yaw = math.Mod(cc.Yaw+yawDelta, 2*math.Pi)
+	cc.Pitch = math.Max(-cc.maxPitch, math.Min(cc.maxPitch, cc.Pitch+pitchDelta))
+}
+
+// Look applies mouse movement in pixels. Screen Y grows downwards, so moving
+// the mouse up looks up unless InvertY is set
+func (cc *CameraController) Look(dx, dy float64) {
+	scale := mouseRadiansPerPixel * cc.settings.MouseSensitivity
+	pitchDelta := -dy * scale
+	if cc.settings.InvertY {
+		pitchDelta = -pitchDelta
+	}
+	cc.Turn(dx*scale, pitchDelta)
+}
+
+// TurnAt turns the camera for deltaTime seconds at a rate of -1..1 of the
+// turning speed: -1, 0 or 1 from keys, anything in between from a stick
+func (cc *CameraController) TurnAt(yaw, pitch, deltaTime float64) {
+	speed := cc.settings.KeyTurnSpeed * deltaTime
+	cc.Turn(yaw*speed, pitch*speed)
+}
+
+// SetDirection points the camera along the given direction
+func (cc *CameraController) SetDirection(direction Vector3) {
+	direction = direction.Normalize()
+	cc.Yaw = math.Atan2(direction.X, direction.Z)
+	cc.Pitch = 0
+	cc.Turn(0, math.Asin(math.Max(-1, math.Min(1, direction.Y))))
+}
+
+// Direction returns the look direction without head bob or shake
+func (cc *CameraController) Direction() Vector3 {
+	forward, _, _ := cameraBasis(cc.Yaw, cc.Pitch)
+	return forward
+}
+
+// Update advances head bob and fear shake by deltaTime seconds of real time
+func (cc *CameraController) Update(deltaTime float64, player *Player, fear float64) {
+	cc.time += deltaTime
+
+	// Head bob follows the distance walked, so sprinting bobs faster
+	speed := math.Hypot(player.Velocity.X, player.Velocity.Z)
+	target := 0.0
+	if player.IsGrounded && speed > 0.1 {
+		target = math.Min(1, speed/player.MoveSpeed)
+		cc.bobPhase = math.Mod(cc.bobPhase+speed*deltaTime*2*math.Pi/strideLength, 4*math.Pi)
+	}
+	cc.bobAmount += (target - cc.bobAmount) * math.Min(1, deltaTime*8)
+
+	cc.fear += (fear - cc.fear) * math.Min(1, deltaTime*fearSmoothing)
+}
+
+// ViewAngles returns yaw and pitch with the fear shake applied
+func (cc *CameraController) ViewAngles() (yaw, pitch float64) {
+	intensity := math.Max(0, cc.fear-fearShakeThreshold) / (1 - fearShakeThreshold)
+	amplitude := fearShakeAngle * intensity * cc.settings.FearShake
+
+	// Sums of sines with unrelated frequencies give an irregular tremble
+	t := cc.time
+	yaw = cc.Yaw + amplitude*(math.Sin(t*13.7)+0.6*math.Sin(t*29.3+1.3))
+	pitch = cc.Pitch + amplitude*(math.Sin(t*17.1+0.7)+0.6*math.Sin(t*23.9+2.1))
+	return yaw, pitch
+}
+
+// ViewDirection returns the rendered look direction, fear shake included
+func (cc *CameraController) ViewDirection() Vector3 {
+	forward, _, _ := cameraBasis(cc.ViewAngles())
+	return forward
+}
+
+// BobOffset returns the head bob displacement to add to the eye position.
+// Two steps make one sideways sway, each step dips the head
+func (cc *CameraController) BobOffset() Vector3 {
+	amount := cc.bobAmount * cc.settings.HeadBob
+	if amount == 0 {
+		return Vector3{}
+	}
+
+	_, right, _ := cameraBasis(cc.Yaw, 0)
+	sway := math.Sin(cc.bobPhase/2) * headBobSway * amount
+	height := -math.Abs(math.Sin(cc.bobPhase/2)) * headBobHeight * amount
+
+	return Vector3{X: right.X * sway, Y: height, Z: right.Z * sway}
+}
+
+// ApplyToRaytracer places the raytracer camera at the eye with the rendered view angles
+func (cc *CameraController) ApplyToRaytracer(rt *Raytracer, eye Vector3) {
+	yaw, pitch := cc.ViewAngles()
+	rt.SetCameraPosition(eye.Add(cc.BobOffset()))
+	rt.SetCameraOrientation(yaw, pitch)
+}
+
+// cameraBasis returns the forward, right and up vectors for yaw and pitch
+func cameraBasis(yaw, pitch float64) (forward, right, up Vector3) {
+	forward = Vector3{
+		X: math.Cos(pitch) * math.Sin(yaw),
+		Y: math.Sin(pitch),
+		Z: math.Cos(pitch) * math.Cos(yaw),
+	}.Normalize()
+
+	worldUp := Vector3{X: 0, Y: 1, Z: 0}
+	right = worldUp.Cross(forward).Normalize()
+	up = forward.Cross(right).Normalize()
+	return forward, right, up
+}
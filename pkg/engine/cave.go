@@ -0,0 +1,523 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+
+	noise "nightmare/internal/math"
+)
+
+// Параметры генерации пещер
+const (
+	caveMinSlope       = 0.6  // Минимальный уклон склона у входа (подъем на метр)
+	caveTunnelRadius   = 2.6  // Базовый горизонтальный радиус туннеля
+	caveVerticalRatio  = 0.75 // Отношение вертикального радиуса туннеля к горизонтальному
+	caveFloorRatio     = 0.55 // Глубина ровного пола под осью туннеля (доля радиуса)
+	caveSegments       = 7    // Максимальное число сегментов туннеля
+	caveSegmentLength  = 6.0  // Средняя длина сегмента
+	caveRockThickness  = 3.0  // Толщина скального массива вокруг туннеля
+	caveRockRatio      = 0.8  // Отношение высоты массива к его ширине
+	caveRockNoise      = 0.25 // Амплитуда шума массива (доля радиуса)
+	caveRockFrequency  = 0.09 // Частота шума массива: крупные выступы и навесы
+	caveChamberScale   = 2.0  // Во сколько раз зал в конце шире туннеля
+	caveWallNoise      = 0.3  // Амплитуда шума стенок (доля радиуса)
+	caveWallFrequency  = 0.18 // Частота шума стенок
+	caveMinSpacing     = 40.0 // Минимальное расстояние между входами
+	caveEntranceClear  = 6.0  // Радиус вокруг входа, свободный от объектов
+	caveMouthDepth     = 4.0  // Расстояние от подножия склона до входа
+	caveDarknessDepth  = 12.0 // Глубина, на которой пещера становится полностью темной
+	caveMarchStep      = 0.25 // Минимальный шаг марширования по полю плотности
+	caveQueryDistance  = 12.0 // Дальность вертикальных запросов пола и потолка
+	caveRefineSteps    = 6    // Итерации уточнения границы методом деления пополам
+	caveNormalEpsilon  = 0.1  // Шаг конечных разностей для нормали
+	caveMaxTraceLength = 80.0 // Максимальная длина луча внутри области пещеры
+)
+
+// Регионы, на склонах которых могут находиться входы в пещеры
+var caveRegions = map[string]bool{
+	"mountains":     true,
+	"mountain_peak": true,
+	"rocky_hills":   true,
+}
+
+// CaveVolume - пещера: область, в которой ландшафт задается трехмерным полем
+// плотности вместо карты высот. Вдоль туннеля над склоном поднимается скальный
+// массив с выступами и навесами, туннель вырезан в нем и в склоне под ним
+// и заканчивается залом
+type CaveVolume struct {
+	ID        int
+	Entrance  Vector3   // Точка входа на поверхности склона
+	Direction Vector3   // Горизонтальное направление туннеля вглубь склона
+	Path      []Vector3 // Ось туннеля от входа до зала
+	Radii     []float64 // Горизонтальный радиус туннеля в точках оси
+	Min       Vector3   // Границы области с полем плотности
+	Max       Vector3
+	Seed      int64 // Сид шума стенок
+
+	terrain  *HeightMap
+	noiseGen *noise.NoiseGenerator
+}
+
+// Contains проверяет, находится ли точка в области поля плотности пещеры
+func (cv *CaveVolume) Contains(p Vector3) bool {
+	return p.X >= cv.Min.X && p.X <= cv.Max.X &&
+		p.Y >= cv.Min.Y && p.Y <= cv.Max.Y &&
+		p.Z >= cv.Min.Z && p.Z <= cv.Max.Z
+}
+
+// DensityAt возвращает плотность породы в точке: положительная внутри породы,
+// отрицательная в воздухе. Величина примерно равна расстоянию до границы в метрах
+func (cv *CaveVolume) DensityAt(p Vector3) float64 {
+	surface := cv.terrain.HeightAt(p.X, p.Z) - p.Y
+	if !cv.Contains(p) {
+		return surface
+	}
+
+	// Склон и скальный массив над ним, из которых вырезан туннель
+	solid := math.Max(surface, cv.rockAt(p))
+	return math.Min(solid, cv.tunnelAt(p))
+}
+
+// rockAt - плотность скального массива над туннелем. Трехмерный шум дает
+// выступы и навесы, которые невозможно описать картой высот
+func (cv *CaveVolume) rockAt(p Vector3) float64 {
+	// Внешний сегмент оси не закрыт массивом, чтобы устье оставалось открытым
+	dist, radius, _ := cv.nearestAxis(p, 1, caveRockRatio)
+	radius += caveRockThickness
+
+	rockNoise := cv.noiseGen.Perlin3D(
+		p.X*caveRockFrequency, p.Y*caveRockFrequency, p.Z*caveRockFrequency, cv.Seed+1)
+	return radius*(1+caveRockNoise*rockNoise) - dist
+}
+
+// tunnelAt - плотность породы относительно туннеля: отрицательная внутри полости
+func (cv *CaveVolume) tunnelAt(p Vector3) float64 {
+	dist, radius, floorY := cv.nearestAxis(p, 0, caveVerticalRatio)
+
+	// Неровные стенки: радиус туннеля искажается трехмерным шумом
+	wallNoise := cv.noiseGen.Perlin3D(
+		p.X*caveWallFrequency, p.Y*caveWallFrequency, p.Z*caveWallFrequency, cv.Seed)
+	wall := dist - radius*(1+caveWallNoise*wallNoise)
+
+	// Ровный пол, по которому можно ходить
+	floor := floorY - p.Y
+
+	return math.Max(wall, floor)
+}
+
+// nearestAxis возвращает расстояние до оси туннеля начиная с сегмента first
+// (по вертикали расстояние растянуто в 1/verticalRatio раз), радиус туннеля
+// и высоту пола в ближайшей точке оси
+func (cv *CaveVolume) nearestAxis(p Vector3, first int, verticalRatio float64) (float64, float64, float64) {
+	bestDist := math.MaxFloat64
+	bestRadius := cv.Radii[first]
+	bestFloor := cv.Path[first].Y - cv.Radii[first]*caveFloorRatio
+
+	for i := first; i+1 < len(cv.Path); i++ {
+		a, b := cv.Path[i], cv.Path[i+1]
+		ab := b.Sub(a)
+		lengthSq := ab.Dot(ab)
+
+		t := 0.0
+		if lengthSq > 0 {
+			t = math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/lengthSq))
+		}
+
+		closest := a.Add(ab.Mul(t))
+		dx := p.X - closest.X
+		dy := (p.Y - closest.Y) / verticalRatio
+		dz := p.Z - closest.Z
+		dist := math.Sqrt(dx*dx + dy*dy + dz*dz)
+
+		if dist < bestDist {
+			bestDist = dist
+			bestRadius = cv.Radii[i] + (cv.Radii[i+1]-cv.Radii[i])*t
+			bestFloor = closest.Y - bestRadius*caveFloorRatio
+		}
+	}
+
+	return bestDist, bestRadius, bestFloor
+}
+
+// NormalAt возвращает нормаль поверхности поля плотности (направлена в воздух)
+func (cv *CaveVolume) NormalAt(p Vector3) Vector3 {
+	e := caveNormalEpsilon
+	grad := Vector3{
+		X: cv.DensityAt(Vector3{X: p.X + e, Y: p.Y, Z: p.Z}) - cv.DensityAt(Vector3{X: p.X - e, Y: p.Y, Z: p.Z}),
+		Y: cv.DensityAt(Vector3{X: p.X, Y: p.Y + e, Z: p.Z}) - cv.DensityAt(Vector3{X: p.X, Y: p.Y - e, Z: p.Z}),
+		Z: cv.DensityAt(Vector3{X: p.X, Y: p.Y, Z: p.Z + e}) - cv.DensityAt(Vector3{X: p.X, Y: p.Y, Z: p.Z - e}),
+	}
+	return grad.Mul(-1).Normalize()
+}
+
+// EnclosureAt возвращает, насколько точка укрыта пещерой: 0 снаружи и у входа,
+// 1 в глубине, где не видно неба
+func (cv *CaveVolume) EnclosureAt(p Vector3) float64 {
+	if !cv.Contains(p) || cv.DensityAt(p) >= 0 {
+		return 0
+	}
+
+	// Над головой должна быть порода, иначе точка под открытым небом
+	if _, ceiling := cv.verticalBounds(p); math.IsInf(ceiling, 1) {
+		return 0
+	}
+
+	dx := p.X - cv.Entrance.X
+	dz := p.Z - cv.Entrance.Z
+	return math.Min(1, math.Sqrt(dx*dx+dz*dz)/caveDarknessDepth)
+}
+
+// ShadeAt - дешевая оценка затемнения стенки для рендеринга: 0 снаружи,
+// растет с удалением от входа для точек, обращенных внутрь туннеля
+func (cv *CaveVolume) ShadeAt(p Vector3) float64 {
+	if cv.tunnelAt(p) >= 0 {
+		return 0
+	}
+
+	dx := p.X - cv.Entrance.X
+	dz := p.Z - cv.Entrance.Z
+	return math.Min(1, math.Sqrt(dx*dx+dz*dz)/caveDarknessDepth)
+}
+
+// verticalBounds возвращает высоту пола под точкой в воздухе и высоту потолка над ней.
+// Если потолка нет в пределах caveQueryDistance, потолок равен +Inf
+func (cv *CaveVolume) verticalBounds(p Vector3) (float64, float64) {
+	down := Vector3{X: 0, Y: -1, Z: 0}
+	up := Vector3{X: 0, Y: 1, Z: 0}
+
+	ground := p.Y - caveQueryDistance
+	if t, ok := cv.march(p, down, caveQueryDistance, true); ok {
+		ground = p.Y - t
+	}
+
+	ceiling := math.Inf(1)
+	if t, ok := cv.march(p, up, caveQueryDistance, true); ok {
+		ceiling = p.Y + t
+	}
+
+	return ground, ceiling
+}
+
+// march идет от точки вдоль направления и возвращает расстояние до границы:
+// до входа в породу (toSolid) или до выхода в воздух
+func (cv *CaveVolume) march(origin, direction Vector3, maxDist float64, toSolid bool) (float64, bool) {
+	inside := func(d float64) bool {
+		if toSolid {
+			return d >= 0
+		}
+		return d < 0
+	}
+
+	prevT := 0.0
+	t := 0.0
+	for t <= maxDist {
+		d := cv.DensityAt(origin.Add(direction.Mul(t)))
+		if inside(d) {
+			if t == 0 {
+				return 0, true
+			}
+			return cv.refine(origin, direction, prevT, t, toSolid), true
+		}
+
+		// Плотность - приблизительное расстояние до границы, поэтому шаг адаптивный
+		prevT = t
+		t += math.Max(caveMarchStep, math.Abs(d)*0.5)
+	}
+
+	return 0, false
+}
+
+// refine уточняет положение границы между t0 (до границы) и t1 (после) делением пополам
+func (cv *CaveVolume) refine(origin, direction Vector3, t0, t1 float64, toSolid bool) float64 {
+	for i := 0; i < caveRefineSteps; i++ {
+		mid := (t0 + t1) / 2
+		solid := cv.DensityAt(origin.Add(direction.Mul(mid))) >= 0
+		if solid == toSolid {
+			t1 = mid
+		} else {
+			t0 = mid
+		}
+	}
+	return t1
+}
+
+// CaveAt возвращает пещеру, в области которой находится точка, или nil
+func (scene *ProceduralScene) CaveAt(p Vector3) *CaveVolume {
+	for _, cave := range scene.Caves {
+		if cave.Contains(p) {
+			return cave
+		}
+	}
+	return nil
+}
+
+// CaveGroundAt возвращает высоту опоры и потолка для точки-зонда внутри области
+// пещеры. Если зонд в породе, опорой считается ближайшая сверху граница с воздухом.
+// ok = false, если точка вне пещер и достаточно карты высот
+func (scene *ProceduralScene) CaveGroundAt(x, probeY, z float64) (ground, ceiling float64, ok bool) {
+	probe := Vector3{X: x, Y: probeY, Z: z}
+
+	cave := scene.CaveAt(probe)
+	if cave == nil {
+		return 0, 0, false
+	}
+
+	if cave.DensityAt(probe) >= 0 {
+		// Зонд в толще породы: ищем верх ближайшей полости или поверхность
+		up := Vector3{X: 0, Y: 1, Z: 0}
+		t, found := cave.march(probe, up, caveQueryDistance, false)
+		if !found {
+			return cave.terrain.HeightAt(x, z), math.Inf(1), true
+		}
+		probe.Y += t + caveMarchStep
+	}
+
+	ground, ceiling = cave.verticalBounds(probe)
+	return ground, ceiling, true
+}
+
+// CaveEnclosureAt возвращает степень укрытия точки пещерой (0..1)
+func (scene *ProceduralScene) CaveEnclosureAt(p Vector3) float64 {
+	if cave := scene.CaveAt(p); cave != nil {
+		return cave.EnclosureAt(p)
+	}
+	return 0
+}
+
+// LightAt оценивает освещенность точки (0..1): дневной свет, которого
+// меньше в глубине пещеры
+func (scene *ProceduralScene) LightAt(p Vector3) float64 {
+	daylight := math.Max(0, -math.Cos(scene.TimeOfDay*2*math.Pi)) // 0 в полночь, 1 в полдень
+	return daylight * (1 - scene.CaveEnclosureAt(p))
+}
+
+// placeCaves размещает входы в пещеры на крутых склонах гор и прокладывает туннели
+func (pg *ProceduralGenerator) placeCaves() {
+	scene := pg.currentScene
+	terrain := scene.Terrain
+	scene.Caves = make([]*CaveVolume, 0)
+
+	if !pg.config.Caves || pg.config.CaveCount <= 0 {
+		return
+	}
+
+	rng := newStream(scene.Seed, streamCaves)
+
+	// Кандидаты - крутые склоны в горных регионах, не у края карты
+	margin := int(caveSegmentLength * 2)
+	candidates := make([][2]int, 0)
+	for z := margin; z < terrain.Height-margin; z++ {
+		for x := margin; x < terrain.Width-margin; x++ {
+			if !caveRegions[terrain.Regions[z][x]] || terrain.Materials[z][x] == 1 {
+				continue
+			}
+			if terrain.slopeAt(x, z) < caveMinSlope {
+				continue
+			}
+			candidates = append(candidates, [2]int{x, z})
+		}
+	}
+
+	rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	for _, cell := range candidates {
+		if len(scene.Caves) >= pg.config.CaveCount {
+			break
+		}
+
+		x := float64(cell[0]) - float64(terrain.Width)/2.0
+		z := float64(cell[1]) - float64(terrain.Height)/2.0
+		if pg.isNearCave(x, z, caveMinSpacing-caveEntranceClear) {
+			continue
+		}
+
+		cave := pg.buildCave(len(scene.Caves)+1, cell[0], cell[1], rng)
+		if cave == nil || pg.overlapsCave(cave) {
+			continue
+		}
+		scene.Caves = append(scene.Caves, cave)
+	}
+}
+
+// buildCave прокладывает туннель от входа в клетке (gridX, gridZ) вверх по склону.
+// Возвращает nil, если туннель получается слишком коротким
+func (pg *ProceduralGenerator) buildCave(id, gridX, gridZ int, rng *rand.Rand) *CaveVolume {
+	terrain := pg.currentScene.Terrain
+
+	// Туннель уходит в склон - по направлению подъема
+	gradX, gradZ := terrain.gradientAt(gridX, gridZ)
+	length := math.Hypot(gradX, gradZ)
+	if length < 0.001 {
+		return nil
+	}
+	direction := Vector3{X: gradX / length, Y: 0, Z: gradZ / length}
+
+	x := float64(gridX) - float64(terrain.Width)/2.0
+	z := float64(gridZ) - float64(terrain.Height)/2.0
+	entrance := Vector3{X: x, Y: terrain.HeightAt(x, z), Z: z}
+
+	radius := caveTunnelRadius * (0.85 + rng.Float64()*0.3)
+
+	// Ось начинается у подножия склона: пол туннеля на уровне земли перед входом,
+	// а устье врезается в склон, оставляя над собой навес
+	outside := Vector3{X: entrance.X - direction.X*caveMouthDepth, Z: entrance.Z - direction.Z*caveMouthDepth}
+	axisY := math.Min(entrance.Y, terrain.HeightAt(outside.X, outside.Z)) + radius*caveFloorRatio
+	outside.Y = axisY
+
+	path := []Vector3{
+		outside,
+		{X: entrance.X, Y: axisY, Z: entrance.Z},
+	}
+	radii := []float64{radius, radius}
+
+	// Скальный массив вокруг зала не должен выходить за край карты
+	margin := int(radius*caveChamberScale+caveRockThickness) + 2
+
+	yaw := math.Atan2(direction.Z, direction.X)
+	current := path[1]
+	for i := 0; i < caveSegments; i++ {
+		yaw += (rng.Float64() - 0.5) * 0.7
+		step := caveSegmentLength * (0.8 + rng.Float64()*0.4)
+
+		next := Vector3{
+			X: current.X + math.Cos(yaw)*step,
+			Y: current.Y - rng.Float64()*1.2, // Туннель постепенно уходит вниз
+			Z: current.Z + math.Sin(yaw)*step,
+		}
+
+		// Свод над туннелем обеспечивает скальный массив, поэтому проверяем только
+		// край карты и воду
+		gx, gz := terrain.cellAt(next.X, next.Z)
+		if gx < margin || gz < margin || gx >= terrain.Width-margin || gz >= terrain.Height-margin {
+			break
+		}
+		if terrain.Materials[gz][gx] == 1 {
+			break
+		}
+
+		path = append(path, next)
+		radii = append(radii, radius)
+		current = next
+	}
+
+	// Слишком короткий туннель - просто ниша, такой вход не интересен
+	if len(path) < 4 {
+		return nil
+	}
+
+	// Зал в конце туннеля, пол зала остается на уровне туннеля
+	last := len(path) - 1
+	radii[last] = radius * caveChamberScale
+	path[last].Y += (radii[last] - radius) * caveFloorRatio
+
+	cave := &CaveVolume{
+		ID:        id,
+		Entrance:  entrance,
+		Direction: direction,
+		Path:      path,
+		Radii:     radii,
+		Seed:      deriveSeed(pg.currentScene.Seed, streamCaves) + int64(id),
+		terrain:   terrain,
+		noiseGen:  pg.noiseGen,
+	}
+	cave.computeBounds()
+
+	return cave
+}
+
+// computeBounds вычисляет границы области поля плотности по оси туннеля
+func (cv *CaveVolume) computeBounds() {
+	cv.Min = Vector3{X: math.MaxFloat64, Y: math.MaxFloat64, Z: math.MaxFloat64}
+	cv.Max = Vector3{X: -math.MaxFloat64, Y: -math.MaxFloat64, Z: -math.MaxFloat64}
+
+	for i, p := range cv.Path {
+		// Скальный массив шире туннеля, плюс запас на шум
+		r := (cv.Radii[i]+caveRockThickness)*(1+caveRockNoise) + 1
+		cv.Min.X = math.Min(cv.Min.X, p.X-r)
+		cv.Min.Y = math.Min(cv.Min.Y, p.Y-r)
+		cv.Min.Z = math.Min(cv.Min.Z, p.Z-r)
+		cv.Max.X = math.Max(cv.Max.X, p.X+r)
+		cv.Max.Y = math.Max(cv.Max.Y, p.Y+r)
+		cv.Max.Z = math.Max(cv.Max.Z, p.Z+r)
+	}
+}
+
+// isNearCave проверяет, находится ли точка у входа в пещеру или под ее скальным массивом
+func (pg *ProceduralGenerator) isNearCave(x, z, margin float64) bool {
+	p := Vector3{X: x, Y: 0, Z: z}
+	for _, cave := range pg.currentScene.Caves {
+		dx := x - cave.Entrance.X
+		dz := z - cave.Entrance.Z
+		limit := caveEntranceClear + margin
+		if dx*dx+dz*dz < limit*limit {
+			return true
+		}
+
+		if x < cave.Min.X-margin || x > cave.Max.X+margin || z < cave.Min.Z-margin || z > cave.Max.Z+margin {
+			continue
+		}
+
+		// Расстояние до оси по горизонтали (вертикальная составляющая не учитывается)
+		for i := 1; i+1 < len(cave.Path); i++ {
+			a := Vector3{X: cave.Path[i].X, Y: 0, Z: cave.Path[i].Z}
+			b := Vector3{X: cave.Path[i+1].X, Y: 0, Z: cave.Path[i+1].Z}
+			ab := b.Sub(a)
+			t := 0.0
+			if lengthSq := ab.Dot(ab); lengthSq > 0 {
+				t = math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/lengthSq))
+			}
+
+			radius := cave.Radii[i] + (cave.Radii[i+1]-cave.Radii[i])*t
+			limit := (radius+caveRockThickness)*(1+caveRockNoise) + margin
+			if Vector3Distance(p, a.Add(ab.Mul(t))) < limit {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// overlapsCave проверяет, пересекается ли область пещеры с уже размещенными
+func (pg *ProceduralGenerator) overlapsCave(cave *CaveVolume) bool {
+	for _, other := range pg.currentScene.Caves {
+		if cave.Min.X <= other.Max.X && cave.Max.X >= other.Min.X &&
+			cave.Min.Z <= other.Max.Z && cave.Max.Z >= other.Min.Z {
+			return true
+		}
+	}
+	return false
+}
+
+// rayBoxRange возвращает отрезок луча внутри выровненного по осям бокса
+func rayBoxRange(ray Ray, boxMin, boxMax Vector3) (float64, float64, bool) {
+	origin := [3]float64{ray.Origin.X, ray.Origin.Y, ray.Origin.Z}
+	direction := [3]float64{ray.Direction.X, ray.Direction.Y, ray.Direction.Z}
+	lo := [3]float64{boxMin.X, boxMin.Y, boxMin.Z}
+	hi := [3]float64{boxMax.X, boxMax.Y, boxMax.Z}
+
+	tNear := 0.0
+	tFar := math.MaxFloat64
+	for axis := 0; axis < 3; axis++ {
+		if math.Abs(direction[axis]) < 1e-9 {
+			if origin[axis] < lo[axis] || origin[axis] > hi[axis] {
+				return 0, 0, false
+			}
+			continue
+		}
+
+		t1 := (lo[axis] - origin[axis]) / direction[axis]
+		t2 := (hi[axis] - origin[axis]) / direction[axis]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tNear = math.Max(tNear, t1)
+		tFar = math.Min(tFar, t2)
+		if tNear > tFar {
+			return 0, 0, false
+		}
+	}
+
+	return tNear, tFar, true
+}
@@ -285,17 +285,22 @@ func (bc *BoxCollider) localToWorld(localPoint Vector3) Vector3 {
 
 // CollisionResolver отвечает за разрешение коллизий
 type CollisionResolver struct {
-	playerRadius float64          // Радиус коллизии игрока
-	playerHeight float64          // Высота коллизии игрока
-	colliders    []CollisionShape // Все коллайдеры в сцене
+	playerRadius    float64                  // Радиус коллизии игрока
+	playerHeight    float64                  // Высота коллизии игрока
+	colliders       []CollisionShape         // Все коллайдеры в сцене
+	objectColliders map[int][]CollisionShape // Коллайдеры объектов сцены по ID объекта
+	looseColliders  []CollisionShape         // Коллайдеры, добавленные напрямую, без объекта сцены
+	index           *SpatialHash             // Пространственный индекс сцены для отбора ближайших объектов
+	nearby          []CollisionShape         // Буфер для отобранных коллайдеров
 }
 
 // NewCollisionResolver создает новый решатель коллизий
 func NewCollisionResolver(playerRadius, playerHeight float64) *CollisionResolver {
 	return &CollisionResolver{
-		playerRadius: playerRadius,
-		playerHeight: playerHeight,
-		colliders:    make([]CollisionShape, 0),
+		playerRadius:    playerRadius,
+		playerHeight:    playerHeight,
+		colliders:       make([]CollisionShape, 0),
+		objectColliders: make(map[int][]CollisionShape),
 	}
 }
 
@@ -306,6 +311,7 @@ func (cr *CollisionResolver) AddSphereCollider(center Vector3, radius float64) *
 		Radius: radius,
 	}
 	cr.colliders = append(cr.colliders, collider)
+	cr.looseColliders = append(cr.looseColliders, collider)
 	return collider
 }
 
@@ -317,6 +323,7 @@ func (cr *CollisionResolver) AddCylinderCollider(base, top Vector3, radius float
 		Radius: radius,
 	}
 	cr.colliders = append(cr.colliders, collider)
+	cr.looseColliders = append(cr.looseColliders, collider)
 	return collider
 }
 
@@ -328,57 +335,160 @@ func (cr *CollisionResolver) AddBoxCollider(center, halfExtent, rotation Vector3
 		Rotation:   rotation,
 	}
 	cr.colliders = append(cr.colliders, collider)
+	cr.looseColliders = append(cr.looseColliders, collider)
 	return collider
 }
 
 // ClearColliders очищает все коллайдеры
 func (cr *CollisionResolver) ClearColliders() {
 	cr.colliders = make([]CollisionShape, 0)
+	cr.objectColliders = make(map[int][]CollisionShape)
+	cr.looseColliders = nil
+}
+
+// SetPlayerHeight меняет высоту коллизии игрока (приседание)
+func (cr *CollisionResolver) SetPlayerHeight(height float64) {
+	cr.playerHeight = height
+}
+
+// SetSpatialIndex задает индекс сцены: с ним проверяются только коллайдеры
+// объектов рядом с игроком, а не все коллайдеры сцены
+func (cr *CollisionResolver) SetSpatialIndex(index *SpatialHash) {
+	cr.index = index
+}
+
+// collidersNear возвращает коллайдеры, которые могут касаться игрока
+// в радиусе reach от точки (x, z)
+func (cr *CollisionResolver) collidersNear(x, z, reach float64) []CollisionShape {
+	if cr.index == nil {
+		return cr.colliders
+	}
+
+	cr.nearby = append(cr.nearby[:0], cr.looseColliders...)
+	cr.index.QueryRadius(Vector3{X: x, Z: z}, reach, func(obj *ProceduralObject) bool {
+		cr.nearby = append(cr.nearby, cr.objectColliders[obj.ID]...)
+		return true
+	})
+	return cr.nearby
 }
 
 // AddSceneObjectsAsColliders добавляет объекты сцены как коллайдеры
 func (cr *CollisionResolver) AddSceneObjectsAsColliders(objects []*ProceduralObject) {
 	for _, obj := range objects {
-		switch obj.Type {
-		case "tree":
-			// Для дерева используем цилиндр для ствола
-			base := obj.Position
-			top := Vector3{
-				X: base.X,
-				Y: base.Y + obj.Scale.Y*0.6, // 60% высоты для ствола
-				Z: base.Z,
-			}
-			radius := obj.Scale.X * 0.3 // 30% ширины для ствола
-			cr.AddCylinderCollider(base, top, radius)
-
-			// Добавляем сферу для кроны дерева
-			crownCenter := Vector3{
-				X: base.X,
-				Y: base.Y + obj.Scale.Y*0.7, // 70% высоты для центра кроны
-				Z: base.Z,
-			}
-			crownRadius := obj.Scale.X * 0.8 // 80% ширины для кроны
-			cr.AddSphereCollider(crownCenter, crownRadius)
-
-		case "rock":
-			// Для камня используем сферу
-			cr.AddSphereCollider(obj.Position, obj.Scale.X)
-
-		case "stump":
-			// Для пня используем цилиндр
-			base := obj.Position
-			top := Vector3{
-				X: base.X,
-				Y: base.Y + obj.Scale.Y,
-				Z: base.Z,
-			}
-			cr.AddCylinderCollider(base, top, obj.Scale.X)
+		cr.AddObjectColliders(obj)
+	}
+}
+
+// AddObjectColliders добавляет коллайдеры объекта сцены
+func (cr *CollisionResolver) AddObjectColliders(obj *ProceduralObject) {
+	shapes := objectColliderShapes(obj)
+	if len(shapes) == 0 {
+		return
+	}
+
+	cr.colliders = append(cr.colliders, shapes...)
+	cr.objectColliders[obj.ID] = append(cr.objectColliders[obj.ID], shapes...)
+}
+
+// RemoveObjectColliders удаляет коллайдеры объекта сцены
+func (cr *CollisionResolver) RemoveObjectColliders(objectID int) {
+	shapes, ok := cr.objectColliders[objectID]
+	if !ok {
+		return
+	}
+	delete(cr.objectColliders, objectID)
+
+	removed := make(map[CollisionShape]bool, len(shapes))
+	for _, shape := range shapes {
+		removed[shape] = true
+	}
+
+	kept := cr.colliders[:0]
+	for _, collider := range cr.colliders {
+		if !removed[collider] {
+			kept = append(kept, collider)
+		}
+	}
+	cr.colliders = kept
+}
+
+// UpdateObjectColliders пересоздает коллайдеры объекта после перемещения или изменения размера
+func (cr *CollisionResolver) UpdateObjectColliders(obj *ProceduralObject) {
+	cr.RemoveObjectColliders(obj.ID)
+	cr.AddObjectColliders(obj)
+}
+
+// objectColliderShapes строит формы коллизии для объекта сцены
+func objectColliderShapes(obj *ProceduralObject) []CollisionShape {
+	var shapes []CollisionShape
+
+	switch obj.Type {
+	case "tree":
+		// Для дерева используем цилиндр для ствола
+		base := obj.Position
+		top := Vector3{
+			X: base.X,
+			Y: base.Y + obj.Scale.Y*0.6, // 60% высоты для ствола
+			Z: base.Z,
+		}
+		radius := obj.Scale.X * 0.3 // 30% ширины для ствола
+		shapes = append(shapes, &CylinderCollider{Base: base, Top: top, Radius: radius})
+
+		// Добавляем сферу для кроны дерева
+		crownCenter := Vector3{
+			X: base.X,
+			Y: base.Y + obj.Scale.Y*0.7, // 70% высоты для центра кроны
+			Z: base.Z,
+		}
+		crownRadius := obj.Scale.X * 0.8 // 80% ширины для кроны
+		shapes = append(shapes, &SphereCollider{Center: crownCenter, Radius: crownRadius})
+
+	case "rock":
+		// Для камня используем сферу
+		shapes = append(shapes, &SphereCollider{Center: obj.Position, Radius: obj.Scale.X})
+
+	case "stump":
+		// Для пня используем цилиндр
+		base := obj.Position
+		top := Vector3{
+			X: base.X,
+			Y: base.Y + obj.Scale.Y,
+			Z: base.Z,
+		}
+		shapes = append(shapes, &CylinderCollider{Base: base, Top: top, Radius: obj.Scale.X})
+
+	case "strange":
+		// Для странных объектов используем сферу
+		shapes = append(shapes, &SphereCollider{Center: obj.Position, Radius: obj.Scale.X})
+
+	case stalkerObjectType:
+		// Сталкер - вертикальный цилиндр во весь рост
+		base := obj.Position
+		top := Vector3{
+			X: base.X,
+			Y: base.Y + obj.Scale.Y,
+			Z: base.Z,
+		}
+		shapes = append(shapes, &CylinderCollider{Base: base, Top: top, Radius: obj.Scale.X})
+
+	case "standing_stone":
+		// Для стоячих камней используем цилиндр
+		base := obj.Position
+		top := Vector3{
+			X: base.X,
+			Y: base.Y + obj.Scale.Y,
+			Z: base.Z,
+		}
+		shapes = append(shapes, &CylinderCollider{Base: base, Top: top, Radius: (obj.Scale.X + obj.Scale.Z) / 4})
 
-		case "strange":
-			// Для странных объектов используем сферу
-			cr.AddSphereCollider(obj.Position, obj.Scale.X)
+	default:
+		// Части построек (стены, надгробия, алтари) - ориентированные боксы
+		if isBoxObjectType(obj.Type) {
+			shapes = append(shapes, structureBoxCollider(obj))
 		}
 	}
+
+	return shapes
 }
 
 // ResolveCollision проверяет и разрешает коллизии для игрока
@@ -428,3 +538,165 @@ func (cr *CollisionResolver) IsPositionValid(position Vector3) bool {
 
 	return true
 }
+
+// CapsuleContact описывает контакт капсулы игрока с коллайдером
+type CapsuleContact struct {
+	Normal      Vector3 // Горизонтальная нормаль выталкивания (от коллайдера к игроку)
+	Penetration float64 // Глубина проникновения
+}
+
+// ResolveCapsule выталкивает вертикальную капсулу игрока (ступни в feet, высота playerHeight)
+// из коллайдеров по горизонтали, чтобы игрок скользил вдоль препятствий.
+// Препятствия, верх которых не выше feet.Y+stepHeight, не блокируют движение -
+// на них игрок поднимается (см. SupportHeight). Возвращает исправленную позицию
+// ступней и контакты, по которым нужно погасить скорость
+func (cr *CollisionResolver) ResolveCapsule(feet Vector3, stepHeight float64) (Vector3, []CapsuleContact) {
+	var contacts []CapsuleContact
+
+	// За все итерации игрок сдвигается не дальше, чем на пару радиусов
+	colliders := cr.collidersNear(feet.X, feet.Z, cr.playerRadius*3)
+
+	// Несколько итераций, чтобы разрешить одновременные контакты (угол между стенами)
+	for iteration := 0; iteration < 4; iteration++ {
+		moved := false
+
+		for _, collider := range colliders {
+			// На низкие препятствия можно шагнуть
+			if top, ok := shapeTopAt(collider, feet.X, feet.Z, cr.playerRadius); ok && top <= feet.Y+stepHeight {
+				continue
+			}
+
+			segmentPoint, shapePoint := cr.capsuleClosestPoints(collider, feet)
+
+			delta := segmentPoint.Sub(shapePoint)
+			distance := delta.Length()
+			inside := collider.Contains(segmentPoint)
+
+			if !inside && distance >= cr.playerRadius {
+				continue
+			}
+			if inside {
+				// Ось капсулы внутри формы: выталкиваем к ближайшей поверхности
+				delta = delta.Mul(-1)
+			}
+
+			horizontal := Vector3{X: delta.X, Y: 0, Z: delta.Z}
+			horizontalDist := horizontal.Length()
+
+			var normal Vector3
+			var penetration float64
+			switch {
+			case horizontalDist < 0.0001:
+				// Контакт строго сверху или снизу: выталкиваем от центра коллайдера
+				normal = horizontalDirection(segmentPoint.Sub(shapeCenter(collider)))
+				penetration = cr.playerRadius
+			case inside:
+				normal = horizontal.Mul(1.0 / horizontalDist)
+				penetration = horizontalDist + cr.playerRadius
+			default:
+				// Горизонтальный сдвиг, после которого расстояние до формы станет равно радиусу
+				normal = horizontal.Mul(1.0 / horizontalDist)
+				penetration = math.Sqrt(cr.playerRadius*cr.playerRadius-delta.Y*delta.Y) - horizontalDist
+			}
+
+			feet = feet.Add(normal.Mul(penetration))
+			contacts = append(contacts, CapsuleContact{Normal: normal, Penetration: penetration})
+			moved = true
+		}
+
+		if !moved {
+			break
+		}
+	}
+
+	return feet, contacts
+}
+
+// SupportHeight возвращает высоту самой высокой опоры среди коллайдеров под игроком
+// в точке (x, z), не выше maxY. ok = false, если опоры нет
+func (cr *CollisionResolver) SupportHeight(x, z, maxY float64) (float64, bool) {
+	best := -math.MaxFloat64
+	found := false
+
+	for _, collider := range cr.collidersNear(x, z, cr.playerRadius) {
+		top, ok := shapeTopAt(collider, x, z, cr.playerRadius)
+		if !ok || top > maxY {
+			continue
+		}
+		if top > best {
+			best = top
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+// capsuleClosestPoints находит ближайшие точки оси капсулы и формы
+// попеременным проецированием (для выпуклых форм сходится за несколько шагов)
+func (cr *CollisionResolver) capsuleClosestPoints(collider CollisionShape, feet Vector3) (Vector3, Vector3) {
+	a := Vector3{X: feet.X, Y: feet.Y + cr.playerRadius, Z: feet.Z}
+	b := Vector3{X: feet.X, Y: feet.Y + math.Max(cr.playerHeight-cr.playerRadius, cr.playerRadius), Z: feet.Z}
+
+	segmentPoint := a.Add(b).Mul(0.5)
+	shapePoint := collider.ClosestPoint(segmentPoint)
+	for i := 0; i < 3; i++ {
+		// Ось капсулы вертикальна, поэтому проекция - это ограничение по Y
+		segmentPoint.Y = math.Max(a.Y, math.Min(b.Y, shapePoint.Y))
+		shapePoint = collider.ClosestPoint(segmentPoint)
+	}
+
+	return segmentPoint, shapePoint
+}
+
+// shapeTopAt возвращает высоту верхней поверхности формы над точкой (x, z)
+// с учетом радиуса ступни игрока
+func shapeTopAt(collider CollisionShape, x, z, footRadius float64) (float64, bool) {
+	switch shape := collider.(type) {
+	case *SphereCollider:
+		d := math.Max(0, math.Hypot(x-shape.Center.X, z-shape.Center.Z)-footRadius)
+		if d >= shape.Radius {
+			return 0, false
+		}
+		return shape.Center.Y + math.Sqrt(shape.Radius*shape.Radius-d*d), true
+
+	case *CylinderCollider:
+		// Коллайдеры объектов сцены - вертикальные цилиндры
+		d := math.Hypot(x-shape.Base.X, z-shape.Base.Z)
+		if d >= shape.Radius+footRadius {
+			return 0, false
+		}
+		return math.Max(shape.Base.Y, shape.Top.Y), true
+
+	case *BoxCollider:
+		local := shape.worldToLocal(Vector3{X: x, Y: shape.Center.Y, Z: z})
+		if math.Abs(local.X) >= shape.HalfExtent.X+footRadius || math.Abs(local.Z) >= shape.HalfExtent.Z+footRadius {
+			return 0, false
+		}
+		return shape.Center.Y + shape.HalfExtent.Y, true
+	}
+
+	return 0, false
+}
+
+// shapeCenter возвращает опорную точку формы для выбора направления выталкивания
+func shapeCenter(collider CollisionShape) Vector3 {
+	switch shape := collider.(type) {
+	case *SphereCollider:
+		return shape.Center
+	case *CylinderCollider:
+		return shape.Base
+	case *BoxCollider:
+		return shape.Center
+	}
+	return Vector3{}
+}
+
+// horizontalDirection возвращает нормализованную проекцию вектора на плоскость XZ
+func horizontalDirection(v Vector3) Vector3 {
+	v.Y = 0
+	if length := v.Length(); length > 0.0001 {
+		return v.Mul(1.0 / length)
+	}
+	return Vector3{X: 0, Y: 0, Z: 1}
+}
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"nightmare/pkg/config"
+)
+
+// Имена потоков случайных чисел генератора мира.
+// Каждая подсистема получает свой поток, производный от ProceduralScene.Seed,
+// поэтому изменения в одной подсистеме не сдвигают случайность в других
+const (
+	streamMaterials   = "terrain.materials"
+	streamClearings   = "terrain.clearings"
+	streamGroves      = "terrain.groves"
+	streamSwampPits   = "terrain.swamp_pits"
+	streamIslands     = "terrain.islands"
+	streamPeaks       = "terrain.peaks"
+	streamRavines     = "terrain.ravines"
+	streamPaths       = "terrain.paths"
+	streamCaves       = "terrain.caves"
+	streamStructures  = "structures"
+	streamScatter     = "scatter"
+	streamObjectTypes = "scatter.objects"
+	streamEvolution   = "evolution"
+	streamWanderBot   = "bot.wander"
+	streamStalker     = "entity.stalker"
+	streamDirector    = "director"
+	streamInteraction = "interactions"
+	streamObjectives  = "objectives"
+)
+
+// deriveSeed получает сид подсистемы из сида сцены (FNV имени потока + финализатор splitmix64)
+func deriveSeed(sceneSeed int64, stream string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(stream))
+
+	x := uint64(sceneSeed) ^ h.Sum64()
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return int64(x)
+}
+
+// newStream создает независимый поток случайных чисел подсистемы
+func newStream(sceneSeed int64, stream string) *rand.Rand {
+	return rand.New(rand.NewSource(deriveSeed(sceneSeed, stream)))
+}
+
+// sortedKeys возвращает ключи карты метаданных в детерминированном порядке
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// worldHasher накапливает хэш состояния мира
+type worldHasher struct {
+	h   hash.Hash64
+	buf [8]byte
+}
+
+func (wh *worldHasher) float(v float64) {
+	binary.LittleEndian.PutUint64(wh.buf[:], math.Float64bits(v))
+	wh.h.Write(wh.buf[:])
+}
+
+func (wh *worldHasher) int(v int64) {
+	binary.LittleEndian.PutUint64(wh.buf[:], uint64(v))
+	wh.h.Write(wh.buf[:])
+}
+
+func (wh *worldHasher) string(s string) {
+	wh.int(int64(len(s)))
+	wh.h.Write([]byte(s))
+}
+
+func (wh *worldHasher) vector(v Vector3) {
+	wh.float(v.X)
+	wh.float(v.Y)
+	wh.float(v.Z)
+}
+
+func (wh *worldHasher) metadata(m map[string]float64) {
+	wh.int(int64(len(m)))
+	for _, k := range sortedKeys(m) {
+		wh.string(k)
+		wh.float(m[k])
+	}
+}
+
+// WorldHash возвращает хэш текущего состояния мира: ландшафта, построек,
+// объектов, погоды и тика симуляции. Одинаковый сид и одинаковые входные
+// данные должны давать одинаковый хэш при любой частоте кадров
+func (pg *ProceduralGenerator) WorldHash() uint64 {
+	pg.mutex.RLock()
+	defer pg.mutex.RUnlock()
+
+	wh := &worldHasher{h: fnv.New64a()}
+
+	wh.int(int64(pg.tick))
+
+	scene := pg.currentScene
+	if scene == nil {
+		return wh.h.Sum64()
+	}
+
+	wh.int(scene.Seed)
+	wh.string(scene.BiomeType)
+	wh.float(scene.TimeOfDay)
+	wh.float(scene.LevelOfFear)
+	wh.metadata(scene.Weather)
+	wh.metadata(scene.Atmosphere)
+
+	if terrain := scene.Terrain; terrain != nil {
+		wh.int(int64(terrain.Width))
+		wh.int(int64(terrain.Height))
+		for z := 0; z < terrain.Height; z++ {
+			for x := 0; x < terrain.Width; x++ {
+				wh.float(terrain.Data[z][x])
+				wh.int(int64(terrain.Materials[z][x]))
+				wh.float(terrain.Humidity[z][x])
+				wh.string(terrain.Regions[z][x])
+			}
+		}
+	}
+
+	for _, cave := range scene.Caves {
+		wh.int(int64(cave.ID))
+		wh.vector(cave.Entrance)
+		for i, p := range cave.Path {
+			wh.vector(p)
+			wh.float(cave.Radii[i])
+		}
+	}
+
+	for _, structure := range scene.Structures {
+		wh.int(int64(structure.ID))
+		wh.string(structure.Type)
+		wh.vector(structure.Center)
+		wh.float(structure.Rotation)
+	}
+
+	for _, obj := range scene.Objects {
+		wh.int(int64(obj.ID))
+		wh.string(obj.Type)
+		wh.vector(obj.Position)
+		wh.vector(obj.Scale)
+		wh.vector(obj.Rotation)
+		wh.int(obj.Seed)
+		wh.metadata(obj.Metadata)
+		if obj.Seen {
+			wh.int(1)
+		} else {
+			wh.int(0)
+		}
+	}
+
+	return wh.h.Sum64()
+}
+
+// VerifyDeterminism генерирует мир дважды с одним сидом, прогоняет каждый из них
+// на заданное число тиков с разной частотой кадров и сравнивает хэши.
+// Возвращает хэш мира или ошибку, если миры разошлись
+func VerifyDeterminism(cfg config.ProceduralConfig, ticks uint64) (uint64, error) {
+	// Сид определяется один раз, чтобы оба прогона использовали один и тот же мир
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+
+	// Шаг кадра меньше тика, поэтому оба прогона останавливаются ровно на нужном тике
+	frameSteps := []float64{1.0 / 30.0, 1.0 / 144.0}
+	hashes := make([]uint64, len(frameSteps))
+
+	for i, dt := range frameSteps {
+		pg, err := NewProceduralGenerator(cfg)
+		if err != nil {
+			return 0, err
+		}
+
+		pg.GenerateInitialWorld()
+		for pg.Tick() < ticks {
+			pg.Update(dt)
+		}
+
+		hashes[i] = pg.WorldHash()
+	}
+
+	if hashes[0] != hashes[1] {
+		return 0, fmt.Errorf("world diverged for seed %d after %d ticks: %016x at %.0f FPS vs %016x at %.0f FPS",
+			cfg.Seed, ticks, hashes[0], 1/frameSteps[0], hashes[1], 1/frameSteps[1])
+	}
+
+	return hashes[0], nil
+}
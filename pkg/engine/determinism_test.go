@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"testing"
+
+	"nightmare/pkg/config"
+)
+
+// determinismConfig загружает настройки игры с фиксированным сидом
+func determinismConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg, err := config.LoadConfig("../../config.yaml")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	cfg.Procedural.Seed = 42
+	cfg.Mods.Enabled = false
+	return cfg
+}
+
+// firstDivergence возвращает первый тик, на котором хэши разошлись, или -1
+func firstDivergence(a, b []uint64) int {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		return min(len(a), len(b))
+	}
+	return -1
+}
+
+func TestWorldDeterminismAcrossFrameRates(t *testing.T) {
+	cfg := determinismConfig(t)
+
+	// Хэш мира после каждого мирового тика при данном шаге кадра
+	run := func(dt float64, ticks uint64) []uint64 {
+		pg, err := NewProceduralGenerator(cfg.Procedural)
+		if err != nil {
+			t.Fatalf("new generator: %v", err)
+		}
+		pg.GenerateInitialWorld()
+
+		hashes := make([]uint64, 0, ticks)
+		for pg.Tick() < ticks {
+			before := pg.Tick()
+			pg.Update(dt)
+			if pg.Tick() != before {
+				hashes = append(hashes, pg.WorldHash())
+			}
+		}
+		return hashes
+	}
+
+	const ticks = 100
+	slow := run(1.0/30.0, ticks)
+	fast := run(1.0/144.0, ticks)
+	if tick := firstDivergence(slow, fast); tick >= 0 {
+		t.Fatalf("world diverged at tick %d", tick)
+	}
+	if len(slow) != ticks {
+		t.Fatalf("got %d world hashes, want %d", len(slow), ticks)
+	}
+}
+
+func TestWorldHashDependsOnSeed(t *testing.T) {
+	worldHash := func(seed int64) uint64 {
+		cfg := determinismConfig(t)
+		cfg.Procedural.Seed = seed
+
+		pg, err := NewProceduralGenerator(cfg.Procedural)
+		if err != nil {
+			t.Fatalf("new generator: %v", err)
+		}
+		pg.GenerateInitialWorld()
+		return pg.WorldHash()
+	}
+
+	first, second := worldHash(42), worldHash(42)
+	if first != second {
+		t.Fatalf("same seed gave different worlds: %016x vs %016x", first, second)
+	}
+	if other := worldHash(43); other == first {
+		t.Fatalf("seeds 42 and 43 gave the same world hash %016x", first)
+	}
+}
@@ -0,0 +1,484 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+
+	"nightmare/internal/util"
+)
+
+// DirectorPhase - фаза темпа страха
+type DirectorPhase int
+
+const (
+	DirectorBuildUp DirectorPhase = iota // Напряжение растет, события все чаще и сильнее
+	DirectorPeak                         // Кульминация: появление сталкера, сильные испуги
+	DirectorRelief                       // Передышка: директор молчит, пока игрок не успокоится
+)
+
+var directorPhaseNames = [...]string{"build-up", "peak", "relief"}
+
+func (p DirectorPhase) String() string {
+	if p < 0 || int(p) >= len(directorPhaseNames) {
+		return "unknown"
+	}
+	return directorPhaseNames[p]
+}
+
+// DirectorEventKind - что директор решил показать игроку
+type DirectorEventKind int
+
+const (
+	DirectorSound   DirectorEventKind = iota // Звук в темноте
+	DirectorGlitch                           // Помехи в изображении
+	DirectorScare                            // Испуг у странного объекта рядом с игроком
+	DirectorStalker                          // Сталкер показался на краю зрения
+	DirectorObject                           // За спиной игрока появился странный объект
+)
+
+var directorEventNames = [...]string{"sound", "glitch", "scare", "stalker", "object"}
+
+func (k DirectorEventKind) String() string {
+	if k < 0 || int(k) >= len(directorEventNames) {
+		return "unknown"
+	}
+	return directorEventNames[k]
+}
+
+// DirectorEvent - событие, которое движок показывает игроку: звук, помехи,
+// дрожь геймпада. Мир (сталкер, объекты) директор меняет сам
+type DirectorEvent struct {
+	Kind      DirectorEventKind
+	Intensity float64 // 0..1
+	Position  Vector3 // Откуда событие: источник звука, объект, сталкер
+}
+
+// Настройки директора
+const (
+	directorRestingHeartRate = 65.0 // Пульс спокойного игрока, уд/мин
+	directorMaxHeartRate     = 170.0
+
+	// Вклад признаков в стресс (оценку пульса)
+	directorShockDecay   = 0.05 // Насколько в секунду проходит испуг
+	directorSprintStress = 0.25 // Бег
+	directorVoiceStress  = 0.4  // Голос в микрофон: крик, тяжелое дыхание
+	directorThreatStress = 0.5  // Сталкер рядом
+	directorDamageShock  = 30.0 // Урон, который пугает до предела
+	directorChaseShock   = 0.6  // Сталкер бросился в погоню
+
+	directorBuildUpTime = 150.0 // Секунд, за которые напряжение нарастает от нуля до пика
+	directorPeakStress  = 0.75  // При таком стрессе нарастание сменяется пиком, а пик - передышкой
+	directorPeakMin     = 15.0  // Самый короткий пик, с
+	directorPeakMax     = 40.0
+	directorReliefMin   = 30.0  // Самая короткая передышка, с
+	directorReliefMax   = 120.0 // Дольше передышка не длится, даже если игрок не успокоился
+	directorCalmStress  = 0.25  // Передышка кончается, когда стресс опустился ниже
+
+	directorIntervalCalm  = 45.0 // Секунд между событиями в начале нарастания
+	directorIntervalTense = 8.0  // На пике напряжения
+	directorIntervalPeak  = 5.0  // Во время пика
+	directorEscalation    = 0.4  // На столько события чаще, когда все цели выполнены
+	directorEscalateJump  = 0.3  // На столько поднимается напряжение, когда цель выполнена
+
+	directorScareRadius   = 5.0  // Странные объекты пугают ближе этого
+	directorScareCooldown = 30.0 // Испуг у странного объекта повторяется не чаще, с
+
+	directorSoundMin   = 5.0 // Звуки раздаются на таком расстоянии от игрока, м
+	directorSoundMax   = 15.0
+	directorObjectMin  = 8.0 // Объекты появляются за спиной на таком расстоянии
+	directorObjectMax  = 14.0
+	directorStalkerMin = 18.0 // Сталкер показывается на таком расстоянии
+	directorStalkerMax = 28.0
+	directorStalkerFar = 30.0 // Сталкер ближе этого уже на виду, переносить его незачем
+	directorAttempts   = 8    // Сколько мест пробовать для объекта или сталкера
+)
+
+// Director - ИИ-режиссер страха в духе Left 4 Dead. По косвенным признакам
+// пульса (недавние испуги, бег, голос в микрофон, близость сталкера, время
+// без событий) оценивает стресс игрока и ведет темп: нарастание, пик,
+// передышка. Решает, какие события устроить и когда: звуки, помехи, испуги
+// у странных объектов, появления сталкера, новые объекты.
+// Директор - часть симуляции: его решения детерминированы сидом мира и вводом
+type Director struct {
+	rng      *rand.Rand
+	rate     float64           // AIConfig.AdaptationRate: как быстро оценка стресса следует за игроком, 1/с
+	analyzer *BehaviorAnalyzer // Следит за реакциями на события; nil, если анализ поведения выключен
+	sanity   *Sanity           // Испуги отнимают рассудок; nil, если рассудок выключен
+
+	phase     DirectorPhase
+	phaseTime float64 // Секунд в текущей фазе
+	tension   float64 // Запланированное напряжение (0..1)
+	nextEvent float64 // Секунд до следующего события
+
+	stress      float64 // Оценка стресса игрока (0..1)
+	shock       float64 // Недавние испуги; чем дольше нет событий, тем меньше
+	scareCool   float64 // Секунд до следующего испуга у странного объекта
+	lastHealth  float64
+	lastStalker StalkerState
+
+	escalation float64 // Доля выполненных целей (0..1): чем ближе развязка, тем злее директор
+
+	events []DirectorEvent
+}
+
+// newDirector создает директора, который начинает с нарастания
+func newDirector(sim *Simulation, adaptationRate float64) *Director {
+	d := &Director{
+		rng:        newStream(sim.Seed(), streamDirector),
+		rate:       adaptationRate,
+		analyzer:   sim.analyzer,
+		sanity:     sim.sanity,
+		phase:      DirectorBuildUp,
+		lastHealth: sim.Player().Health,
+	}
+	d.nextEvent = d.interval()
+	return d
+}
+
+// Phase возвращает текущую фазу
+func (d *Director) Phase() DirectorPhase {
+	return d.phase
+}
+
+// Stress возвращает оценку стресса игрока (0..1)
+func (d *Director) Stress() float64 {
+	return d.stress
+}
+
+// HeartRate возвращает оценку пульса игрока, уд/мин
+func (d *Director) HeartRate() float64 {
+	return util.Lerp(directorRestingHeartRate, directorMaxHeartRate, d.stress)
+}
+
+// Tension возвращает запланированное напряжение (0..1): растет при
+// нарастании, держится на пике и спадает в передышку
+func (d *Director) Tension() float64 {
+	return d.tension
+}
+
+// TakeEvents возвращает накопленные события и сбрасывает их
+func (d *Director) TakeEvents() []DirectorEvent {
+	events := d.events
+	d.events = nil
+	return events
+}
+
+// Update продвигает директора на один тик. voice - громкость голоса игрока
+// в микрофон из кадра ввода
+func (d *Director) Update(sim *Simulation, voice, deltaTime float64) {
+	d.phaseTime += deltaTime
+	d.scareCool = math.Max(0, d.scareCool-deltaTime)
+	d.estimateStress(sim, voice, deltaTime)
+
+	switch d.phase {
+	case DirectorBuildUp:
+		d.tension = math.Min(1, d.tension+deltaTime*(1+d.escalation)/directorBuildUpTime)
+		if d.tension >= 1 || d.stress >= directorPeakStress {
+			d.setPhase(sim, DirectorPeak)
+		}
+
+	case DirectorPeak:
+		if d.phaseTime >= directorPeakMax || (d.phaseTime >= directorPeakMin && d.stress >= directorPeakStress) {
+			d.setPhase(sim, DirectorRelief)
+		}
+
+	case DirectorRelief:
+		// Сталкер не нападает, пока игрок переводит дух
+		if sim.stalker != nil {
+			sim.stalker.HoldOff(sim, deltaTime)
+		}
+		d.tension = math.Max(0, d.tension-deltaTime/directorReliefMin)
+		if d.phaseTime >= directorReliefMax || (d.phaseTime >= directorReliefMin && d.stress < directorCalmStress) {
+			d.setPhase(sim, DirectorBuildUp)
+		}
+	}
+
+	// В передышку директор молчит, даже странные объекты не пугают
+	if d.phase != DirectorRelief {
+		d.scareNearStrange(sim)
+
+		d.nextEvent -= deltaTime
+		if d.nextEvent <= 0 {
+			d.spawnEvent(sim)
+			d.nextEvent = d.interval()
+		}
+	}
+
+	sim.procedural.GetCurrentScene().LevelOfFear = util.Lerp(0.1, 1.0, d.tension)
+}
+
+// estimateStress обновляет оценку стресса по косвенным признакам пульса
+func (d *Director) estimateStress(sim *Simulation, voice, deltaTime float64) {
+	player := sim.Player()
+
+	// Урон (падения, нападения) и начало погони пугают; испуг проходит
+	// со временем, прошедшим с последнего события
+	if damage := d.lastHealth - player.Health; damage > 0 {
+		d.frighten(damage / directorDamageShock)
+	}
+	d.lastHealth = player.Health
+
+	threat := 0.0
+	if stalker := sim.stalker; stalker != nil {
+		if stalker.State() == StalkerChase && d.lastStalker != StalkerChase {
+			d.frighten(directorChaseShock)
+		}
+		d.lastStalker = stalker.State()
+		threat = stalker.Threat(player)
+	}
+	d.shock = math.Min(1, d.shock) * math.Exp(-directorShockDecay*deltaTime)
+
+	target := d.shock + directorThreatStress*threat + directorVoiceStress*util.Clamp(voice, 0, 1)
+	if player.IsSprinting {
+		target += directorSprintStress
+	}
+	target = util.Clamp(target, 0, 1)
+
+	// Оценка следует за признаками с задержкой: пульс не скачет мгновенно
+	d.stress += (target - d.stress) * (1 - math.Exp(-d.rate*deltaTime))
+}
+
+// setPhase переключает фазу
+func (d *Director) setPhase(sim *Simulation, phase DirectorPhase) {
+	d.phase = phase
+	d.phaseTime = 0
+
+	switch phase {
+	case DirectorBuildUp:
+		d.tension = 0
+		d.nextEvent = d.interval()
+
+	case DirectorPeak:
+		// Кульминация начинается сразу: сталкер показывается, а если его нет - сильный испуг
+		d.tension = 1
+		if !d.showStalker(sim, 1) {
+			d.emit(DirectorGlitch, 1, sim.Player().Position)
+			d.emit(DirectorSound, 1, d.aroundPlayer(sim, directorSoundMin, directorSoundMax))
+		}
+		d.nextEvent = d.interval()
+	}
+}
+
+// interval возвращает время до следующего события: чем выше напряжение,
+// тем чаще события, с разбросом, чтобы их нельзя было предугадать
+func (d *Director) interval() float64 {
+	base := util.Lerp(directorIntervalCalm, directorIntervalTense, d.tension)
+	if d.phase == DirectorPeak {
+		base = directorIntervalPeak
+	}
+	base *= 1 - directorEscalation*d.escalation
+	return base * (0.5 + d.rng.Float64())
+}
+
+// spawnEvent выбирает событие по напряжению: сначала только звуки,
+// потом помехи и объекты, ближе к пику - появления сталкера. Профиль
+// страха делает чаще то, что на этого игрока заметно действует
+func (d *Director) spawnEvent(sim *Simulation) {
+	intensity := util.Lerp(util.Lerp(0.3, 0.6, d.escalation), 1.0, d.tension)
+
+	// Если сталкера не показать или объекту нет места, выбирается событие попроще
+	switch d.chooseEvent() {
+	case DirectorStalker:
+		if d.showStalker(sim, intensity) {
+			return
+		}
+		fallthrough
+	case DirectorObject:
+		if d.placeObject(sim, intensity) {
+			return
+		}
+		fallthrough
+	case DirectorGlitch:
+		d.emit(DirectorGlitch, intensity, sim.Player().Position)
+	default:
+		d.emit(DirectorSound, intensity, d.aroundPlayer(sim, directorSoundMin, directorSoundMax))
+	}
+}
+
+// chooseEvent выбирает вид события. С ростом напряжения открываются
+// помехи (выше 0.25), объекты (0.4) и сталкер (0.6); вес каждого вида
+// умножается на то, как он действует на игрока и подходит его привычкам
+func (d *Director) chooseEvent() DirectorEventKind {
+	t := d.tension
+	weights := [...]float64{
+		DirectorSound:   math.Min(t, 0.25),
+		DirectorGlitch:  util.Clamp(t-0.25, 0, 0.15),
+		DirectorScare:   0, // Испуги устраивают сами странные объекты
+		DirectorStalker: math.Max(0, t-0.6),
+		DirectorObject:  util.Clamp(t-0.4, 0, 0.2),
+	}
+
+	total := 0.0
+	for kind := range weights {
+		weights[kind] *= d.weight(DirectorEventKind(kind))
+		total += weights[kind]
+	}
+	if total <= 0 {
+		return DirectorSound
+	}
+
+	roll := d.rng.Float64() * total
+	for kind, weight := range weights {
+		if roll < weight {
+			return DirectorEventKind(kind)
+		}
+		roll -= weight
+	}
+	return DirectorSound
+}
+
+// weight возвращает множитель частоты событий вида kind по профилю страха
+func (d *Director) weight(kind DirectorEventKind) float64 {
+	if d.analyzer == nil {
+		return 1
+	}
+	return d.analyzer.Profile().Weight(kind)
+}
+
+// scareNearStrange пугает игрока, подошедшего к странному объекту
+func (d *Director) scareNearStrange(sim *Simulation) {
+	if d.scareCool > 0 {
+		return
+	}
+
+	player := sim.Player().Position
+	closest, found := directorScareRadius, false
+	var source Vector3
+	sim.procedural.GetCurrentScene().Index.QueryRadius(player, directorScareRadius, func(obj *ProceduralObject) bool {
+		if dist := Vector3Distance(player, obj.Position); obj.Type == "strange" && dist < closest {
+			closest, source, found = dist, obj.Position, true
+		}
+		return true
+	})
+	if !found {
+		return
+	}
+
+	// Чем ближе объект и выше напряжение, тем сильнее испуг
+	intensity := (0.5 + (directorScareRadius-closest)/directorScareRadius*0.5) * util.Lerp(0.6, 1.0, d.tension)
+	d.emit(DirectorScare, intensity, source)
+
+	// Испуги, которые не действуют на игрока, повторяются реже
+	d.scareCool = directorScareCooldown / d.weight(DirectorScare)
+}
+
+// showStalker переносит сталкера туда, где игрок может его заметить.
+// false, если сталкера нет, он уже рядом или ему не нашлось места
+func (d *Director) showStalker(sim *Simulation, intensity float64) bool {
+	stalker := sim.stalker
+	if stalker == nil || stalker.State() == StalkerChase ||
+		horizontalDistance(stalker.Position(), sim.Player().Position) < directorStalkerFar {
+		return false
+	}
+
+	// Впереди, на краю зрения
+	player := sim.Player()
+	eye := player.Position.Add(Vector3{Y: player.Height/2 - 0.1})
+	yaw := math.Atan2(player.Direction.X, player.Direction.Z)
+	for attempt := 0; attempt < directorAttempts; attempt++ {
+		angle := yaw + (d.rng.Float64()-0.5)*math.Pi/2
+		distance := directorStalkerMin + d.rng.Float64()*(directorStalkerMax-directorStalkerMin)
+		x, z := player.Position.X+math.Sin(angle)*distance, player.Position.Z+math.Cos(angle)*distance
+		if !d.placeable(sim, x, z) {
+			continue
+		}
+
+		position := Vector3{X: x, Y: sim.procedural.GetCurrentScene().Terrain.HeightAt(x, z), Z: z}
+		if !sim.sight.Visible(eye, position.Add(Vector3{Y: stalkerEyeHeight}), stalker.ObjectID()) {
+			continue
+		}
+		stalker.Appear(sim, position)
+		d.emit(DirectorStalker, intensity, position)
+		return true
+	}
+	return false
+}
+
+// placeObject ставит странный объект за спиной игрока: обернувшись, он
+// увидит то, чего там не было. false, если места не нашлось
+func (d *Director) placeObject(sim *Simulation, intensity float64) bool {
+	player := sim.Player()
+	scene := sim.procedural.GetCurrentScene()
+	behind := math.Atan2(-player.Direction.X, -player.Direction.Z)
+
+	for attempt := 0; attempt < directorAttempts; attempt++ {
+		angle := behind + (d.rng.Float64()-0.5)*math.Pi/2
+		distance := directorObjectMin + d.rng.Float64()*(directorObjectMax-directorObjectMin)
+		x, z := player.Position.X+math.Sin(angle)*distance, player.Position.Z+math.Cos(angle)*distance
+		if !d.placeable(sim, x, z) || sim.procedural.isInsideStructure(x, z, 0.5) || sim.procedural.isNearCave(x, z, 0.5) {
+			continue
+		}
+
+		size := 0.5 + d.rng.Float64()
+		obj := &ProceduralObject{
+			ID:       scene.NextObjectID(),
+			Type:     "strange",
+			Position: Vector3{X: x, Y: scene.Terrain.HeightAt(x, z), Z: z},
+			Scale:    Vector3{X: size, Y: size * 3, Z: size},
+			Rotation: Vector3{Y: d.rng.Float64() * 2 * math.Pi},
+			Metadata: map[string]float64{
+				"atmosphere.fear":       0.7 + 0.3*intensity,
+				"atmosphere.dread":      0.8 + 0.2*intensity,
+				"visuals.twisted":       0.7 + d.rng.Float64()*0.3,
+				"conditions.silhouette": 0.8 + d.rng.Float64()*0.2,
+				"conditions.unnatural":  1.0,
+			},
+			Seed: d.rng.Int63(),
+		}
+		scene.AddObject(obj)
+		d.emit(DirectorObject, intensity, obj.Position)
+		return true
+	}
+	return false
+}
+
+// placeable сообщает, что в точке можно стоять и она внутри карты
+func (d *Director) placeable(sim *Simulation, x, z float64) bool {
+	terrain := sim.procedural.GetCurrentScene().Terrain
+	halfW := float64(terrain.Width)/2 - stalkerMapMargin
+	halfH := float64(terrain.Height)/2 - stalkerMapMargin
+	return math.Abs(x) <= halfW && math.Abs(z) <= halfH && sim.physics.CanStandAt(x, z)
+}
+
+// aroundPlayer возвращает случайную точку вокруг игрока
+func (d *Director) aroundPlayer(sim *Simulation, minDistance, maxDistance float64) Vector3 {
+	player := sim.Player().Position
+	angle := d.rng.Float64() * 2 * math.Pi
+	distance := minDistance + d.rng.Float64()*(maxDistance-minDistance)
+	return Vector3{X: player.X + math.Sin(angle)*distance, Y: player.Y, Z: player.Z + math.Cos(angle)*distance}
+}
+
+// escalate сообщает, что игрок выполнил цель и теперь ближе к развязке
+// (progress - доля выполненных целей): напряжение растет быстрее, события
+// чаще и сильнее, а сама цель не проходит тихо
+func (d *Director) escalate(sim *Simulation, progress float64) {
+	d.escalation = util.Clamp(progress, 0, 1)
+	if d.phase == DirectorBuildUp {
+		d.tension = math.Min(1, d.tension+directorEscalateJump)
+	}
+	d.emit(DirectorSound, util.Lerp(0.5, 1.0, d.escalation), d.aroundPlayer(sim, directorSoundMin, directorSoundMax))
+}
+
+// startle пугает игрока событием, которое устроил не директор
+// (например, взаимодействие с объектом)
+func (d *Director) startle(amount float64) {
+	d.shock += amount
+}
+
+// frighten пугает игрока тем, что устроил или заметил сам директор
+func (d *Director) frighten(amount float64) {
+	d.shock += amount
+	if d.sanity != nil {
+		d.sanity.Scare(amount)
+	}
+}
+
+// emit сообщает о событии; событие и само пугает игрока. Анализатор
+// поведения смотрит, как игрок на него отреагирует
+func (d *Director) emit(kind DirectorEventKind, intensity float64, position Vector3) {
+	d.events = append(d.events, DirectorEvent{Kind: kind, Intensity: intensity, Position: position})
+	d.frighten(0.3 * intensity)
+	if d.analyzer != nil {
+		d.analyzer.Notice(kind, position)
+	}
+}
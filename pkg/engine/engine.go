@@ -11,21 +11,39 @@ import (
 	"github.com/go-gl/glfw/v3.3/glfw"
 
 	"nightmare/internal/logger"
+	"nightmare/internal/util"
 	"nightmare/pkg/config"
 )
 
 type Engine struct {
-	window      *glfw.Window
-	config      *config.Config
-	logger      *logger.Logger
-	renderer    *PixelRenderer
-	procedural  *ProceduralGenerator
-	audioEngine *AudioEngine
-	physics     *PhysicsSystem
-	isRunning   bool
-	lastUpdate  time.Time
-	frameRate   int
-	input       *InputHandler
+	window         *glfw.Window
+	config         *config.Config
+	logger         *logger.Logger
+	renderer       *PixelRenderer
+	sim            *Simulation // World and player physics, stepped by fixed ticks
+	procedural     *ProceduralGenerator
+	audioEngine    *AudioEngine
+	physics        *PhysicsSystem
+	isRunning      bool
+	lastUpdate     time.Time
+	frameRate      int
+	input          *InputHandler
+	clock          *FixedStep // Fixed-rate clock for physics and world simulation
+	live           *liveInput // Input from the keyboard, mouse and gamepad
+	source         InputSource
+	replay         *Replay         // Replay being played back instead of live input
+	recorder       *ReplayRecorder // Records the input of every tick if set
+	profile        *FearProfile    // Player's fear profile at the start of the session, nil if not analyzed
+	saveProfile    bool            // Save the profile on exit: only live play tells how the player reacts
+	camera         *CameraController
+	raytracer      *Raytracer      // Follows the camera
+	behaviors      *BehaviorSystem // Behavior trees of the world and of scene objects
+	hallucinations *Hallucinations // Objects only the player sees at low sanity, nil if disabled
+	fear           float64         // Fear level around the player from the last tick
+	scareCooldown  float64         // Seconds until a strange object may scare again, without the director
+	gamepadName    string          // Connected gamepad, empty if none
+	message        string          // Last objective or death message, shown for messageTime more seconds
+	messageTime    float64
 	// Window dimensions
 	windowWidth  int
 	windowHeight int
@@ -78,6 +96,7 @@ func NewEngine(cfg *config.Config, log *logger.Logger) (*Engine, error) {
 		logger:         log,
 		isRunning:      false,
 		frameRate:      cfg.Graphics.FrameRate,
+		clock:          NewFixedStep(SimulationTickRate, maxSimulationSteps),
 		windowWidth:    cfg.Graphics.Width,
 		windowHeight:   cfg.Graphics.Height,
 		frameCount:     0,
@@ -90,8 +109,14 @@ func NewEngine(cfg *config.Config, log *logger.Logger) (*Engine, error) {
 		engine.resizeCallback(w, width, height)
 	}))
 
-	// Create input handler
+	// Create input handler with the key bindings from the config
 	engine.input = NewInputHandler(window)
+	actions, err := NewActionMap(cfg.Controls)
+	if err != nil {
+		log.Warnf("Some controls are ignored: %v", err)
+	}
+	engine.input.SetActionMap(actions)
+	engine.input.SetGamepad(cfg.Gamepad.Enabled, cfg.Gamepad.Deadzone)
 
 	// Initialize pixel renderer
 	pixelRenderer, err := NewPixelRenderer(cfg.Renderer)
@@ -100,11 +125,28 @@ func NewEngine(cfg *config.Config, log *logger.Logger) (*Engine, error) {
 	}
 	engine.renderer = pixelRenderer
 
-	procedural, err := NewProceduralGenerator(cfg.Procedural)
+	sim, err := NewSimulation(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize procedural generator: %v", err)
 	}
-	engine.procedural = procedural
+	engine.sim = sim
+	engine.procedural = sim.Procedural()
+	engine.physics = sim.Physics()
+
+	// The fear profile carries over from earlier sessions. Without the AI
+	// it only shapes the simple scares and is not updated
+	if cfg.AI.BehaviorAnalysis && cfg.AI.ProfilePath != "" {
+		profile, err := LoadFearProfile(cfg.AI.ProfilePath)
+		if err != nil {
+			// Keep the damaged file for a look instead of overwriting it
+			log.Warnf("Failed to load fear profile: %v. Starting a new one.", err)
+		} else {
+			engine.profile = profile
+			engine.saveProfile = cfg.AI.Enabled
+			sim.SetFearProfile(profile)
+			log.Infof("Fear profile loaded: %d sessions, %.0f minutes played", profile.Sessions, profile.PlayTime/60)
+		}
+	}
 
 	audioEngine, err := NewAudioEngine(cfg.Audio)
 	if err != nil {
@@ -116,8 +158,35 @@ func NewEngine(cfg *config.Config, log *logger.Logger) (*Engine, error) {
 	}
 	engine.audioEngine = audioEngine
 
-	// Initialize physics system
-	engine.physics = NewPhysicsSystem()
+	// The camera owns the look direction of the player and the raytracer
+	engine.camera = NewCameraController(cfg.Camera)
+
+	raytracer, err := NewRaytracer(cfg.Raytracer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize raytracer: %v", err)
+	}
+	engine.raytracer = raytracer
+
+	engine.live = &liveInput{
+		input:        engine.input,
+		camera:       engine.camera,
+		tickDuration: engine.clock.TickDuration,
+	}
+	if cfg.AI.MicEnabled {
+		// The AI director hears the player through the microphone
+		engine.live.audio = audioEngine
+	}
+	engine.source = engine.live
+
+	// Behavior trees from mods think for haunted objects and creatures
+	engine.behaviors = NewBehaviorSystem(log, sim.Seed())
+	engine.registerEngineBehaviors(engine.behaviors.Registry())
+	engine.behaviors.LoadMods(cfg.Mods)
+
+	// Hallucinations are part of sanity: switching sanity off turns them off too
+	if cfg.Sanity.Enabled && cfg.Sanity.Hallucinations {
+		engine.hallucinations = NewHallucinations()
+	}
 
 	return engine, nil
 }
@@ -128,13 +197,18 @@ func (e *Engine) Run() {
 	e.isRunning = true
 	e.lastUpdate = time.Now()
 
-	// Set up world
+	// Set up world and physics
 	e.logger.Info("Starting world generation")
-	e.procedural.GenerateInitialWorld()
+	e.sim.Start()
 	e.logger.Info("World generation completed")
+	for _, err := range e.sim.ModErrors() {
+		e.logger.Warnf("Skipping interactions: %v", err)
+	}
+	e.raytracer.SetScene(e.procedural.GetCurrentScene())
+	e.camera.SetDirection(e.physics.GetPlayer().Direction)
 
-	// Set up physics
-	e.physics.SetScene(e.procedural.GetCurrentScene())
+	// Mouse look
+	e.input.SetCursorCaptured(e.config.Camera.CaptureCursor)
 
 	// Initialize atmosphere
 	e.logger.Info("Generating atmosphere")
@@ -162,18 +236,35 @@ func (e *Engine) Run() {
 			lastFpsTime = currentTime
 		}
 
-		deltaTime := currentTime.Sub(e.lastUpdate).Seconds()
+		frameTime := currentTime.Sub(e.lastUpdate).Seconds()
 		e.lastUpdate = currentTime
 
 		// Process input
 		e.input.Update()
-		e.processInput(deltaTime)
+		e.processInput()
 
-		// Update physics
-		e.physics.Update(deltaTime)
+		// Simulate whole fixed ticks; a slow frame runs several of them
+		// (up to maxSimulationSteps), a fast one may run none
+		tickDuration := e.clock.TickDuration
+		for steps := e.clock.Advance(frameTime); steps > 0; steps-- {
+			e.simulateTick(tickDuration)
+		}
+		e.handlePhysicsEvents(e.physics.TakeEvents())
+		if stalker := e.sim.Stalker(); stalker != nil {
+			e.handleStalkerEvents(stalker, stalker.TakeEvents())
+		}
+		if director := e.sim.Director(); director != nil {
+			e.handleDirectorEvents(director.TakeEvents())
+		}
+		e.handleInteractionEvents(e.sim.Interactions().TakeEvents())
+		if objectives := e.sim.Objectives(); objectives != nil {
+			e.handleObjectiveEvents(objectives.TakeEvents())
+		}
+		e.messageTime = math.Max(0, e.messageTime-frameTime)
 
-		// Update game state
-		e.update(deltaTime)
+		// Audio and camera motion follow real time, not simulation ticks
+		e.audioEngine.Update(frameTime)
+		e.camera.Update(frameTime, e.physics.GetPlayer(), e.fear)
 
 		// Render frame
 		e.render()
@@ -195,6 +286,109 @@ func (e *Engine) Run() {
 	e.cleanup()
 }
 
+// RecordTo records the input of every simulation tick to a replay file.
+// Call it before Run; the recording is closed when the engine shuts down
+func (e *Engine) RecordTo(path string) error {
+	recorder, err := NewReplayRecorder(path, e.config, e.sim.Seed(), e.profile)
+	if err != nil {
+		return err
+	}
+	e.recorder = recorder
+	e.logger.Infof("Recording input to %s", path)
+	return nil
+}
+
+// PlayReplay feeds the replay to the simulation instead of live input.
+// The engine must be created with the replay's config. When the replay
+// ends, the final state is checked and control returns to the player
+func (e *Engine) PlayReplay(replay *Replay) {
+	replay.Rewind()
+	e.replay = replay
+	e.source = replay
+
+	// The director must see the player as they were when the replay was recorded
+	e.profile = replay.Profile
+	e.saveProfile = false
+	e.sim.SetFearProfile(replay.Profile)
+	e.logger.Infof("Playing replay: %d ticks, seed %d", len(replay.Frames), replay.Seed)
+}
+
+// DriveWith feeds input from a bot or a script to the simulation instead of
+// live input; the camera follows its view. When the source runs out,
+// control returns to the player. A bot's reactions say nothing about the
+// player, so the fear profile is not saved
+func (e *Engine) DriveWith(source InputSource) {
+	e.replay = nil
+	e.source = source
+	e.saveProfile = false
+}
+
+// finishSource is called when a replay, bot or script runs out of input.
+// It checks the state at the end of a replay and switches back to live input
+func (e *Engine) finishSource() {
+	if e.replay != nil {
+		if !e.replay.Complete {
+			e.logger.Infof("Replay ended after %d ticks (recording was cut off, nothing to compare)", e.sim.Tick())
+		} else if hash := e.sim.StateHash(); hash != e.replay.Hash {
+			e.logger.Errorf("Replay diverged after %d ticks: state %016x, recorded %016x",
+				e.sim.Tick(), hash, e.replay.Hash)
+		} else {
+			e.logger.Infof("Replay ended after %d ticks, state matches the recording", e.sim.Tick())
+		}
+	} else {
+		e.logger.Infof("Scripted input ended after %d ticks, control returns to the player", e.sim.Tick())
+	}
+
+	e.replay = nil
+	e.source = e.live
+}
+
+// stopRecording closes the recording with the current simulation state
+func (e *Engine) stopRecording() {
+	if e.recorder == nil {
+		return
+	}
+
+	ticks := e.recorder.Ticks()
+	if err := e.recorder.Close(e.sim.StateHash()); err != nil {
+		e.logger.Errorf("Failed to save replay: %v", err)
+	} else {
+		e.logger.Infof("Replay saved: %d ticks", ticks)
+	}
+	e.recorder = nil
+}
+
+// storeFearProfile saves what this session learned about the player
+func (e *Engine) storeFearProfile() {
+	profile := e.sim.FearProfile()
+	if !e.saveProfile || profile == nil {
+		return
+	}
+
+	if err := SaveFearProfile(profile, e.config.AI.ProfilePath); err != nil {
+		e.logger.Errorf("Failed to save fear profile: %v", err)
+		return
+	}
+	e.logger.Infof("Fear profile saved: %d sessions, %d turn-arounds, %.0f%% of the time in the dark",
+		profile.Sessions, profile.TurnArounds, profile.DarkShare()*100)
+}
+
+// Behaviors returns the behavior tree system: register conditions and
+// actions and add trees before Run
+func (e *Engine) Behaviors() *BehaviorSystem {
+	return e.behaviors
+}
+
+// Tick returns the number of fixed simulation ticks since the engine started
+func (e *Engine) Tick() uint64 {
+	return e.clock.Tick()
+}
+
+// Seed returns the world seed (chosen at random if the config has none)
+func (e *Engine) Seed() int64 {
+	return e.sim.Seed()
+}
+
 // Vector3Distance calculates the distance between two Vector3 points
 func Vector3Distance(a, b Vector3) float64 {
 	dx := a.X - b.X
@@ -231,45 +425,21 @@ func (e *Engine) render() {
 
 // populateObjectsInView calculates which objects are in the player's view
 func (e *Engine) populateObjectsInView(scene *SceneData, procScene *ProceduralScene, playerPos, viewDir Vector3) {
-	// Field of view in radians
-	fov := 60.0 * (math.Pi / 180.0)
-
 	// Dot product threshold for FOV
-	cosHalfFOV := math.Cos(fov / 2)
-
-	// Max view distance
-	maxDistance := 100.0
-
-	// Process all objects
-	for _, obj := range procScene.Objects {
-		// Calculate direction and distance to object
-		dirToObj := Vector3{
-			X: obj.Position.X - playerPos.X,
-			Y: obj.Position.Y - playerPos.Y,
-			Z: obj.Position.Z - playerPos.Z,
-		}
-		distance := math.Sqrt(dirToObj.X*dirToObj.X + dirToObj.Y*dirToObj.Y + dirToObj.Z*dirToObj.Z)
-
-		// Skip if too far
-		if distance > maxDistance {
-			continue
-		}
-
-		// Normalize direction
-		dirToObj.X /= distance
-		dirToObj.Y /= distance
-		dirToObj.Z /= distance
+	cosHalfFOV := math.Cos(viewFieldOfView / 2)
 
-		// Calculate dot product to check if in FOV
-		dotProduct := dirToObj.X*viewDir.X + dirToObj.Y*viewDir.Y + dirToObj.Z*viewDir.Z
+	// Process only objects from grid cells intersecting the view cone
+	procScene.Index.QueryFrustum(playerPos, viewDir, cosHalfFOV, viewMaxDistance, func(obj *ProceduralObject) bool {
+		// The same view test decides what gaze-aware evolution may change
+		dirToObj, distance, visible := inView(playerPos, viewDir, obj.Position, 0)
 
 		// If object is in front of player and within FOV
-		if dotProduct > cosHalfFOV {
+		if visible {
 			// Calculate size based on distance
 			size := obj.Scale.X / math.Max(1.0, distance/5.0)
 
 			// Calculate visibility based on distance, fog, and darkness
-			visibility := 1.0 - math.Min(1.0, distance/maxDistance)
+			visibility := 1.0 - math.Min(1.0, distance/viewMaxDistance)
 
 			// Modify visibility based on fog
 			if fogAmount, ok := scene.SpecialEffects["fog"]; ok {
@@ -296,12 +466,16 @@ func (e *Engine) populateObjectsInView(scene *SceneData, procScene *ProceduralSc
 			// Add to objects in view
 			scene.ObjectsInView = append(scene.ObjectsInView, sceneObj)
 		}
-	}
+
+		return true
+	})
 }
 
 // cleanup performs cleanup before exiting
 func (e *Engine) cleanup() {
 	e.logger.Info("Shutting down engine...")
+	e.stopRecording()
+	e.storeFearProfile()
 	e.audioEngine.Shutdown()
 	e.renderer.Close()
 	glfw.Terminate()
@@ -353,7 +527,7 @@ func (e *Engine) analyzeEnvironment(playerPos Vector3) map[string]float64 {
 	nearbyObjects := 0
 	totalMood := make(map[string]float64)
 
-	for _, obj := range scene.Objects {
+	scene.Index.QueryRadius(playerPos, detectionRadius, func(obj *ProceduralObject) bool {
 		dist := Vector3Distance(playerPos, obj.Position)
 
 		if dist < detectionRadius {
@@ -374,7 +548,8 @@ func (e *Engine) analyzeEnvironment(playerPos Vector3) map[string]float64 {
 				result["visuals.distorted"] += 0.2 * influence
 			}
 		}
-	}
+		return true
+	})
 
 	// Average object influence
 	if nearbyObjects > 0 {
@@ -387,6 +562,40 @@ func (e *Engine) analyzeEnvironment(playerPos Vector3) map[string]float64 {
 		}
 	}
 
+	// Influence of nearby structures: grows while approaching, peaks inside
+	const structureApproachRadius = 25.0
+	for _, structure := range scene.Structures {
+		dx := playerPos.X - structure.Center.X
+		dz := playerPos.Z - structure.Center.Z
+		dist := math.Sqrt(dx*dx + dz*dz)
+
+		if dist >= structure.Radius+structureApproachRadius {
+			continue
+		}
+
+		influence := 1.0
+		if dist > structure.Radius {
+			influence = 1.0 - (dist-structure.Radius)/structureApproachRadius
+		}
+
+		for key, value := range structure.Metadata {
+			result[key] = math.Max(result[key], value*influence)
+		}
+		result["conditions.structure"] = math.Max(result["conditions.structure"], influence)
+	}
+
+	// Wind reaches the player only in the open
+	result["conditions.wind"] = scene.Weather["wind"]
+
+	// Inside caves it's dark and sheltered from the wind regardless of the time of day
+	if enclosure := scene.CaveEnclosureAt(playerPos); enclosure > 0 {
+		result["conditions.enclosed"] = enclosure
+		result["conditions.darkness"] = math.Max(result["conditions.darkness"], 0.6+0.4*enclosure)
+		result["visuals.dark"] = math.Max(result["visuals.dark"], 0.6+0.4*enclosure)
+		result["conditions.wind"] *= 1.0 - enclosure
+		result["atmosphere.dread"] += 0.3 * enclosure
+	}
+
 	// Limit values to 0-1 range
 	for key, value := range result {
 		result[key] = math.Max(0, math.Min(1, value))
@@ -395,7 +604,8 @@ func (e *Engine) analyzeEnvironment(playerPos Vector3) map[string]float64 {
 	return result
 }
 
-// processEnvironmentTriggers processes environment triggers
+// processEnvironmentTriggers processes environment triggers. It runs only
+// without the AI director, which paces scares itself
 func (e *Engine) processEnvironmentTriggers(playerPos Vector3, deltaTime float64) {
 	// Skip if scene is not initialized
 	if e.procedural == nil || e.procedural.currentScene == nil {
@@ -404,14 +614,32 @@ func (e *Engine) processEnvironmentTriggers(playerPos Vector3, deltaTime float64
 
 	scene := e.procedural.currentScene
 
+	// The fear profile makes what scares this player happen more often.
+	// The simulation's profile learns during the session, the snapshot
+	// from the start is the fallback
+	profile := e.sim.FearProfile()
+	if profile == nil {
+		profile = e.profile
+	}
+	weight := func(kind DirectorEventKind) float64 {
+		if profile == nil {
+			return 1
+		}
+		return profile.Weight(kind)
+	}
+
+	// Like the director, scares repeat sooner for a player they work on
+	e.scareCooldown = math.Max(0, e.scareCooldown-deltaTime)
+
 	// Check distance to "strange" objects for fear triggers
-	for _, obj := range scene.Objects {
+	const scareRadius = directorScareRadius
+	scene.Index.QueryRadius(playerPos, scareRadius, func(obj *ProceduralObject) bool {
 		if obj.Type == "strange" {
 			dist := Vector3Distance(playerPos, obj.Position)
 
 			// Close object triggers reaction
-			if dist < 5.0 && e.audioEngine.CanPlayEffect("scare") {
-				intensity := 0.5 + (5.0-dist)/5.0*0.5 // 0.5-1.0 based on distance
+			if dist < scareRadius && e.scareCooldown == 0 {
+				intensity := 0.5 + (scareRadius-dist)/scareRadius*0.5 // 0.5-1.0 based on distance
 
 				scareMeta := map[string]float64{
 					"atmosphere.fear":      0.8,
@@ -422,18 +650,22 @@ func (e *Engine) processEnvironmentTriggers(playerPos Vector3, deltaTime float64
 
 				e.audioEngine.PlayProceduralSound("scare", float32(intensity), 0.0, scareMeta)
 				e.logger.Debug("Scare triggered by strange object at distance %.2f", dist)
+				e.rumble(intensity, 0.4)
+				e.scareCooldown = directorScareCooldown / weight(DirectorScare)
 
 				// Random image distortion when scared
-				if intensity > 0.7 {
+				if intensity > 1-0.3*weight(DirectorGlitch) {
 					e.renderer.ApplyGlitchEffect(0.5, 0.3)
 				}
+				return false
 			}
 		}
-	}
+		return true
+	})
 
 	// Wandering sounds in darkness
 	if scene.TimeOfDay < 0.25 || scene.TimeOfDay > 0.75 { // night or evening
-		if e.audioEngine.CanPlayEffect("ambient") && rand.Float64() < 0.01*deltaTime {
+		if e.audioEngine.CanPlayEffect("ambient") && rand.Float64() < 0.01*weight(DirectorSound)*deltaTime {
 			// Random direction for sound
 			angle := rand.Float64() * 2 * math.Pi
 			distance := 5.0 + rand.Float64()*10.0
@@ -454,112 +686,233 @@ func (e *Engine) processEnvironmentTriggers(playerPos Vector3, deltaTime float64
 	}
 }
 
-// processInput handles user input
-func (e *Engine) processInput(deltaTime float64) {
-	// Close game on ESC
-	if e.input.IsKeyPressed(glfw.KeyEscape) {
-		e.isRunning = false
-		return
+// handlePhysicsEvents plays footsteps for the surface under the player and
+// reacts to hard landings and deaths. Footstep volume follows the noise the
+// player made
+func (e *Engine) handlePhysicsEvents(events PhysicsEvents) {
+	if events.Footsteps > 0 {
+		stepMeta := map[string]float64{
+			"surface.hardness":   events.Surface.Hardness,
+			"conditions.wetness": events.Surface.Wetness,
+			"player.noise":       events.Noise,
+		}
+		e.audioEngine.PlayFootstep(float32(0.15+0.5*events.Noise), 0.0, stepMeta)
 	}
 
-	// Process movement (WASD)
-	if e.input.IsKeyDown(glfw.KeyW) {
-		// Move forward
-		e.physics.MoveForward(e.input.IsKeyDown(glfw.KeyLeftShift))
-	}
-	if e.input.IsKeyDown(glfw.KeyS) {
-		// Move backward
-		e.physics.MoveBackward()
-	}
-	if e.input.IsKeyDown(glfw.KeyA) {
-		// Strafe left
-		e.physics.MoveLeft()
-	}
-	if e.input.IsKeyDown(glfw.KeyD) {
-		// Strafe right
-		e.physics.MoveRight()
+	if events.Damage > 0 {
+		player := e.physics.GetPlayer()
+		e.logger.Warnf("Fall damage %.0f at %.1f m/s, health %.0f/%.0f",
+			events.Damage, events.ImpactSpeed, player.Health, player.MaxHealth)
+
+		// A hard landing blurs the picture for a moment
+		e.renderer.ApplyGlitchEffect(float32(math.Min(1.0, events.Damage/50.0)), 0.4)
+		e.rumble(math.Min(1.0, events.Damage/30.0), 0.25)
 	}
 
-	// Rotation (arrow keys)
-	rotateSpeed := 1.5 * deltaTime
-	if e.input.IsKeyDown(glfw.KeyLeft) {
-		e.physics.RotateLeft(deltaTime)
+	if events.Died {
+		e.logger.Warnf("Player died, back at the start (death %d)", e.sim.Deaths())
+		e.renderer.ApplyGlitchEffect(1.0, 1.5)
+		e.rumble(1.0, 0.8)
+		e.message = "You wake up where it all began"
+		e.messageTime = objectiveMessageTime
 	}
-	if e.input.IsKeyDown(glfw.KeyRight) {
-		e.physics.RotateRight(deltaTime)
+}
+
+// handleStalkerEvents plays the stalker's sounds from its direction and
+// reacts to its attacks
+func (e *Engine) handleStalkerEvents(stalker *Stalker, events StalkerEvents) {
+	player := e.physics.GetPlayer()
+	toStalker := stalker.Position().Sub(player.Position)
+	pan := float32(math.Max(-1, math.Min(1, horizontalDirection(toStalker).Dot(e.raytracer.GetCameraRight()))))
+
+	if events.StateChanged {
+		e.logger.Debugf("Stalker is now %s, %.1f m away", events.State, horizontalDistance(stalker.Position(), player.Position))
+
+		if events.State == StalkerChase {
+			chaseMeta := map[string]float64{
+				"atmosphere.fear":      1.0,
+				"atmosphere.tension":   1.0,
+				"conditions.unnatural": 0.9,
+			}
+			e.audioEngine.PlayProceduralSound("scare", 1.0, pan, chaseMeta)
+			e.renderer.ApplyGlitchEffect(0.6, 0.5)
+			e.rumble(0.6, 0.5)
+		}
 	}
-	if e.input.IsKeyDown(glfw.KeyUp) {
-		// Look up - adjust player's pitch
-		if player := e.physics.GetPlayer(); player != nil {
-			direction := player.Direction
 
-			// Calculate current pitch
-			pitch := math.Asin(math.Max(-0.99, math.Min(0.99, direction.Y)))
+	if events.Attacked {
+		e.logger.Warnf("Stalker attack: %.0f damage, health %.0f/%.0f", events.Damage, player.Health, player.MaxHealth)
+		e.renderer.ApplyGlitchEffect(1.0, 0.8)
+		e.rumble(1.0, 0.6)
+	}
+}
 
-			// Adjust pitch (look up)
-			newPitch := math.Max(-math.Pi/2.5, pitch-rotateSpeed)
+// handleDirectorEvents presents the events scheduled by the AI director:
+// sounds come from the event's direction, strong events glitch the picture
+// and rumble the gamepad
+func (e *Engine) handleDirectorEvents(events []DirectorEvent) {
+	player := e.physics.GetPlayer()
+
+	for _, event := range events {
+		toEvent := event.Position.Sub(player.Position)
+		pan := float32(math.Max(-1, math.Min(1, horizontalDirection(toEvent).Dot(e.raytracer.GetCameraRight()))))
+		intensity := float32(event.Intensity)
+		e.logger.Debugf("Director: %s (%.2f) %.1f m away", event.Kind, event.Intensity, horizontalDistance(event.Position, player.Position))
+
+		switch event.Kind {
+		case DirectorSound:
+			soundMeta := map[string]float64{
+				"atmosphere.fear":    0.3 + 0.4*event.Intensity,
+				"atmosphere.ominous": 0.4 + 0.3*event.Intensity,
+				"atmosphere.dread":   0.2 + 0.3*event.Intensity,
+			}
+			e.audioEngine.PlayProceduralSound("ambient", 0.3+0.4*intensity, pan, soundMeta)
 
-			// Recalculate direction vector
-			direction.Y = math.Sin(newPitch)
-			horizontalScale := math.Cos(newPitch)
+		case DirectorGlitch:
+			e.renderer.ApplyGlitchEffect(0.3+0.5*intensity, 0.3)
 
-			// Get yaw angle (horizontal direction)
-			yaw := math.Atan2(direction.X, direction.Z)
+		case DirectorScare:
+			scareMeta := map[string]float64{
+				"atmosphere.fear":      0.8,
+				"atmosphere.tension":   0.9,
+				"visuals.distorted":    0.7,
+				"conditions.unnatural": 0.8,
+			}
+			e.audioEngine.PlayProceduralSound("scare", intensity, pan, scareMeta)
+			e.rumble(event.Intensity, 0.4)
+			if event.Intensity > 0.7 {
+				e.renderer.ApplyGlitchEffect(0.5, 0.3)
+			}
 
-			// Update direction vector with new pitch
-			direction.X = horizontalScale * math.Sin(yaw)
-			direction.Z = horizontalScale * math.Cos(yaw)
+		case DirectorStalker:
+			// A quiet sting: the player should notice the figure, not be told about it
+			stingMeta := map[string]float64{
+				"atmosphere.dread":     0.9,
+				"atmosphere.tension":   0.8,
+				"conditions.unnatural": 0.9,
+			}
+			e.audioEngine.PlayProceduralSound("scare", 0.3*intensity, pan, stingMeta)
 
-			// Normalize direction
-			length := math.Sqrt(direction.X*direction.X + direction.Y*direction.Y + direction.Z*direction.Z)
-			if length > 0 {
-				direction.X /= length
-				direction.Y /= length
-				direction.Z /= length
+		case DirectorObject:
+			objectMeta := map[string]float64{
+				"atmosphere.dread":     0.7,
+				"conditions.unnatural": 0.8,
 			}
+			e.audioEngine.PlayProceduralSound("interact", 0.2+0.3*intensity, pan, objectMeta)
+		}
+	}
+}
 
-			// Set new direction
-			player.Direction = direction
+// handleInteractionEvents presents what the player's interactions did:
+// the sound comes from the object, a glitch flickers the picture
+func (e *Engine) handleInteractionEvents(events []InteractionEvent) {
+	player := e.physics.GetPlayer()
+
+	for _, event := range events {
+		toObject := event.Position.Sub(player.Position)
+		pan := float32(math.Max(-1, math.Min(1, horizontalDirection(toObject).Dot(e.raytracer.GetCameraRight()))))
+		e.logger.Debugf("Interaction: %s %s #%d", event.Name, event.Target, event.ObjectID)
+
+		if event.Sound != "" {
+			interactMeta := map[string]float64{
+				"atmosphere.fear":    0.5,
+				"atmosphere.tension": 0.6,
+			}
+			// The sound is as eerie as the object has become
+			for key, value := range event.Metadata {
+				interactMeta[key] = math.Max(interactMeta[key], value)
+			}
+			e.audioEngine.PlayProceduralSound(event.Sound, 0.7, pan, interactMeta)
+		}
+		if event.Glitch {
+			e.renderer.ApplyGlitchEffect(0.6, 0.4)
+			e.rumble(0.5, 0.3)
+		}
+		if event.Message != "" {
+			e.logger.Infof("%s", event.Message)
 		}
 	}
-	if e.input.IsKeyDown(glfw.KeyDown) {
-		// Look down - adjust player's pitch
-		if player := e.physics.GetPlayer(); player != nil {
-			direction := player.Direction
+}
 
-			// Calculate current pitch
-			pitch := math.Asin(math.Max(-0.99, math.Min(0.99, direction.Y)))
+// handleObjectiveEvents presents the story: every step is logged and shown
+// on screen for a while, a found note rustles, a finished objective tolls
+func (e *Engine) handleObjectiveEvents(events []ObjectiveEvent) {
+	for _, event := range events {
+		if event.Message != "" {
+			e.logger.Infof("%s", event.Message)
+			e.message = event.Message
+			e.messageTime = objectiveMessageTime
+		}
 
-			// Adjust pitch (look down)
-			newPitch := math.Min(math.Pi/2.5, pitch+rotateSpeed)
+		switch {
+		case event.Finished:
+			finishMeta := map[string]float64{
+				"atmosphere.ominous": 0.7,
+				"atmosphere.tension": 0.6,
+			}
+			e.audioEngine.PlayProceduralSound("ambient", 0.6, 0, finishMeta)
+		case event.Kind == ObjectiveNotes && event.Message != "":
+			noteMeta := map[string]float64{
+				"atmosphere.tension": 0.3,
+			}
+			e.audioEngine.PlayProceduralSound("interact", 0.4, 0, noteMeta)
+		}
+	}
+}
 
-			// Recalculate direction vector
-			direction.Y = math.Sin(newPitch)
-			horizontalScale := math.Cos(newPitch)
+// handleHallucinationSounds plays the phantom footsteps from where they seem to come
+func (e *Engine) handleHallucinationSounds(sounds []HallucinationSound) {
+	player := e.physics.GetPlayer()
 
-			// Get yaw angle (horizontal direction)
-			yaw := math.Atan2(direction.X, direction.Z)
+	for _, sound := range sounds {
+		toSound := sound.Position.Sub(player.Position)
+		pan := float32(math.Max(-1, math.Min(1, horizontalDirection(toSound).Dot(e.raytracer.GetCameraRight()))))
+		stepMeta := map[string]float64{
+			"surface.hardness":   0.4,
+			"conditions.wetness": e.procedural.GetCurrentScene().Weather["mist"],
+		}
+		e.audioEngine.PlayPhantomFootstep(float32(sound.Volume), pan, stepMeta)
+	}
+}
 
-			// Update direction vector with new pitch
-			direction.X = horizontalScale * math.Sin(yaw)
-			direction.Z = horizontalScale * math.Cos(yaw)
+// processInput handles per-frame input: key presses that toggle something
+// or request an action. Held movement keys are read on every simulation
+// tick by the live input source
+func (e *Engine) processInput() {
+	// Close game (Escape by default)
+	if e.input.ActionPressed(ActionQuit) {
+		e.isRunning = false
+		return
+	}
 
-			// Normalize direction
-			length := math.Sqrt(direction.X*direction.X + direction.Y*direction.Y + direction.Z*direction.Z)
-			if length > 0 {
-				direction.X /= length
-				direction.Y /= length
-				direction.Z /= length
-			}
+	// Release cursor (Tab by default) frees the cursor, clicking into the window captures it again
+	if e.input.ActionPressed(ActionReleaseCursor) {
+		e.input.SetCursorCaptured(false)
+	} else if !e.input.IsCursorCaptured() && e.config.Camera.CaptureCursor &&
+		e.input.IsMouseButtonPressed(glfw.MouseButtonLeft) {
+		e.input.SetCursorCaptured(true)
+	}
 
-			// Set new direction
-			player.Direction = direction
+	// Report gamepads coming and going
+	if name := e.input.GamepadName(); name != e.gamepadName {
+		if name != "" {
+			e.logger.Infof("Gamepad connected: %s", name)
+		} else {
+			e.logger.Infof("Gamepad disconnected: %s", e.gamepadName)
 		}
+		e.gamepadName = name
 	}
 
-	// Jump
-	if e.input.IsKeyPressed(glfw.KeySpace) {
-		e.physics.Jump()
+	// Mouse look every frame, so the view doesn't lag behind the mouse.
+	// The player turns on the next tick
+	if e.input.IsCursorCaptured() {
+		delta := e.input.GetMouseDelta()
+		e.camera.Look(delta[0], delta[1])
+	}
+
+	// Jump: the press is latched until the next simulation tick consumes it
+	if e.input.ActionPressed(ActionJump) {
+		e.live.RequestJump()
 
 		// Generate interaction sound
 		interactMeta := map[string]float64{
@@ -570,14 +923,24 @@ func (e *Engine) processInput(deltaTime float64) {
 		e.audioEngine.PlayProceduralSound("interact", 0.7, 0.0, interactMeta)
 	}
 
+	// Interact: inspect or touch the object in front of the player, latched like the jump
+	if e.input.ActionPressed(ActionInteract) {
+		e.live.RequestInteract()
+	}
+
+	// Print what the behavior trees are doing (F3 by default)
+	if e.input.ActionPressed(ActionDumpBehaviors) {
+		e.logger.Infof("Behavior trees:\n%s", e.behaviors.Dump())
+	}
+
 	// Toggle post-processing effects
-	if e.input.IsKeyPressed(glfw.KeyP) {
+	if e.input.ActionPressed(ActionTogglePostProcessing) {
 		e.renderer.TogglePostProcessing()
 		e.logger.Info("Post-processing toggled")
 	}
 
 	// Adjust pixel size (pixelation level)
-	if e.input.IsKeyPressed(glfw.KeyEqual) || e.input.IsKeyPressed(glfw.KeyKPAdd) {
+	if e.input.ActionPressed(ActionPixelSizeDown) {
 		// Decrease pixel size (more detail)
 		current := e.renderer.pixelSize
 		if current > 1 {
@@ -585,7 +948,7 @@ func (e *Engine) processInput(deltaTime float64) {
 			e.logger.Info("Pixel size decreased to %d", current-1)
 		}
 	}
-	if e.input.IsKeyPressed(glfw.KeyMinus) || e.input.IsKeyPressed(glfw.KeyKPSubtract) {
+	if e.input.ActionPressed(ActionPixelSizeUp) {
 		// Increase pixel size (more pixelated)
 		current := e.renderer.pixelSize
 		if current < 16 {
@@ -595,44 +958,87 @@ func (e *Engine) processInput(deltaTime float64) {
 	}
 
 	// Audio volume controls
-	if e.input.IsKeyPressed(glfw.KeyM) {
+	if e.input.ActionPressed(ActionToggleMute) {
 		e.audioEngine.ToggleMute()
 		e.logger.Info("Audio mute toggled")
 	}
 }
 
-// update handles game logic updates
-func (e *Engine) update(deltaTime float64) {
-	// Update procedural generation
-	e.procedural.Update(deltaTime)
+// rumble shakes the gamepad if rumble is enabled and a handler is installed
+// with InputHandler.SetRumbleHandler
+func (e *Engine) rumble(strength, duration float64) {
+	if e.config.Gamepad.Rumble {
+		e.input.Rumble(strength, duration)
+	}
+}
+
+// simulateTick runs one fixed simulation tick with input from the current
+// source (live controls, a replay, a bot or a script) and records it if recording
+func (e *Engine) simulateTick(deltaTime float64) {
+	frame, ok := e.source.NextFrame(e.sim)
+	if !ok {
+		// The input is over: give control back to the player
+		e.finishSource()
+		frame, _ = e.source.NextFrame(e.sim)
+	}
+
+	if e.source != e.live {
+		// Look where the recorded player or the bot looks
+		e.camera.Yaw, e.camera.Pitch = frame.Yaw, frame.Pitch
+	}
 
-	// Update audio
-	e.audioEngine.Update(deltaTime)
+	if e.recorder != nil {
+		if err := e.recorder.Record(frame); err != nil {
+			e.logger.Errorf("Recording stopped: %v", err)
+			e.recorder = nil
+		}
+	}
 
+	e.sim.Step(frame)
+
+	// Update game state
+	e.update(deltaTime)
+}
+
+// update handles game logic updates
+func (e *Engine) update(deltaTime float64) {
 	// Get player position
 	playerPos := e.physics.GetPlayer().Position
 
 	// Analyze environment around player
 	environmentMood := e.analyzeEnvironment(playerPos)
+	e.fear = environmentMood["atmosphere.fear"]
+	if stalker := e.sim.Stalker(); stalker != nil {
+		e.fear = math.Max(e.fear, stalker.Threat(e.physics.GetPlayer()))
+	}
 
 	// Update atmosphere based on environment
 	if deltaTime > 0 && int(e.lastUpdate.Second())%10 == 0 {
 		e.audioEngine.UpdateAtmosphere(environmentMood, 5.0) // Smooth transition over 5 seconds
 	}
 
-	// Process environment triggers
-	e.processEnvironmentTriggers(playerPos, deltaTime)
+	// The director paces scares by the player's stress; without it scares
+	// fire on distance and cooldowns
+	if director := e.sim.Director(); director != nil {
+		environmentMood["atmosphere.tension"] = math.Max(environmentMood["atmosphere.tension"], director.Tension())
+	} else {
+		e.processEnvironmentTriggers(playerPos, deltaTime)
+	}
+
+	// Haunted objects and creatures decide what to do
+	e.behaviors.Update(e.procedural.GetCurrentScene(), playerPos, deltaTime)
 
 	// Update renderer effects based on scene conditions
+	vignette, noise := 0.4, 0.03
 	scene := e.procedural.GetCurrentScene()
 	if scene != nil {
 		// Get renderer effects from scene conditions
 		if scene.Weather != nil {
 			if fogAmount, ok := scene.Weather["fog"]; ok {
 				// Lower noise in foggy scenes for better visibility
-				e.renderer.SetNoiseAmount(float32(0.02 * (1.0 - fogAmount*0.5)))
+				noise = 0.02 * (1.0 - fogAmount*0.5)
 				// Increase vignette in foggy scenes
-				e.renderer.SetVignetteAmount(float32(0.4 + fogAmount*0.2))
+				vignette = 0.4 + fogAmount*0.2
 			}
 		}
 
@@ -648,10 +1054,56 @@ func (e *Engine) update(deltaTime float64) {
 
 			// Increase vignette in dark scenes
 			if darkness > 0.5 {
-				e.renderer.SetVignetteAmount(float32(0.4 + (darkness-0.5)*0.3))
+				vignette = 0.4 + (darkness-0.5)*0.3
 			}
 		}
 	}
+
+	// Losing sanity closes in the view and adds grain
+	madness := e.madness()
+	vignette += 0.4 * madness
+	noise += 0.15 * madness
+	e.renderer.SetVignetteAmount(float32(vignette))
+	e.renderer.SetNoiseAmount(float32(noise))
+
+	e.presentSanity(scene, environmentMood, madness, deltaTime)
+}
+
+// madness returns how far the player has lost their sanity: 0 while
+// sanity is above sanityUneasy, 1 when none is left
+func (e *Engine) madness() float64 {
+	sanity := e.physics.GetPlayer().Sanity
+	return util.Clamp((sanityUneasy-sanity)/sanityUneasy, 0, 1)
+}
+
+// presentSanity shows what low sanity does to the player: flickers,
+// whispers only they hear, a distorted voice in the microphone and
+// things that aren't there
+func (e *Engine) presentSanity(scene *ProceduralScene, mood map[string]float64, madness, deltaTime float64) {
+	e.audioEngine.SetVoiceDistortion(madness)
+	if e.hallucinations != nil {
+		player := e.physics.GetPlayer()
+		eye := player.Position.Add(Vector3{Y: player.EyeHeight() - player.Height/2})
+		e.hallucinations.Update(scene, eye, player.Direction, mood, madness, deltaTime)
+		e.handleHallucinationSounds(e.hallucinations.TakeSounds())
+	}
+	if madness <= 0 {
+		return
+	}
+
+	if rand.Float64() < sanityGlitchRate*madness*deltaTime {
+		e.renderer.ApplyGlitchEffect(float32(0.2+0.4*madness), 0.2)
+	}
+	if rand.Float64() < sanityWhisperRate*madness*deltaTime {
+		whisperMeta := map[string]float64{
+			"atmosphere.fear":      0.4 + 0.5*madness,
+			"atmosphere.dread":     0.5 + 0.4*madness,
+			"conditions.unnatural": madness,
+		}
+		// Close to one ear or the other
+		pan := float32(rand.Float64()*2 - 1)
+		e.audioEngine.PlayWhisper(float32(0.2+0.4*madness), pan, whisperMeta)
+	}
 }
 
 // resizeCallback handles window resize events
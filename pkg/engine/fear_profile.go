@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+
+	"nightmare/internal/util"
+)
+
+// Реакции игрока на испуг
+const (
+	ReactionIgnore   = "ignore"   // Не обратил внимания
+	ReactionFreeze   = "freeze"   // Замер на месте
+	ReactionFlee     = "flee"     // Бросился прочь
+	ReactionApproach = "approach" // Пошел посмотреть
+)
+
+// Настройки профиля страха
+const (
+	profileNeutralEffect = 0.5 // Действие незнакомого события: ни сильное, ни слабое
+	profileLearnRate     = 0.2 // Насколько одна реакция сдвигает оценку действия события
+
+	profileHabitTime   = 120.0 // Секунд игры, после которых привычкам игрока можно верить
+	profileHabitSpread = 0.3   // Насколько привычки сдвигают вес события: 1 ± spread
+	profileLookDown    = 0.3   // Наклон взгляда вниз (рад), при котором игрок смотрит под ноги
+	profileThreatGaze  = 0.1   // Доля времени со взглядом на угрозы, выше которой игрок их разглядывает
+	profileTurnRate    = 2.0   // Разворотов в минуту, при которых игрок постоянно оглядывается
+)
+
+// reactionEffect - насколько реакция показывает, что событие напугало:
+// замер или бегство - да, интерес - отчасти, безразличие - нет
+var reactionEffect = map[string]float64{
+	ReactionIgnore:   0,
+	ReactionFreeze:   1,
+	ReactionFlee:     1,
+	ReactionApproach: 0.4,
+}
+
+// ScareReactions - реакции игрока на один вид событий
+type ScareReactions struct {
+	Freeze   int     `yaml:"freeze"`
+	Flee     int     `yaml:"flee"`
+	Approach int     `yaml:"approach"`
+	Ignore   int     `yaml:"ignore"`
+	Effect   float64 `yaml:"effect"` // 0..1, насколько события этого вида действуют на игрока
+}
+
+// FearProfile - чего боится игрок и как он себя ведет, по всем его сессиям.
+// Профиль хранится между сессиями, и ИИ-режиссер чаще устраивает то, что
+// на этого игрока заметно действует
+type FearProfile struct {
+	Sessions    int     `yaml:"sessions"`
+	PlayTime    float64 `yaml:"play_time"`    // Секунд игры
+	DarkTime    float64 `yaml:"dark_time"`    // Секунд в темноте (ночью, в пещерах)
+	LitTime     float64 `yaml:"lit_time"`     // Секунд на свету
+	TurnArounds int     `yaml:"turn_arounds"` // Сколько раз игрок резко оборачивался
+	LookPitch   float64 `yaml:"look_pitch"`   // Средний наклон взгляда, рад: < 0 - под ноги, > 0 - вверх
+	ThreatGaze  float64 `yaml:"threat_gaze"`  // Доля времени, когда игрок смотрит на сталкера или странные объекты
+
+	// Реакции по видам событий директора (DirectorEventKind.String())
+	Reactions map[string]*ScareReactions `yaml:"reactions"`
+}
+
+// NewFearProfile создает профиль нового игрока
+func NewFearProfile() *FearProfile {
+	return &FearProfile{Reactions: make(map[string]*ScareReactions)}
+}
+
+// LoadFearProfile читает профиль из файла. Если файла нет, игрок новый
+func LoadFearProfile(path string) (*FearProfile, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewFearProfile(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fear profile: %v", err)
+	}
+	return DecodeFearProfile(data)
+}
+
+// SaveFearProfile сохраняет профиль в файл
+func SaveFearProfile(profile *FearProfile, path string) error {
+	data, err := EncodeFearProfile(profile)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write fear profile: %v", err)
+	}
+	return nil
+}
+
+// EncodeFearProfile сериализует профиль в YAML
+func EncodeFearProfile(profile *FearProfile) ([]byte, error) {
+	data, err := yaml.Marshal(profile)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing fear profile: %v", err)
+	}
+	return data, nil
+}
+
+// DecodeFearProfile разбирает профиль из YAML
+func DecodeFearProfile(data []byte) (*FearProfile, error) {
+	profile := NewFearProfile()
+	if err := yaml.Unmarshal(data, profile); err != nil {
+		return nil, fmt.Errorf("error parsing fear profile: %v", err)
+	}
+	if profile.Reactions == nil {
+		profile.Reactions = make(map[string]*ScareReactions)
+	}
+	for _, reactions := range profile.Reactions {
+		reactions.Effect = util.Clamp(reactions.Effect, 0, 1)
+	}
+	return profile, nil
+}
+
+// Clone возвращает независимую копию профиля
+func (fp *FearProfile) Clone() *FearProfile {
+	clone := *fp
+	clone.Reactions = make(map[string]*ScareReactions, len(fp.Reactions))
+	for kind, reactions := range fp.Reactions {
+		copied := *reactions
+		clone.Reactions[kind] = &copied
+	}
+	return &clone
+}
+
+// reactionsTo возвращает реакции на вид событий, заводя их при первой встрече
+func (fp *FearProfile) reactionsTo(kind DirectorEventKind) *ScareReactions {
+	reactions, ok := fp.Reactions[kind.String()]
+	if !ok {
+		reactions = &ScareReactions{Effect: profileNeutralEffect}
+		fp.Reactions[kind.String()] = reactions
+	}
+	return reactions
+}
+
+// Effect возвращает, насколько события вида kind действуют на игрока (0..1).
+// О незнакомых событиях профиль ничего не знает и считает их средними
+func (fp *FearProfile) Effect(kind DirectorEventKind) float64 {
+	if reactions, ok := fp.Reactions[kind.String()]; ok {
+		return reactions.Effect
+	}
+	return profileNeutralEffect
+}
+
+// Weight возвращает множитель частоты событий вида kind: от 0.5 до 1.5 по
+// реакциям игрока на такие события, умноженный на вес по его привычкам
+func (fp *FearProfile) Weight(kind DirectorEventKind) float64 {
+	return (0.5 + fp.Effect(kind)) * fp.HabitWeight(kind)
+}
+
+// HabitWeight возвращает множитель частоты событий вида kind по тому, как
+// игрок себя ведет: где проводит время, куда смотрит и как часто оглядывается.
+// Пока игрок играл мало, привычки не учитываются
+func (fp *FearProfile) HabitWeight(kind DirectorEventKind) float64 {
+	if fp.PlayTime < profileHabitTime {
+		return 1
+	}
+
+	// -1..1: насколько привычки игрока говорят в пользу событий этого вида
+	lean := 0.0
+	switch kind {
+	case DirectorSound:
+		// Кто избегает темноты, боится того, чего не видно
+		lean = 1 - 2*fp.DarkShare()
+	case DirectorGlitch:
+		// Кто смотрит под ноги, не заметит далекого, а помехи на экране увидит
+		lean = util.Clamp(-fp.LookPitch/profileLookDown, -1, 1)
+	case DirectorScare, DirectorObject:
+		// Кто разглядывает угрозы, подойдет к странному объекту и заметит новый
+		lean = util.Clamp(fp.ThreatGaze/profileThreatGaze-1, -1, 1)
+	case DirectorStalker:
+		// Кто постоянно оглядывается, ждет того, кто идет следом
+		lean = util.Clamp(fp.TurnRate()/profileTurnRate-1, -1, 1)
+	}
+	return 1 + lean*profileHabitSpread
+}
+
+// TurnRate возвращает, сколько раз в минуту игрок резко оборачивается
+func (fp *FearProfile) TurnRate() float64 {
+	if fp.PlayTime <= 0 {
+		return 0
+	}
+	return float64(fp.TurnArounds) / (fp.PlayTime / 60)
+}
+
+// DarkShare возвращает долю времени, которую игрок проводит в темноте
+func (fp *FearProfile) DarkShare() float64 {
+	if total := fp.DarkTime + fp.LitTime; total > 0 {
+		return fp.DarkTime / total
+	}
+	return 0.5
+}
+
+// recordReaction учитывает реакцию игрока на событие
+func (fp *FearProfile) recordReaction(kind DirectorEventKind, reaction string) {
+	reactions := fp.reactionsTo(kind)
+	switch reaction {
+	case ReactionFreeze:
+		reactions.Freeze++
+	case ReactionFlee:
+		reactions.Flee++
+	case ReactionApproach:
+		reactions.Approach++
+	default:
+		reactions.Ignore++
+	}
+	reactions.Effect += (reactionEffect[reaction] - reactions.Effect) * profileLearnRate
+}
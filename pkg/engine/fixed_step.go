@@ -0,0 +1,66 @@
+package engine
+
+import "math"
+
+// SimulationTickRate is the number of fixed physics and world simulation ticks per second
+const SimulationTickRate = 60.0
+
+// maxSimulationSteps caps the ticks run for a single frame. After a long hitch
+// the rest of the backlog is dropped instead of making the next frame even longer
+const maxSimulationSteps = 5
+
+// tickEpsilon absorbs rounding when frame time is a multiple of the tick
+// (six steps of 1/60 s must give exactly one 10 Hz world tick)
+const tickEpsilon = 1e-9
+
+// FixedStep turns variable frame time into a whole number of fixed ticks.
+// The fraction left over is used to interpolate render state between ticks
+type FixedStep struct {
+	TickDuration float64 // Seconds per tick
+	MaxSteps     int     // Max ticks per Advance, 0 = unlimited
+	accumulator  float64
+	tick         uint64
+}
+
+// NewFixedStep creates a fixed-step clock with the given rate in ticks per second
+func NewFixedStep(rate float64, maxSteps int) *FixedStep {
+	return &FixedStep{
+		TickDuration: 1.0 / rate,
+		MaxSteps:     maxSteps,
+	}
+}
+
+// Advance adds frame time and returns how many ticks must be simulated now
+func (fs *FixedStep) Advance(frameTime float64) int {
+	fs.accumulator += math.Max(0, frameTime)
+
+	steps := 0
+	for fs.accumulator >= fs.TickDuration-tickEpsilon {
+		if fs.MaxSteps > 0 && steps == fs.MaxSteps {
+			// Spiral of death guard: forget the time we can't catch up with
+			fs.accumulator = math.Mod(fs.accumulator, fs.TickDuration)
+			break
+		}
+		fs.accumulator -= fs.TickDuration
+		steps++
+	}
+
+	fs.tick += uint64(steps)
+	return steps
+}
+
+// Alpha returns how far the clock is between the last tick and the next one (0..1)
+func (fs *FixedStep) Alpha() float64 {
+	return math.Max(0, math.Min(1, fs.accumulator/fs.TickDuration))
+}
+
+// Tick returns the number of ticks since the clock was created or reset
+func (fs *FixedStep) Tick() uint64 {
+	return fs.tick
+}
+
+// Reset restarts the clock from tick 0
+func (fs *FixedStep) Reset() {
+	fs.accumulator = 0
+	fs.tick = 0
+}
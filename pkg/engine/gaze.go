@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"math"
+)
+
+// Поле зрения игрока: одно и то же для отрисовки (populateObjectsInView)
+// и для эволюции сцены с учетом взгляда
+const (
+	viewFieldOfView = 60.0 * math.Pi / 180 // Угол обзора
+	viewMaxDistance = 100.0                // Дальше объекты не видны
+)
+
+// Настройки эволюции с учетом взгляда
+const (
+	gazeSeenDistance    = 40.0  // Дальше этого игрок не запоминает объекты
+	gazeObjectMargin    = 0.5   // Запас к размеру объекта при проверке взгляда, м
+	gazeNudgeChance     = 0.05  // Шанс сдвинуть объект при изменении (как без учета взгляда)
+	gazeNudgeRange      = 0.5   // Наибольший сдвиг, м
+	gazeFaceChance      = 0.3   // Шанс, что странный объект повернется к игроку
+	gazeRelocateChance  = 0.25  // Шанс, что замеченный объект за спиной переберется вперед
+	gazeRelocatePeriod  = 600   // Не чаще одного раза в столько тиков мира (минута)
+	gazeRelocateAngle   = 0.25  // На сколько за край поля зрения он встает, рад
+	gazeRelocateSpacing = 2.0   // Свободное место вокруг нового положения, м
+	gazeBehindCos       = -0.25 // Объект за спиной, если косинус угла со взглядом меньше
+)
+
+// gazeRelocatable - объекты, которые перебираются за спиной у игрока.
+// Деревья только чуть сдвигаются: перенос целого дерева заметен издалека
+var gazeRelocatable = map[string]bool{"stump": true, "strange": true}
+
+// gazeMovable - объекты, которые эволюция с учетом взгляда вообще двигает
+var gazeMovable = map[string]bool{"stump": true, "strange": true, "tree": true}
+
+// gazeViewer - откуда и куда смотрит игрок
+type gazeViewer struct {
+	eye       Vector3
+	direction Vector3 // Единичный
+}
+
+// inView проверяет, попадает ли точка target в поле зрения из eye вдоль
+// direction. radius расширяет поле зрения на угловой размер объекта: край
+// большого объекта виден, даже когда центр за краем. Возвращает единичное
+// направление на точку, расстояние до нее и результат
+func inView(eye, direction, target Vector3, radius float64) (Vector3, float64, bool) {
+	toTarget := target.Sub(eye)
+	distance := toTarget.Length()
+	if distance > viewMaxDistance+radius {
+		return toTarget, distance, false
+	}
+	if distance <= radius {
+		return toTarget, distance, true
+	}
+	toTarget = toTarget.Mul(1 / distance)
+
+	halfAngle := viewFieldOfView / 2
+	if radius > 0 {
+		halfAngle += math.Atan(radius / distance)
+	}
+	return toTarget, distance, toTarget.Dot(direction) > math.Cos(halfAngle)
+}
+
+// objectBounds описывает объект сферой: центр на половине высоты
+func objectBounds(obj *ProceduralObject) (Vector3, float64) {
+	center := obj.Position.Add(Vector3{Y: obj.Scale.Y / 2})
+	return center, math.Hypot(math.Max(obj.Scale.X, obj.Scale.Z), obj.Scale.Y/2)
+}
+
+// SetViewer сообщает генератору, откуда и куда смотрит игрок. Пока зрителя
+// нет, эволюция с учетом взгляда считает, что никто не смотрит
+func (pg *ProceduralGenerator) SetViewer(eye, direction Vector3) {
+	pg.mutex.Lock()
+	defer pg.mutex.Unlock()
+
+	pg.viewer = &gazeViewer{eye: eye, direction: direction.Normalize()}
+}
+
+// observe отмечает объекты, которые игрок сейчас видит
+func (pg *ProceduralGenerator) observe() {
+	viewer := pg.viewer
+	if viewer == nil || pg.currentScene.Index == nil {
+		return
+	}
+
+	cosHalfFOV := math.Cos(viewFieldOfView / 2)
+	pg.currentScene.Index.QueryFrustum(viewer.eye, viewer.direction, cosHalfFOV, gazeSeenDistance, func(obj *ProceduralObject) bool {
+		center, _ := objectBounds(obj)
+		if _, distance, ok := inView(viewer.eye, viewer.direction, center, 0); ok && distance <= gazeSeenDistance {
+			obj.Seen = true
+		}
+		return true
+	})
+}
+
+// unseen сообщает, что сферу (center, radius) можно менять незаметно: она
+// вне поля зрения и не ближе GazeSafeDistance к игроку
+func (pg *ProceduralGenerator) unseen(center Vector3, radius float64) bool {
+	viewer := pg.viewer
+	if viewer == nil {
+		return true
+	}
+	if horizontalDistance(center, viewer.eye)-radius < pg.config.GazeSafeDistance {
+		return false
+	}
+	_, _, visible := inView(viewer.eye, viewer.direction, center, radius+gazeObjectMargin)
+	return !visible
+}
+
+// unseenObject сообщает, что объект можно менять незаметно
+func (pg *ProceduralGenerator) unseenObject(obj *ProceduralObject) bool {
+	center, radius := objectBounds(obj)
+	return pg.unseen(center, radius)
+}
+
+// evolveUnseen меняет объект, пока на него не смотрят: странные объекты
+// поворачиваются к игроку, замеченные объекты за спиной перебираются к краю
+// поля зрения, остальные чуть сдвигаются
+func (pg *ProceduralGenerator) evolveUnseen(obj *ProceduralObject) {
+	if !gazeMovable[obj.Type] || obj.StructureID != 0 || !pg.unseenObject(obj) {
+		return
+	}
+
+	if obj.Seen && gazeRelocatable[obj.Type] && pg.tick >= pg.nextRelocation &&
+		pg.behindViewer(obj) && pg.evolutionRng.Float64() < gazeRelocateChance {
+		if pg.relocateSeen(obj) {
+			pg.nextRelocation = pg.tick + gazeRelocatePeriod
+			return
+		}
+	}
+
+	if obj.Type == "strange" && pg.viewer != nil && pg.evolutionRng.Float64() < gazeFaceChance {
+		obj.Rotation.Y = math.Atan2(pg.viewer.eye.X-obj.Position.X, pg.viewer.eye.Z-obj.Position.Z)
+	}
+
+	if pg.evolutionRng.Float64() < gazeNudgeChance {
+		position := obj.Position
+		position.X += (pg.evolutionRng.Float64()*2.0 - 1.0) * gazeNudgeRange
+		position.Z += (pg.evolutionRng.Float64()*2.0 - 1.0) * gazeNudgeRange
+		_, radius := objectBounds(obj)
+		if pg.unseen(position.Add(Vector3{Y: obj.Scale.Y / 2}), radius) {
+			pg.currentScene.MoveObject(obj, position)
+		}
+	}
+}
+
+// behindViewer сообщает, что объект у игрока за спиной и достаточно близко,
+// чтобы игрок его помнил
+func (pg *ProceduralGenerator) behindViewer(obj *ProceduralObject) bool {
+	if pg.viewer == nil || horizontalDistance(obj.Position, pg.viewer.eye) > gazeSeenDistance {
+		return false
+	}
+	to := horizontalDirection(obj.Position.Sub(pg.viewer.eye))
+	return to.Dot(horizontalDirection(pg.viewer.direction)) < gazeBehindCos
+}
+
+// relocateSeen переносит замеченный объект на то же расстояние от игрока,
+// но сразу за край поля зрения: стоит игроку повернуть голову или пройти
+// вперед, и пень, что был позади, оказывается перед ним. Возвращает false,
+// если подходящего места нет
+func (pg *ProceduralGenerator) relocateSeen(obj *ProceduralObject) bool {
+	viewer := pg.viewer
+	terrain := pg.currentScene.Terrain
+	if viewer == nil || pg.currentScene.Index == nil {
+		return false
+	}
+	center, radius := objectBounds(obj)
+
+	distance := math.Max(horizontalDistance(center, viewer.eye), pg.config.GazeSafeDistance+radius)
+	look := math.Atan2(viewer.direction.X, viewer.direction.Z)
+	offset := viewFieldOfView/2 + math.Atan((radius+gazeObjectMargin)/distance) + gazeRelocateAngle
+
+	// Сторона выбирается случайно; если там нет места, пробуем другую
+	sides := [2]float64{1, -1}
+	if pg.evolutionRng.Float64() < 0.5 {
+		sides[0], sides[1] = -1, 1
+	}
+	for _, side := range sides {
+		angle := look + side*offset
+		x := viewer.eye.X + math.Sin(angle)*distance
+		z := viewer.eye.Z + math.Cos(angle)*distance
+		if !terrain.InBounds(x, z) || terrain.SurfaceAt(x, z).Name == surfaceWater.Name ||
+			pg.isInsideStructure(x, z, 0.5) || pg.isNearCave(x, z, 0.5) {
+			continue
+		}
+
+		position := Vector3{X: x, Y: terrain.HeightAt(x, z), Z: z}
+		if !pg.unseen(position.Add(Vector3{Y: obj.Scale.Y / 2}), radius) {
+			continue
+		}
+
+		crowded := false
+		pg.currentScene.Index.QueryRadius(position, gazeRelocateSpacing, func(other *ProceduralObject) bool {
+			crowded = other != obj
+			return !crowded
+		})
+		if crowded {
+			continue
+		}
+
+		pg.currentScene.MoveObject(obj, position)
+		// Перенос заметят, только когда объект снова попадется на глаза
+		obj.Seen = false
+		return true
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+
+	"nightmare/internal/util"
+)
+
+// Hallucinations are things only the player sees and hears. Objects and
+// silhouettes are added to the frame's ObjectsInView and never to the scene,
+// so nothing collides with them, creatures don't notice them and replays
+// don't depend on them. Each one lives a few seconds and vanishes when the
+// player looks straight at it or comes close. Lost sanity and the mood
+// around the player decide how often they come: twisted places
+// (visuals.twisted) breed objects, places where figures are seen
+// (conditions.silhouette) breed silhouettes, dread breeds footsteps
+const (
+	hallucinationMax      = 4     // At most this many at once
+	hallucinationFirstID  = -1000 // IDs count down from here, away from real objects and -1 (no object)
+	hallucinationFadeTime = 0.4   // Seconds to fade in and to vanish
+	hallucinationGlance   = 0.1   // Looking closer than this to it (radians) makes it vanish
+	hallucinationNear     = 8.0   // Coming closer than this (m) makes it vanish
+
+	hallucinationObjectRate  = 0.15 // Objects per second at full pressure
+	hallucinationObjectMin   = 12.0 // Distance from the player, m
+	hallucinationObjectMax   = 30.0
+	hallucinationObjectLife  = 8.0 // Seconds an object lasts at most
+	hallucinationFigureRate  = 0.05
+	hallucinationFigureLife  = 12.0
+	hallucinationFogFade     = 0.025 // Fog thinning per meter, as in populateObjectsInView
+	hallucinationFogEdge     = 0.25  // A silhouette stands where fog leaves this much visibility
+	hallucinationStepsRate   = 0.03
+	hallucinationStepsStart  = 10.0 // Footsteps start this far behind the player, m
+	hallucinationStepsStop   = 2.0  // ...and stop this close
+	hallucinationStepLength  = 0.7
+	hallucinationStepTime    = 0.55 // Seconds between footsteps
+	hallucinationStepsSpread = 0.8  // Footsteps start this far (radians) to either side of straight behind
+)
+
+// HallucinationKind is what the player imagines
+type HallucinationKind int
+
+const (
+	HallucinationObject     HallucinationKind = iota // A twisted object nearby
+	HallucinationSilhouette                          // A figure standing at the edge of the fog
+	HallucinationFootsteps                           // Footsteps creeping up behind the player
+)
+
+// hallucinationObjectTypes are the objects the player imagines
+var hallucinationObjectTypes = []string{"strange", "strange", "tree"}
+
+// hallucination is one imagined object, figure or set of footsteps
+type hallucination struct {
+	id       int
+	kind     HallucinationKind
+	objType  string
+	position Vector3
+	size     float64
+	age      float64
+	life     float64 // Seconds left
+	vanish   bool    // Looked at or approached: fading out
+	nextStep float64 // Seconds to the next footstep
+}
+
+// HallucinationSound is a phantom footstep for the engine to play
+type HallucinationSound struct {
+	Position Vector3
+	Volume   float64 // 0..1
+}
+
+// Hallucinations tracks the things the player imagines
+type Hallucinations struct {
+	active []*hallucination
+	nextID int
+	sounds []HallucinationSound
+}
+
+// NewHallucinations creates an empty set of hallucinations
+func NewHallucinations() *Hallucinations {
+	return &Hallucinations{nextID: hallucinationFirstID}
+}
+
+// hallucinationPressure tells how strongly the player is pushed to imagine
+// things: lost sanity makes eerie places count, and very eerie places get
+// to anyone
+func hallucinationPressure(madness, mood float64) float64 {
+	return util.Clamp(madness*(0.5+mood)+0.5*math.Max(0, mood-0.6), 0, 1)
+}
+
+// Update ages the hallucinations, makes the ones the player looks at or
+// reaches vanish and imagines new ones. madness is how far the player has
+// lost their sanity (0..1), mood is the atmosphere around them
+func (h *Hallucinations) Update(scene *ProceduralScene, eye, view Vector3, mood map[string]float64, madness, deltaTime float64) {
+	active := h.active[:0]
+	for _, imagined := range h.active {
+		imagined.age += deltaTime
+		imagined.life -= deltaTime
+		if !imagined.vanish && h.noticed(imagined, eye, view) {
+			// The player caught it: it's gone before they can be sure
+			imagined.vanish = true
+			imagined.life = math.Min(imagined.life, hallucinationFadeTime)
+		}
+		if imagined.kind == HallucinationFootsteps && !imagined.vanish {
+			h.step(imagined, eye, deltaTime)
+		}
+		if imagined.life > 0 {
+			active = append(active, imagined)
+		}
+	}
+	h.active = active
+
+	if scene == nil || scene.Terrain == nil || len(h.active) >= hallucinationMax {
+		return
+	}
+
+	objects := hallucinationPressure(madness, mood["visuals.twisted"])
+	figures := hallucinationPressure(madness, mood["conditions.silhouette"])
+	steps := hallucinationPressure(madness, mood["atmosphere.dread"])
+	switch {
+	case rand.Float64() < hallucinationObjectRate*objects*deltaTime:
+		h.imagineObject(scene, eye, view)
+	case rand.Float64() < hallucinationFigureRate*figures*deltaTime:
+		h.imagineSilhouette(scene, eye, view)
+	case rand.Float64() < hallucinationStepsRate*steps*deltaTime:
+		h.imagineFootsteps(eye, view)
+	}
+}
+
+// noticed tells that the player looks straight at the hallucination or has
+// come close to it. Footsteps stop when the player turns around
+func (h *Hallucinations) noticed(imagined *hallucination, eye, view Vector3) bool {
+	distance := horizontalDistance(imagined.position, eye)
+	if imagined.kind == HallucinationFootsteps {
+		_, _, visible := inView(eye, view, imagined.position, 0)
+		return visible
+	}
+	if distance < hallucinationNear {
+		return true
+	}
+
+	// Aim at the middle of it, not the feet
+	center := imagined.position.Add(Vector3{Y: imagined.size / 2})
+	direction := center.Sub(eye).Normalize()
+	return direction.Dot(view) > math.Cos(hallucinationGlance)
+}
+
+// step moves phantom footsteps closer and makes them heard
+func (h *Hallucinations) step(imagined *hallucination, eye Vector3, deltaTime float64) {
+	imagined.nextStep -= deltaTime
+	if imagined.nextStep > 0 {
+		return
+	}
+	imagined.nextStep = hallucinationStepTime
+
+	toPlayer := horizontalDirection(eye.Sub(imagined.position))
+	imagined.position = imagined.position.Add(toPlayer.Mul(hallucinationStepLength))
+	distance := horizontalDistance(imagined.position, eye)
+	if distance <= hallucinationStepsStop {
+		imagined.vanish = true
+		imagined.life = 0
+		return
+	}
+
+	h.sounds = append(h.sounds, HallucinationSound{
+		Position: imagined.position,
+		Volume:   util.Lerp(0.5, 0.1, (distance-hallucinationStepsStop)/(hallucinationStepsStart-hallucinationStepsStop)),
+	})
+}
+
+// add starts a new hallucination
+func (h *Hallucinations) add(imagined *hallucination) {
+	imagined.id = h.nextID
+	h.nextID--
+	h.active = append(h.active, imagined)
+}
+
+// imagineObject puts a twisted object somewhere ahead, so the player has a
+// chance to see it before it goes
+func (h *Hallucinations) imagineObject(scene *ProceduralScene, eye, view Vector3) {
+	yaw := math.Atan2(view.X, view.Z) + (rand.Float64()*2-1)*viewFieldOfView
+	distance := hallucinationObjectMin + rand.Float64()*(hallucinationObjectMax-hallucinationObjectMin)
+	position, ok := groundAround(scene, eye, yaw, distance)
+	if !ok {
+		return
+	}
+
+	h.add(&hallucination{
+		kind:     HallucinationObject,
+		objType:  hallucinationObjectTypes[rand.Intn(len(hallucinationObjectTypes))],
+		position: position,
+		size:     0.8 + rand.Float64()*1.5,
+		life:     hallucinationObjectLife * (0.5 + 0.5*rand.Float64()),
+	})
+}
+
+// imagineSilhouette puts a figure at the edge of the fog, off the middle of
+// the view where the player would look at it straight away
+func (h *Hallucinations) imagineSilhouette(scene *ProceduralScene, eye, view Vector3) {
+	fog := math.Max(0.1, scene.Weather["fog"])
+	distance := util.Clamp(math.Log(1/hallucinationFogEdge)/(fog*hallucinationFogFade), 2*hallucinationNear, 0.8*viewMaxDistance)
+
+	side := 1.0
+	if rand.Float64() < 0.5 {
+		side = -1
+	}
+	yaw := math.Atan2(view.X, view.Z) + side*(0.2+rand.Float64()*0.25)*viewFieldOfView
+	position, ok := groundAround(scene, eye, yaw, distance)
+	if !ok {
+		return
+	}
+
+	h.add(&hallucination{
+		kind:     HallucinationSilhouette,
+		objType:  stalkerObjectType,
+		position: position,
+		size:     2.2 + rand.Float64()*0.6,
+		life:     hallucinationFigureLife * (0.5 + 0.5*rand.Float64()),
+	})
+}
+
+// imagineFootsteps starts footsteps behind the player's back
+func (h *Hallucinations) imagineFootsteps(eye, view Vector3) {
+	back := horizontalDirection(view).Mul(-1)
+	yaw := math.Atan2(back.X, back.Z) + (rand.Float64()*2-1)*hallucinationStepsSpread
+	position := eye.Add(Vector3{X: math.Sin(yaw), Z: math.Cos(yaw)}.Mul(hallucinationStepsStart))
+
+	steps := (hallucinationStepsStart - hallucinationStepsStop) / hallucinationStepLength
+	h.add(&hallucination{
+		kind:     HallucinationFootsteps,
+		position: position,
+		life:     steps*hallucinationStepTime + 1,
+	})
+}
+
+// groundAround returns the ground point distance meters from eye along yaw
+func groundAround(scene *ProceduralScene, eye Vector3, yaw, distance float64) (Vector3, bool) {
+	x := eye.X + math.Sin(yaw)*distance
+	z := eye.Z + math.Cos(yaw)*distance
+	if !scene.Terrain.InBounds(x, z) {
+		return Vector3{}, false
+	}
+	return Vector3{X: x, Y: scene.Terrain.HeightAt(x, z), Z: z}, true
+}
+
+// TakeSounds returns the phantom footsteps since the last call and forgets them
+func (h *Hallucinations) TakeSounds() []HallucinationSound {
+	sounds := h.sounds
+	h.sounds = nil
+	return sounds
+}
+
+// AddToScene adds the hallucinations in view to the frame
+func (h *Hallucinations) AddToScene(scene *SceneData) {
+	for _, imagined := range h.active {
+		if imagined.kind == HallucinationFootsteps {
+			continue
+		}
+		direction, distance, visible := inView(scene.PlayerPosition, scene.ViewDirection, imagined.position, 0)
+		if !visible {
+			continue
+		}
+
+		// Fade in and out instead of popping
+		fade := math.Min(1, math.Min(imagined.age, imagined.life)/hallucinationFadeTime)
+		metadata := map[string]float64{
+			"visuals.twisted":      0.8,
+			"conditions.unnatural": 0.9,
+		}
+		if imagined.kind == HallucinationSilhouette {
+			metadata = map[string]float64{
+				"conditions.silhouette": 1.0,
+				"atmosphere.dread":      0.9,
+			}
+		}
+
+		scene.AddObjectInView(&SceneObject{
+			Type:          imagined.objType,
+			ID:            imagined.id,
+			Distance:      distance,
+			Direction:     direction,
+			Size:          imagined.size / math.Max(1.0, distance/5.0),
+			Metadata:      metadata,
+			Visibility:    fade * (1.0 - math.Min(1.0, distance/viewMaxDistance)),
+			Hallucination: true,
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package engine
 
 import (
+	"math"
+
 	"github.com/go-gl/glfw/v3.3/glfw"
 )
 
-// InputHandler управляет вводом с клавиатуры и мыши
+// InputHandler управляет вводом с клавиатуры, мыши и геймпада
 type InputHandler struct {
 	window            *glfw.Window
 	currentKeys       map[glfw.Key]bool
@@ -15,8 +17,30 @@ type InputHandler struct {
 	previousMouseBtns map[glfw.MouseButton]bool
 	mouseDelta        [2]float64
 	mouseWheelDelta   float64
+	cursorCaptured    bool
+	actions           *ActionMap
+	monitoredKeys     []glfw.Key
+	skipMouseDelta    bool // Курсор прыгнул (первый кадр, захват): этот скачок не считаем движением
+	currentGamepad    gamepadFrame
+	previousGamepad   gamepadFrame
+	gamepad           glfw.Joystick // Подключенный геймпад, если hasGamepad
+	hasGamepad        bool
+	gamepadEnabled    bool
+	deadzone          float64
+	rumble            RumbleFunc
+}
+
+// gamepadFrame - состояние геймпада за кадр: стики уже без мертвой зоны,
+// курки приведены к 0..1
+type gamepadFrame struct {
+	buttons [glfw.ButtonLast + 1]bool
+	axes    [glfw.AxisLast + 1]float64
 }
 
+// RumbleFunc включает вибрацию геймпада: strength 0..1, duration в секундах.
+// GLFW вибрацию не умеет, поэтому ее подключают через SetRumbleHandler там, где она доступна
+type RumbleFunc func(joystick glfw.Joystick, strength, duration float64)
+
 // NewInputHandler создает новый обработчик ввода
 func NewInputHandler(window *glfw.Window) *InputHandler {
 	handler := &InputHandler{
@@ -25,8 +49,13 @@ func NewInputHandler(window *glfw.Window) *InputHandler {
 		previousKeys:      make(map[glfw.Key]bool),
 		currentMouseBtns:  make(map[glfw.MouseButton]bool),
 		previousMouseBtns: make(map[glfw.MouseButton]bool),
+		skipMouseDelta:    true,
 	}
 
+	// Раскладка по умолчанию, пока движок не задал раскладку из конфига
+	actions, _ := NewActionMap(nil)
+	handler.SetActionMap(actions)
+
 	// Установка обработчика колесика мыши
 	window.SetScrollCallback(func(_ *glfw.Window, _, yoffset float64) {
 		handler.mouseWheelDelta += yoffset
@@ -49,6 +78,10 @@ func (ih *InputHandler) Update() {
 		ih.previousMouseBtns[b] = v
 	}
 
+	// Опрашиваем геймпад
+	ih.previousGamepad = ih.currentGamepad
+	ih.updateGamepad()
+
 	// Сохраняем текущую позицию мыши как предыдущую
 	ih.previousMousePos = ih.currentMousePos
 
@@ -59,18 +92,14 @@ func (ih *InputHandler) Update() {
 	// Вычисляем дельту перемещения мыши
 	ih.mouseDelta[0] = ih.currentMousePos[0] - ih.previousMousePos[0]
 	ih.mouseDelta[1] = ih.currentMousePos[1] - ih.previousMousePos[1]
-
-	// Сканируем только известные клавиши, которые нам нужны
-	// Избегаем использования glfw.KeyUnknown (-1), которое вызывает ошибку
-	monitoredKeys := []glfw.Key{
-		glfw.KeyEscape, glfw.KeyW, glfw.KeyA, glfw.KeyS, glfw.KeyD,
-		glfw.KeyUp, glfw.KeyDown, glfw.KeyLeft, glfw.KeyRight,
-		glfw.KeySpace, glfw.KeyEqual, glfw.KeyMinus,
-		glfw.KeyKPAdd, glfw.KeyKPSubtract, glfw.KeyM,
+	if ih.skipMouseDelta {
+		ih.mouseDelta = [2]float64{}
+		ih.skipMouseDelta = false
 	}
 
-	// Обновляем состояние только для наблюдаемых клавиш
-	for _, key := range monitoredKeys {
+	// Сканируем только клавиши из привязок действий
+	// Избегаем использования glfw.KeyUnknown (-1), которое вызывает ошибку
+	for _, key := range ih.monitoredKeys {
 		ih.currentKeys[key] = ih.window.GetKey(key) == glfw.Press
 	}
 
@@ -126,3 +155,199 @@ func (ih *InputHandler) GetMouseWheelDelta() float64 {
 	ih.mouseWheelDelta = 0 // сбрасываем после получения
 	return delta
 }
+
+// SetCursorCaptured прячет курсор и привязывает его к окну для обзора мышью
+// или возвращает обычный курсор
+func (ih *InputHandler) SetCursorCaptured(captured bool) {
+	if captured == ih.cursorCaptured {
+		return
+	}
+	ih.cursorCaptured = captured
+
+	if captured {
+		ih.window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+		// Без ускорения ОС обзор одинаков на любой системе
+		if glfw.RawMouseMotionSupported() {
+			ih.window.SetInputMode(glfw.RawMouseMotion, glfw.True)
+		}
+	} else {
+		ih.window.SetInputMode(glfw.CursorMode, glfw.CursorNormal)
+	}
+
+	// При смене режима курсор прыгает
+	ih.skipMouseDelta = true
+}
+
+// IsCursorCaptured сообщает, захвачен ли курсор для обзора мышью
+func (ih *InputHandler) IsCursorCaptured() bool {
+	return ih.cursorCaptured
+}
+
+// SetActionMap задает привязки действий и список опрашиваемых клавиш
+func (ih *InputHandler) SetActionMap(actions *ActionMap) {
+	ih.actions = actions
+	ih.monitoredKeys = actions.Keys()
+
+	// Клавиши старой раскладки больше не опрашиваются
+	ih.currentKeys = make(map[glfw.Key]bool)
+}
+
+// ActionDown проверяет, активно ли действие в данный момент
+func (ih *InputHandler) ActionDown(action Action) bool {
+	for _, b := range ih.actions.Bindings(action) {
+		if ih.bindingDown(b, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// ActionValue возвращает силу действия 0..1: клавиши и кнопки дают 0 или 1,
+// стики и курки - насколько они отклонены
+func (ih *InputHandler) ActionValue(action Action) float64 {
+	value := 0.0
+	for _, b := range ih.actions.Bindings(action) {
+		value = math.Max(value, ih.bindingValue(b, true))
+	}
+	return value
+}
+
+// ActionPressed проверяет, стало ли действие активным в этом кадре
+func (ih *InputHandler) ActionPressed(action Action) bool {
+	return ih.ActionDown(action) && !ih.actionWasDown(action)
+}
+
+// ActionReleased проверяет, перестало ли действие быть активным в этом кадре
+func (ih *InputHandler) ActionReleased(action Action) bool {
+	return !ih.ActionDown(action) && ih.actionWasDown(action)
+}
+
+// actionWasDown проверяет, было ли действие активно в прошлом кадре
+func (ih *InputHandler) actionWasDown(action Action) bool {
+	for _, b := range ih.actions.Bindings(action) {
+		if ih.bindingDown(b, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindingDown проверяет привязку в текущем или прошлом кадре
+func (ih *InputHandler) bindingDown(b Binding, current bool) bool {
+	return ih.bindingValue(b, current) >= analogPressThreshold
+}
+
+// bindingValue возвращает силу привязки в текущем или прошлом кадре
+func (ih *InputHandler) bindingValue(b Binding, current bool) float64 {
+	keys, buttons, gamepad := ih.currentKeys, ih.currentMouseBtns, &ih.currentGamepad
+	if !current {
+		keys, buttons, gamepad = ih.previousKeys, ih.previousMouseBtns, &ih.previousGamepad
+	}
+
+	pressed := false
+	switch b.kind {
+	case bindingKey:
+		pressed = keys[b.key]
+	case bindingMouseButton:
+		pressed = buttons[b.mouseButton]
+	case bindingGamepadButton:
+		pressed = gamepad.buttons[b.gamepadButton]
+	case bindingGamepadAxis:
+		return math.Max(0, gamepad.axes[b.gamepadAxis]*b.axisSign)
+	}
+
+	if pressed {
+		return 1
+	}
+	return 0
+}
+
+// SetGamepad включает или выключает геймпад и задает мертвую зону стиков
+func (ih *InputHandler) SetGamepad(enabled bool, deadzone float64) {
+	ih.gamepadEnabled = enabled
+	ih.deadzone = math.Max(0, math.Min(0.9, deadzone))
+	if !enabled {
+		ih.hasGamepad = false
+		ih.currentGamepad = gamepadFrame{}
+	}
+}
+
+// GamepadName возвращает имя подключенного геймпада или пустую строку
+func (ih *InputHandler) GamepadName() string {
+	if !ih.hasGamepad {
+		return ""
+	}
+	return ih.gamepad.GetGamepadName()
+}
+
+// updateGamepad читает состояние первого подключенного геймпада
+func (ih *InputHandler) updateGamepad() {
+	ih.currentGamepad = gamepadFrame{}
+	if !ih.gamepadEnabled {
+		return
+	}
+
+	// Геймпад отключили - ищем другой
+	if ih.hasGamepad && !ih.gamepad.IsGamepad() {
+		ih.hasGamepad = false
+	}
+	if !ih.hasGamepad {
+		for joy := glfw.Joystick1; joy <= glfw.JoystickLast; joy++ {
+			if joy.IsGamepad() {
+				ih.gamepad = joy
+				ih.hasGamepad = true
+				break
+			}
+		}
+	}
+	if !ih.hasGamepad {
+		return
+	}
+
+	state := ih.gamepad.GetGamepadState()
+	if state == nil {
+		ih.hasGamepad = false
+		return
+	}
+
+	for i, action := range state.Buttons {
+		ih.currentGamepad.buttons[i] = action == glfw.Press
+	}
+
+	// Стики: круглая мертвая зона, чтобы дрейф не двигал игрока
+	lx, ly := ih.applyDeadzone(float64(state.Axes[glfw.AxisLeftX]), float64(state.Axes[glfw.AxisLeftY]))
+	rx, ry := ih.applyDeadzone(float64(state.Axes[glfw.AxisRightX]), float64(state.Axes[glfw.AxisRightY]))
+	ih.currentGamepad.axes[glfw.AxisLeftX] = lx
+	ih.currentGamepad.axes[glfw.AxisLeftY] = ly
+	ih.currentGamepad.axes[glfw.AxisRightX] = rx
+	ih.currentGamepad.axes[glfw.AxisRightY] = ry
+
+	// Курки в покое дают -1, нажатые до упора - 1
+	ih.currentGamepad.axes[glfw.AxisLeftTrigger] = (float64(state.Axes[glfw.AxisLeftTrigger]) + 1) / 2
+	ih.currentGamepad.axes[glfw.AxisRightTrigger] = (float64(state.Axes[glfw.AxisRightTrigger]) + 1) / 2
+}
+
+// applyDeadzone обнуляет отклонение стика внутри мертвой зоны и растягивает
+// остальное на 0..1, чтобы стик плавно трогался с места
+func (ih *InputHandler) applyDeadzone(x, y float64) (float64, float64) {
+	magnitude := math.Hypot(x, y)
+	if magnitude <= ih.deadzone {
+		return 0, 0
+	}
+
+	scaled := math.Min(1, (magnitude-ih.deadzone)/(1-ih.deadzone))
+	return x / magnitude * scaled, y / magnitude * scaled
+}
+
+// SetRumbleHandler подключает вибрацию геймпада (nil отключает)
+func (ih *InputHandler) SetRumbleHandler(rumble RumbleFunc) {
+	ih.rumble = rumble
+}
+
+// Rumble просит геймпад завибрировать, если он подключен и вибрация доступна
+func (ih *InputHandler) Rumble(strength, duration float64) {
+	if ih.rumble == nil || !ih.hasGamepad {
+		return
+	}
+	ih.rumble(ih.gamepad, math.Max(0, math.Min(1, strength)), duration)
+}
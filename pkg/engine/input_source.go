@@ -0,0 +1,99 @@
+package engine
+
+import "math"
+
+// InputFrame - ввод игрока за один тик симуляции. Это все, что симуляция
+// знает о вводе, поэтому записанные кадры воспроизводят игру тик в тик
+type InputFrame struct {
+	Forward  float64 // -1..1, вперед/назад
+	Strafe   float64 // -1..1, вправо/влево
+	Yaw      float64 // Направление взгляда (см. CameraController)
+	Pitch    float64
+	Sprint   bool
+	Crouch   bool
+	Jump     bool
+	Interact bool    // Нажата клавиша действия: осмотреть или тронуть объект перед собой
+	Voice    float64 // 0..1, громкость голоса игрока в микрофон (ее слышит ИИ-режиссер)
+}
+
+// Quantize округляет кадр до точности файла записи. Живая игра тоже
+// использует округленный кадр, иначе воспроизведение разойдется с оригиналом
+func (f InputFrame) Quantize() InputFrame {
+	f.Forward = float64(quantizeAxis(f.Forward)) / 127
+	f.Strafe = float64(quantizeAxis(f.Strafe)) / 127
+	f.Yaw = float64(float32(f.Yaw))
+	f.Pitch = float64(float32(f.Pitch))
+	f.Voice = float64(quantizeLevel(f.Voice)) / 255
+	return f
+}
+
+// quantizeAxis переводит -1..1 в -127..127
+func quantizeAxis(v float64) int8 {
+	return int8(math.Round(math.Max(-1, math.Min(1, v)) * 127))
+}
+
+// quantizeLevel переводит 0..1 в 0..255
+func quantizeLevel(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+// Direction возвращает направление взгляда кадра
+func (f InputFrame) Direction() Vector3 {
+	forward, _, _ := cameraBasis(f.Yaw, f.Pitch)
+	return forward
+}
+
+// InputSource поставляет ввод симуляции: по одному кадру на тик.
+// Источники - живой ввод (InputHandler), запись (Replay) и боты
+type InputSource interface {
+	// NextFrame возвращает ввод для следующего тика; false - ввод закончился
+	NextFrame(sim *Simulation) (InputFrame, bool)
+}
+
+// liveInput собирает кадры из InputHandler и камеры
+type liveInput struct {
+	input         *InputHandler
+	camera        *CameraController
+	audio         *AudioEngine // Микрофон; nil, если ИИ не слушает игрока
+	tickDuration  float64
+	jumpRequested bool // Прыжок нажат после прошлого тика
+	interactReq   bool // Клавиша действия нажата после прошлого тика
+}
+
+// RequestJump запоминает нажатие прыжка до следующего тика: нажатия читаются
+// каждый кадр, а кадров между тиками может быть несколько или ни одного
+func (li *liveInput) RequestJump() {
+	li.jumpRequested = true
+}
+
+// RequestInteract запоминает нажатие клавиши действия до следующего тика
+func (li *liveInput) RequestInteract() {
+	li.interactReq = true
+}
+
+// NextFrame реализует InputSource
+func (li *liveInput) NextFrame(_ *Simulation) (InputFrame, bool) {
+	// Повороты с клавиатуры и стика идут с шагом тика, мышь - каждый кадр в processInput
+	turn := li.input.ActionValue(ActionTurnRight) - li.input.ActionValue(ActionTurnLeft)
+	look := li.input.ActionValue(ActionLookUp) - li.input.ActionValue(ActionLookDown)
+	if turn != 0 || look != 0 {
+		li.camera.TurnAt(turn, look, li.tickDuration)
+	}
+
+	frame := InputFrame{
+		Forward:  li.input.ActionValue(ActionMoveForward) - li.input.ActionValue(ActionMoveBackward),
+		Strafe:   li.input.ActionValue(ActionMoveRight) - li.input.ActionValue(ActionMoveLeft),
+		Yaw:      li.camera.Yaw,
+		Pitch:    li.camera.Pitch,
+		Sprint:   li.input.ActionDown(ActionSprint),
+		Crouch:   li.input.ActionDown(ActionCrouch),
+		Jump:     li.jumpRequested,
+		Interact: li.interactReq,
+	}
+	li.jumpRequested, li.interactReq = false, false
+	if li.audio != nil {
+		frame.Voice = li.audio.MicLevel()
+	}
+
+	return frame.Quantize(), true
+}
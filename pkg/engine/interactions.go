@@ -0,0 +1,311 @@
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"nightmare/internal/util"
+	"nightmare/pkg/config"
+)
+
+// Взаимодействие с объектами: игрок смотрит на объект в пределах
+// досягаемости и нажимает клавишу действия (ActionInteract). Что при этом
+// происходит, описывают данные: для типа объекта (strange, stump...) или
+// постройки (shrine, graves...) - какие метаданные atmosphere.* добавить
+// объекту, как изменить погоду, насколько испугать игрока, будет ли помеха.
+// Взаимодействие - часть симуляции: нажатие записывается в кадр ввода,
+// а случайность идет из потока мира
+const (
+	interactReach      = 2.5 // Досягаемость по умолчанию, м
+	interactionsFolder = "interactions"
+)
+
+// InteractionEffects - последствия взаимодействия
+type InteractionEffects struct {
+	Metadata map[string]float64 `yaml:"metadata"` // Добавки к метаданным объекта или постройки (atmosphere.fear: 0.2), итог 0..1
+	Weather  map[string]float64 `yaml:"weather"`  // Добавки к погоде (fog, mist, wind), итог 0..1
+	Shock    float64            `yaml:"shock"`    // Насколько испугать игрока (вклад в стресс для ИИ-режиссера)
+	Glitch   float64            `yaml:"glitch"`   // Шанс помех изображения
+	Sound    string             `yaml:"sound"`    // Звук: interact, scare, ambient
+	Message  string             `yaml:"message"`  // Что пишется в лог
+}
+
+// Interaction описывает одно действие с объектами одного типа, например:
+//
+//	name: inspect
+//	target: strange
+//	cooldown: 10
+//	effects:
+//	  metadata: {atmosphere.fear: 0.2, atmosphere.dread: 0.1}
+//	  shock: 0.3
+//	  glitch: 0.5
+//	  sound: scare
+type Interaction struct {
+	Name     string             `yaml:"name"`     // Как называется действие: inspect, touch
+	Target   string             `yaml:"target"`   // Тип объекта сцены или постройки
+	Reach    float64            `yaml:"reach"`    // Досягаемость, м; 0 - interactReach
+	Cooldown float64            `yaml:"cooldown"` // Секунд до повтора с тем же объектом
+	Effects  InteractionEffects `yaml:"effects"`
+}
+
+// interactionsFile - файл взаимодействий мода
+type interactionsFile struct {
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// defaultInteractions - взаимодействия без модов. Мод заменяет
+// взаимодействие с тем же target
+var defaultInteractions = []Interaction{
+	{Name: "inspect", Target: "strange", Cooldown: 10, Effects: InteractionEffects{
+		Metadata: map[string]float64{"atmosphere.fear": 0.2, "atmosphere.dread": 0.1},
+		Shock:    0.3,
+		Glitch:   0.5,
+		Sound:    "scare",
+		Message:  "It is warm. It should not be warm",
+	}},
+	{Name: "touch", Target: "shrine", Cooldown: 60, Effects: InteractionEffects{
+		Metadata: map[string]float64{"atmosphere.ominous": 0.2},
+		Weather:  map[string]float64{"fog": 0.4, "wind": 0.3},
+		Shock:    0.1,
+		Sound:    "ambient",
+		Message:  "The wind rises. Fog crawls out of the trees",
+	}},
+	{Name: "inspect", Target: "graves", Cooldown: 30, Effects: InteractionEffects{
+		Metadata: map[string]float64{"atmosphere.dread": 0.2},
+		Shock:    0.15,
+		Glitch:   0.2,
+		Sound:    "ambient",
+		Message:  "The names are scratched out",
+	}},
+	{Name: "touch", Target: "stone_circle", Cooldown: 60, Effects: InteractionEffects{
+		Metadata: map[string]float64{"atmosphere.ominous": 0.2, "conditions.unnatural": 0.2},
+		Weather:  map[string]float64{"mist": 0.3},
+		Glitch:   0.3,
+		Sound:    "ambient",
+	}},
+	{Name: "inspect", Target: "stump", Cooldown: 5, Effects: InteractionEffects{
+		Metadata: map[string]float64{"atmosphere.tension": 0.05},
+		Sound:    "interact",
+	}},
+	{Name: "touch", Target: "tree", Cooldown: 5, Effects: InteractionEffects{
+		Sound: "interact",
+	}},
+	{Name: "touch", Target: "rock", Cooldown: 5, Effects: InteractionEffects{
+		Sound: "interact",
+	}},
+}
+
+// InteractionEvent - взаимодействие, которое движок показывает игроку:
+// звук, помехи, сообщение. Мир симуляция уже изменила
+type InteractionEvent struct {
+	Name     string
+	Target   string
+	ObjectID int
+	Position Vector3
+	Metadata map[string]float64 // Метаданные объекта после взаимодействия (копия)
+	Glitch   bool               // Выпала помеха изображения
+	Sound    string
+	Message  string
+}
+
+// InteractionSystem выбирает объект перед игроком и применяет взаимодействие с ним
+type InteractionSystem struct {
+	rng          *rand.Rand
+	interactions map[string]*Interaction // По target
+	cooldowns    map[int]float64         // Секунды симуляции, когда объект снова доступен
+	time         float64
+	events       []InteractionEvent
+}
+
+// newInteractionSystem создает систему со взаимодействиями по умолчанию
+func newInteractionSystem(seed int64) *InteractionSystem {
+	is := &InteractionSystem{
+		rng:          newStream(seed, streamInteraction),
+		interactions: make(map[string]*Interaction),
+		cooldowns:    make(map[int]float64),
+	}
+	for i := range defaultInteractions {
+		is.Add(defaultInteractions[i])
+	}
+	return is
+}
+
+// Add добавляет взаимодействие или заменяет прежнее с тем же target
+func (is *InteractionSystem) Add(interaction Interaction) error {
+	if interaction.Target == "" {
+		return fmt.Errorf("interaction %q has no target", interaction.Name)
+	}
+	if interaction.Name == "" {
+		interaction.Name = "interact"
+	}
+	if interaction.Reach <= 0 {
+		interaction.Reach = interactReach
+	}
+	is.interactions[interaction.Target] = &interaction
+	return nil
+}
+
+// LoadModInteractions читает взаимодействия включенных модов: каждый
+// *.yaml в <mods_folder>/<mod>/interactions. Сломанный файл пропускается,
+// его ошибка возвращается вместе с остальными
+func LoadModInteractions(mods config.ModsConfig) ([]Interaction, []error) {
+	if !mods.Enabled {
+		return nil, nil
+	}
+
+	var interactions []Interaction
+	var errs []error
+	for _, mod := range mods.EnabledMods {
+		paths, _ := filepath.Glob(filepath.Join(mods.ModsFolder, mod, interactionsFolder, "*.yaml"))
+		sort.Strings(paths)
+		for _, path := range paths {
+			file, err := loadInteractionsFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("mod %s: %v", mod, err))
+				continue
+			}
+			for _, interaction := range file.Interactions {
+				if interaction.Target == "" {
+					errs = append(errs, fmt.Errorf("mod %s: %s: interaction %q has no target", mod, path, interaction.Name))
+					continue
+				}
+				interactions = append(interactions, interaction)
+			}
+		}
+	}
+	return interactions, errs
+}
+
+// loadInteractionsFile читает файл взаимодействий
+func loadInteractionsFile(path string) (*interactionsFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read interactions: %v", err)
+	}
+
+	file := &interactionsFile{}
+	if err := yaml.UnmarshalStrict(data, file); err != nil {
+		return nil, fmt.Errorf("error parsing interactions %s: %v", path, err)
+	}
+	return file, nil
+}
+
+// Focus возвращает объект перед игроком, с которым можно взаимодействовать,
+// и само взаимодействие; nil, если такого нет
+func (is *InteractionSystem) Focus(sim *Simulation) (*ProceduralObject, *Interaction) {
+	player := sim.Player()
+	eye := player.Position.Add(Vector3{Y: player.EyeHeight() - player.Height/2})
+
+	reach := 0.0
+	for _, interaction := range is.interactions {
+		reach = math.Max(reach, interaction.Reach)
+	}
+
+	obj, distance := sim.sight.Pick(eye, player.Direction, reach)
+	if obj == nil {
+		return nil, nil
+	}
+	interaction := is.interactions[is.targetOf(sim, obj)]
+	if interaction == nil || distance > interaction.Reach {
+		return nil, nil
+	}
+	return obj, interaction
+}
+
+// targetOf возвращает тип объекта или, для части постройки, тип постройки
+func (is *InteractionSystem) targetOf(sim *Simulation, obj *ProceduralObject) string {
+	if structure := is.structureOf(sim, obj); structure != nil {
+		return structure.Type
+	}
+	return obj.Type
+}
+
+// structureOf возвращает постройку, частью которой является объект, или nil
+func (is *InteractionSystem) structureOf(sim *Simulation, obj *ProceduralObject) *PlacedStructure {
+	if obj.StructureID == 0 {
+		return nil
+	}
+	for _, structure := range sim.procedural.GetCurrentScene().Structures {
+		if structure.ID == obj.StructureID {
+			return structure
+		}
+	}
+	return nil
+}
+
+// Update продвигает время симуляции; interact - нажата клавиша действия
+func (is *InteractionSystem) Update(sim *Simulation, interact bool, deltaTime float64) {
+	is.time += deltaTime
+	if interact {
+		is.interact(sim)
+	}
+}
+
+// interact применяет взаимодействие с объектом перед игроком
+func (is *InteractionSystem) interact(sim *Simulation) {
+	obj, interaction := is.Focus(sim)
+	if obj == nil || is.time < is.cooldowns[obj.ID] {
+		return
+	}
+	is.cooldowns[obj.ID] = is.time + interaction.Cooldown
+
+	effects := interaction.Effects
+	scene := sim.procedural.GetCurrentScene()
+
+	// Постройка меняется целиком: на ее метаданные реагирует окружение
+	metadata := obj.Metadata
+	if structure := is.structureOf(sim, obj); structure != nil {
+		metadata = structure.Metadata
+	}
+	if metadata != nil {
+		applyEffects(metadata, effects.Metadata)
+	}
+	if scene.Weather != nil {
+		applyEffects(scene.Weather, effects.Weather)
+	}
+
+	if effects.Shock > 0 {
+		sim.frighten(effects.Shock)
+	}
+
+	is.events = append(is.events, InteractionEvent{
+		Name:     interaction.Name,
+		Target:   interaction.Target,
+		ObjectID: obj.ID,
+		Position: obj.Position,
+		Metadata: copyMetadata(metadata),
+		Glitch:   effects.Glitch > 0 && is.rng.Float64() < effects.Glitch,
+		Sound:    effects.Sound,
+		Message:  effects.Message,
+	})
+}
+
+// applyEffects добавляет значения к метаданным, оставляя итог в 0..1.
+// Ключи обходятся по порядку, чтобы не зависеть от порядка map
+func applyEffects(metadata, deltas map[string]float64) {
+	for _, key := range sortedKeys(deltas) {
+		metadata[key] = util.Clamp(metadata[key]+deltas[key], 0, 1)
+	}
+}
+
+// copyMetadata копирует метаданные, чтобы движок не видел их дальнейших изменений
+func copyMetadata(metadata map[string]float64) map[string]float64 {
+	copied := make(map[string]float64, len(metadata))
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
+
+// TakeEvents возвращает накопленные события и сбрасывает их
+func (is *InteractionSystem) TakeEvents() []InteractionEvent {
+	events := is.events
+	is.events = nil
+	return events
+}
@@ -0,0 +1,168 @@
+package engine
+
+import "math"
+
+// Stamina, crouch and noise tuning
+const (
+	sprintStaminaCost     = 20.0 // Stamina per second of sprinting
+	jumpStaminaCost       = 15.0 // Stamina per jump
+	staminaRegenRate      = 12.0 // Stamina per second while not sprinting
+	restingRegenFactor    = 2.0  // Standing still regenerates faster
+	staminaRegenDelay     = 1.0  // Seconds after spending stamina before it regenerates
+	sprintRecoveryStamina = 25.0 // After running dry sprint unlocks at this stamina
+
+	eyeBelowTop = 0.1 // Eyes are this far below the top of the head
+
+	walkNoise        = 0.4  // Noise of walking on a neutral surface
+	sprintNoise      = 0.8  // Noise of sprinting
+	crouchNoise      = 0.12 // Noise of sneaking
+	loudLandingSpeed = 12.0 // Landing at this speed makes the loudest noise
+	maxNoiseRadius   = 30.0 // Noise 1.0 is heard this far (m)
+)
+
+// walkSpeed returns the walking speed for the current surface and stance
+func (ps *PhysicsSystem) walkSpeed() float64 {
+	speed := ps.player.MoveSpeed * ps.surface.SpeedFactor
+	if ps.player.IsCrouching {
+		speed *= ps.player.CrouchModifier
+	}
+	return speed
+}
+
+// canSprint reports whether the player is standing and has stamina left to sprint
+func (ps *PhysicsSystem) canSprint() bool {
+	return !ps.player.IsCrouching && !ps.exhausted && ps.player.Stamina > 0
+}
+
+// spendStamina takes stamina and postpones regeneration
+func (ps *PhysicsSystem) spendStamina(amount float64) {
+	ps.player.Stamina = math.Max(0, ps.player.Stamina-amount)
+	ps.staminaDelay = staminaRegenDelay
+
+	if ps.player.Stamina == 0 {
+		ps.exhausted = true
+	}
+}
+
+// updateStamina drains stamina while sprinting and regenerates it otherwise
+func (ps *PhysicsSystem) updateStamina(deltaTime float64) {
+	dx := ps.player.Position.X - ps.previous.X
+	dz := ps.player.Position.Z - ps.previous.Z
+	moving := dx*dx+dz*dz > 1e-6
+
+	ps.player.IsSprinting = ps.sprintRequested && moving
+	ps.sprintRequested = false
+
+	if ps.player.IsSprinting {
+		ps.spendStamina(sprintStaminaCost * deltaTime)
+		return
+	}
+
+	if ps.staminaDelay > 0 {
+		ps.staminaDelay -= deltaTime
+		return
+	}
+
+	regen := staminaRegenRate * deltaTime
+	if !moving {
+		regen *= restingRegenFactor
+	}
+	ps.player.Stamina = math.Min(ps.player.MaxStamina, ps.player.Stamina+regen)
+
+	if ps.exhausted && ps.player.Stamina >= sprintRecoveryStamina {
+		ps.exhausted = false
+	}
+}
+
+// SetCrouch crouches or stands up. Crouching lowers the collision height and
+// the camera; standing up waits until there's room above the head
+func (ps *PhysicsSystem) SetCrouch(crouch bool) {
+	if crouch == ps.player.IsCrouching {
+		return
+	}
+	if !crouch && !ps.canStandUp() {
+		return
+	}
+
+	height := ps.player.StandHeight
+	if crouch {
+		height = ps.player.CrouchHeight
+	}
+
+	// Position is the center of the body: keep the feet where they are
+	ps.player.Position.Y += (height - ps.player.Height) / 2
+	ps.player.Height = height
+	ps.player.IsCrouching = crouch
+
+	if ps.resolver != nil {
+		ps.resolver.SetPlayerHeight(height)
+	}
+}
+
+// canStandUp checks that a standing player fits under cave ceilings and objects
+func (ps *PhysicsSystem) canStandUp() bool {
+	feet := ps.player.Position
+	feet.Y -= ps.player.Height / 2
+
+	if ps.scene != nil && ps.scene.Terrain != nil {
+		if ground := ps.groundAt(feet.X, feet.Y, feet.Z); ground.Ceiling-feet.Y < ps.player.StandHeight {
+			return false
+		}
+	}
+
+	if ps.resolver != nil {
+		ps.resolver.SetPlayerHeight(ps.player.StandHeight)
+		_, contacts := ps.resolver.ResolveCapsule(feet, ps.player.StepHeight)
+		ps.resolver.SetPlayerHeight(ps.player.Height)
+		return len(contacts) == 0
+	}
+	return true
+}
+
+// updateNoise computes how loud the player was during this tick from the
+// movement mode, the surface under the feet and landings
+func (ps *PhysicsSystem) updateNoise(deltaTime float64) {
+	noise := 0.0
+
+	dx := ps.player.Position.X - ps.previous.X
+	dz := ps.player.Position.Z - ps.previous.Z
+	speed := math.Sqrt(dx*dx+dz*dz) / deltaTime
+
+	if ps.player.IsGrounded && speed > 0.1 {
+		switch {
+		case ps.player.IsSprinting:
+			noise = sprintNoise
+		case ps.player.IsCrouching:
+			noise = crouchNoise
+		default:
+			noise = walkNoise
+		}
+
+		// Moving slower than the stance allows is quieter
+		noise *= math.Min(1, speed/ps.walkSpeed())
+	}
+
+	noise = math.Max(noise, ps.landingNoise)
+	ps.landingNoise = 0
+
+	ps.player.NoiseEmitted = math.Min(1, noise*ps.surface.Loudness)
+	ps.events.Noise = math.Max(ps.events.Noise, ps.player.NoiseEmitted)
+}
+
+// NoiseRadius returns how far the noise the player made during the last tick can be heard
+func (p *Player) NoiseRadius() float64 {
+	return p.NoiseEmitted * maxNoiseRadius
+}
+
+// EyeHeight returns the height of the eyes above the feet for the current stance
+func (p *Player) EyeHeight() float64 {
+	return p.Height - eyeBelowTop
+}
+
+// InterpolatedEyePosition returns the camera position between the previous
+// and the current tick (see InterpolatedPosition)
+func (ps *PhysicsSystem) InterpolatedEyePosition(alpha float64) Vector3 {
+	position := ps.InterpolatedPosition(alpha)
+	position.Y += ps.player.EyeHeight() - ps.player.Height/2
+	return position
+}
@@ -0,0 +1,540 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"nightmare/pkg/config"
+)
+
+// Цели мира - короткая история, которую каждый сид рассказывает по-своему:
+// в этом лесу кто-то пропал, игрок находит записки у построек, зажигает
+// огни в святилищах и до рассвета поднимается на хребет. Цели идут по
+// очереди; каждая выполненная цель подстегивает директора. Цели - часть
+// симуляции: места, тексты и порядок детерминированы сидом мира
+const (
+	objectiveNoteMin      = 4.0   // Записки лежат не ближе этого к краю постройки, м
+	objectiveNoteMax      = 25.0  // ...и не дальше этого от ее центра
+	objectiveNoteSpacing  = 15.0  // Записки не лежат ближе этого друг к другу
+	objectiveNoteAttempts = 24    // Сколько мест пробовать у постройки
+	objectiveNoteReach    = 1.5   // Записку подбирают ближе этого
+	objectiveFiresMax     = 3     // Больше огней зажигать не нужно
+	objectiveFireReach    = 3.0   // Огонь зажигают, глядя на святилище не дальше этого
+	objectiveRidgeReach   = 6.0   // Хребет достигнут ближе этого
+	objectiveNoteFirstID  = -2000 // ID записок в кадре: их нет в сцене, как и галлюцинаций
+
+	objectiveDayLength   = 15 * 60.0 // Длина суток, с (как в ProceduralGenerator.advanceTick)
+	objectiveDawn        = 0.25      // Рассвет (ProceduralScene.TimeOfDay)
+	objectiveRidgeMinRun = 120.0     // Если до рассвета меньше, срок - следующий рассвет
+
+	objectiveMessageTime = 8.0 // Столько секунд движок показывает сообщение истории
+)
+
+// noteRegions - регионы, где записку не оставить: ее не найти или не достать
+var noteRegions = map[string]bool{"swamp_pit": true, "ravine": true}
+
+// ObjectiveKind - вид цели
+type ObjectiveKind int
+
+const (
+	ObjectiveNotes ObjectiveKind = iota // Найти записки у построек
+	ObjectiveFires                      // Зажечь огни в святилищах
+	ObjectiveRidge                      // Подняться на хребет до рассвета
+)
+
+var objectiveKindNames = [...]string{"notes", "fires", "ridge"}
+
+func (k ObjectiveKind) String() string {
+	if k < 0 || int(k) >= len(objectiveKindNames) {
+		return "unknown"
+	}
+	return objectiveKindNames[k]
+}
+
+// MarshalYAML записывает вид цели именем
+func (k ObjectiveKind) MarshalYAML() (interface{}, error) {
+	return k.String(), nil
+}
+
+// UnmarshalYAML читает вид цели по имени
+func (k *ObjectiveKind) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err != nil {
+		return err
+	}
+	for kind, kindName := range objectiveKindNames {
+		if kindName == name {
+			*k = ObjectiveKind(kind)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown objective kind %q", name)
+}
+
+// ObjectiveTarget - место, куда нужно попасть: записка, святилище, хребет
+type ObjectiveTarget struct {
+	Position    Vector3 `yaml:"position"`
+	StructureID int     `yaml:"structure_id,omitempty"` // Святилище, в котором зажигают огонь
+	Done        bool    `yaml:"done"`
+}
+
+// Objective - одна цель истории
+type Objective struct {
+	Kind     ObjectiveKind     `yaml:"kind"`
+	Title    string            `yaml:"title"`
+	Start    string            `yaml:"start"`           // Что игрок узнает, когда цель начинается
+	Texts    []string          `yaml:"texts,omitempty"` // Тексты записок по порядку находки
+	Targets  []ObjectiveTarget `yaml:"targets"`
+	Deadline float64           `yaml:"deadline,omitempty"` // Срок, с от начала игры; 0 - без срока
+	Done     bool              `yaml:"done"`
+	Failed   bool              `yaml:"failed"`
+}
+
+// Reached возвращает число выполненных мест цели
+func (o *Objective) Reached() int {
+	reached := 0
+	for _, target := range o.Targets {
+		if target.Done {
+			reached++
+		}
+	}
+	return reached
+}
+
+// ObjectiveEvent - продвижение истории, которое движок показывает игроку
+type ObjectiveEvent struct {
+	Kind     ObjectiveKind
+	Message  string
+	Position Vector3 // Где это случилось
+	Finished bool    // Цель выполнена или провалена
+}
+
+// Objectives ведет цели мира
+type Objectives struct {
+	Missing string       `yaml:"missing"` // Кого ищет игрок
+	Intro   string       `yaml:"intro"`
+	Ending  string       `yaml:"ending"`  // Чем история кончается, если все цели выполнены
+	Failure string       `yaml:"failure"` // ...и если нет
+	Goals   []*Objective `yaml:"objectives"`
+	Current int          `yaml:"current"` // Номер текущей цели; len(Goals) - история кончилась
+	Time    float64      `yaml:"time"`    // Секунд с начала игры
+
+	events []ObjectiveEvent
+}
+
+// Истории: имена пропавших, записки и развязки
+var (
+	objectiveMissing = []string{"Ella", "Tomas", "Mara", "Ilya", "Vera", "Anton", "Nadia", "Oskar"}
+	objectiveIntros  = []string{
+		"%s went into these woods and did not come back",
+		"Nobody has seen %s since the fog came down",
+		"%[1]s wrote that the forest was changing. Then %[1]s was gone",
+	}
+	objectiveNotes = []string{
+		"The paths are not where I left them",
+		"Something walks behind me. It stops when I stop",
+		"The stones are warm at night. Why are they warm",
+		"The fog has a shape. It waits at the edge and watches",
+		"I hear my own footsteps behind me",
+		"Whatever it is, it hates fire. The shrines still have wood",
+		"If you find this, do not turn around",
+		"From the ridge you can see the road. It has to be before dawn",
+	}
+	objectiveEndings = []string{
+		"From the ridge you see the lights of the road. %s's coat hangs on a branch beside you",
+		"The sun finds you on the ridge. Far below, someone waves. It could be %s",
+	}
+	objectiveFailures = []string{
+		"Dawn comes and the fog swallows the ridge. %s's trail is gone",
+		"The sky turns grey. Somewhere in the trees %s stops calling",
+	}
+)
+
+// newObjectives придумывает историю для текущего мира. Цели, которым в
+// мире нет места (нет построек, святилищ), пропускаются
+func newObjectives(sim *Simulation, cfg config.ObjectivesConfig) *Objectives {
+	rng := newStream(sim.Seed(), streamObjectives)
+	missing := objectiveMissing[rng.Intn(len(objectiveMissing))]
+	o := &Objectives{
+		Missing: missing,
+		Intro:   fmt.Sprintf(objectiveIntros[rng.Intn(len(objectiveIntros))], missing),
+		Ending:  fmt.Sprintf(objectiveEndings[rng.Intn(len(objectiveEndings))], missing),
+		Failure: fmt.Sprintf(objectiveFailures[rng.Intn(len(objectiveFailures))], missing),
+	}
+
+	scene := sim.procedural.GetCurrentScene()
+	if notes := o.placeNotes(sim, scene, cfg.Notes, rng); len(notes) > 0 {
+		// Записки выбираются случайно, но читаются в порядке истории
+		chosen := rng.Perm(len(objectiveNotes))
+		chosen = chosen[:min(len(notes), len(chosen))]
+		sort.Ints(chosen)
+		texts := make([]string, len(notes))
+		for i := range texts {
+			texts[i] = objectiveNotes[chosen[i%len(chosen)]]
+		}
+		o.Goals = append(o.Goals, &Objective{
+			Kind:    ObjectiveNotes,
+			Title:   fmt.Sprintf("Find %s's notes", missing),
+			Start:   "They left notes by the old buildings",
+			Texts:   texts,
+			Targets: notes,
+		})
+	}
+	if fires := o.chooseShrines(scene, rng); len(fires) > 0 {
+		o.Goals = append(o.Goals, &Objective{
+			Kind:    ObjectiveFires,
+			Title:   "Light the shrine fires",
+			Start:   fmt.Sprintf("%s tried to keep the shrines burning. Light them again", missing),
+			Targets: fires,
+		})
+	}
+	if ridge, ok := o.findRidge(sim, scene); ok {
+		o.Goals = append(o.Goals, &Objective{
+			Kind:    ObjectiveRidge,
+			Title:   "Reach the ridge before dawn",
+			Start:   "Climb to the highest ridge before the sun comes up",
+			Targets: []ObjectiveTarget{{Position: ridge}},
+		})
+	}
+
+	if len(o.Goals) > 0 {
+		o.emit(o.Goals[0], o.Intro, sim.Player().Position, false)
+	}
+	o.begin(sim)
+	return o
+}
+
+// placeNotes раскладывает записки у построек, по возможности на тропах:
+// по ним игрок и ходит от постройки к постройке
+func (o *Objectives) placeNotes(sim *Simulation, scene *ProceduralScene, count int, rng *rand.Rand) []ObjectiveTarget {
+	structures := make([]*PlacedStructure, 0, len(scene.Structures))
+	for _, structure := range scene.Structures {
+		// Изгородь сама стоит вдоль тропы, записка у нее ничего не значит
+		if structure.Type != "fence" {
+			structures = append(structures, structure)
+		}
+	}
+	if len(structures) == 0 || scene.Terrain == nil {
+		return nil
+	}
+
+	terrain := scene.Terrain
+	notes := make([]ObjectiveTarget, 0, count)
+	for _, i := range rng.Perm(len(structures)) {
+		if len(notes) >= count {
+			break
+		}
+		structure := structures[i]
+
+		var found, onPath bool
+		var spot Vector3
+		for attempt := 0; attempt < objectiveNoteAttempts && !onPath; attempt++ {
+			angle := rng.Float64() * 2 * math.Pi
+			distance := math.Min(structure.Radius+objectiveNoteMin, objectiveNoteMax)
+			distance += rng.Float64() * (objectiveNoteMax - distance)
+			x := structure.Center.X + math.Sin(angle)*distance
+			z := structure.Center.Z + math.Cos(angle)*distance
+			if !o.noteFits(sim, terrain, notes, x, z) {
+				continue
+			}
+
+			// Первое подходящее место запоминается, место на тропе лучше
+			cellX, cellZ := terrain.cellAt(x, z)
+			if terrain.Regions[cellZ][cellX] == "path" || !found {
+				spot = Vector3{X: x, Y: terrain.HeightAt(x, z), Z: z}
+				found = true
+				onPath = terrain.Regions[cellZ][cellX] == "path"
+			}
+		}
+		if found {
+			notes = append(notes, ObjectiveTarget{Position: spot})
+		}
+	}
+	return notes
+}
+
+// noteFits проверяет, что записку в (x, z) можно найти и подобрать
+func (o *Objectives) noteFits(sim *Simulation, terrain *HeightMap, notes []ObjectiveTarget, x, z float64) bool {
+	if !terrain.InBounds(x, z) || !sim.physics.CanStandAt(x, z) {
+		return false
+	}
+	cellX, cellZ := terrain.cellAt(x, z)
+	if noteRegions[terrain.Regions[cellZ][cellX]] || terrain.SurfaceAt(x, z).Name == surfaceWater.Name {
+		return false
+	}
+	for _, note := range notes {
+		if horizontalDistance(note.Position, Vector3{X: x, Z: z}) < objectiveNoteSpacing {
+			return false
+		}
+	}
+	return true
+}
+
+// chooseShrines выбирает святилища, в которых нужно зажечь огонь
+func (o *Objectives) chooseShrines(scene *ProceduralScene, rng *rand.Rand) []ObjectiveTarget {
+	shrines := make([]ObjectiveTarget, 0)
+	for _, structure := range scene.Structures {
+		if structure.Type == "shrine" {
+			shrines = append(shrines, ObjectiveTarget{Position: structure.Center, StructureID: structure.ID})
+		}
+	}
+
+	rng.Shuffle(len(shrines), func(i, j int) {
+		shrines[i], shrines[j] = shrines[j], shrines[i]
+	})
+	if len(shrines) > objectiveFiresMax {
+		shrines = shrines[:objectiveFiresMax]
+	}
+	return shrines
+}
+
+// findRidge находит самое высокое место на горных вершинах, где можно
+// стоять; если вершин нет - самое высокое место на карте
+func (o *Objectives) findRidge(sim *Simulation, scene *ProceduralScene) (Vector3, bool) {
+	terrain := scene.Terrain
+	if terrain == nil {
+		return Vector3{}, false
+	}
+
+	peaks := make([][2]int, 0)
+	cells := make([][2]int, 0, terrain.Width*terrain.Height)
+	for z := 0; z < terrain.Height; z++ {
+		for x := 0; x < terrain.Width; x++ {
+			cells = append(cells, [2]int{x, z})
+			if terrain.Regions[z][x] == "mountain_peak" {
+				peaks = append(peaks, [2]int{x, z})
+			}
+		}
+	}
+	if len(peaks) > 0 {
+		cells = peaks
+	}
+
+	// Сначала самые высокие; при равной высоте порядок обхода карты
+	sort.SliceStable(cells, func(i, j int) bool {
+		return terrain.Data[cells[i][1]][cells[i][0]] > terrain.Data[cells[j][1]][cells[j][0]]
+	})
+	for _, cell := range cells {
+		x := float64(cell[0]) - float64(terrain.Width)/2 + 0.5
+		z := float64(cell[1]) - float64(terrain.Height)/2 + 0.5
+		if sim.physics.CanStandAt(x, z) {
+			return Vector3{X: x, Y: terrain.HeightAt(x, z), Z: z}, true
+		}
+	}
+	return Vector3{}, false
+}
+
+// Update продвигает цели на один тик. interact - нажата клавиша действия
+func (o *Objectives) Update(sim *Simulation, interact bool, deltaTime float64) {
+	o.Time += deltaTime
+	goal := o.Goal()
+	if goal == nil {
+		return
+	}
+
+	player := sim.Player()
+	switch goal.Kind {
+	case ObjectiveNotes:
+		for i := range goal.Targets {
+			target := &goal.Targets[i]
+			if !target.Done && horizontalDistance(target.Position, player.Position) < objectiveNoteReach {
+				target.Done = true
+				o.emit(goal, goal.Texts[goal.Reached()-1], target.Position, false)
+			}
+		}
+
+	case ObjectiveFires:
+		if !interact {
+			break
+		}
+		eye := player.Position.Add(Vector3{Y: player.EyeHeight() - player.Height/2})
+		obj, _ := sim.sight.Pick(eye, player.Direction, objectiveFireReach)
+		if obj == nil || obj.StructureID == 0 {
+			break
+		}
+		for i := range goal.Targets {
+			target := &goal.Targets[i]
+			if !target.Done && target.StructureID == obj.StructureID {
+				target.Done = true
+				o.light(sim, target.StructureID)
+				o.emit(goal, "The fire catches. The whispering goes quiet", target.Position, false)
+			}
+		}
+
+	case ObjectiveRidge:
+		if horizontalDistance(goal.Targets[0].Position, player.Position) < objectiveRidgeReach {
+			goal.Targets[0].Done = true
+		} else if o.Time >= goal.Deadline {
+			goal.Failed = true
+			o.Current = len(o.Goals)
+			o.emit(goal, o.Failure, goal.Targets[0].Position, true)
+			return
+		}
+	}
+
+	if goal.Reached() == len(goal.Targets) {
+		o.complete(sim, goal)
+	}
+}
+
+// light зажигает огонь в святилище: вокруг него становится не так страшно
+func (o *Objectives) light(sim *Simulation, structureID int) {
+	for _, structure := range sim.procedural.GetCurrentScene().Structures {
+		if structure.ID == structureID && structure.Metadata != nil {
+			applyEffects(structure.Metadata, map[string]float64{
+				"atmosphere.fear":    -0.3,
+				"atmosphere.ominous": -0.4,
+				"visuals.dark":       -0.3,
+			})
+		}
+	}
+}
+
+// complete завершает цель и начинает следующую. Директор узнает, что
+// игрок ближе к развязке
+func (o *Objectives) complete(sim *Simulation, goal *Objective) {
+	goal.Done = true
+	o.Current++
+	position := goal.Targets[len(goal.Targets)-1].Position
+	if o.Current == len(o.Goals) {
+		o.emit(goal, o.Ending, position, true)
+	} else {
+		o.events = append(o.events, ObjectiveEvent{Kind: goal.Kind, Position: position, Finished: true})
+		o.begin(sim)
+	}
+
+	if sim.director != nil {
+		sim.director.escalate(sim, o.Progress())
+	}
+}
+
+// begin начинает текущую цель
+func (o *Objectives) begin(sim *Simulation) {
+	goal := o.Goal()
+	if goal == nil {
+		return
+	}
+	if goal.Kind == ObjectiveRidge {
+		// Срок - ближайший рассвет, но не слишком близкий: иначе цель не выполнить.
+		// Время суток считается, как в advanceTick: до первого тика сцена
+		// показывает время из GenerateInitialWorld
+		timeOfDay := math.Mod(o.Time/objectiveDayLength, 1)
+		left := math.Mod(objectiveDawn-timeOfDay+1, 1) * objectiveDayLength
+		if left < objectiveRidgeMinRun {
+			left += objectiveDayLength
+		}
+		goal.Deadline = o.Time + left
+	}
+	o.emit(goal, goal.Start, sim.Player().Position, false)
+}
+
+// emit сообщает о продвижении истории
+func (o *Objectives) emit(goal *Objective, message string, position Vector3, finished bool) {
+	o.events = append(o.events, ObjectiveEvent{Kind: goal.Kind, Message: message, Position: position, Finished: finished})
+}
+
+// Goal возвращает текущую цель или nil, если история кончилась
+func (o *Objectives) Goal() *Objective {
+	if o.Current >= len(o.Goals) {
+		return nil
+	}
+	return o.Goals[o.Current]
+}
+
+// Progress возвращает долю выполненных целей (0..1)
+func (o *Objectives) Progress() float64 {
+	if len(o.Goals) == 0 {
+		return 0
+	}
+	done := 0
+	for _, goal := range o.Goals {
+		if goal.Done {
+			done++
+		}
+	}
+	return float64(done) / float64(len(o.Goals))
+}
+
+// Lines возвращает текущую цель короткими строками для экрана; пусто,
+// когда история кончилась
+func (o *Objectives) Lines() []string {
+	goal := o.Goal()
+	if goal == nil {
+		return nil
+	}
+
+	line := fmt.Sprintf("%s %d/%d", goal.Title, goal.Reached(), len(goal.Targets))
+	if goal.Deadline > 0 {
+		left := int(math.Max(0, goal.Deadline-o.Time))
+		line = fmt.Sprintf("%s %d:%02d", goal.Title, left/60, left%60)
+	}
+	return []string{line}
+}
+
+// TakeEvents возвращает накопленные события и сбрасывает их
+func (o *Objectives) TakeEvents() []ObjectiveEvent {
+	events := o.events
+	o.events = nil
+	return events
+}
+
+// AddToScene показывает в кадре записки, которые игрок еще не нашел
+func (o *Objectives) AddToScene(scene *SceneData) {
+	goal := o.Goal()
+	if goal == nil || goal.Kind != ObjectiveNotes {
+		return
+	}
+	for i, target := range goal.Targets {
+		if target.Done {
+			continue
+		}
+		direction, distance, visible := inView(scene.PlayerPosition, scene.ViewDirection, target.Position, 0)
+		if !visible {
+			continue
+		}
+		scene.AddObjectInView(&SceneObject{
+			Type:       "note",
+			ID:         objectiveNoteFirstID - i,
+			Distance:   distance,
+			Direction:  direction,
+			Size:       0.3 / math.Max(1.0, distance/5.0),
+			Visibility: 1.0 - math.Min(1.0, distance/viewMaxDistance),
+		})
+	}
+}
+
+// hash добавляет состояние целей в хэш симуляции
+func (o *Objectives) hash(wh *worldHasher) {
+	wh.int(int64(o.Current))
+	for _, goal := range o.Goals {
+		wh.int(int64(goal.Reached()))
+		wh.float(goal.Deadline)
+	}
+}
+
+// EncodeObjectives сериализует цели в YAML
+func EncodeObjectives(objectives *Objectives) ([]byte, error) {
+	data, err := yaml.Marshal(objectives)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing objectives: %v", err)
+	}
+	return data, nil
+}
+
+// DecodeObjectives разбирает цели из YAML
+func DecodeObjectives(data []byte) (*Objectives, error) {
+	objectives := &Objectives{}
+	if err := yaml.Unmarshal(data, objectives); err != nil {
+		return nil, fmt.Errorf("error parsing objectives: %v", err)
+	}
+	for _, goal := range objectives.Goals {
+		if goal.Kind == ObjectiveNotes && len(goal.Texts) < len(goal.Targets) {
+			return nil, fmt.Errorf("objective %q has %d notes but %d texts", goal.Title, len(goal.Targets), len(goal.Texts))
+		}
+	}
+	return objectives, nil
+}
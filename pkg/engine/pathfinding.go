@@ -0,0 +1,430 @@
+package engine
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Навигационная сетка для ботов и существ. A* идет по узлам сетки карты
+// высот (шаг 1 м) с 8 соседями. Узел проходим, если на нем помещается
+// игрок (PhysicsSystem.CanStandAt), переход - если земля вдоль него не круче,
+// чем можно подняться пешком (maxWalkableNormalY): иначе физика стащит игрока вниз.
+// Тропы при генерации мира прокладывает свой поиск (ProceduralGenerator.findPath):
+// он идет по сырой карте высот, когда проходимости еще нет
+const (
+	pathMaxNodes     = 20000 // Предел раскрытых узлов: недостижимая цель не обходит всю карту
+	pathSlopeCost    = 4.0   // Надбавка за крутизну перехода (0..1 - уклон до 45 градусов)
+	pathWaterCost    = 3.0   // Надбавка за метр по воде: там вдвое медленнее
+	pathMaxUphill    = 1.0   // Максимальный подъем на метр пути (45 градусов)
+	pathGoalRadius   = 1.5   // Цель считается достигнутой в пределах этого расстояния
+	pathNormalMargin = 0.05  // Запас к maxWalkableNormalY: на самой границе игрок уже съезжает
+	pathSlopeSamples = 4     // Сколько отрезков перехода проверять на крутизну
+	pathLateralProbe = 0.4   // Насколько в стороны от линии перехода проверять крутизну, м
+	pathEdgeMargin   = 2     // Узлы у края карты непроходимы: за краем игрок падает в пустоту
+
+	pathSmoothStep      = 0.5 // Шаг проверки прямого отрезка при сглаживании, м
+	pathSmoothLookahead = 8   // Через сколько точек пути сглаживание пробует срезать
+	navInvalidateMargin = 1.5 // Запас к размеру объекта, в котором узлы пересчитываются, м
+)
+
+// Состояние узла сетки: проходимость считается лениво и сбрасывается,
+// когда рядом меняются объекты
+const (
+	navUnknown byte = iota
+	navOpen
+	navBlocked
+)
+
+// pathNode - узел сетки в открытом списке A*
+type pathNode struct {
+	index int
+	cost  float64 // Пройденная стоимость + эвристика
+}
+
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
+
+// NavCell - узел сетки в координатах мира (округленных до метра)
+type NavCell [2]int
+
+// NavCellOf возвращает узел сетки, ближайший к точке
+func NavCellOf(p Vector3) NavCell {
+	return NavCell{int(math.Round(p.X)), int(math.Round(p.Z))}
+}
+
+// NavCost - надбавка к стоимости метра пути в точке (x, z), не меньше нуля.
+// Так существо выбирает путь по вкусу: в темноте, вне взгляда игрока
+type NavCost func(x, z float64) float64
+
+// NavQuery - запрос пути
+type NavQuery struct {
+	From, To Vector3
+	Avoid    map[NavCell]bool // Узлы, которые надо обойти (бот не смог через них пройти); может быть nil
+	Cost     NavCost          // Надбавки к стоимости; nil - кратчайший путь
+	Smooth   bool             // Срезать углы, где можно пройти по прямой
+}
+
+// NavGrid - навигационная сетка мира: проходимость узлов по карте высот,
+// уклонам, пещерам и коллайдерам объектов. Узлы считаются при первом
+// запросе и пересчитываются, только когда рядом появляется, исчезает или
+// сдвигается объект (NavGrid подписан на изменения сцены). Одну сетку
+// делят бот, сценарии и существа
+type NavGrid struct {
+	physics      *PhysicsSystem
+	terrain      *HeightMap
+	width        int
+	height       int
+	halfW, halfH float64
+
+	nodes     []byte  // Проходимость узлов (navUnknown, navOpen, navBlocked)
+	edgeKnown []uint8 // Биты направлений, для которых переход уже проверен
+	edgeOpen  []uint8 // Биты направлений, по которым переход пологий
+}
+
+// navDirections - смещения 8 соседей узла; номер смещения - бит в edgeKnown/edgeOpen
+var navDirections = [8][2]int{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}, {1, 0}, {-1, 1}, {0, 1}, {1, 1}}
+
+// NewNavGrid создает сетку для сцены и подписывает ее на изменения объектов
+func NewNavGrid(physics *PhysicsSystem, scene *ProceduralScene) *NavGrid {
+	terrain := scene.Terrain
+	ng := &NavGrid{
+		physics:   physics,
+		terrain:   terrain,
+		width:     terrain.Width,
+		height:    terrain.Height,
+		halfW:     float64(terrain.Width) / 2,
+		halfH:     float64(terrain.Height) / 2,
+		nodes:     make([]byte, terrain.Width*terrain.Height),
+		edgeKnown: make([]uint8, terrain.Width*terrain.Height),
+		edgeOpen:  make([]uint8, terrain.Width*terrain.Height),
+	}
+	scene.AddObserver(ng)
+	return ng
+}
+
+// nodeAt возвращает узел, ближайший к точке. Узел (gx, gz) стоит в мировой
+// точке (gx - width/2, gz - height/2)
+func (ng *NavGrid) nodeAt(x, z float64) (int, bool) {
+	gx := int(math.Round(x + ng.halfW))
+	gz := int(math.Round(z + ng.halfH))
+	if gx < 0 || gx >= ng.width || gz < 0 || gz >= ng.height {
+		return 0, false
+	}
+	return gz*ng.width + gx, true
+}
+
+// position возвращает мировые координаты узла
+func (ng *NavGrid) position(index int) (float64, float64) {
+	return float64(index%ng.width) - ng.halfW, float64(index/ng.width) - ng.halfH
+}
+
+// Walkable сообщает, что на узле, ближайшем к точке, помещается стоящий человек
+func (ng *NavGrid) Walkable(p Vector3) bool {
+	index, ok := ng.nodeAt(p.X, p.Z)
+	return ok && ng.open(index)
+}
+
+// open сообщает, что узел проходим, и запоминает ответ
+func (ng *NavGrid) open(index int) bool {
+	if ng.nodes[index] == navUnknown {
+		ng.nodes[index] = navBlocked
+		gx, gz := index%ng.width, index/ng.width
+		inside := gx >= pathEdgeMargin && gx < ng.width-pathEdgeMargin && gz >= pathEdgeMargin && gz < ng.height-pathEdgeMargin
+		if x, z := ng.position(index); inside && ng.physics.canStandAt(x, z, ng.physics.player.StandHeight) {
+			ng.nodes[index] = navOpen
+		}
+	}
+	return ng.nodes[index] == navOpen
+}
+
+// edge сообщает, что переход из узла в соседа direction не круче, чем можно
+// пройти пешком. Зависит только от земли, поэтому не пересчитывается
+func (ng *NavGrid) edge(index, direction int) bool {
+	bit := uint8(1) << direction
+	if ng.edgeKnown[index]&bit == 0 {
+		ng.edgeKnown[index] |= bit
+		d := navDirections[direction]
+		cx, cz := ng.position(index)
+		x, z := cx+float64(d[0]), cz+float64(d[1])
+		step := math.Hypot(float64(d[0]), float64(d[1]))
+		slope := math.Abs(ng.terrain.HeightAt(x, z)-ng.terrain.HeightAt(cx, cz)) / step
+		if slope <= pathMaxUphill && gentleSlope(ng.terrain, cx, cz, x, z) {
+			ng.edgeOpen[index] |= bit
+		}
+	}
+	return ng.edgeOpen[index]&bit != 0
+}
+
+// Invalidate сбрасывает проходимость узлов в радиусе от точки: там
+// появился, исчез или сдвинулся объект
+func (ng *NavGrid) Invalidate(center Vector3, radius float64) {
+	minX := int(math.Floor(center.X - radius + ng.halfW))
+	maxX := int(math.Ceil(center.X + radius + ng.halfW))
+	minZ := int(math.Floor(center.Z - radius + ng.halfH))
+	maxZ := int(math.Ceil(center.Z + radius + ng.halfH))
+	for gz := clamp(minZ, 0, ng.height-1); gz <= clamp(maxZ, 0, ng.height-1); gz++ {
+		for gx := clamp(minX, 0, ng.width-1); gx <= clamp(maxX, 0, ng.width-1); gx++ {
+			ng.nodes[gz*ng.width+gx] = navUnknown
+		}
+	}
+}
+
+// objectReach - радиус вокруг объекта, где он может мешать стоять
+func (ng *NavGrid) objectReach(obj *ProceduralObject) float64 {
+	return math.Hypot(obj.Scale.X, obj.Scale.Z) + ng.physics.player.Radius + navInvalidateMargin
+}
+
+// ObjectAdded реализует SceneObserver
+func (ng *NavGrid) ObjectAdded(obj *ProceduralObject) {
+	ng.Invalidate(obj.Position, ng.objectReach(obj))
+}
+
+// ObjectRemoved реализует SceneObserver
+func (ng *NavGrid) ObjectRemoved(obj *ProceduralObject) {
+	ng.Invalidate(obj.Position, ng.objectReach(obj))
+}
+
+// ObjectMoved реализует SceneObserver: узлы освобождаются на старом месте
+// и занимаются на новом
+func (ng *NavGrid) ObjectMoved(obj *ProceduralObject, from Vector3) {
+	reach := ng.objectReach(obj)
+	ng.Invalidate(from, reach)
+	ng.Invalidate(obj.Position, reach)
+}
+
+// FindPath ищет путь пешком. Возвращает точки пути на земле (последняя -
+// цель или ближайший к ней проходимый узел) или nil, если пути нет или
+// он слишком длинный
+func (ng *NavGrid) FindPath(query NavQuery) []Vector3 {
+	start, ok := ng.nodeAt(query.From.X, query.From.Z)
+	if !ok {
+		return nil
+	}
+	goal, ok := ng.nodeAt(query.To.X, query.To.Z)
+	if !ok {
+		return nil
+	}
+	goalX, goalZ := ng.position(goal)
+
+	canStand := func(index int) bool {
+		if query.Avoid != nil {
+			if x, z := ng.position(index); query.Avoid[NavCellOf(Vector3{X: x, Z: z})] {
+				return false
+			}
+		}
+		return ng.open(index)
+	}
+
+	cost := make(map[int]float64)
+	parent := make(map[int]int)
+	cost[start] = 0
+
+	queue := &pathQueue{{index: start}}
+	expanded := 0
+	found := -1
+
+	for queue.Len() > 0 && expanded < pathMaxNodes {
+		current := heap.Pop(queue).(pathNode)
+		cx, cz := ng.position(current.index)
+
+		// Узел мог попасть в очередь несколько раз; берем только лучшую запись
+		if current.cost > cost[current.index]+math.Hypot(goalX-cx, goalZ-cz)+1e-9 {
+			continue
+		}
+		expanded++
+
+		if math.Hypot(goalX-cx, goalZ-cz) <= pathGoalRadius {
+			found = current.index
+			break
+		}
+
+		for direction, d := range navDirections {
+			nx, nz := current.index%ng.width+d[0], current.index/ng.width+d[1]
+			if nx < 0 || nx >= ng.width || nz < 0 || nz >= ng.height {
+				continue
+			}
+			next := nz*ng.width + nx
+			if !canStand(next) {
+				continue
+			}
+
+			// По диагонали не срезаем углы препятствий
+			if d[0] != 0 && d[1] != 0 && (!canStand(current.index+d[0]) || !canStand(current.index+d[1]*ng.width)) {
+				continue
+			}
+			if !ng.edge(current.index, direction) {
+				continue
+			}
+
+			x, z := ng.position(next)
+			step := math.Hypot(float64(d[0]), float64(d[1]))
+			slope := math.Abs(ng.terrain.HeightAt(x, z)-ng.terrain.HeightAt(cx, cz)) / step
+
+			stepCost := step * (1 + slope*pathSlopeCost)
+			if ng.terrain.SurfaceAt(x, z).Name == surfaceWater.Name {
+				stepCost += step * pathWaterCost
+			}
+			if query.Cost != nil {
+				// Надбавки неотрицательны, поэтому эвристика (прямое расстояние) остается допустимой
+				stepCost += step * math.Max(0, query.Cost(x, z))
+			}
+
+			newCost := cost[current.index] + stepCost
+			if old, seen := cost[next]; seen && old <= newCost {
+				continue
+			}
+			cost[next] = newCost
+			parent[next] = current.index
+			heap.Push(queue, pathNode{index: next, cost: newCost + math.Hypot(goalX-x, goalZ-z)})
+		}
+	}
+
+	if found < 0 {
+		return nil
+	}
+
+	// Собираем путь от цели к началу. Начальный узел тоже в пути: от точки
+	// from к соседям напрямую может быть круто, проверены только переходы между узлами
+	var reversed []Vector3
+	for index := found; ; index = parent[index] {
+		x, z := ng.position(index)
+		reversed = append(reversed, Vector3{X: x, Y: ng.terrain.HeightAt(x, z), Z: z})
+		if index == start {
+			break
+		}
+	}
+
+	path := make([]Vector3, 0, len(reversed)+1)
+	for i := len(reversed) - 1; i >= 0; i-- {
+		path = append(path, reversed[i])
+	}
+
+	// Точная цель - только если к ней можно подойти от последнего узла
+	fx, fz := ng.position(found)
+	if gentleSlope(ng.terrain, fx, fz, query.To.X, query.To.Z) {
+		path = append(path, query.To)
+	}
+
+	if query.Smooth {
+		path = ng.smooth(path)
+	}
+	return path
+}
+
+// smooth убирает лишние точки пути: от каждой точки идет сразу к самой
+// дальней из ближайших, до которой можно дойти по прямой
+func (ng *NavGrid) smooth(path []Vector3) []Vector3 {
+	if len(path) < 3 {
+		return path
+	}
+
+	smoothed := []Vector3{path[0]}
+	for i := 0; i < len(path)-1; {
+		next := i + 1
+		for j := int(math.Min(float64(len(path)-1), float64(i+pathSmoothLookahead))); j > i+1; j-- {
+			if ng.straightWalk(path[i], path[j]) {
+				next = j
+				break
+			}
+		}
+		smoothed = append(smoothed, path[next])
+		i = next
+	}
+	return smoothed
+}
+
+// straightWalk сообщает, что от a до b можно пройти по прямой: земля
+// пологая и по всей линии помещается человек
+func (ng *NavGrid) straightWalk(a, b Vector3) bool {
+	if !gentleSlope(ng.terrain, a.X, a.Z, b.X, b.Z) {
+		return false
+	}
+
+	length := horizontalDistance(a, b)
+	samples := int(math.Ceil(length / pathSmoothStep))
+	previous := ng.terrain.HeightAt(a.X, a.Z)
+	for i := 1; i < samples; i++ {
+		t := float64(i) / float64(samples)
+		x, z := a.X+(b.X-a.X)*t, a.Z+(b.Z-a.Z)*t
+		h := ng.terrain.HeightAt(x, z)
+		if math.Abs(h-previous) > pathMaxUphill*length/float64(samples) ||
+			!ng.physics.canStandAt(x, z, ng.physics.player.StandHeight) {
+			return false
+		}
+		previous = h
+	}
+	return true
+}
+
+// DarknessCost - надбавка за свет (ProceduralScene.LightAt): днем путь
+// жмется к пещерам, ночью надбавки почти нет.
+// weight - сколько метров обхода стоит метр на полном свету
+func DarknessCost(scene *ProceduralScene, weight float64) NavCost {
+	return func(x, z float64) float64 {
+		return weight * scene.LightAt(Vector3{X: x, Y: scene.Terrain.HeightAt(x, z), Z: z})
+	}
+}
+
+// ViewConeCost - надбавка за точки в поле зрения наблюдателя (в конусе
+// halfAngle вокруг direction не дальше maxRange): путь обходит взгляд игрока.
+// Укрытия не учитываются - проверять видимость для каждого узла слишком дорого
+func ViewConeCost(origin, direction Vector3, halfAngle, maxRange, weight float64) NavCost {
+	look := horizontalDirection(direction)
+	minDot := math.Cos(halfAngle)
+	return func(x, z float64) float64 {
+		to := Vector3{X: x - origin.X, Z: z - origin.Z}
+		distance := to.Length()
+		if distance > maxRange || distance < 0.001 {
+			return 0
+		}
+		if to.Mul(1/distance).Dot(look) < minDot {
+			return 0
+		}
+		// Ближе к наблюдателю заметнее
+		return weight * (1 - distance/maxRange)
+	}
+}
+
+// CombineCosts складывает надбавки
+func CombineCosts(costs ...NavCost) NavCost {
+	return func(x, z float64) float64 {
+		total := 0.0
+		for _, cost := range costs {
+			total += cost(x, z)
+		}
+		return total
+	}
+}
+
+// gentleSlope проверяет, что земля вдоль перехода достаточно пологая для
+// ходьбы. Нормаль билинейной поверхности меняется внутри ячейки, поэтому
+// пробуем несколько точек на линии перехода и по сторонам от нее: игрок
+// на повороте и при скольжении отходит от линии
+func gentleSlope(terrain *HeightMap, x0, z0, x1, z1 float64) bool {
+	length := math.Hypot(x1-x0, z1-z0)
+	if length == 0 {
+		return terrain.NormalAt(x0, z0).Y >= maxWalkableNormalY+pathNormalMargin
+	}
+	sideX, sideZ := -(z1-z0)/length*pathLateralProbe, (x1-x0)/length*pathLateralProbe
+
+	for i := 0; i <= pathSlopeSamples; i++ {
+		t := float64(i) / pathSlopeSamples
+		x, z := x0+(x1-x0)*t, z0+(z1-z0)*t
+		for _, side := range [3]float64{0, -1, 1} {
+			if terrain.NormalAt(x+sideX*side, z+sideZ*side).Y < maxWalkableNormalY+pathNormalMargin {
+				return false
+			}
+		}
+	}
+	return true
+}
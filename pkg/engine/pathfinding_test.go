@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+const navTestSize = 24 // Сторона ровной тестовой карты, м
+
+// newTestNavGrid строит сетку над ровной землей без объектов
+func newTestNavGrid(t *testing.T) (*NavGrid, *ProceduralScene) {
+	t.Helper()
+
+	terrain := &HeightMap{Width: navTestSize, Height: navTestSize}
+	terrain.Data = make([][]float64, navTestSize)
+	terrain.Materials = make([][]int, navTestSize)
+	for z := range terrain.Data {
+		terrain.Data[z] = make([]float64, navTestSize)
+		terrain.Materials[z] = make([]int, navTestSize)
+		for x := range terrain.Materials[z] {
+			terrain.Materials[z][x] = 2
+		}
+	}
+
+	scene := &ProceduralScene{Terrain: terrain}
+	physics := NewPhysicsSystem()
+	physics.SetScene(scene)
+	return NewNavGrid(physics, scene), scene
+}
+
+// addPost ставит пень, рядом с которым игроку не встать
+func addPost(scene *ProceduralScene, x, z float64) {
+	scene.AddObject(&ProceduralObject{
+		ID:       scene.NextObjectID(),
+		Type:     "stump",
+		Position: Vector3{X: x, Z: z},
+		Scale:    Vector3{X: 0.5, Y: 3, Z: 0.5},
+	})
+}
+
+// addWall ставит ряд пней поперек карты по x от z0 до z1 включительно
+func addWall(scene *ProceduralScene, x float64, z0, z1 int) {
+	for z := z0; z <= z1; z++ {
+		addPost(scene, x, float64(z))
+	}
+}
+
+// checkPath проверяет, что путь ведет от начала к цели по проходимым узлам
+// и каждый его отрезок можно пройти
+func checkPath(t *testing.T, ng *NavGrid, path []Vector3, from, to Vector3) {
+	t.Helper()
+
+	if len(path) < 2 {
+		t.Fatalf("path %v, want at least two points", path)
+	}
+	if d := horizontalDistance(path[0], from); d > 1 {
+		t.Errorf("path starts %.2f m away from the start", d)
+	}
+	if d := horizontalDistance(path[len(path)-1], to); d > pathGoalRadius {
+		t.Errorf("path ends %.2f m away from the goal", d)
+	}
+	for i := 1; i < len(path); i++ {
+		if !ng.straightWalk(path[i-1], path[i]) {
+			t.Errorf("segment %d from %v to %v can't be walked", i, path[i-1], path[i])
+		}
+	}
+}
+
+func TestFindPathAroundWall(t *testing.T) {
+	ng, scene := newTestNavGrid(t)
+	// Стена от края карты до z = 3: обход только сверху
+	addWall(scene, 0, -navTestSize/2, 3)
+
+	from, to := Vector3{X: -5, Z: -5}, Vector3{X: 5, Z: -5}
+	path := ng.FindPath(NavQuery{From: from, To: to})
+	checkPath(t, ng, path, from, to)
+
+	detour := false
+	for _, p := range path {
+		if !ng.Walkable(p) {
+			t.Errorf("path goes through blocked node %v", p)
+		}
+		detour = detour || p.Z > 3
+	}
+	if !detour {
+		t.Errorf("path %v doesn't go around the wall", path)
+	}
+
+	smoothed := ng.FindPath(NavQuery{From: from, To: to, Smooth: true})
+	checkPath(t, ng, smoothed, from, to)
+	if len(smoothed) >= len(path) {
+		t.Errorf("smoothing kept %d of %d points", len(smoothed), len(path))
+	}
+}
+
+func TestFindPathUnreachable(t *testing.T) {
+	ng, scene := newTestNavGrid(t)
+	// Стена через всю карту отрезает цель
+	addWall(scene, 0, -navTestSize/2, navTestSize/2)
+
+	if path := ng.FindPath(NavQuery{From: Vector3{X: -5}, To: Vector3{X: 5}}); path != nil {
+		t.Errorf("found path %v through the wall", path)
+	}
+	// Цель за краем карты
+	if path := ng.FindPath(NavQuery{From: Vector3{X: -5}, To: Vector3{X: 2 * navTestSize}}); path != nil {
+		t.Errorf("found path %v off the map", path)
+	}
+}
+
+func TestFindPathCost(t *testing.T) {
+	ng, _ := newTestNavGrid(t)
+	from, to := Vector3{X: -6}, Vector3{X: 6}
+
+	// Дорогой участок посреди прямой дороги
+	expensive := func(x, z float64) bool { return math.Abs(x) <= 1 && math.Abs(z) <= 2 }
+	crosses := func(path []Vector3) bool {
+		for _, p := range path {
+			if expensive(p.X, p.Z) {
+				return true
+			}
+		}
+		return false
+	}
+
+	straight := ng.FindPath(NavQuery{From: from, To: to})
+	checkPath(t, ng, straight, from, to)
+	if !crosses(straight) {
+		t.Fatalf("shortest path %v doesn't cross the middle", straight)
+	}
+
+	cost := func(x, z float64) float64 {
+		if expensive(x, z) {
+			return 20
+		}
+		return 0
+	}
+	detour := ng.FindPath(NavQuery{From: from, To: to, Cost: cost})
+	checkPath(t, ng, detour, from, to)
+	if crosses(detour) {
+		t.Errorf("path %v crosses the expensive patch", detour)
+	}
+}
+
+func TestObjectAddedInvalidatesNodes(t *testing.T) {
+	ng, scene := newTestNavGrid(t)
+	spot := Vector3{X: 3, Z: 3}
+
+	if !ng.Walkable(spot) {
+		t.Fatalf("node at %v is blocked on empty ground", spot)
+	}
+	from, to := Vector3{X: 3, Z: -5}, Vector3{X: 3, Z: 8}
+	if path := ng.FindPath(NavQuery{From: from, To: to}); !pathVisits(path, spot) {
+		t.Fatalf("path %v doesn't go straight through %v", path, spot)
+	}
+
+	// Сетка запомнила узел открытым; новый объект должен сбросить ответ
+	addPost(scene, spot.X, spot.Z)
+	if ng.Walkable(spot) {
+		t.Errorf("node at %v is still open after an object was added on it", spot)
+	}
+	path := ng.FindPath(NavQuery{From: from, To: to})
+	checkPath(t, ng, path, from, to)
+	if pathVisits(path, spot) {
+		t.Errorf("path %v goes through the new object", path)
+	}
+
+	// Объект убрали - узел снова проходим
+	scene.RemoveObjectAt(len(scene.Objects) - 1)
+	if !ng.Walkable(spot) {
+		t.Errorf("node at %v is still blocked after the object was removed", spot)
+	}
+}
+
+// pathVisits сообщает, что путь проходит через узел точки
+func pathVisits(path []Vector3, p Vector3) bool {
+	for _, point := range path {
+		if NavCellOf(point) == NavCellOf(p) {
+			return true
+		}
+	}
+	return false
+}
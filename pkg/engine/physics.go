@@ -14,22 +14,65 @@ type PhysicsSystem struct {
 	gravity      float64
 	groundOffset float64 // Height offset from ground
 	lastUpdate   time.Time
+	resolver     *CollisionResolver // Colliders of scene objects, kept in sync with scene evolution
+	previous     Vector3            // Player position before the last tick, for render interpolation
+	spawn        Vector3            // Where the player starts and comes back after dying
+	surface      SurfaceMaterial    // Surface under the player, affects speed and footsteps
+	stride       float64            // Distance walked since the last footstep
+	events       PhysicsEvents      // Collected until TakeEvents
+
+	sprintRequested bool    // MoveForward sprinted during this tick
+	staminaDelay    float64 // Seconds until stamina starts to regenerate
+	exhausted       bool    // Stamina ran dry, sprint is locked until it recovers
+	landingNoise    float64 // Noise of a landing during this tick
+}
+
+// Slope, landing and footstep tuning
+const (
+	maxWalkableNormalY = 0.707 // Ground steeper than 45 degrees can't be walked up
+	slideFriction      = 0.9   // Slide velocity kept per tick on walkable ground
+	minLandingSpeed    = 2.0   // Slower landings are just walking over bumps
+	safeLandingSpeed   = 8.0   // Falls up to ~3 m don't hurt
+	fallDamagePerSpeed = 10.0  // Health lost per m/s of impact above the safe speed
+	strideLength       = 1.6   // Meters walked per footstep
+)
+
+// PhysicsEvents reports what happened to the player during the last ticks
+type PhysicsEvents struct {
+	Footsteps   int             // Footsteps taken
+	Surface     SurfaceMaterial // Surface of the last footstep
+	Landed      bool            // Landed after a fall or jump
+	ImpactSpeed float64         // Vertical speed at the hardest landing
+	Damage      float64         // Health lost to falls
+	Died        bool            // Health ran out and the player was brought back to the spawn point
+	Noise       float64         // Loudest noise the player made (0..1)
 }
 
 // Player represents the player's physical presence in the world
 type Player struct {
 	Position       Vector3
 	Velocity       Vector3
-	Direction      Vector3 // Look direction
+	Direction      Vector3 // Look direction, set from CameraController
 	Height         float64
 	Radius         float64
 	IsGrounded     bool
 	IsJumping      bool
 	JumpForce      float64
 	MoveSpeed      float64
-	RotationSpeed  float64
 	SprintModifier float64
 	StepHeight     float64 // Max height player can step up without jumping
+	SlideVelocity  Vector3 // Downhill sliding on steep slopes, separate from walking Velocity
+	Health         float64
+	MaxHealth      float64
+	Stamina        float64
+	MaxStamina     float64
+	Sanity         float64 // 0..1, worn down by darkness and scares, see Sanity
+	IsSprinting    bool    // Sprinted during the last tick
+	IsCrouching    bool
+	StandHeight    float64 // Collision height when standing
+	CrouchHeight   float64 // Collision height when crouching
+	CrouchModifier float64 // Speed multiplier when crouching
+	NoiseEmitted   float64 // How loud the player was during the last tick (0..1), see NoiseRadius
 }
 
 // NewPhysicsSystem creates a new physics system
@@ -38,6 +81,9 @@ func NewPhysicsSystem() *PhysicsSystem {
 		gravity:      9.8,
 		groundOffset: 1.7, // Eye height from ground
 		lastUpdate:   time.Now(),
+		previous:     Vector3{X: 0, Y: 5, Z: 0},
+		spawn:        Vector3{X: 0, Y: 5, Z: 0},
+		surface:      surfaceDirt,
 		player: &Player{
 			Position:       Vector3{X: 0, Y: 5, Z: 0}, // Start slightly above ground
 			Velocity:       Vector3{X: 0, Y: 0, Z: 0},
@@ -48,9 +94,16 @@ func NewPhysicsSystem() *PhysicsSystem {
 			IsJumping:      false,
 			JumpForce:      5.0,
 			MoveSpeed:      5.0, // Units per second
-			RotationSpeed:  2.0, // Radians per second
 			SprintModifier: 1.8, // Speed multiplier when sprinting
 			StepHeight:     0.5, // Can step up half meter obstacles
+			Health:         100,
+			MaxHealth:      100,
+			Stamina:        100,
+			MaxStamina:     100,
+			Sanity:         1,
+			StandHeight:    1.8,
+			CrouchHeight:   1.1,
+			CrouchModifier: 0.5, // Sneaking is slow
 		},
 	}
 }
@@ -58,6 +111,37 @@ func NewPhysicsSystem() *PhysicsSystem {
 // SetScene sets the current scene for physics calculations
 func (ps *PhysicsSystem) SetScene(scene *ProceduralScene) {
 	ps.scene = scene
+	ps.resolver = nil
+	if scene == nil {
+		return
+	}
+
+	// Build colliders once and follow scene evolution instead of testing every object each frame
+	ps.resolver = NewCollisionResolver(ps.player.Radius, ps.player.Height)
+	ps.resolver.AddSceneObjectsAsColliders(scene.Objects)
+	ps.resolver.SetSpatialIndex(scene.Index)
+	scene.AddObserver(ps)
+}
+
+// ObjectAdded implements SceneObserver
+func (ps *PhysicsSystem) ObjectAdded(obj *ProceduralObject) {
+	if ps.resolver != nil {
+		ps.resolver.AddObjectColliders(obj)
+	}
+}
+
+// ObjectRemoved implements SceneObserver
+func (ps *PhysicsSystem) ObjectRemoved(obj *ProceduralObject) {
+	if ps.resolver != nil {
+		ps.resolver.RemoveObjectColliders(obj.ID)
+	}
+}
+
+// ObjectMoved implements SceneObserver
+func (ps *PhysicsSystem) ObjectMoved(obj *ProceduralObject, from Vector3) {
+	if ps.resolver != nil {
+		ps.resolver.UpdateObjectColliders(obj)
+	}
 }
 
 // GetPlayer returns the player object
@@ -65,62 +149,80 @@ func (ps *PhysicsSystem) GetPlayer() *Player {
 	return ps.player
 }
 
-// Update updates the physics simulation
+// InterpolatedPosition returns the player position between the previous and
+// the current tick; alpha is the fraction of the next tick already elapsed
+func (ps *PhysicsSystem) InterpolatedPosition(alpha float64) Vector3 {
+	return ps.previous.Add(ps.player.Position.Sub(ps.previous).Mul(alpha))
+}
+
+// Update advances the physics simulation by one fixed tick of deltaTime seconds
 func (ps *PhysicsSystem) Update(deltaTime float64) {
+	ps.previous = ps.player.Position
+
 	// Skip if we don't have a scene yet
 	if ps.scene == nil || ps.scene.Terrain == nil {
 		return
 	}
 
+	wasGrounded := ps.player.IsGrounded
+
 	// Apply gravity if not grounded
 	if !ps.player.IsGrounded {
 		ps.player.Velocity.Y -= ps.gravity * deltaTime
 	}
 
-	// Calculate next position based on velocity
-	nextPosition := ps.player.Position.Add(ps.player.Velocity.Mul(deltaTime))
+	// Check ground at the current position (caves can have ground below the terrain surface)
+	feetY := ps.player.Position.Y - ps.player.Height/2
+	current := ps.groundAt(ps.player.Position.X, feetY, ps.player.Position.Z)
 
-	// Check terrain height at current and next position
-	currentTerrainHeight := ps.getTerrainHeightAtPosition(ps.player.Position.X, ps.player.Position.Z)
-	nextTerrainHeight := ps.getTerrainHeightAtPosition(nextPosition.X, nextPosition.Z)
+	// Steep slopes push the player downhill and can't be walked up
+	if ps.player.IsGrounded {
+		ps.applySlope(current.Normal, deltaTime)
+	}
 
-	// Adjust next position if slope is too steep
-	if math.Abs(nextTerrainHeight-currentTerrainHeight) > ps.player.StepHeight && ps.player.IsGrounded {
-		slopeRatio := (nextTerrainHeight - currentTerrainHeight) /
-			math.Sqrt(math.Pow(nextPosition.X-ps.player.Position.X, 2)+
-				math.Pow(nextPosition.Z-ps.player.Position.Z, 2))
+	// Calculate next position based on velocity: horizontal movement slides along objects
+	nextPosition := ps.moveHorizontally(deltaTime)
+	nextPosition.Y += ps.player.Velocity.Y * deltaTime
 
-		// If slope is too steep (> ~30 degrees), limit horizontal movement
-		if math.Abs(slopeRatio) > 0.58 {
-			nextPosition.X = ps.player.Position.X
-			nextPosition.Z = ps.player.Position.Z
-			// Also stop horizontal velocity
-			ps.player.Velocity.X = 0
-			ps.player.Velocity.Z = 0
-		}
-	}
+	next := ps.groundAt(nextPosition.X, feetY, nextPosition.Z)
 
-	// Check for object collisions
-	objectCollision := ps.checkObjectCollisions(nextPosition)
-	if objectCollision {
-		// Stop movement in case of collision
-		nextPosition = ps.player.Position
+	// Ledges higher than a step (cave rims, object tops) can't be walked onto.
+	// Don't squeeze into passages lower than the player either
+	if ps.player.IsGrounded &&
+		(next.Height-current.Height > ps.player.StepHeight || next.Ceiling-next.Height < ps.player.Height) {
+		nextPosition.X = ps.player.Position.X
+		nextPosition.Z = ps.player.Position.Z
 		ps.player.Velocity.X = 0
 		ps.player.Velocity.Z = 0
+		ps.player.SlideVelocity = Vector3{}
 	}
 
 	// Update player position
 	ps.player.Position = nextPosition
 
-	// Check if player is on ground
-	groundHeight := ps.getTerrainHeightAtPosition(ps.player.Position.X, ps.player.Position.Z)
-	ps.player.IsGrounded = ps.player.Position.Y <= groundHeight+ps.player.Height/2
+	// Check if player is on ground. Walking down a slope keeps the player
+	// glued to the ground instead of taking tiny falls every tick
+	ground := ps.groundAt(ps.player.Position.X, ps.player.Position.Y-ps.player.Height/2, ps.player.Position.Z)
+	feetY = ps.player.Position.Y - ps.player.Height/2
+	ps.player.IsGrounded = feetY <= ground.Height ||
+		(wasGrounded && ps.player.Velocity.Y <= 0 && feetY-ground.Height <= ps.player.StepHeight)
+
+	// Hit the head on a cave ceiling
+	if ps.player.Position.Y+ps.player.Height/2 > ground.Ceiling && !ps.player.IsGrounded {
+		ps.player.Position.Y = math.Max(ground.Ceiling-ps.player.Height/2, ground.Height+ps.player.Height/2)
+		ps.player.Velocity.Y = math.Min(ps.player.Velocity.Y, 0)
+	}
 
 	// Snap to ground if grounded
 	if ps.player.IsGrounded {
-		ps.player.Position.Y = groundHeight + ps.player.Height/2
+		if !wasGrounded {
+			ps.land(-ps.player.Velocity.Y)
+		}
+
+		ps.player.Position.Y = ground.Height + ps.player.Height/2
 		ps.player.Velocity.Y = 0
 		ps.player.IsJumping = false
+		ps.surface = ground.Surface
 	}
 
 	// Apply friction if on ground
@@ -135,41 +237,231 @@ func (ps *PhysicsSystem) Update(deltaTime float64) {
 		if math.Abs(ps.player.Velocity.Z) < 0.01 {
 			ps.player.Velocity.Z = 0
 		}
+
+		ps.countSteps()
 	}
 
+	ps.updateStamina(deltaTime)
+	ps.updateNoise(deltaTime)
+
 	// Cap falling velocity to prevent tunneling through terrain
 	if ps.player.Velocity.Y < -20 {
 		ps.player.Velocity.Y = -20
 	}
 }
 
-// getTerrainHeightAtPosition gets the terrain height at the given position
-func (ps *PhysicsSystem) getTerrainHeightAtPosition(x, z float64) float64 {
+// applySlope makes the player slide down slopes steeper than maxWalkableNormalY
+// and removes the uphill part of walking there. On walkable ground the slide
+// slows down and stops
+func (ps *PhysicsSystem) applySlope(normal Vector3, deltaTime float64) {
+	slide := &ps.player.SlideVelocity
+
+	if normal.Y >= maxWalkableNormalY {
+		*slide = slide.Mul(slideFriction)
+		if slide.Length() < 0.05 {
+			*slide = Vector3{}
+		}
+		return
+	}
+
+	// Gravity along the slope; its horizontal part points downhill
+	slide.X += ps.gravity * normal.Y * normal.X * deltaTime
+	slide.Z += ps.gravity * normal.Y * normal.Z * deltaTime
+
+	downhill := horizontalDirection(normal)
+	if uphill := ps.player.Velocity.X*downhill.X + ps.player.Velocity.Z*downhill.Z; uphill < 0 {
+		ps.player.Velocity.X -= downhill.X * uphill
+		ps.player.Velocity.Z -= downhill.Z * uphill
+	}
+}
+
+// land applies the landing impact: falls faster than safeLandingSpeed hurt
+func (ps *PhysicsSystem) land(impactSpeed float64) {
+	if impactSpeed < minLandingSpeed {
+		return
+	}
+
+	ps.events.Landed = true
+	ps.events.ImpactSpeed = math.Max(ps.events.ImpactSpeed, impactSpeed)
+	ps.landingNoise = math.Max(ps.landingNoise, math.Min(1, impactSpeed/loudLandingSpeed))
+
+	if impactSpeed > safeLandingSpeed {
+		damage := (impactSpeed - safeLandingSpeed) * fallDamagePerSpeed
+		ps.player.Health = math.Max(0, ps.player.Health-damage)
+		ps.events.Damage += damage
+	}
+}
+
+// Respawn brings a player whose health ran out back to the spawn point,
+// standing on the ground with full health and stamina
+func (ps *PhysicsSystem) Respawn() {
+	position := ps.spawn
+	if ps.scene != nil && ps.scene.Terrain != nil {
+		position.Y = ps.scene.Terrain.HeightAt(position.X, position.Z) + ps.player.Height/2
+	}
+
+	ps.player.Position = position
+	ps.player.Velocity = Vector3{}
+	ps.player.SlideVelocity = Vector3{}
+	ps.player.IsGrounded = true
+	ps.player.IsJumping = false
+	ps.player.Health = ps.player.MaxHealth
+	ps.player.Stamina = ps.player.MaxStamina
+	ps.exhausted = false
+	ps.staminaDelay = 0
+	ps.stride = 0
+
+	// Don't interpolate the camera across the map
+	ps.previous = position
+	ps.events.Died = true
+}
+
+// countSteps turns distance walked on the ground into footstep events
+func (ps *PhysicsSystem) countSteps() {
+	dx := ps.player.Position.X - ps.previous.X
+	dz := ps.player.Position.Z - ps.previous.Z
+	ps.stride += math.Sqrt(dx*dx + dz*dz)
+
+	if ps.stride >= strideLength {
+		ps.stride -= strideLength
+		ps.events.Footsteps++
+		ps.events.Surface = ps.surface
+	}
+}
+
+// TakeEvents returns what happened to the player since the last call and clears it
+func (ps *PhysicsSystem) TakeEvents() PhysicsEvents {
+	events := ps.events
+	ps.events = PhysicsEvents{}
+	return events
+}
+
+// Surface returns the surface material the player last stood on
+func (ps *PhysicsSystem) Surface() SurfaceMaterial {
+	return ps.surface
+}
+
+// moveHorizontally advances the player along its horizontal velocity (walking
+// plus sliding) and resolves the capsule against object colliders. The move is
+// split into substeps no longer than the player radius so thin colliders can't
+// be skipped, and after every contact the velocity loses its component into the
+// obstacle, so the player slides along trunks and walls instead of sticking to them
+func (ps *PhysicsSystem) moveHorizontally(deltaTime float64) Vector3 {
+	position := ps.player.Position
+	velocity := ps.player.Velocity.Add(ps.player.SlideVelocity)
+	move := Vector3{X: velocity.X * deltaTime, Y: 0, Z: velocity.Z * deltaTime}
+	if ps.resolver == nil {
+		return position.Add(move)
+	}
+
+	steps := int(math.Ceil(move.Length() / ps.player.Radius))
+	steps = max(steps, 1)
+	stepTime := deltaTime / float64(steps)
+
+	for i := 0; i < steps; i++ {
+		position.X += (ps.player.Velocity.X + ps.player.SlideVelocity.X) * stepTime
+		position.Z += (ps.player.Velocity.Z + ps.player.SlideVelocity.Z) * stepTime
+
+		feet := Vector3{X: position.X, Y: position.Y - ps.player.Height/2, Z: position.Z}
+		resolved, contacts := ps.resolver.ResolveCapsule(feet, ps.player.StepHeight)
+		position.X = resolved.X
+		position.Z = resolved.Z
+
+		for _, contact := range contacts {
+			removeHorizontalComponent(&ps.player.Velocity, contact.Normal)
+			removeHorizontalComponent(&ps.player.SlideVelocity, contact.Normal)
+		}
+	}
+
+	return position
+}
+
+// removeHorizontalComponent removes the part of v that points into a surface with the given normal
+func removeHorizontalComponent(v *Vector3, normal Vector3) {
+	if into := v.X*normal.X + v.Z*normal.Z; into < 0 {
+		v.X -= normal.X * into
+		v.Z -= normal.Z * into
+	}
+}
+
+// groundSample describes the ground under a point
+type groundSample struct {
+	Height  float64
+	Ceiling float64 // +Inf in the open
+	Normal  Vector3
+	Surface SurfaceMaterial
+}
+
+// groundAt returns the ground under the player's feet and the ceiling above
+// them. Inside caves the ground comes from the density field, elsewhere from
+// the height map with interpolated height and analytical normal. Objects low
+// enough to step on (rocks, stumps, graves) raise the ground
+func (ps *PhysicsSystem) groundAt(x, feetY, z float64) groundSample {
+	sample := groundSample{
+		Height:  -1000, // Out of the map the player falls
+		Ceiling: math.Inf(1),
+		Normal:  Vector3{X: 0, Y: 1, Z: 0},
+		Surface: surfaceDirt,
+	}
 	if ps.scene == nil || ps.scene.Terrain == nil {
-		return 0
+		sample.Height = 0
+		return sample
+	}
+
+	terrain := ps.scene.Terrain
+	if terrain.InBounds(x, z) {
+		sample.Height = terrain.HeightAt(x, z)
+		sample.Normal = terrain.NormalAt(x, z)
+		sample.Surface = terrain.SurfaceAt(x, z)
+	}
+
+	// Probe at step height so that small ledges on the cave floor can be climbed
+	if caveGround, caveCeiling, ok := ps.scene.CaveGroundAt(x, feetY+ps.player.StepHeight, z); ok {
+		sample.Height, sample.Ceiling = caveGround, caveCeiling
+		sample.Normal = Vector3{X: 0, Y: 1, Z: 0}
+		sample.Surface = surfaceRock
+	}
+
+	if ps.resolver != nil {
+		if top, ok := ps.resolver.SupportHeight(x, z, feetY+ps.player.StepHeight); ok && top > sample.Height {
+			sample.Height = top
+			sample.Normal = Vector3{X: 0, Y: 1, Z: 0}
+		}
 	}
 
-	// Convert world position to terrain grid coordinates
-	terrainWidth := ps.scene.Terrain.Width
-	terrainHeight := ps.scene.Terrain.Height
+	return sample
+}
 
-	gridX := int(x + float64(terrainWidth)/2)
-	gridZ := int(z + float64(terrainHeight)/2)
+// CanStandAt reports whether the player fits standing on the ground at (x, z):
+// over the map, with enough headroom and not inside an object taller than a step
+func (ps *PhysicsSystem) CanStandAt(x, z float64) bool {
+	return ps.canStandAt(x, z, ps.player.Height)
+}
 
-	// Check bounds
-	if gridX < 0 || gridX >= terrainWidth || gridZ < 0 || gridZ >= terrainHeight {
-		// Return a very low height for out of bounds
-		return -1000
+// canStandAt reports whether a player of the given height fits at (x, z).
+// Navigation asks for the standing height, so crouching doesn't change paths
+func (ps *PhysicsSystem) canStandAt(x, z, height float64) bool {
+	if ps.scene == nil || ps.scene.Terrain == nil || !ps.scene.Terrain.InBounds(x, z) {
+		return false
+	}
+
+	ground := ps.groundAt(x, ps.scene.Terrain.HeightAt(x, z), z)
+	if ground.Ceiling-ground.Height < height {
+		return false
+	}
+	if ps.resolver == nil {
+		return true
 	}
 
-	// Get elevation and scale it
-	elevation := ps.scene.Terrain.Data[gridZ][gridX]
-	return elevation * 20 // Same scale as used in object placement
+	_, contacts := ps.resolver.ResolveCapsule(Vector3{X: x, Y: ground.Height, Z: z}, ps.player.StepHeight)
+	return len(contacts) == 0
 }
 
-// Jump makes the player jump if they're on the ground
+// Jump makes the player jump if they're on the ground, standing and have the stamina for it
 func (ps *PhysicsSystem) Jump() {
-	if ps.player.IsGrounded && !ps.player.IsJumping {
+	if ps.player.IsGrounded && !ps.player.IsJumping && !ps.player.IsCrouching &&
+		ps.player.Stamina >= jumpStaminaCost {
+		ps.spendStamina(jumpStaminaCost)
 		ps.player.Velocity.Y = ps.player.JumpForce
 		ps.player.IsGrounded = false
 		ps.player.IsJumping = true
@@ -178,9 +470,10 @@ func (ps *PhysicsSystem) Jump() {
 
 // MoveForward moves the player in the direction they're facing
 func (ps *PhysicsSystem) MoveForward(sprint bool) {
-	speed := ps.player.MoveSpeed
-	if sprint {
+	speed := ps.walkSpeed()
+	if sprint && ps.canSprint() {
 		speed *= ps.player.SprintModifier
+		ps.sprintRequested = true
 	}
 
 	// Only apply horizontal movement
@@ -203,8 +496,9 @@ func (ps *PhysicsSystem) MoveBackward() {
 		Z: -ps.player.Direction.Z,
 	}.Normalize()
 
-	ps.player.Velocity.X = moveDir.X * ps.player.MoveSpeed * 0.7 // Slower backward movement
-	ps.player.Velocity.Z = moveDir.Z * ps.player.MoveSpeed * 0.7
+	speed := ps.walkSpeed() * 0.7 // Slower backward movement
+	ps.player.Velocity.X = moveDir.X * speed
+	ps.player.Velocity.Z = moveDir.Z * speed
 }
 
 // MoveLeft strafes the player to the left
@@ -216,8 +510,9 @@ func (ps *PhysicsSystem) MoveLeft() {
 		Z: ps.player.Direction.X,
 	}.Normalize()
 
-	ps.player.Velocity.X = left.X * ps.player.MoveSpeed
-	ps.player.Velocity.Z = left.Z * ps.player.MoveSpeed
+	speed := ps.walkSpeed()
+	ps.player.Velocity.X = left.X * speed
+	ps.player.Velocity.Z = left.Z * speed
 }
 
 // MoveRight strafes the player to the right
@@ -229,73 +524,33 @@ func (ps *PhysicsSystem) MoveRight() {
 		Z: -ps.player.Direction.X,
 	}.Normalize()
 
-	ps.player.Velocity.X = right.X * ps.player.MoveSpeed
-	ps.player.Velocity.Z = right.Z * ps.player.MoveSpeed
+	speed := ps.walkSpeed()
+	ps.player.Velocity.X = right.X * speed
+	ps.player.Velocity.Z = right.Z * speed
 }
 
-// RotateLeft rotates the player's view to the left
-func (ps *PhysicsSystem) RotateLeft(deltaTime float64) {
-	// Rotate around Y axis
-	angle := ps.player.RotationSpeed * deltaTime
-
-	// Apply rotation to direction vector
-	ps.player.Direction = Vector3{
-		X: ps.player.Direction.X*math.Cos(angle) - ps.player.Direction.Z*math.Sin(angle),
-		Y: ps.player.Direction.Y,
-		Z: ps.player.Direction.X*math.Sin(angle) + ps.player.Direction.Z*math.Cos(angle),
-	}.Normalize()
-}
-
-// RotateRight rotates the player's view to the right
-func (ps *PhysicsSystem) RotateRight(deltaTime float64) {
-	// Rotate around Y axis
-	angle := -ps.player.RotationSpeed * deltaTime
-
-	// Apply rotation to direction vector
-	ps.player.Direction = Vector3{
-		X: ps.player.Direction.X*math.Cos(angle) - ps.player.Direction.Z*math.Sin(angle),
-		Y: ps.player.Direction.Y,
-		Z: ps.player.Direction.X*math.Sin(angle) + ps.player.Direction.Z*math.Cos(angle),
-	}.Normalize()
-}
-
-// checkObjectCollisions checks for collisions with objects in the scene
-func (ps *PhysicsSystem) checkObjectCollisions(position Vector3) bool {
-	if ps.scene == nil {
-		return false
+// Move walks the player from analog input: forward and strafe are -1..1
+// (stick deflection or held keys), so diagonals and half-tilted sticks work
+func (ps *PhysicsSystem) Move(forward, strafe float64, sprint bool) {
+	// Pushing a stick diagonally isn't faster than pushing it straight
+	if amount := math.Hypot(forward, strafe); amount > 1 {
+		forward /= amount
+		strafe /= amount
+	}
+	if forward < 0 {
+		forward *= 0.7 // Slower backward movement
 	}
 
-	// Check collision with each object
-	for _, obj := range ps.scene.Objects {
-		// Simple sphere-sphere collision check
-		objPos := obj.Position
-
-		// Calculate collision radius based on object type
-		collisionRadius := 1.0 // Default
-		switch obj.Type {
-		case "tree":
-			collisionRadius = obj.Scale.X * 0.8 // Tree trunk
-		case "rock":
-			collisionRadius = (obj.Scale.X + obj.Scale.Z) / 2 * 0.9
-		case "standing_stone":
-			collisionRadius = (obj.Scale.X + obj.Scale.Z) / 2 * 0.9
-		case "ruin":
-			collisionRadius = math.Max(obj.Scale.X, obj.Scale.Z) * 0.8
-		case "strange", "altar", "ritual_stone":
-			collisionRadius = math.Max(obj.Scale.X, obj.Scale.Z) * 0.9
-		}
-
-		// Calculate distance (only in XZ plane)
-		dx := position.X - objPos.X
-		dz := position.Z - objPos.Z
-		distSquared := dx*dx + dz*dz
-
-		minDist := ps.player.Radius + collisionRadius
-
-		if distSquared < minDist*minDist {
-			return true // Collision detected
-		}
+	speed := ps.walkSpeed()
+	if sprint && forward > 0 && ps.canSprint() {
+		speed *= ps.player.SprintModifier
+		ps.sprintRequested = true
 	}
 
-	return false
+	// Only apply horizontal movement
+	ahead := Vector3{X: ps.player.Direction.X, Y: 0, Z: ps.player.Direction.Z}.Normalize()
+	right := Vector3{X: ahead.Z, Y: 0, Z: -ahead.X}
+
+	ps.player.Velocity.X = (ahead.X*forward + right.X*strafe) * speed
+	ps.player.Velocity.Z = (ahead.Z*forward + right.Z*strafe) * speed
 }
@@ -1556,8 +1556,12 @@ func (e *Engine) createSceneData() *SceneData {
 
 		// Set player position and view direction
 		if e.physics != nil && e.physics.GetPlayer() != nil {
-			scene.PlayerPosition = e.physics.GetPlayer().Position
-			scene.ViewDirection = e.physics.GetPlayer().Direction
+			// Render from the eyes between the last two simulation ticks so motion
+			// stays smooth at any FPS and crouching lowers the camera
+			eye := e.physics.InterpolatedEyePosition(e.clock.Alpha())
+			scene.PlayerPosition = eye.Add(e.camera.BobOffset())
+			scene.ViewDirection = e.camera.ViewDirection()
+			e.camera.ApplyToRaytracer(e.raytracer, eye)
 		} else {
 			// Default values
 			scene.PlayerPosition = Vector3{X: 0, Y: 1.7, Z: 0}
@@ -1566,6 +1570,16 @@ func (e *Engine) createSceneData() *SceneData {
 
 		// Populate with objects
 		e.populateObjectsInView(scene, procScene, scene.PlayerPosition, scene.ViewDirection)
+		if e.hallucinations != nil {
+			e.hallucinations.AddToScene(scene)
+		}
+		if objectives := e.sim.Objectives(); objectives != nil {
+			objectives.AddToScene(scene)
+			scene.Overlay = objectives.Lines()
+		}
+		if e.messageTime > 0 {
+			scene.Overlay = append(scene.Overlay, e.message)
+		}
 	}
 
 	fmt.Printf("Created scene with %d objects in view\n", len(scene.ObjectsInView))
@@ -1584,6 +1598,8 @@ func (r *PixelRenderer) renderSimpleObject(obj *SceneObject, x, y, size float64)
 		color = [3]float32{0.8, 0.8, 0.8} // Light gray
 	case "strange":
 		color = [3]float32{1.0, 0.2, 1.0} // Bright purple
+	case stalkerObjectType:
+		color = [3]float32{0.6, 0.0, 0.0} // Dark red
 	case "debug":
 		color = [3]float32{1.0, 0.0, 0.0} // Bright red
 	default:
@@ -2386,6 +2402,87 @@ func (r *PixelRenderer) Render(scene *SceneData) {
 
 	// Draw scene content
 	r.drawSceneContent(scene)
+	if scene != nil {
+		r.drawOverlay(scene.Overlay)
+	}
+}
+
+// overlayFont is a 3x5 pixel font for the overlay text: five rows per
+// glyph, three bits per row, the high bit on the left. Letters are upper case
+var overlayFont = map[rune][5]uint8{
+	'A': {2, 5, 7, 5, 5}, 'B': {6, 5, 6, 5, 6}, 'C': {3, 4, 4, 4, 3}, 'D': {6, 5, 5, 5, 6},
+	'E': {7, 4, 6, 4, 7}, 'F': {7, 4, 6, 4, 4}, 'G': {3, 4, 5, 5, 3}, 'H': {5, 5, 7, 5, 5},
+	'I': {7, 2, 2, 2, 7}, 'J': {1, 1, 1, 5, 2}, 'K': {5, 5, 6, 5, 5}, 'L': {4, 4, 4, 4, 7},
+	'M': {5, 7, 7, 5, 5}, 'N': {6, 5, 5, 5, 5}, 'O': {2, 5, 5, 5, 2}, 'P': {6, 5, 6, 4, 4},
+	'Q': {2, 5, 5, 6, 3}, 'R': {6, 5, 6, 5, 5}, 'S': {3, 4, 2, 1, 6}, 'T': {7, 2, 2, 2, 2},
+	'U': {5, 5, 5, 5, 7}, 'V': {5, 5, 5, 5, 2}, 'W': {5, 5, 7, 7, 5}, 'X': {5, 5, 2, 5, 5},
+	'Y': {5, 5, 2, 2, 2}, 'Z': {7, 1, 2, 4, 7},
+	'0': {7, 5, 5, 5, 7}, '1': {2, 6, 2, 2, 7}, '2': {6, 1, 2, 4, 7}, '3': {6, 1, 2, 1, 6},
+	'4': {5, 5, 7, 1, 1}, '5': {7, 4, 6, 1, 6}, '6': {3, 4, 6, 5, 2}, '7': {7, 1, 2, 2, 2},
+	'8': {2, 5, 2, 5, 2}, '9': {2, 5, 3, 1, 6},
+	'.': {0, 0, 0, 0, 2}, ',': {0, 0, 0, 2, 4}, ':': {0, 2, 0, 2, 0}, '\'': {2, 2, 0, 0, 0},
+	'/': {1, 1, 2, 4, 4}, '-': {0, 0, 7, 0, 0}, '!': {2, 2, 2, 0, 2}, '?': {6, 1, 2, 0, 2},
+}
+
+// drawOverlay draws lines of text in the top left corner, each on a dark
+// strip so it reads over any picture. Long lines wrap at the window edge
+func (r *PixelRenderer) drawOverlay(lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	winWidth, winHeight := r.getWindowDimensions()
+	scale := int(math.Max(2, float64(winHeight/160)))
+	margin := 4 * scale
+	maxChars := int(math.Max(1, float64((winWidth-2*margin)/(4*scale))))
+
+	y := margin
+	for _, line := range lines {
+		for _, row := range wrapText(strings.ToUpper(line), maxChars) {
+			r.drawColoredQuad(margin-scale, y-scale, len(row)*4*scale+scale, 7*scale, 0.0, 0.0, 0.0)
+			for i, char := range row {
+				glyph := overlayFont[char]
+				for glyphY, bits := range glyph {
+					for glyphX := 0; glyphX < 3; glyphX++ {
+						if bits&(4>>glyphX) != 0 {
+							r.drawColoredQuad(margin+(i*4+glyphX)*scale, y+glyphY*scale, scale, scale, 0.85, 0.85, 0.8)
+						}
+					}
+				}
+			}
+			y += 7 * scale
+		}
+	}
+}
+
+// wrapText breaks text into rows of at most width characters between words
+func wrapText(text string, width int) []string {
+	rows := make([]string, 0, 1)
+	row := ""
+	for _, word := range strings.Fields(text) {
+		for len(word) > width {
+			// A word longer than the row is cut
+			if row != "" {
+				rows = append(rows, row)
+				row = ""
+			}
+			rows = append(rows, word[:width])
+			word = word[width:]
+		}
+		switch {
+		case row == "":
+			row = word
+		case len(row)+1+len(word) <= width:
+			row += " " + word
+		default:
+			rows = append(rows, row)
+			row = word
+		}
+	}
+	if row != "" {
+		rows = append(rows, row)
+	}
+	return rows
 }
 
 // Draw the scene contents directly to the screen
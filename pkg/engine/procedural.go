@@ -8,31 +8,48 @@ import (
 	"time"
 
 	noise "nightmare/internal/math"
-	"nightmare/internal/util"
 	"nightmare/pkg/config"
 )
 
 // ProceduralObject represents a procedurally generated object
 type ProceduralObject struct {
-	ID       int
-	Type     string             // Type of object (tree, rock, terrain, etc.)
-	Position Vector3            // Position in world space
-	Scale    Vector3            // Scale of the object
-	Rotation Vector3            // Rotation of the object (in radians)
-	Metadata map[string]float64 // Hierarchical metadata with weights
-	Seed     int64              // Seed for reproducibility
+	ID          int
+	Type        string             // Type of object (tree, rock, terrain, etc.)
+	Position    Vector3            // Position in world space
+	Scale       Vector3            // Scale of the object
+	Rotation    Vector3            // Rotation of the object (in radians)
+	Metadata    map[string]float64 // Hierarchical metadata with weights
+	Seed        int64              // Seed for reproducibility
+	StructureID int                // ID of the structure this object belongs to (0 = none)
+
+	// Gaze-aware evolution: whether the player has seen the object since it was last relocated
+	Seen bool
 }
 
 // ProceduralScene represents the current procedural scene
 type ProceduralScene struct {
 	Objects     []*ProceduralObject
+	Structures  []*PlacedStructure // Multi-object structures (cabins, shrines, graves...)
+	Caves       []*CaveVolume      // Regions where terrain is a 3D density field (caves, overhangs)
 	Terrain     *HeightMap
 	TimeOfDay   float64            // 0.0-1.0, 0 = midnight, 0.5 = noon
 	Weather     map[string]float64 // Weather conditions (fog, rain, etc.) with weights
 	Seed        int64              // Scene seed
 	BiomeType   string             // Type of biome (forest, mountains, etc.)
 	Atmosphere  map[string]float64 // Atmospheric conditions
-	LevelOfFear float64            // General fear level of the scene
+	LevelOfFear float64            // General fear level of the scene: set by the biome, then paced by the AI director; sanity drains faster the higher it is
+	Index       *SpatialHash       // Uniform XZ grid over Objects for radius, box, frustum and ray queries
+
+	nextObjectID int             // Последний выданный ID объекта
+	observers    []SceneObserver // Подписчики на изменения состава сцены
+}
+
+// SceneObserver получает уведомления, когда эволюция сцены добавляет,
+// удаляет или сдвигает объекты (например, чтобы обновить коллайдеры)
+type SceneObserver interface {
+	ObjectAdded(obj *ProceduralObject)
+	ObjectRemoved(obj *ProceduralObject)
+	ObjectMoved(obj *ProceduralObject, from Vector3) // from - где объект стоял до сдвига
 }
 
 // HeightMap represents terrain elevation data
@@ -43,6 +60,7 @@ type HeightMap struct {
 	Materials [][]int
 	Humidity  [][]float64  // Влажность почвы
 	Regions   [][]string   // Регионы (лес, поляна, болото и т.д.)
+	Paths     [][][2]int   // Тропинки в координатах сетки (из createPaths)
 	Mutex     sync.RWMutex // Для безопасного доступа из разных потоков
 }
 
@@ -65,16 +83,30 @@ type ProceduralGenerator struct {
 	config       config.ProceduralConfig
 	currentScene *ProceduralScene
 	noiseGen     *noise.NoiseGenerator
+	seed         int64 // Сид мира, определяется один раз при создании генератора
 	time         float64
 	mutex        sync.RWMutex
 
+	// Фиксированные тики симуляции мира
+	tick         uint64
+	worldClock   *FixedStep // Переводит время кадра в целые тики мира
+	evolutionRng *rand.Rand // Поток случайных чисел для эволюции сцены
+
+	// Эволюция с учетом взгляда (gaze.go)
+	viewer         *gazeViewer // nil, пока симуляция не сообщила, куда смотрит игрок
+	nextRelocation uint64      // Тик, раньше которого замеченные объекты не переносятся
+
 	// Биомы и регионы
 	biomes map[string]BiomeParams
+
+	// Шаблоны построек
+	structurePrefabs []StructurePrefab
 }
 
 // NewProceduralGenerator creates a new procedural generator
 func NewProceduralGenerator(config config.ProceduralConfig) (*ProceduralGenerator, error) {
-	// Используем указанный в конфигурации seed, или случайный, если seed=0
+	// Используем указанный в конфигурации seed, или случайный, если seed=0.
+	// Сид определяется только здесь: все подсистемы получают из него свои потоки
 	seed := config.Seed
 	if seed == 0 {
 		seed = time.Now().UnixNano()
@@ -83,12 +115,14 @@ func NewProceduralGenerator(config config.ProceduralConfig) (*ProceduralGenerato
 	gen := &ProceduralGenerator{
 		config:   config,
 		noiseGen: noise.NewNoiseGenerator(seed),
+		seed:     seed,
 		time:     0,
 		biomes:   make(map[string]BiomeParams),
 	}
 
 	// Инициализируем настройки биомов
 	gen.initBiomes()
+	gen.initStructurePrefabs()
 
 	return gen, nil
 }
@@ -201,16 +235,14 @@ func (pg *ProceduralGenerator) GenerateInitialWorld() {
 
 	fmt.Println("Starting world generation...") // This will print to console even if logger isn't working
 
-	// Create a new scene with seed from config or random
-	seed := pg.config.Seed
-	if seed == 0 {
-		seed = time.Now().UnixNano()
-	}
+	// Create a new scene with the seed resolved by NewProceduralGenerator
+	seed := pg.seed
 
-	fmt.Println("Creating scene with seed:", seed)
+	fmt.Printf("Creating scene with seed: %d (code %s)\n", seed, config.FormatSeed(seed))
 
 	pg.currentScene = &ProceduralScene{
 		Objects:     make([]*ProceduralObject, 0),
+		Index:       NewSpatialHash(spatialCellSize),
 		TimeOfDay:   0.2, // Early morning
 		Weather:     map[string]float64{"fog": 0.7, "mist": 0.3},
 		Seed:        seed,
@@ -219,6 +251,12 @@ func (pg *ProceduralGenerator) GenerateInitialWorld() {
 		LevelOfFear: 0.5,
 	}
 
+	// Эволюция и тики начинаются заново для нового мира
+	pg.tick = 0
+	pg.worldClock = NewFixedStep(WorldTickRate, 0)
+	pg.time = 0
+	pg.evolutionRng = newStream(seed, streamEvolution)
+
 	fmt.Println("Scene created, initializing atmosphere...")
 
 	// Initialize atmosphere from biome
@@ -238,6 +276,16 @@ func (pg *ProceduralGenerator) GenerateInitialWorld() {
 	pg.currentScene.Terrain = pg.generateTerrain(seed, pg.currentScene.BiomeType)
 	fmt.Println("Terrain generation completed")
 
+	fmt.Println("Carving caves...")
+	// Caves come before structures so that nothing blocks their entrances
+	pg.placeCaves()
+	fmt.Println("Cave generation completed")
+
+	fmt.Println("Placing structures...")
+	// Place structures first so that trees and rocks avoid them
+	pg.placeStructures()
+	fmt.Println("Structure placement completed")
+
 	fmt.Println("Populating scene with objects...")
 	// Generate initial objects based on the terrain
 	pg.populateScene()
@@ -246,7 +294,14 @@ func (pg *ProceduralGenerator) GenerateInitialWorld() {
 	fmt.Println("World generation completed")
 }
 
-// Update updates the procedural generation based on time
+// WorldTickRate is the number of fixed world simulation ticks per second
+const WorldTickRate = 10.0
+
+// evolutionTickPeriod is the number of ticks between scene evolution steps (3 seconds)
+const evolutionTickPeriod = 30
+
+// Update advances the world by deltaTime using fixed simulation ticks,
+// so the world evolves identically regardless of frame rate
 func (pg *ProceduralGenerator) Update(deltaTime float64) {
 	pg.mutex.Lock()
 	defer pg.mutex.Unlock()
@@ -255,19 +310,45 @@ func (pg *ProceduralGenerator) Update(deltaTime float64) {
 		return
 	}
 
-	// Update internal time
-	pg.time += deltaTime
+	for steps := pg.worldClock.Advance(deltaTime); steps > 0; steps-- {
+		pg.advanceTick()
+	}
+}
+
+// advanceTick advances the world by exactly one simulation tick
+func (pg *ProceduralGenerator) advanceTick() {
+	pg.tick++
+
+	// Internal time is derived from the tick counter, never from frame time
+	pg.time = float64(pg.tick) / WorldTickRate
 
 	// Update time of day (complete cycle in 15 minutes real time)
 	cycleDuration := 15 * 60.0 // 15 minutes in seconds
 	pg.currentScene.TimeOfDay = math.Mod(pg.time/cycleDuration, 1.0)
 
+	if pg.config.GazeAwareEvolution {
+		pg.observe()
+	}
+
 	// Every few seconds, potentially update some aspects of the scene
-	if int(pg.time*10)%30 == 0 { // Every 3 seconds
+	if pg.tick%evolutionTickPeriod == 0 {
 		pg.evolveScene()
 	}
 }
 
+// Tick returns the number of world simulation ticks since the world was generated
+func (pg *ProceduralGenerator) Tick() uint64 {
+	pg.mutex.RLock()
+	defer pg.mutex.RUnlock()
+
+	return pg.tick
+}
+
+// Seed returns the resolved world seed
+func (pg *ProceduralGenerator) Seed() int64 {
+	return pg.seed
+}
+
 // GetCurrentScene returns the current scene
 func (pg *ProceduralGenerator) GetCurrentScene() *ProceduralScene {
 	pg.mutex.RLock()
@@ -291,55 +372,12 @@ func (pg *ProceduralGenerator) GetTerrainHeightAt(x, z float64) float64 {
 	terrain.Mutex.RLock()
 	defer terrain.Mutex.RUnlock()
 
-	// Преобразуем мировые координаты в координаты сетки ландшафта
-	terrainWidth := float64(terrain.Width)
-	terrainHeight := float64(terrain.Height)
-
-	// Смещение для центрирования ландшафта
-	offsetX := terrainWidth / 2.0
-	offsetZ := terrainHeight / 2.0
-
-	// Координаты в сетке ландшафта
-	gridX := x + offsetX
-	gridZ := z + offsetZ
-
 	// Проверяем, что координаты находятся в пределах ландшафта
-	if gridX < 0 || gridX >= terrainWidth || gridZ < 0 || gridZ >= terrainHeight {
+	if !terrain.InBounds(x, z) {
 		return 0.0 // За пределами ландшафта
 	}
 
-	// Координаты ближайших точек сетки
-	x0 := int(math.Floor(gridX))
-	z0 := int(math.Floor(gridZ))
-	x1 := x0 + 1
-	z1 := z0 + 1
-
-	// Ограничиваем индексы границами массива
-	x0 = clamp(x0, 0, terrain.Width-1)
-	z0 = clamp(z0, 0, terrain.Height-1)
-	x1 = clamp(x1, 0, terrain.Width-1)
-	z1 = clamp(z1, 0, terrain.Height-1)
-
-	// Веса для билинейной интерполяции
-	wx := gridX - float64(x0)
-	wz := gridZ - float64(z0)
-
-	// Извлекаем высоты в ближайших точках
-	h00 := terrain.Data[z0][x0]
-	h10 := terrain.Data[z0][x1]
-	h01 := terrain.Data[z1][x0]
-	h11 := terrain.Data[z1][x1]
-
-	// Билинейная интерполяция
-	h0 := h00*(1-wx) + h10*wx
-	h1 := h01*(1-wx) + h11*wx
-	height := h0*(1-wz) + h1*wz
-
-	// Масштабируем высоту для получения реалистичных значений
-	// (в зависимости от вашей системы координат)
-	heightScale := 20.0 // Коэффициент масштабирования высоты
-
-	return height * heightScale
+	return terrain.HeightAt(x, z)
 }
 
 // GetBiomeAt возвращает тип биома в указанной точке
@@ -414,6 +452,9 @@ func (pg *ProceduralGenerator) generateTerrain(seed int64, biomeType string) *He
 		Regions:   make([][]string, height),
 	}
 
+	// Поток случайных чисел для выбора материалов
+	materialRng := newStream(seed, streamMaterials)
+
 	// Set up noise parameters
 	baseScale := 0.03
 	detailScale := 0.1
@@ -479,7 +520,7 @@ func (pg *ProceduralGenerator) generateTerrain(seed int64, biomeType string) *He
 			heightMap.Humidity[y][x] = humidity
 
 			// Determine material based on elevation and humidity
-			heightMap.Materials[y][x] = pg.determineMaterial(elevation, humidity, biomeType)
+			heightMap.Materials[y][x] = pg.determineMaterial(elevation, humidity, biomeType, materialRng)
 
 			// Определяем регионы (подбиомы)
 			heightMap.Regions[y][x] = pg.determineRegion(elevation, humidity, worldX, worldZ, seed)
@@ -570,7 +611,7 @@ func (pg *ProceduralGenerator) applyTerrainFeatures(baseElevation, x, z, scale f
 }
 
 // determineMaterial determines the material type based on elevation and other factors
-func (pg *ProceduralGenerator) determineMaterial(elevation, humidity float64, biomeType string) int {
+func (pg *ProceduralGenerator) determineMaterial(elevation, humidity float64, biomeType string, rng *rand.Rand) int {
 	// Получаем доступные материалы для биома
 	biomeParams, ok := pg.biomes[biomeType]
 	if !ok {
@@ -596,7 +637,7 @@ func (pg *ProceduralGenerator) determineMaterial(elevation, humidity float64, bi
 	// Учитываем влажность
 	if materialID == 2 && humidity > 0.7 {
 		// Очень влажная земля, может быть болотом
-		if rand.Float64() < 0.3 {
+		if rng.Float64() < 0.3 {
 			materialID = 1 // Больше шансов на воду в очень влажных местах
 		}
 	}
@@ -635,7 +676,7 @@ func (pg *ProceduralGenerator) determineMaterial(elevation, humidity float64, bi
 		}
 
 		// Выбираем материал с учетом весов
-		selection := rand.Float64()
+		selection := rng.Float64()
 		cumulativeWeight := 0.0
 
 		for i, weight := range weights {
@@ -741,17 +782,17 @@ func (pg *ProceduralGenerator) postProcessTerrain(terrain *HeightMap, seed int64
 
 // createClearings создает поляны в лесу
 func (pg *ProceduralGenerator) createClearings(terrain *HeightMap, count int, seed int64) {
-	rand.Seed(seed)
+	rng := newStream(seed, streamClearings)
 	width := terrain.Width
 	height := terrain.Height
 
 	for i := 0; i < count; i++ {
 		// Выбираем случайную позицию для центра поляны
-		centerX := rand.Intn(width)
-		centerY := rand.Intn(height)
+		centerX := rng.Intn(width)
+		centerY := rng.Intn(height)
 
 		// Размер поляны
-		radius := 5 + rand.Intn(10)
+		radius := 5 + rng.Intn(10)
 
 		// Создаем поляну
 		for y := centerY - radius; y <= centerY+radius; y++ {
@@ -772,7 +813,7 @@ func (pg *ProceduralGenerator) createClearings(terrain *HeightMap, count int, se
 					factor := (1.0 - dist/float64(radius)) * 0.8
 
 					// Выравниваем высоту
-					targetHeight := 0.4 + rand.Float64()*0.1
+					targetHeight := 0.4 + rng.Float64()*0.1
 					terrain.Data[y][x] = terrain.Data[y][x]*(1.0-factor) + targetHeight*factor
 
 					// Устанавливаем регион
@@ -788,17 +829,17 @@ func (pg *ProceduralGenerator) createClearings(terrain *HeightMap, count int, se
 
 // createDenseGroves создает участки густого леса
 func (pg *ProceduralGenerator) createDenseGroves(terrain *HeightMap, count int, seed int64) {
-	rand.Seed(seed + 1000)
+	rng := newStream(seed, streamGroves)
 	width := terrain.Width
 	height := terrain.Height
 
 	for i := 0; i < count; i++ {
 		// Выбираем случайную позицию для центра рощи
-		centerX := rand.Intn(width)
-		centerY := rand.Intn(height)
+		centerX := rng.Intn(width)
+		centerY := rng.Intn(height)
 
 		// Размер рощи
-		radius := 8 + rand.Intn(12)
+		radius := 8 + rng.Intn(12)
 
 		// Создаем рощу
 		for y := centerY - radius; y <= centerY+radius; y++ {
@@ -819,7 +860,7 @@ func (pg *ProceduralGenerator) createDenseGroves(terrain *HeightMap, count int,
 					factor := (1.0 - dist/float64(radius)) * 0.7
 
 					// Устанавливаем регион
-					if rand.Float64() < factor {
+					if rng.Float64() < factor {
 						terrain.Regions[y][x] = "dense_forest"
 					}
 				}
@@ -830,17 +871,17 @@ func (pg *ProceduralGenerator) createDenseGroves(terrain *HeightMap, count int,
 
 // createSwampPits создает болотные ямы
 func (pg *ProceduralGenerator) createSwampPits(terrain *HeightMap, count int, seed int64) {
-	rand.Seed(seed + 2000)
+	rng := newStream(seed, streamSwampPits)
 	width := terrain.Width
 	height := terrain.Height
 
 	for i := 0; i < count; i++ {
 		// Выбираем случайную позицию для центра ямы
-		centerX := rand.Intn(width)
-		centerY := rand.Intn(height)
+		centerX := rng.Intn(width)
+		centerY := rng.Intn(height)
 
 		// Размер ямы
-		radius := 4 + rand.Intn(8)
+		radius := 4 + rng.Intn(8)
 
 		// Создаем яму
 		for y := centerY - radius; y <= centerY+radius; y++ {
@@ -861,7 +902,7 @@ func (pg *ProceduralGenerator) createSwampPits(terrain *HeightMap, count int, se
 					factor := (1.0 - dist/float64(radius)) * 0.9
 
 					// Понижаем высоту для создания ямы
-					targetHeight := 0.15 + rand.Float64()*0.1
+					targetHeight := 0.15 + rng.Float64()*0.1
 					terrain.Data[y][x] = terrain.Data[y][x]*(1.0-factor) + targetHeight*factor
 
 					// Устанавливаем регион
@@ -880,17 +921,17 @@ func (pg *ProceduralGenerator) createSwampPits(terrain *HeightMap, count int, se
 
 // createSmallIslands создает маленькие островки в болоте
 func (pg *ProceduralGenerator) createSmallIslands(terrain *HeightMap, count int, seed int64) {
-	rand.Seed(seed + 3000)
+	rng := newStream(seed, streamIslands)
 	width := terrain.Width
 	height := terrain.Height
 
 	for i := 0; i < count; i++ {
 		// Выбираем случайную позицию для центра острова
-		centerX := rand.Intn(width)
-		centerY := rand.Intn(height)
+		centerX := rng.Intn(width)
+		centerY := rng.Intn(height)
 
 		// Размер острова
-		radius := 2 + rand.Intn(4)
+		radius := 2 + rng.Intn(4)
 
 		// Создаем остров
 		for y := centerY - radius; y <= centerY+radius; y++ {
@@ -911,7 +952,7 @@ func (pg *ProceduralGenerator) createSmallIslands(terrain *HeightMap, count int,
 					factor := (1.0 - dist/float64(radius)) * 0.8
 
 					// Повышаем высоту для создания острова
-					targetHeight := 0.35 + rand.Float64()*0.1
+					targetHeight := 0.35 + rng.Float64()*0.1
 					terrain.Data[y][x] = terrain.Data[y][x]*(1.0-factor) + targetHeight*factor
 
 					// Устанавливаем регион
@@ -927,7 +968,7 @@ func (pg *ProceduralGenerator) createSmallIslands(terrain *HeightMap, count int,
 
 // createMountainPeaks создает горные вершины
 func (pg *ProceduralGenerator) createMountainPeaks(terrain *HeightMap, count int, seed int64) {
-	rand.Seed(seed + 4000)
+	rng := newStream(seed, streamPeaks)
 	width := terrain.Width
 	height := terrain.Height
 
@@ -948,7 +989,7 @@ func (pg *ProceduralGenerator) createMountainPeaks(terrain *HeightMap, count int
 	}
 
 	// Перемешиваем высокие участки
-	rand.Shuffle(len(highSpots), func(i, j int) {
+	rng.Shuffle(len(highSpots), func(i, j int) {
 		highSpots[i], highSpots[j] = highSpots[j], highSpots[i]
 	})
 
@@ -960,7 +1001,7 @@ func (pg *ProceduralGenerator) createMountainPeaks(terrain *HeightMap, count int
 		centerY := highSpots[i][1]
 
 		// Радиус влияния пика
-		radius := 3 + rand.Intn(5)
+		radius := 3 + rng.Intn(5)
 
 		// Создаем пик
 		for y := centerY - radius; y <= centerY+radius; y++ {
@@ -981,7 +1022,7 @@ func (pg *ProceduralGenerator) createMountainPeaks(terrain *HeightMap, count int
 					peakFactor := math.Exp(-dist * dist / (float64(radius) * float64(radius) * 0.5))
 
 					// Повышаем высоту
-					peakHeight := 0.9 + rand.Float64()*0.1
+					peakHeight := 0.9 + rng.Float64()*0.1
 					terrain.Data[y][x] = math.Max(terrain.Data[y][x],
 						terrain.Data[y][x]*(1.0-peakFactor)+peakHeight*peakFactor)
 
@@ -1004,21 +1045,21 @@ func (pg *ProceduralGenerator) createMountainPeaks(terrain *HeightMap, count int
 
 // createRavines создает ущелья
 func (pg *ProceduralGenerator) createRavines(terrain *HeightMap, count int, seed int64) {
-	rand.Seed(seed + 5000)
+	rng := newStream(seed, streamRavines)
 	width := terrain.Width
 	height := terrain.Height
 
 	for i := 0; i < count; i++ {
 		// Выбираем случайную начальную точку
-		startX := rand.Intn(width)
-		startY := rand.Intn(height)
+		startX := rng.Intn(width)
+		startY := rng.Intn(height)
 
 		// Выбираем случайное направление
-		angle := rand.Float64() * 2 * math.Pi
-		length := 20 + rand.Intn(30)
+		angle := rng.Float64() * 2 * math.Pi
+		length := 20 + rng.Intn(30)
 
 		// Ширина ущелья
-		ravineWidth := 2 + rand.Intn(4)
+		ravineWidth := 2 + rng.Intn(4)
 
 		// Создаем ущелье путем прокладывания криволинейного пути
 		curX, curY := float64(startX), float64(startY)
@@ -1026,7 +1067,7 @@ func (pg *ProceduralGenerator) createRavines(terrain *HeightMap, count int, seed
 
 		for step := 0; step < length; step++ {
 			// Небольшое изменение направления для естественности
-			angle += (rand.Float64()*2.0 - 1.0) * angleVariation
+			angle += (rng.Float64()*2.0 - 1.0) * angleVariation
 
 			// Перемещаемся в новом направлении
 			curX += math.Cos(angle)
@@ -1074,7 +1115,7 @@ func (pg *ProceduralGenerator) createRavines(terrain *HeightMap, count int, seed
 
 // createPaths создает тропинки
 func (pg *ProceduralGenerator) createPaths(terrain *HeightMap, count int, seed int64) {
-	rand.Seed(seed + 6000)
+	rng := newStream(seed, streamPaths)
 	width := terrain.Width
 	height := terrain.Height
 
@@ -1083,46 +1124,49 @@ func (pg *ProceduralGenerator) createPaths(terrain *HeightMap, count int, seed i
 		var startX, startY int
 
 		// Выбираем случайную сторону
-		side := rand.Intn(4)
+		side := rng.Intn(4)
 		switch side {
 		case 0: // Top
-			startX = rand.Intn(width)
+			startX = rng.Intn(width)
 			startY = 0
 		case 1: // Right
 			startX = width - 1
-			startY = rand.Intn(height)
+			startY = rng.Intn(height)
 		case 2: // Bottom
-			startX = rand.Intn(width)
+			startX = rng.Intn(width)
 			startY = height - 1
 		case 3: // Left
 			startX = 0
-			startY = rand.Intn(height)
+			startY = rng.Intn(height)
 		}
 
 		// Создаем противоположную точку назначения (примерно)
 		var endX, endY int
 		switch side {
 		case 0: // Top -> Bottom
-			endX = rand.Intn(width)
+			endX = rng.Intn(width)
 			endY = height - 1
 		case 1: // Right -> Left
 			endX = 0
-			endY = rand.Intn(height)
+			endY = rng.Intn(height)
 		case 2: // Bottom -> Top
-			endX = rand.Intn(width)
+			endX = rng.Intn(width)
 			endY = 0
 		case 3: // Left -> Right
 			endX = width - 1
-			endY = rand.Intn(height)
+			endY = rng.Intn(height)
 		}
 
 		// Создаем путь с использованием A* или другого алгоритма поиска пути
-		path := pg.findPath(terrain, startX, startY, endX, endY)
+		path := pg.findPath(terrain, startX, startY, endX, endY, rng)
 
 		// Если путь найден, создаем тропинку
 		if len(path) > 0 {
+			// Запоминаем тропинку для размещения построек вдоль нее
+			terrain.Paths = append(terrain.Paths, path)
+
 			// Ширина тропинки
-			pathWidth := 1 + rand.Intn(2)
+			pathWidth := 1 + rng.Intn(2)
 
 			// Проходим по всем точкам пути
 			for _, point := range path {
@@ -1168,7 +1212,7 @@ func (pg *ProceduralGenerator) createPaths(terrain *HeightMap, count int, seed i
 
 // findPath находит путь между двумя точками
 // Упрощенная версия A* алгоритма для поиска пути
-func (pg *ProceduralGenerator) findPath(terrain *HeightMap, startX, startY, endX, endY int) [][2]int {
+func (pg *ProceduralGenerator) findPath(terrain *HeightMap, startX, startY, endX, endY int, rng *rand.Rand) [][2]int {
 	// Максимальное количество шагов для предотвращения бесконечного поиска
 	maxSteps := terrain.Width * terrain.Height / 10
 
@@ -1235,8 +1279,8 @@ func (pg *ProceduralGenerator) findPath(terrain *HeightMap, startX, startY, endX
 		path = append(path, [2]int{curX, curY})
 
 		// Добавляем немного случайности
-		if rand.Float64() < 0.2 { // 20% шанс на случайное направление
-			randomDir := rand.Intn(len(dirs))
+		if rng.Float64() < 0.2 { // 20% шанс на случайное направление
+			randomDir := rng.Intn(len(dirs))
 			nx := curX + dirs[randomDir][0]
 			ny := curY + dirs[randomDir][1]
 
@@ -1311,358 +1355,268 @@ func (pg *ProceduralGenerator) populateScene() {
 		biomeParams = pg.biomes["dark_forest"] // По умолчанию
 	}
 
-	// Get terrain dimensions
 	terrain := pg.currentScene.Terrain
-	terrainWidth := terrain.Width
-	terrainHeight := terrain.Height
-
-	// Генерируем деревья
-	numTrees := int(biomeParams.TreeDensity) * terrainWidth * terrainHeight / 1000
-
-	// Массив для хранения занятых позиций
-	occupiedPositions := make(map[string]bool)
+	rng := newStream(pg.currentScene.Seed, streamObjectTypes)
+
+	// Все слои разбрасываются одним сэмплером, поэтому деревья, камни
+	// и странные объекты не перекрывают друг друга
+	scatter := NewScatterSampler(terrain, deriveSeed(pg.currentScene.Seed, streamScatter))
+
+	// Генерируем деревья. Базовое расстояние соответствует плотности биома
+	// (TreeDensity объектов на 1000 клеток), плотность в точке задает treeDensityAt
+	treeSpacing := math.Sqrt(1000/math.Max(biomeParams.TreeDensity, 0.1)) * 0.8
+	treePoints := scatter.Sample(ScatterLayer{
+		MinRadius: treeSpacing * 0.45,
+		MaxRadius: treeSpacing * 1.6,
+		Footprint: 1.0,
+		Density:   pg.treeDensityAt,
+	})
 
-	for i := 0; i < numTrees; i++ {
-		// Pick a random position on the terrain
-		x := pg.noiseGen.RandomFloat()*float64(terrainWidth) - float64(terrainWidth)/2
-		z := pg.noiseGen.RandomFloat()*float64(terrainHeight) - float64(terrainHeight)/2
+	for i, point := range treePoints {
+		x, z := point.X, point.Z
+		terrainX, terrainZ := terrain.cellAt(x, z)
+		elevation := terrain.Data[terrainZ][terrainX]
+		region := terrain.Regions[terrainZ][terrainX]
 
-		// Find the terrain height at this position
-		terrainX := int(x + float64(terrainWidth)/2)
-		terrainZ := int(z + float64(terrainHeight)/2)
+		// Определяем тип дерева в зависимости от региона и случайности
+		treeType := "pine" // По умолчанию
 
-		// Ensure within bounds
-		if terrainX < 0 || terrainX >= terrainWidth || terrainZ < 0 || terrainZ >= terrainHeight {
-			continue
+		// Доступные типы деревьев для биома
+		availableTypes := biomeParams.TreeTypes
+		if len(availableTypes) > 0 {
+			treeType = availableTypes[rng.Intn(len(availableTypes))]
 		}
 
-		elevation := pg.currentScene.Terrain.Data[terrainZ][terrainX]
-		region := pg.currentScene.Terrain.Regions[terrainZ][terrainX]
-
-		// Only place trees at certain elevations and not in water
-		if elevation > 0.3 && elevation < 0.8 && pg.currentScene.Terrain.Materials[terrainZ][terrainX] != 1 {
-			// Check if position is already occupied
-			posKey := fmt.Sprintf("%d,%d", terrainX, terrainZ)
-			if occupiedPositions[posKey] {
-				continue // Skip if position is already occupied
-			}
-
-			// Mark position as occupied
-			occupiedPositions[posKey] = true
-
-			// Определяем тип дерева в зависимости от региона и случайности
-			treeType := "pine" // По умолчанию
-
-			// Доступные типы деревьев для биома
-			availableTypes := biomeParams.TreeTypes
-			if len(availableTypes) > 0 {
-				treeType = availableTypes[rand.Intn(len(availableTypes))]
-			}
-
-			// Коррекции на основе региона
-			if region == "swamp" || region == "swamp_pit" {
-				treeType = "dead_tree"
-			} else if region == "dense_forest" {
-				// В густом лесу больше вероятность искривленных деревьев
-				if rand.Float64() < 0.4 {
-					treeType = "twisted_tree"
-				}
-			} else if region == "clearing" || region == "path" {
-				// На полянах и тропах меньше деревьев
-				if rand.Float64() < 0.7 {
-					continue // 70% шанс пропустить дерево
-				}
-
-				// На полянах чаще встречаются низкие деревья и кусты
-				if rand.Float64() < 0.5 {
-					treeType = "small_pine"
-				} else if rand.Float64() < 0.3 {
-					treeType = "bush"
-				}
-			}
-
-			// Различные параметры в зависимости от типа дерева
-			var treeHeight, treeWidth float64
-			var treeMeta map[string]float64
-
-			switch treeType {
-			case "pine":
-				treeHeight = 3.0 + pg.noiseGen.RandomFloat()*2.0
-				treeWidth = 0.8 + pg.noiseGen.RandomFloat()*0.4
-				treeMeta = map[string]float64{
-					"atmosphere.fear":       0.3 + pg.noiseGen.RandomFloat()*0.3,
-					"atmosphere.ominous":    0.2 + pg.noiseGen.RandomFloat()*0.4,
-					"visuals.distorted":     pg.noiseGen.RandomFloat() * 0.5,
-					"visuals.dark":          0.3 + pg.noiseGen.RandomFloat()*0.4,
-					"conditions.silhouette": 0.2 + pg.noiseGen.RandomFloat()*0.7,
-				}
-
-			case "dead_tree":
-				treeHeight = 2.5 + pg.noiseGen.RandomFloat()*1.5
-				treeWidth = 0.6 + pg.noiseGen.RandomFloat()*0.3
-				treeMeta = map[string]float64{
-					"atmosphere.fear":       0.5 + pg.noiseGen.RandomFloat()*0.3,
-					"atmosphere.ominous":    0.4 + pg.noiseGen.RandomFloat()*0.4,
-					"atmosphere.dread":      0.3 + pg.noiseGen.RandomFloat()*0.4,
-					"visuals.distorted":     0.2 + pg.noiseGen.RandomFloat()*0.3,
-					"visuals.dark":          0.5 + pg.noiseGen.RandomFloat()*0.3,
-					"conditions.silhouette": 0.5 + pg.noiseGen.RandomFloat()*0.5,
-				}
-
-			case "twisted_tree":
-				treeHeight = 2.0 + pg.noiseGen.RandomFloat()*2.5
-				treeWidth = 0.7 + pg.noiseGen.RandomFloat()*0.5
-				treeMeta = map[string]float64{
-					"atmosphere.fear":       0.4 + pg.noiseGen.RandomFloat()*0.4,
-					"atmosphere.ominous":    0.5 + pg.noiseGen.RandomFloat()*0.3,
-					"atmosphere.dread":      0.4 + pg.noiseGen.RandomFloat()*0.3,
-					"visuals.distorted":     0.4 + pg.noiseGen.RandomFloat()*0.4,
-					"visuals.twisted":       0.6 + pg.noiseGen.RandomFloat()*0.4,
-					"conditions.silhouette": 0.4 + pg.noiseGen.RandomFloat()*0.4,
-				}
-
-			case "small_pine":
-				treeHeight = 1.5 + pg.noiseGen.RandomFloat()*1.0
-				treeWidth = 0.6 + pg.noiseGen.RandomFloat()*0.3
-				treeMeta = map[string]float64{
-					"atmosphere.fear":       0.2 + pg.noiseGen.RandomFloat()*0.2,
-					"atmosphere.ominous":    0.1 + pg.noiseGen.RandomFloat()*0.3,
-					"visuals.dark":          0.2 + pg.noiseGen.RandomFloat()*0.3,
-					"conditions.silhouette": 0.1 + pg.noiseGen.RandomFloat()*0.5,
-				}
-
-			case "bush":
-				treeHeight = 0.7 + pg.noiseGen.RandomFloat()*0.5
-				treeWidth = 0.8 + pg.noiseGen.RandomFloat()*0.4
-				treeMeta = map[string]float64{
-					"atmosphere.fear": 0.1 + pg.noiseGen.RandomFloat()*0.2,
-					"visuals.dark":    0.2 + pg.noiseGen.RandomFloat()*0.2,
-				}
-			}
-
-			// Создаем дерево с соответствующими параметрами
-			tree := &ProceduralObject{
-				ID:       len(pg.currentScene.Objects) + 1,
-				Type:     "tree",
-				Position: Vector3{X: x, Y: elevation * 20, Z: z}, // Scale elevation
-				Scale:    Vector3{X: treeWidth, Y: treeHeight, Z: treeWidth},
-				Rotation: Vector3{X: 0, Y: pg.noiseGen.RandomFloat() * 2 * math.Pi, Z: 0},
-				Metadata: treeMeta,
-				Seed:     pg.currentScene.Seed + int64(i),
+		// Коррекции на основе региона
+		if region == "swamp" || region == "swamp_pit" {
+			treeType = "dead_tree"
+		} else if region == "dense_forest" {
+			// В густом лесу больше вероятность искривленных деревьев
+			if rng.Float64() < 0.4 {
+				treeType = "twisted_tree"
 			}
-
-			// Добавляем случайный наклон для некоторых деревьев
-			if treeType == "twisted_tree" || treeType == "dead_tree" {
-				tree.Rotation.X = (pg.noiseGen.RandomFloat()*2.0 - 1.0) * 0.2 // Наклон до 0.2 радиан
-				tree.Rotation.Z = (pg.noiseGen.RandomFloat()*2.0 - 1.0) * 0.2
+		} else if region == "clearing" || region == "path" {
+			// На полянах чаще встречаются низкие деревья и кусты
+			if rng.Float64() < 0.5 {
+				treeType = "small_pine"
+			} else if rng.Float64() < 0.3 {
+				treeType = "bush"
 			}
-
-			pg.currentScene.Objects = append(pg.currentScene.Objects, tree)
-		}
-	}
-
-	// Генерируем камни
-	numRocks := int(biomeParams.RockDensity) * terrainWidth * terrainHeight / 1000
-
-	for i := 0; i < numRocks; i++ {
-		// Similar logic as for trees
-		x := pg.noiseGen.RandomFloat()*float64(terrainWidth) - float64(terrainWidth)/2
-		z := pg.noiseGen.RandomFloat()*float64(terrainHeight) - float64(terrainHeight)/2
-
-		terrainX := int(x + float64(terrainWidth)/2)
-		terrainZ := int(z + float64(terrainHeight)/2)
-
-		if terrainX < 0 || terrainX >= terrainWidth || terrainZ < 0 || terrainZ >= terrainHeight {
-			continue
 		}
 
-		elevation := pg.currentScene.Terrain.Data[terrainZ][terrainX]
-		region := pg.currentScene.Terrain.Regions[terrainZ][terrainX]
-
-		// Rocks can be at more places than trees, but not in water
-		if elevation > 0.2 && pg.currentScene.Terrain.Materials[terrainZ][terrainX] != 1 {
-			// Check if position is already occupied
-			posKey := fmt.Sprintf("%d,%d", terrainX, terrainZ)
-			if occupiedPositions[posKey] {
-				continue // Skip if position is already occupied
+		// Различные параметры в зависимости от типа дерева
+		var treeHeight, treeWidth float64
+		var treeMeta map[string]float64
+
+		switch treeType {
+		case "pine":
+			treeHeight = 3.0 + rng.Float64()*2.0
+			treeWidth = 0.8 + rng.Float64()*0.4
+			treeMeta = map[string]float64{
+				"atmosphere.fear":       0.3 + rng.Float64()*0.3,
+				"atmosphere.ominous":    0.2 + rng.Float64()*0.4,
+				"visuals.distorted":     rng.Float64() * 0.5,
+				"visuals.dark":          0.3 + rng.Float64()*0.4,
+				"conditions.silhouette": 0.2 + rng.Float64()*0.7,
 			}
 
-			// Mark position as occupied
-			occupiedPositions[posKey] = true
-
-			// Определяем тип камня и размер
-			rockSize := 0.5 + pg.noiseGen.RandomFloat()*1.5
-			rockType := "rock"
-
-			// Модификации на основе региона
-			if region == "mountain_peak" || region == "mountains" || region == "rocky_hills" {
-				// Больше и разнообразнее камни в горах
-				rockSize = 1.0 + pg.noiseGen.RandomFloat()*2.0
-
-				if rand.Float64() < 0.3 {
-					rockType = "boulder"
-				}
-			} else if region == "ravine" {
-				// В ущельях больше узких и острых камней
-				rockType = "sharp_rock"
-				rockSize = 0.7 + pg.noiseGen.RandomFloat()*1.2
-			} else if region == "path" || region == "clearing" {
-				// На тропах и полянах меньше камней
-				if rand.Float64() < 0.7 {
-					continue // 70% шанс пропустить камень
-				}
-
-				// Небольшие камни
-				rockSize = 0.3 + pg.noiseGen.RandomFloat()*0.5
+		case "dead_tree":
+			treeHeight = 2.5 + rng.Float64()*1.5
+			treeWidth = 0.6 + rng.Float64()*0.3
+			treeMeta = map[string]float64{
+				"atmosphere.fear":       0.5 + rng.Float64()*0.3,
+				"atmosphere.ominous":    0.4 + rng.Float64()*0.4,
+				"atmosphere.dread":      0.3 + rng.Float64()*0.4,
+				"visuals.distorted":     0.2 + rng.Float64()*0.3,
+				"visuals.dark":          0.5 + rng.Float64()*0.3,
+				"conditions.silhouette": 0.5 + rng.Float64()*0.5,
 			}
 
-			// Метаданные для камней
-			rockMeta := map[string]float64{
-				"atmosphere.ominous": 0.1 + pg.noiseGen.RandomFloat()*0.3,
-				"visuals.rough":      0.4 + pg.noiseGen.RandomFloat()*0.4,
-				"conditions.shadow":  0.3 + pg.noiseGen.RandomFloat()*0.3,
+		case "twisted_tree":
+			treeHeight = 2.0 + rng.Float64()*2.5
+			treeWidth = 0.7 + rng.Float64()*0.5
+			treeMeta = map[string]float64{
+				"atmosphere.fear":       0.4 + rng.Float64()*0.4,
+				"atmosphere.ominous":    0.5 + rng.Float64()*0.3,
+				"atmosphere.dread":      0.4 + rng.Float64()*0.3,
+				"visuals.distorted":     0.4 + rng.Float64()*0.4,
+				"visuals.twisted":       0.6 + rng.Float64()*0.4,
+				"conditions.silhouette": 0.4 + rng.Float64()*0.4,
 			}
 
-			// Специфические метаданные для разных типов камней
-			if rockType == "boulder" {
-				rockMeta["atmosphere.dread"] = 0.2 + pg.noiseGen.RandomFloat()*0.2
-				rockMeta["visuals.dark"] = 0.3 + pg.noiseGen.RandomFloat()*0.3
-			} else if rockType == "sharp_rock" {
-				rockMeta["atmosphere.tension"] = 0.3 + pg.noiseGen.RandomFloat()*0.3
-				rockMeta["visuals.distorted"] = 0.2 + pg.noiseGen.RandomFloat()*0.2
+		case "small_pine":
+			treeHeight = 1.5 + rng.Float64()*1.0
+			treeWidth = 0.6 + rng.Float64()*0.3
+			treeMeta = map[string]float64{
+				"atmosphere.fear":       0.2 + rng.Float64()*0.2,
+				"atmosphere.ominous":    0.1 + rng.Float64()*0.3,
+				"visuals.dark":          0.2 + rng.Float64()*0.3,
+				"conditions.silhouette": 0.1 + rng.Float64()*0.5,
 			}
 
-			// Create a rock object
-			rock := &ProceduralObject{
-				ID:       len(pg.currentScene.Objects) + 1,
-				Type:     "rock",
-				Position: Vector3{X: x, Y: elevation * 20, Z: z},
-				Scale:    Vector3{X: rockSize, Y: rockSize * 0.7, Z: rockSize},
-				Rotation: Vector3{X: pg.noiseGen.RandomFloat() * 0.3, Y: pg.noiseGen.RandomFloat() * 2 * math.Pi, Z: pg.noiseGen.RandomFloat() * 0.3},
-				Metadata: rockMeta,
-				Seed:     pg.currentScene.Seed + int64(i+1000), // Different seed range than trees
+		case "bush":
+			treeHeight = 0.7 + rng.Float64()*0.5
+			treeWidth = 0.8 + rng.Float64()*0.4
+			treeMeta = map[string]float64{
+				"atmosphere.fear": 0.1 + rng.Float64()*0.2,
+				"visuals.dark":    0.2 + rng.Float64()*0.2,
 			}
-
-			pg.currentScene.Objects = append(pg.currentScene.Objects, rock)
 		}
-	}
 
-	// Генерируем странные объекты
-	numStrange := int(biomeParams.StrangeDensity * 10)
-
-	for i := 0; i < numStrange; i++ {
-		// Выбираем позицию для странного объекта
-		// Стараемся поместить их в места, которые усилят атмосферу страха
+		// Создаем дерево с соответствующими параметрами
+		tree := &ProceduralObject{
+			ID:       pg.currentScene.NextObjectID(),
+			Type:     "tree",
+			Position: Vector3{X: x, Y: elevation * 20, Z: z}, // Scale elevation
+			Scale:    Vector3{X: treeWidth, Y: treeHeight, Z: treeWidth},
+			Rotation: Vector3{X: 0, Y: rng.Float64() * 2 * math.Pi, Z: 0},
+			Metadata: treeMeta,
+			Seed:     pg.currentScene.Seed + int64(i),
+		}
 
-		// Изначально случайная позиция
-		x := pg.noiseGen.RandomFloat()*float64(terrainWidth) - float64(terrainWidth)/2
-		z := pg.noiseGen.RandomFloat()*float64(terrainHeight) - float64(terrainHeight)/2
+		// Добавляем случайный наклон для некоторых деревьев
+		if treeType == "twisted_tree" || treeType == "dead_tree" {
+			tree.Rotation.X = (rng.Float64()*2.0 - 1.0) * 0.2 // Наклон до 0.2 радиан
+			tree.Rotation.Z = (rng.Float64()*2.0 - 1.0) * 0.2
+		}
 
-		// Если возможно, помещаем их в зловещие регионы
-		darkRegions := []string{"swamp", "dense_forest", "ravine", "swamp_pit"}
+		pg.currentScene.AddObject(tree)
+	}
 
-		// Пытаемся найти подходящий регион
-		attempts := 0
-		maxAttempts := 10
+	// Генерируем камни
+	rockSpacing := math.Sqrt(1000/math.Max(biomeParams.RockDensity, 0.1)) * 0.8
+	rockPoints := scatter.Sample(ScatterLayer{
+		MinRadius: rockSpacing * 0.45,
+		MaxRadius: rockSpacing * 1.6,
+		Footprint: 1.5,
+		Density:   pg.rockDensityAt,
+	})
 
-		for attempts < maxAttempts {
-			terrainX := int(x + float64(terrainWidth)/2)
-			terrainZ := int(z + float64(terrainHeight)/2)
+	for i, point := range rockPoints {
+		x, z := point.X, point.Z
+		terrainX, terrainZ := terrain.cellAt(x, z)
+		elevation := terrain.Data[terrainZ][terrainX]
+		region := terrain.Regions[terrainZ][terrainX]
 
-			if terrainX >= 0 && terrainX < terrainWidth && terrainZ >= 0 && terrainZ < terrainHeight {
-				region := pg.currentScene.Terrain.Regions[terrainZ][terrainX]
+		// Определяем тип камня и размер
+		rockSize := 0.5 + rng.Float64()*1.5
+		rockType := "rock"
 
-				// Проверяем, подходит ли регион
-				isGoodRegion := false
-				for _, darkRegion := range darkRegions {
-					if region == darkRegion {
-						isGoodRegion = true
-						break
-					}
-				}
+		// Модификации на основе региона
+		if region == "mountain_peak" || region == "mountains" || region == "rocky_hills" {
+			// Больше и разнообразнее камни в горах
+			rockSize = 1.0 + rng.Float64()*2.0
 
-				if isGoodRegion {
-					break // Нашли хорошее место
-				}
+			if rng.Float64() < 0.3 {
+				rockType = "boulder"
 			}
-
-			// Пробуем новую случайную позицию
-			x = pg.noiseGen.RandomFloat()*float64(terrainWidth) - float64(terrainWidth)/2
-			z = pg.noiseGen.RandomFloat()*float64(terrainHeight) - float64(terrainHeight)/2
-			attempts++
+		} else if region == "ravine" {
+			// В ущельях больше узких и острых камней
+			rockType = "sharp_rock"
+			rockSize = 0.7 + rng.Float64()*1.2
+		} else if region == "path" || region == "clearing" {
+			// На тропах и полянах небольшие камни
+			rockSize = 0.3 + rng.Float64()*0.5
 		}
 
-		terrainX := int(x + float64(terrainWidth)/2)
-		terrainZ := int(z + float64(terrainHeight)/2)
-
-		// Проверяем, что координаты в пределах ландшафта
-		if terrainX < 0 || terrainX >= terrainWidth || terrainZ < 0 || terrainZ >= terrainHeight {
-			continue
+		// Метаданные для камней
+		rockMeta := map[string]float64{
+			"atmosphere.ominous": 0.1 + rng.Float64()*0.3,
+			"visuals.rough":      0.4 + rng.Float64()*0.4,
+			"conditions.shadow":  0.3 + rng.Float64()*0.3,
 		}
 
-		elevation := pg.currentScene.Terrain.Data[terrainZ][terrainX]
-
-		// Проверяем, что объект не будет в воде
-		if pg.currentScene.Terrain.Materials[terrainZ][terrainX] == 1 {
-			continue
+		// Специфические метаданные для разных типов камней
+		if rockType == "boulder" {
+			rockMeta["atmosphere.dread"] = 0.2 + rng.Float64()*0.2
+			rockMeta["visuals.dark"] = 0.3 + rng.Float64()*0.3
+		} else if rockType == "sharp_rock" {
+			rockMeta["atmosphere.tension"] = 0.3 + rng.Float64()*0.3
+			rockMeta["visuals.distorted"] = 0.2 + rng.Float64()*0.2
 		}
 
-		// Check if position is already occupied
-		posKey := fmt.Sprintf("%d,%d", terrainX, terrainZ)
-		if occupiedPositions[posKey] {
-			continue // Skip if position is already occupied
+		// Create a rock object
+		rock := &ProceduralObject{
+			ID:       pg.currentScene.NextObjectID(),
+			Type:     "rock",
+			Position: Vector3{X: x, Y: elevation * 20, Z: z},
+			Scale:    Vector3{X: rockSize, Y: rockSize * 0.7, Z: rockSize},
+			Rotation: Vector3{X: rng.Float64() * 0.3, Y: rng.Float64() * 2 * math.Pi, Z: rng.Float64() * 0.3},
+			Metadata: rockMeta,
+			Seed:     pg.currentScene.Seed + int64(i+1000), // Different seed range than trees
 		}
 
-		// Mark position as occupied
-		occupiedPositions[posKey] = true
+		pg.currentScene.AddObject(rock)
+	}
+
+	// Генерируем странные объекты: разбрасываем редкие кандидаты
+	// (предпочтительно в зловещих регионах) и берем из них нужное количество
+	numStrange := int(biomeParams.StrangeDensity * 10)
+	strangePoints := scatter.Sample(ScatterLayer{
+		MinRadius: 20.0,
+		MaxRadius: 45.0,
+		Footprint: 2.0,
+		Density:   pg.strangeDensityAt,
+	})
+	rng.Shuffle(len(strangePoints), func(a, b int) {
+		strangePoints[a], strangePoints[b] = strangePoints[b], strangePoints[a]
+	})
+	if len(strangePoints) > numStrange {
+		strangePoints = strangePoints[:numStrange]
+	}
+
+	for i, point := range strangePoints {
+		x, z := point.X, point.Z
+		terrainX, terrainZ := terrain.cellAt(x, z)
+		elevation := terrain.Data[terrainZ][terrainX]
 
 		// Выбираем тип странного объекта
 		strangeTypes := []string{"obelisk", "strange_tree", "anomaly", "ritual_stones"}
-		strangeType := strangeTypes[rand.Intn(len(strangeTypes))]
+		strangeType := strangeTypes[rng.Intn(len(strangeTypes))]
 
 		// Параметры объекта в зависимости от типа
 		var strangeSize, strangeHeight float64
 
 		switch strangeType {
 		case "obelisk":
-			strangeSize = 0.5 + pg.noiseGen.RandomFloat()*0.5
-			strangeHeight = 3.0 + pg.noiseGen.RandomFloat()*2.0
+			strangeSize = 0.5 + rng.Float64()*0.5
+			strangeHeight = 3.0 + rng.Float64()*2.0
 
 		case "strange_tree":
-			strangeSize = 0.8 + pg.noiseGen.RandomFloat()*0.8
-			strangeHeight = 4.0 + pg.noiseGen.RandomFloat()*3.0
+			strangeSize = 0.8 + rng.Float64()*0.8
+			strangeHeight = 4.0 + rng.Float64()*3.0
 
 		case "anomaly":
-			strangeSize = 1.0 + pg.noiseGen.RandomFloat()*1.5
+			strangeSize = 1.0 + rng.Float64()*1.5
 			strangeHeight = strangeSize
 
 		case "ritual_stones":
-			strangeSize = 1.2 + pg.noiseGen.RandomFloat()*0.8
-			strangeHeight = 1.5 + pg.noiseGen.RandomFloat()*1.0
+			strangeSize = 1.2 + rng.Float64()*0.8
+			strangeHeight = 1.5 + rng.Float64()*1.0
 		}
 
 		// Метаданные для странных объектов - высокие значения страха и неестественности
 		strangeMeta := map[string]float64{
-			"atmosphere.fear":       0.7 + pg.noiseGen.RandomFloat()*0.3,
-			"atmosphere.dread":      0.8 + pg.noiseGen.RandomFloat()*0.2,
-			"visuals.distorted":     0.6 + pg.noiseGen.RandomFloat()*0.4,
-			"visuals.twisted":       0.7 + pg.noiseGen.RandomFloat()*0.3,
-			"conditions.silhouette": 0.8 + pg.noiseGen.RandomFloat()*0.2,
-			"conditions.unnatural":  0.9 + pg.noiseGen.RandomFloat()*0.1,
+			"atmosphere.fear":       0.7 + rng.Float64()*0.3,
+			"atmosphere.dread":      0.8 + rng.Float64()*0.2,
+			"visuals.distorted":     0.6 + rng.Float64()*0.4,
+			"visuals.twisted":       0.7 + rng.Float64()*0.3,
+			"conditions.silhouette": 0.8 + rng.Float64()*0.2,
+			"conditions.unnatural":  0.9 + rng.Float64()*0.1,
 		}
 
 		// Создаем странный объект
 		strange := &ProceduralObject{
-			ID:       len(pg.currentScene.Objects) + 1,
+			ID:       pg.currentScene.NextObjectID(),
 			Type:     "strange",
 			Position: Vector3{X: x, Y: elevation * 20, Z: z},
 			Scale:    Vector3{X: strangeSize, Y: strangeHeight, Z: strangeSize},
-			Rotation: Vector3{X: 0, Y: pg.noiseGen.RandomFloat() * 2 * math.Pi, Z: 0},
+			Rotation: Vector3{X: 0, Y: rng.Float64() * 2 * math.Pi, Z: 0},
 			Metadata: strangeMeta,
 			Seed:     pg.currentScene.Seed + int64(i+5000),
 		}
 
-		pg.currentScene.Objects = append(pg.currentScene.Objects, strange)
+		pg.currentScene.AddObject(strange)
 	}
 }
 
@@ -1679,20 +1633,13 @@ func (pg *ProceduralGenerator) evolveScene() {
 	pg.modifyObjects()
 
 	// Occasionally add new objects or remove existing ones
-	if pg.noiseGen.RandomFloat() < 0.1 { // 10% chance each evolution cycle
-		if pg.noiseGen.RandomFloat() < 0.5 {
+	if pg.evolutionRng.Float64() < 0.1 { // 10% chance each evolution cycle
+		if pg.evolutionRng.Float64() < 0.5 {
 			pg.addRandomObject()
 		} else {
 			pg.removeRandomObject()
 		}
 	}
-
-	// Случайно обновляем уровень страха
-	if pg.noiseGen.RandomFloat() < 0.05 { // 5% chance
-		// Небольшие случайные колебания для естественности
-		randomChange := (pg.noiseGen.RandomFloat()*2.0 - 1.0) * 0.1 // ±0.1
-		pg.currentScene.LevelOfFear = util.Clamp(pg.currentScene.LevelOfFear+randomChange, 0.1, 1.0)
-	}
 }
 
 // updateWeather updates the weather conditions
@@ -1719,7 +1666,7 @@ func (pg *ProceduralGenerator) updateWeather() {
 	pg.currentScene.Weather["mist"] = pg.currentScene.Weather["fog"] * 0.7
 
 	// Обновляем ветер - случайные порывы
-	if pg.noiseGen.RandomFloat() < 0.2 { // 20% шанс изменения ветра
+	if pg.evolutionRng.Float64() < 0.2 { // 20% шанс изменения ветра
 		// Базовый ветер + случайные порывы
 		baseWind := 0.3 + randomFactor*0.3
 
@@ -1741,16 +1688,17 @@ func (pg *ProceduralGenerator) modifyObjects() {
 	// Modify a random subset of objects
 	for _, obj := range pg.currentScene.Objects {
 		// Only modify with a small chance
-		if pg.noiseGen.RandomFloat() < 0.05 { // 5% chance per object
+		if pg.evolutionRng.Float64() < 0.05 { // 5% chance per object
 			// Slightly modify metadata
-			for key, value := range obj.Metadata {
+			// Keys are visited in sorted order so the random stream is consumed deterministically
+			for _, key := range sortedKeys(obj.Metadata) {
 				// Add small random variation
-				delta := (pg.noiseGen.RandomFloat()*0.2 - 0.1) // -0.1 to +0.1
-				obj.Metadata[key] = math.Max(0.0, math.Min(1.0, value+delta))
+				delta := (pg.evolutionRng.Float64()*0.2 - 0.1) // -0.1 to +0.1
+				obj.Metadata[key] = math.Max(0.0, math.Min(1.0, obj.Metadata[key]+delta))
 			}
 
 			// Occasionally add a completely new metadata property
-			if pg.noiseGen.RandomFloat() < 0.1 { // 10% chance
+			if pg.evolutionRng.Float64() < 0.1 { // 10% chance
 				// Choose a random metadata property
 				categories := []string{"atmosphere", "visuals", "conditions"}
 				properties := map[string][]string{
@@ -1759,24 +1707,29 @@ func (pg *ProceduralGenerator) modifyObjects() {
 					"conditions": {"fog", "shadow", "silhouette", "darkness"},
 				}
 
-				category := categories[int(pg.noiseGen.RandomFloat()*float64(len(categories)))]
+				category := categories[int(pg.evolutionRng.Float64()*float64(len(categories)))]
 				propertyList := properties[category]
-				property := propertyList[int(pg.noiseGen.RandomFloat()*float64(len(propertyList)))]
+				property := propertyList[int(pg.evolutionRng.Float64()*float64(len(propertyList)))]
 
 				// Set the property with a random value
 				key := category + "." + property
 				if _, exists := obj.Metadata[key]; !exists {
-					obj.Metadata[key] = pg.noiseGen.RandomFloat()
+					obj.Metadata[key] = pg.evolutionRng.Float64()
 				}
 			}
 
 			// Для странных объектов и деревьев иногда меняем положение
-			if obj.Type == "strange" || obj.Type == "tree" {
-				if pg.noiseGen.RandomFloat() < 0.05 { // 5% шанс
+			if pg.config.GazeAwareEvolution {
+				// Только пока игрок не смотрит
+				pg.evolveUnseen(obj)
+			} else if obj.Type == "strange" || obj.Type == "tree" {
+				if pg.evolutionRng.Float64() < 0.05 { // 5% шанс
 					// Небольшое случайное смещение
 					offsetRange := 0.5
-					obj.Position.X += (pg.noiseGen.RandomFloat()*2.0 - 1.0) * offsetRange
-					obj.Position.Z += (pg.noiseGen.RandomFloat()*2.0 - 1.0) * offsetRange
+					position := obj.Position
+					position.X += (pg.evolutionRng.Float64()*2.0 - 1.0) * offsetRange
+					position.Z += (pg.evolutionRng.Float64()*2.0 - 1.0) * offsetRange
+					pg.currentScene.MoveObject(obj, position)
 				}
 			}
 		}
@@ -1791,15 +1744,15 @@ func (pg *ProceduralGenerator) addRandomObject() {
 
 	// Decide on object type
 	objectTypes := []string{"tree", "rock", "stump", "strange"}
-	objectType := objectTypes[int(pg.noiseGen.RandomFloat()*float64(len(objectTypes)))]
+	objectType := objectTypes[int(pg.evolutionRng.Float64()*float64(len(objectTypes)))]
 
 	// Get terrain dimensions
 	terrainWidth := pg.currentScene.Terrain.Width
 	terrainHeight := pg.currentScene.Terrain.Height
 
 	// Pick a random position
-	x := pg.noiseGen.RandomFloat()*float64(terrainWidth) - float64(terrainWidth)/2
-	z := pg.noiseGen.RandomFloat()*float64(terrainHeight) - float64(terrainHeight)/2
+	x := pg.evolutionRng.Float64()*float64(terrainWidth) - float64(terrainWidth)/2
+	z := pg.evolutionRng.Float64()*float64(terrainHeight) - float64(terrainHeight)/2
 
 	// Find the terrain height at this position
 	terrainX := int(x + float64(terrainWidth)/2)
@@ -1817,6 +1770,11 @@ func (pg *ProceduralGenerator) addRandomObject() {
 		return
 	}
 
+	// Не ставим объекты внутри построек и у входов в пещеры
+	if pg.isInsideStructure(x, z, 0.5) || pg.isNearCave(x, z, 0.5) {
+		return
+	}
+
 	// Проверяем, что позиция не занята другим объектом
 	for _, obj := range pg.currentScene.Objects {
 		dist := math.Sqrt(math.Pow(obj.Position.X-x, 2) + math.Pow(obj.Position.Z-z, 2))
@@ -1830,78 +1788,83 @@ func (pg *ProceduralGenerator) addRandomObject() {
 
 	switch objectType {
 	case "tree":
-		height := 2.0 + pg.noiseGen.RandomFloat()*3.0
+		height := 2.0 + pg.evolutionRng.Float64()*3.0
 		newObject = &ProceduralObject{
-			ID:       len(pg.currentScene.Objects) + 1,
+			ID:       pg.currentScene.NextObjectID(),
 			Type:     "tree",
 			Position: Vector3{X: x, Y: elevation * 20, Z: z},
 			Scale:    Vector3{X: 1.0, Y: height, Z: 1.0},
-			Rotation: Vector3{X: 0, Y: pg.noiseGen.RandomFloat() * 2 * math.Pi, Z: 0},
+			Rotation: Vector3{X: 0, Y: pg.evolutionRng.Float64() * 2 * math.Pi, Z: 0},
 			Metadata: map[string]float64{
-				"atmosphere.fear":       0.3 + pg.noiseGen.RandomFloat()*0.3,
-				"atmosphere.ominous":    0.2 + pg.noiseGen.RandomFloat()*0.4,
-				"visuals.distorted":     pg.noiseGen.RandomFloat() * 0.5,
-				"visuals.dark":          0.3 + pg.noiseGen.RandomFloat()*0.4,
-				"conditions.silhouette": 0.2 + pg.noiseGen.RandomFloat()*0.7,
+				"atmosphere.fear":       0.3 + pg.evolutionRng.Float64()*0.3,
+				"atmosphere.ominous":    0.2 + pg.evolutionRng.Float64()*0.4,
+				"visuals.distorted":     pg.evolutionRng.Float64() * 0.5,
+				"visuals.dark":          0.3 + pg.evolutionRng.Float64()*0.4,
+				"conditions.silhouette": 0.2 + pg.evolutionRng.Float64()*0.7,
 			},
 			Seed: pg.currentScene.Seed + int64(len(pg.currentScene.Objects)),
 		}
 
 	case "rock":
-		size := 0.5 + pg.noiseGen.RandomFloat()*1.5
+		size := 0.5 + pg.evolutionRng.Float64()*1.5
 		newObject = &ProceduralObject{
-			ID:       len(pg.currentScene.Objects) + 1,
+			ID:       pg.currentScene.NextObjectID(),
 			Type:     "rock",
 			Position: Vector3{X: x, Y: elevation * 20, Z: z},
 			Scale:    Vector3{X: size, Y: size, Z: size},
-			Rotation: Vector3{X: pg.noiseGen.RandomFloat(), Y: pg.noiseGen.RandomFloat() * 2 * math.Pi, Z: pg.noiseGen.RandomFloat()},
+			Rotation: Vector3{X: pg.evolutionRng.Float64(), Y: pg.evolutionRng.Float64() * 2 * math.Pi, Z: pg.evolutionRng.Float64()},
 			Metadata: map[string]float64{
-				"atmosphere.ominous": 0.1 + pg.noiseGen.RandomFloat()*0.3,
-				"visuals.rough":      0.4 + pg.noiseGen.RandomFloat()*0.4,
-				"conditions.shadow":  0.3 + pg.noiseGen.RandomFloat()*0.3,
+				"atmosphere.ominous": 0.1 + pg.evolutionRng.Float64()*0.3,
+				"visuals.rough":      0.4 + pg.evolutionRng.Float64()*0.4,
+				"conditions.shadow":  0.3 + pg.evolutionRng.Float64()*0.3,
 			},
 			Seed: pg.currentScene.Seed + int64(len(pg.currentScene.Objects)),
 		}
 
 	case "stump":
 		newObject = &ProceduralObject{
-			ID:       len(pg.currentScene.Objects) + 1,
+			ID:       pg.currentScene.NextObjectID(),
 			Type:     "stump",
 			Position: Vector3{X: x, Y: elevation * 20, Z: z},
 			Scale:    Vector3{X: 0.8, Y: 0.5, Z: 0.8},
-			Rotation: Vector3{X: 0, Y: pg.noiseGen.RandomFloat() * 2 * math.Pi, Z: 0},
+			Rotation: Vector3{X: 0, Y: pg.evolutionRng.Float64() * 2 * math.Pi, Z: 0},
 			Metadata: map[string]float64{
-				"atmosphere.dread":    0.4 + pg.noiseGen.RandomFloat()*0.4,
-				"visuals.decay":       0.5 + pg.noiseGen.RandomFloat()*0.3,
-				"conditions.darkness": 0.3 + pg.noiseGen.RandomFloat()*0.3,
+				"atmosphere.dread":    0.4 + pg.evolutionRng.Float64()*0.4,
+				"visuals.decay":       0.5 + pg.evolutionRng.Float64()*0.3,
+				"conditions.darkness": 0.3 + pg.evolutionRng.Float64()*0.3,
 			},
 			Seed: pg.currentScene.Seed + int64(len(pg.currentScene.Objects)),
 		}
 
 	case "strange":
 		// This is a special, more scary object that appears rarely
-		size := 0.5 + pg.noiseGen.RandomFloat()
+		size := 0.5 + pg.evolutionRng.Float64()
 		newObject = &ProceduralObject{
-			ID:       len(pg.currentScene.Objects) + 1,
+			ID:       pg.currentScene.NextObjectID(),
 			Type:     "strange",
 			Position: Vector3{X: x, Y: elevation * 20, Z: z},
 			Scale:    Vector3{X: size, Y: size * 3, Z: size},
-			Rotation: Vector3{X: 0, Y: pg.noiseGen.RandomFloat() * 2 * math.Pi, Z: 0},
+			Rotation: Vector3{X: 0, Y: pg.evolutionRng.Float64() * 2 * math.Pi, Z: 0},
 			Metadata: map[string]float64{
-				"atmosphere.fear":       0.7 + pg.noiseGen.RandomFloat()*0.3,
-				"atmosphere.dread":      0.8 + pg.noiseGen.RandomFloat()*0.2,
-				"visuals.distorted":     0.6 + pg.noiseGen.RandomFloat()*0.4,
-				"visuals.twisted":       0.7 + pg.noiseGen.RandomFloat()*0.3,
-				"conditions.silhouette": 0.8 + pg.noiseGen.RandomFloat()*0.2,
-				"conditions.unnatural":  0.9 + pg.noiseGen.RandomFloat()*0.1,
+				"atmosphere.fear":       0.7 + pg.evolutionRng.Float64()*0.3,
+				"atmosphere.dread":      0.8 + pg.evolutionRng.Float64()*0.2,
+				"visuals.distorted":     0.6 + pg.evolutionRng.Float64()*0.4,
+				"visuals.twisted":       0.7 + pg.evolutionRng.Float64()*0.3,
+				"conditions.silhouette": 0.8 + pg.evolutionRng.Float64()*0.2,
+				"conditions.unnatural":  0.9 + pg.evolutionRng.Float64()*0.1,
 			},
 			Seed: pg.currentScene.Seed + int64(len(pg.currentScene.Objects)),
 		}
 	}
 
+	// Появляется только там, куда игрок не смотрит
+	if newObject != nil && pg.config.GazeAwareEvolution && !pg.unseenObject(newObject) {
+		return
+	}
+
 	// Add the new object to the scene
 	if newObject != nil {
-		pg.currentScene.Objects = append(pg.currentScene.Objects, newObject)
+		pg.currentScene.AddObject(newObject)
 	}
 }
 
@@ -1912,13 +1875,76 @@ func (pg *ProceduralGenerator) removeRandomObject() {
 	}
 
 	// Choose a random object to remove
-	indexToRemove := int(pg.noiseGen.RandomFloat() * float64(len(pg.currentScene.Objects)))
+	indexToRemove := int(pg.evolutionRng.Float64() * float64(len(pg.currentScene.Objects)))
+
+	// Постройки не исчезают по частям, существа не исчезают вовсе
+	if obj := pg.currentScene.Objects[indexToRemove]; obj.StructureID != 0 || obj.Type == stalkerObjectType {
+		return
+	}
+
+	// На глазах у игрока ничего не исчезает
+	if pg.config.GazeAwareEvolution && !pg.unseenObject(pg.currentScene.Objects[indexToRemove]) {
+		return
+	}
 
 	// Remove the object
-	pg.currentScene.Objects = append(
-		pg.currentScene.Objects[:indexToRemove],
-		pg.currentScene.Objects[indexToRemove+1:]...,
-	)
+	pg.currentScene.RemoveObjectAt(indexToRemove)
+}
+
+// AddObserver подписывает наблюдателя на изменения объектов сцены
+func (scene *ProceduralScene) AddObserver(observer SceneObserver) {
+	for _, existing := range scene.observers {
+		if existing == observer {
+			return
+		}
+	}
+	scene.observers = append(scene.observers, observer)
+}
+
+// NextObjectID выдает уникальный ID для нового объекта сцены.
+// В отличие от len(Objects)+1, ID не повторяются после удаления объектов
+func (scene *ProceduralScene) NextObjectID() int {
+	scene.nextObjectID++
+	return scene.nextObjectID
+}
+
+// AddObject добавляет объект в сцену и индекс и уведомляет наблюдателей
+func (scene *ProceduralScene) AddObject(obj *ProceduralObject) {
+	scene.Objects = append(scene.Objects, obj)
+	if scene.Index != nil {
+		scene.Index.Insert(obj)
+	}
+	for _, observer := range scene.observers {
+		observer.ObjectAdded(obj)
+	}
+}
+
+// RemoveObjectAt удаляет объект с указанным индексом из сцены и индекса и уведомляет наблюдателей
+func (scene *ProceduralScene) RemoveObjectAt(index int) {
+	if index < 0 || index >= len(scene.Objects) {
+		return
+	}
+
+	obj := scene.Objects[index]
+	scene.Objects = append(scene.Objects[:index], scene.Objects[index+1:]...)
+	if scene.Index != nil {
+		scene.Index.Remove(obj)
+	}
+	for _, observer := range scene.observers {
+		observer.ObjectRemoved(obj)
+	}
+}
+
+// MoveObject переносит объект в новую позицию, обновляет индекс и уведомляет наблюдателей
+func (scene *ProceduralScene) MoveObject(obj *ProceduralObject, position Vector3) {
+	from := obj.Position
+	obj.Position = position
+	if scene.Index != nil {
+		scene.Index.Update(obj)
+	}
+	for _, observer := range scene.observers {
+		observer.ObjectMoved(obj, from)
+	}
 }
 
 // clamp ограничивает значение указанным диапазоном
@@ -88,8 +88,8 @@ func (pag *ProceduralAudioGenerator) generateAmbient(samples []float32, metadata
 	// LFO (Low Frequency Oscillator) rate
 	lfoRate := 0.1 + tension*0.4 // 0.1-0.5 Hz
 
-	// Wind noise amount (increased by fog)
-	windAmount := 0.1 + fog*0.3
+	// Wind noise amount (increased by fog, muted in enclosed spaces like caves)
+	windAmount := (0.1 + fog*0.3) * (1.0 - getMetadataValue(metadata, "conditions.enclosed", 0.0))
 
 	for i := 0; i < numSamples; i++ {
 		time := float64(i) / float64(pag.sampleRate)
@@ -136,10 +136,12 @@ func (pag *ProceduralAudioGenerator) generateFootstep(samples []float32, metadat
 	// Determine surface type based on metadata
 	wetness := getMetadataValue(metadata, "conditions.wetness", 0.0)
 	material := getMetadataValue(metadata, "surface.hardness", 0.5)
+	loudness := getMetadataValue(metadata, "player.noise", 0.4)
 
 	// Attack and decay times
 	attackTime := 0.01              // 10 milliseconds
 	decayTime := 0.1 + material*0.2 // Harder surfaces have shorter decay
+	decayTime *= 0.6 + loudness     // Heavy running steps ring longer than sneaking ones
 
 	// Main frequency
 	baseFreq := 100.0 + material*200.0 // 100-300 Hz
@@ -176,7 +178,7 @@ func (pag *ProceduralAudioGenerator) generateFootstep(samples []float32, metadat
 		// Add crunch for some surfaces
 		crunch := 0.0
 		if material < 0.3 { // Soft materials like leaves or gravel
-			crunch = (ng.RandomFloat()*2.0 - 1.0) * envelope * 0.7
+			crunch = (ng.RandomFloat()*2.0 - 1.0) * envelope * (0.3 + 0.6*loudness)
 		}
 
 		// Combine all components
@@ -184,31 +184,27 @@ func (rt *Raytracer) RotateCamera(yawDelta, pitchDelta float64) {
 	rt.mutex.Lock()
 	defer rt.mutex.Unlock()
 
-	// Обновляем углы камеры
-	rt.camera.Yaw += yawDelta
-	rt.camera.Pitch += pitchDelta
-
 	// Ограничиваем углы наклона (pitch), чтобы не перевернуть камеру
 	const maxPitch = math.Pi/2.0 - 0.1 // Немного меньше 90 градусов
-	if rt.camera.Pitch > maxPitch {
-		rt.camera.Pitch = maxPitch
-	} else if rt.camera.Pitch < -maxPitch {
-		rt.camera.Pitch = -maxPitch
-	}
+	pitch := math.Max(-maxPitch, math.Min(maxPitch, rt.camera.Pitch+pitchDelta))
 
-	// Вычисляем новые векторы направления
-	// Сначала вычисляем вектор направления (Forward)
-	rt.camera.Forward.X = math.Cos(rt.camera.Pitch) * math.Sin(rt.camera.Yaw)
-	rt.camera.Forward.Y = math.Sin(rt.camera.Pitch)
-	rt.camera.Forward.Z = math.Cos(rt.camera.Pitch) * math.Cos(rt.camera.Yaw)
-	rt.camera.Forward = rt.camera.Forward.Normalize()
+	rt.setOrientation(rt.camera.Yaw+yawDelta, pitch)
+}
 
-	// Пересчитываем правый вектор (Right) как перпендикулярный Forward и мировому Up
-	worldUp := Vector3{X: 0, Y: 1, Z: 0}
-	rt.camera.Right = worldUp.Cross(rt.camera.Forward).Normalize()
+// SetCameraOrientation sets the camera yaw and pitch (in radians),
+// normally from CameraController
+func (rt *Raytracer) SetCameraOrientation(yaw, pitch float64) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+
+	rt.setOrientation(yaw, pitch)
+}
 
-	// Пересчитываем вектор Up как перпендикулярный Forward и Right
-	rt.camera.Up = rt.camera.Forward.Cross(rt.camera.Right).Normalize()
+// setOrientation обновляет углы и пересчитывает векторы камеры (тот же расчет, что у CameraController)
+func (rt *Raytracer) setOrientation(yaw, pitch float64) {
+	rt.camera.Yaw = yaw
+	rt.camera.Pitch = pitch
+	rt.camera.Forward, rt.camera.Right, rt.camera.Up = cameraBasis(yaw, pitch)
 }
 
 // TraceScene performs ray tracing for the entire scene
@@ -343,20 +339,33 @@ func (rt *Raytracer) trace(ray Ray) HitInfo {
 		// Проверяем пересечение с ландшафтом
 		terrainHit := rt.traceTerrainIntersection(ray)
 
+		// В пещерах ландшафт задан полем плотности: маршируем по нему
+		for _, cave := range rt.scene.Caves {
+			if caveHit, ok := rt.traceCaveIntersection(ray, cave, terrainHit.Distance); ok {
+				terrainHit = caveHit
+			}
+		}
+
 		// Если есть пересечение, обновляем информацию о ближайшем объекте
 		if terrainHit.ObjectID != -1 && terrainHit.Distance < hitInfo.Distance {
 			hitInfo = terrainHit
 		}
 
-		// Проверяем пересечение с объектами сцены
-		for _, obj := range rt.scene.Objects {
+		// Проверяем пересечение с объектами сцены: обходим ячейки индекса вдоль луча
+		// по порядку и останавливаемся, когда ячейки стали дальше ближайшего попадания
+		rt.scene.Index.QueryRay(ray, hitInfo.Distance, func(obj *ProceduralObject, enter float64) bool {
+			if enter > hitInfo.Distance {
+				return false
+			}
+
 			objHit := rt.traceObjectIntersection(ray, obj)
 
 			// Если есть пересечение и оно ближе текущего, обновляем
 			if objHit.ObjectID != -1 && objHit.Distance < hitInfo.Distance {
 				hitInfo = objHit
 			}
-		}
+			return true
+		})
 
 		// Добавляем эффект тумана
 		if fogAmount, ok := rt.scene.Weather["fog"]; ok && fogAmount > 0 {
@@ -380,6 +389,90 @@ func (rt *Raytracer) trace(ray Ray) HitInfo {
 	return hitInfo
 }
 
+// sightMarchStep - шаг проверки земли вдоль линии видимости, м
+const sightMarchStep = 0.5
+
+// Visible проверяет прямую видимость между точками: линию не перекрывают
+// земля, порода пещер и объекты сцены (стволы и кроны деревьев, камни,
+// постройки). Объект с ID ignore (тот, кто смотрит) не учитывается.
+// Землю проверяем шагами по карте высот: для коротких отрезков над самой
+// землей пересечение с плоскостью, как в traceTerrainIntersection, слишком грубое
+func (rt *Raytracer) Visible(from, to Vector3, ignore int) bool {
+	if rt.scene == nil || rt.scene.Terrain == nil {
+		return true
+	}
+
+	delta := to.Sub(from)
+	distance := delta.Length()
+	if distance < 1e-6 {
+		return true
+	}
+	ray := Ray{Origin: from, Direction: delta.Mul(1 / distance)}
+	if rt.groundBlocks(ray, distance) {
+		return false
+	}
+
+	visible := true
+	rt.scene.Index.QueryRay(ray, distance, func(obj *ProceduralObject, enter float64) bool {
+		if obj.ID == ignore {
+			return true
+		}
+		if hit := rt.traceObjectIntersection(ray, obj); hit.ObjectID != -1 && hit.Distance < distance {
+			visible = false
+			return false
+		}
+		return true
+	})
+	return visible
+}
+
+// groundBlocks проверяет, упирается ли луч в землю или породу пещер
+// раньше, чем пройдет distance
+func (rt *Raytracer) groundBlocks(ray Ray, distance float64) bool {
+	terrain := rt.scene.Terrain
+	for t := sightMarchStep; t < distance; t += sightMarchStep {
+		p := ray.Origin.Add(ray.Direction.Mul(t))
+		if cave := rt.scene.CaveAt(p); cave != nil {
+			if cave.DensityAt(p) > 0 {
+				return true
+			}
+		} else if terrain.InBounds(p.X, p.Z) && p.Y < terrain.HeightAt(p.X, p.Z) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pick возвращает ближайший объект сцены, в который упирается луч из origin
+// вдоль direction не дальше maxDistance, и расстояние до него; nil, если
+// луч никуда не попал или раньше уперся в землю. Это тот же объект, что
+// виден в центре экрана (TracedPixel.ObjectID), но без отрисовки кадра,
+// поэтому Pick работает и в симуляции без окна
+func (rt *Raytracer) Pick(origin, direction Vector3, maxDistance float64) (*ProceduralObject, float64) {
+	if rt.scene == nil || rt.scene.Terrain == nil || rt.scene.Index == nil {
+		return nil, 0
+	}
+
+	ray := Ray{Origin: origin, Direction: direction.Normalize()}
+	var picked *ProceduralObject
+	nearest := maxDistance
+	rt.scene.Index.QueryRay(ray, maxDistance, func(obj *ProceduralObject, enter float64) bool {
+		if enter > nearest {
+			// Дальше ближе ничего не будет
+			return false
+		}
+		if hit := rt.traceObjectIntersection(ray, obj); hit.ObjectID != -1 && hit.Distance < nearest {
+			picked, nearest = obj, hit.Distance
+		}
+		return true
+	})
+
+	if picked == nil || rt.groundBlocks(ray, nearest) {
+		return nil, 0
+	}
+	return picked, nearest
+}
+
 // traceTerrainIntersection проверяет пересечение луча с ландшафтом
 func (rt *Raytracer) traceTerrainIntersection(ray Ray) HitInfo {
 	hitInfo := HitInfo{
@@ -510,6 +603,62 @@ func (rt *Raytracer) traceTerrainIntersection(ray Ray) HitInfo {
 	return hitInfo
 }
 
+// traceCaveIntersection маршем по полю плотности ищет пересечение луча с породой
+// внутри области пещеры. ok = true, если результат заменяет пересечение с картой
+// высот: луч попал в стенку пещеры или прошел сквозь вырезанную в склоне полость
+func (rt *Raytracer) traceCaveIntersection(ray Ray, cave *CaveVolume, terrainDistance float64) (HitInfo, bool) {
+	hitInfo := HitInfo{
+		Distance:   math.MaxFloat64,
+		ObjectID:   -1,
+		ObjectType: "cave",
+		MaterialID: 3, // Камень
+		Intensity:  0,
+	}
+
+	tNear, tFar, ok := rayBoxRange(ray, cave.Min, cave.Max)
+	if !ok || tNear > terrainDistance {
+		return hitInfo, false
+	}
+
+	length := math.Min(tFar, tNear+caveMaxTraceLength) - tNear
+	origin := ray.Origin.Add(ray.Direction.Mul(tNear))
+
+	t, found := cave.march(origin, ray.Direction, length, true)
+	if !found {
+		// Пересечение с картой высот внутри области - это вырезанная порода,
+		// поэтому продолжаем луч от выхода из области
+		if terrainDistance >= tNear && terrainDistance <= tFar {
+			exitRay := Ray{Origin: ray.Origin.Add(ray.Direction.Mul(tFar)), Direction: ray.Direction}
+			beyond := rt.traceTerrainIntersection(exitRay)
+			if beyond.ObjectID != -1 {
+				beyond.Distance += tFar
+			}
+			return beyond, true
+		}
+		return hitInfo, false
+	}
+
+	distance := tNear + t
+	if distance > terrainDistance && (terrainDistance < tNear || terrainDistance > tFar) {
+		return hitInfo, false
+	}
+
+	hitPoint := ray.Origin.Add(ray.Direction.Mul(distance))
+	normal := cave.NormalAt(hitPoint)
+
+	// Чем глубже в пещере, тем темнее стенки
+	intensity := computeTerrainIntensity(normal, hitInfo.MaterialID, rt.scene.TimeOfDay)
+	intensity *= 1.0 - 0.85*cave.ShadeAt(hitPoint.Add(normal.Mul(caveNormalEpsilon*2)))
+
+	hitInfo.Distance = distance
+	hitInfo.Position = hitPoint
+	hitInfo.Normal = normal
+	hitInfo.ObjectID = 0 // Пещера - часть ландшафта
+	hitInfo.Intensity = intensity
+
+	return hitInfo, true
+}
+
 // traceObjectIntersection проверяет пересечение луча с объектом сцены
 func (rt *Raytracer) traceObjectIntersection(ray Ray, obj *ProceduralObject) HitInfo {
 	hitInfo := HitInfo{
@@ -567,7 +716,55 @@ func (rt *Raytracer) traceObjectIntersection(ray Ray, obj *ProceduralObject) Hit
 			hitInfo.Intensity = 0.2 // Странные объекты темные
 		}
 
+	case stalkerObjectType:
+		// Сталкер - длинное худое тело и голова, почти черный силуэт
+		bodyHit := rt.traceCylinderIntersection(ray,
+			obj.Position,
+			obj.Position.Add(Vector3{X: 0, Y: obj.Scale.Y * 0.8, Z: 0}),
+			obj.Scale.X)
+		headHit := rt.traceSphereIntersection(ray,
+			obj.Position.Add(Vector3{X: 0, Y: obj.Scale.Y * 0.9, Z: 0}),
+			obj.Scale.X*0.6)
+
+		if bodyHit.ObjectID != -1 && (headHit.ObjectID == -1 || bodyHit.Distance < headHit.Distance) {
+			hitInfo = bodyHit
+		} else if headHit.ObjectID != -1 {
+			hitInfo = headHit
+		}
+		if hitInfo.ObjectID != -1 {
+			hitInfo.ObjectID = obj.ID
+			hitInfo.ObjectType = stalkerObjectType
+			hitInfo.Intensity = 0.05
+		}
+
+	case "standing_stone":
+		// Стоячие камни как вертикальные цилиндры
+		pillarHit := rt.traceCylinderIntersection(ray,
+			obj.Position,
+			obj.Position.Add(Vector3{X: 0, Y: obj.Scale.Y, Z: 0}),
+			(obj.Scale.X+obj.Scale.Z)/4)
+
+		if pillarHit.ObjectID != -1 {
+			hitInfo = pillarHit
+			hitInfo.ObjectID = obj.ID
+			hitInfo.ObjectType = obj.Type
+			hitInfo.Intensity = 0.5
+		}
+
 	default:
+		// Части построек - ориентированные боксы
+		if isBoxObjectType(obj.Type) {
+			boxHit := rt.traceBoxIntersection(ray, structureBoxCollider(obj))
+
+			if boxHit.ObjectID != -1 {
+				hitInfo = boxHit
+				hitInfo.ObjectID = obj.ID
+				hitInfo.ObjectType = obj.Type
+				hitInfo.Intensity *= 0.6 // Старое темное дерево и камень
+			}
+			break
+		}
+
 		// Для прочих объектов используем сферу
 		sphereHit := rt.traceSphereIntersection(ray, obj.Position, obj.Scale.X)
 
@@ -620,6 +817,75 @@ func (rt *Raytracer) traceSphereIntersection(ray Ray, center Vector3, radius flo
 	return hitInfo
 }
 
+// traceBoxIntersection проверяет пересечение луча с ориентированным боксом (метод слэбов)
+func (rt *Raytracer) traceBoxIntersection(ray Ray, box *BoxCollider) HitInfo {
+	hitInfo := HitInfo{
+		Distance:   math.MaxFloat64,
+		ObjectID:   -1,
+		MaterialID: 3, // Материал по умолчанию для бокса
+		Intensity:  0,
+	}
+
+	// Переводим луч в локальное пространство бокса
+	origin := box.worldToLocal(ray.Origin)
+	direction := box.worldToLocal(ray.Origin.Add(ray.Direction)).Sub(origin)
+
+	originAxes := [3]float64{origin.X, origin.Y, origin.Z}
+	dirAxes := [3]float64{direction.X, direction.Y, direction.Z}
+	extents := [3]float64{box.HalfExtent.X, box.HalfExtent.Y, box.HalfExtent.Z}
+
+	tNear := -math.MaxFloat64
+	tFar := math.MaxFloat64
+	nearAxis := 0
+	nearSign := 1.0
+
+	for axis := 0; axis < 3; axis++ {
+		if math.Abs(dirAxes[axis]) < 1e-9 {
+			// Луч параллелен слэбу - проверяем, что начало внутри
+			if originAxes[axis] < -extents[axis] || originAxes[axis] > extents[axis] {
+				return hitInfo
+			}
+			continue
+		}
+
+		t1 := (-extents[axis] - originAxes[axis]) / dirAxes[axis]
+		t2 := (extents[axis] - originAxes[axis]) / dirAxes[axis]
+		sign := -1.0
+		if t1 > t2 {
+			t1, t2 = t2, t1
+			sign = 1.0
+		}
+
+		if t1 > tNear {
+			tNear = t1
+			nearAxis = axis
+			nearSign = sign
+		}
+		tFar = math.Min(tFar, t2)
+
+		if tNear > tFar || tFar < 0.001 {
+			return hitInfo
+		}
+	}
+
+	if tNear <= 0.001 {
+		return hitInfo
+	}
+
+	// Нормаль грани, через которую вошел луч
+	localNormal := [3]float64{}
+	localNormal[nearAxis] = nearSign
+	normal := box.localToWorld(Vector3{X: localNormal[0], Y: localNormal[1], Z: localNormal[2]}).Sub(box.Center)
+
+	hitInfo.Distance = tNear
+	hitInfo.Position = ray.Origin.Add(ray.Direction.Mul(tNear))
+	hitInfo.Normal = normal.Normalize()
+	hitInfo.ObjectID = 1 // Временный ID
+	hitInfo.Intensity = math.Abs(hitInfo.Normal.Dot(ray.Direction.Mul(-1)))
+
+	return hitInfo
+}
+
 // traceCylinderIntersection проверяет пересечение луча с цилиндром
 func (rt *Raytracer) traceCylinderIntersection(ray Ray, baseCenter, topCenter Vector3, radius float64) HitInfo {
 	hitInfo := HitInfo{
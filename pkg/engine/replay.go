@@ -0,0 +1,330 @@
+package engine
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"nightmare/pkg/config"
+)
+
+// Формат файла записи: заголовок replayMagic + версия, дальше gzip-поток:
+// сид, частота тиков, конфиг и профиль страха в YAML и записи. Запись кадра - 13 байт,
+// в конце - число тиков и хэш состояния для проверки воспроизведения
+const (
+	replayMagic   = "NMREPLAY"
+	replayVersion = 1
+
+	replayRecordFrame = 1
+	replayRecordEnd   = 2
+
+	// replayFlushTicks - как часто сбрасывать запись на диск: если игра упадет,
+	// запись обрывается не больше чем на секунду раньше
+	replayFlushTicks = 60
+)
+
+// Флаги кадра
+const (
+	frameSprint = 1 << iota
+	frameCrouch
+	frameJump
+	frameInteract
+)
+
+// ReplayRecorder пишет ввод игры по тикам в файл
+type ReplayRecorder struct {
+	file   *os.File
+	gz     *gzip.Writer
+	ticks  uint64
+	err    error
+	buffer [13]byte
+}
+
+// NewReplayRecorder создает файл записи. В запись попадают сид мира, весь
+// конфиг и профиль страха на начало сессии (nil - новый игрок), чтобы
+// воспроизведение построило тот же мир и ИИ-режиссер принимал те же решения
+func NewReplayRecorder(path string, cfg *config.Config, seed int64, profile *FearProfile) (*ReplayRecorder, error) {
+	// Сид в конфиге должен быть настоящим, а не 0 (случайный)
+	recorded := *cfg
+	recorded.Procedural.Seed = seed
+	configData, err := config.Encode(&recorded)
+	if err != nil {
+		return nil, err
+	}
+
+	var profileData []byte
+	if profile != nil {
+		if profileData, err = EncodeFearProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create replay file: %v", err)
+	}
+
+	rr := &ReplayRecorder{file: file}
+
+	header := make([]byte, 0, len(replayMagic)+2)
+	header = append(header, replayMagic...)
+	header = binary.LittleEndian.AppendUint16(header, replayVersion)
+	if _, err := file.Write(header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write replay header: %v", err)
+	}
+
+	rr.gz = gzip.NewWriter(file)
+	rr.write(seed)
+	rr.write(float64(SimulationTickRate))
+	rr.write(uint32(len(configData)))
+	rr.write(configData)
+	rr.write(uint32(len(profileData)))
+	rr.write(profileData)
+
+	if rr.err != nil {
+		file.Close()
+		return nil, rr.err
+	}
+	return rr, nil
+}
+
+// write пишет значение в поток, запоминая первую ошибку
+func (rr *ReplayRecorder) write(v interface{}) {
+	if rr.err != nil {
+		return
+	}
+	if err := binary.Write(rr.gz, binary.LittleEndian, v); err != nil {
+		rr.err = fmt.Errorf("failed to write replay: %v", err)
+	}
+}
+
+// Record добавляет кадр ввода одного тика
+func (rr *ReplayRecorder) Record(frame InputFrame) error {
+	frame = frame.Quantize()
+
+	flags := byte(0)
+	if frame.Sprint {
+		flags |= frameSprint
+	}
+	if frame.Crouch {
+		flags |= frameCrouch
+	}
+	if frame.Jump {
+		flags |= frameJump
+	}
+	if frame.Interact {
+		flags |= frameInteract
+	}
+
+	b := rr.buffer[:]
+	b[0] = replayRecordFrame
+	b[1] = flags
+	b[2] = byte(quantizeAxis(frame.Forward))
+	b[3] = byte(quantizeAxis(frame.Strafe))
+	binary.LittleEndian.PutUint32(b[4:], math.Float32bits(float32(frame.Yaw)))
+	binary.LittleEndian.PutUint32(b[8:], math.Float32bits(float32(frame.Pitch)))
+	b[12] = quantizeLevel(frame.Voice)
+
+	rr.write(b)
+	rr.ticks++
+
+	if rr.err == nil && rr.ticks%replayFlushTicks == 0 {
+		if err := rr.gz.Flush(); err != nil {
+			rr.err = fmt.Errorf("failed to flush replay: %v", err)
+		}
+	}
+	return rr.err
+}
+
+// Ticks возвращает число записанных тиков
+func (rr *ReplayRecorder) Ticks() uint64 {
+	return rr.ticks
+}
+
+// Close завершает запись хэшем состояния симуляции (Simulation.StateHash)
+// после последнего записанного тика
+func (rr *ReplayRecorder) Close(stateHash uint64) error {
+	rr.write(byte(replayRecordEnd))
+	rr.write(rr.ticks)
+	rr.write(stateHash)
+
+	if err := rr.gz.Close(); err != nil && rr.err == nil {
+		rr.err = fmt.Errorf("failed to finish replay: %v", err)
+	}
+	if err := rr.file.Close(); err != nil && rr.err == nil {
+		rr.err = fmt.Errorf("failed to close replay: %v", err)
+	}
+	return rr.err
+}
+
+// Replay - загруженная запись. Реализует InputSource
+type Replay struct {
+	Config   *config.Config // Конфиг записи, сид в нем уже настоящий
+	Profile  *FearProfile   // Профиль страха на начало записи; nil - новый игрок
+	Seed     int64
+	Frames   []InputFrame
+	Complete bool   // Запись закрыта штатно; иначе оборвана (например, игра упала)
+	Hash     uint64 // Хэш состояния после последнего кадра, если Complete
+	position int
+}
+
+// LoadReplay читает файл записи. Оборванная запись загружается до последнего
+// сохраненного кадра с Complete = false
+func LoadReplay(path string) (*Replay, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	header := make([]byte, len(replayMagic)+2)
+	if _, err := io.ReadFull(reader, header); err != nil || string(header[:len(replayMagic)]) != replayMagic {
+		return nil, fmt.Errorf("%s is not a replay file", path)
+	}
+	if version := binary.LittleEndian.Uint16(header[len(replayMagic):]); version != replayVersion {
+		return nil, fmt.Errorf("unsupported replay version %d", version)
+	}
+
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay: %v", err)
+	}
+	defer gz.Close()
+
+	replay := &Replay{}
+	var tickRate float64
+	var configSize uint32
+	if err := readAll(gz, &replay.Seed, &tickRate, &configSize); err != nil {
+		return nil, fmt.Errorf("failed to read replay header: %v", err)
+	}
+	if tickRate != SimulationTickRate {
+		return nil, fmt.Errorf("replay was recorded at %.0f ticks per second, the game runs at %.0f", tickRate, float64(SimulationTickRate))
+	}
+
+	configData := make([]byte, configSize)
+	if _, err := io.ReadFull(gz, configData); err != nil {
+		return nil, fmt.Errorf("failed to read replay config: %v", err)
+	}
+	if replay.Config, err = config.Decode(configData); err != nil {
+		return nil, err
+	}
+	replay.Config.Procedural.Seed = replay.Seed
+
+	var profileSize uint32
+	if err := readAll(gz, &profileSize); err != nil {
+		return nil, fmt.Errorf("failed to read replay header: %v", err)
+	}
+	if profileSize > 0 {
+		profileData := make([]byte, profileSize)
+		if _, err := io.ReadFull(gz, profileData); err != nil {
+			return nil, fmt.Errorf("failed to read replay fear profile: %v", err)
+		}
+		if replay.Profile, err = DecodeFearProfile(profileData); err != nil {
+			return nil, err
+		}
+	}
+
+	var b [12]byte
+	for {
+		var tag byte
+		if err := readAll(gz, &tag); err != nil {
+			if isTruncated(err) {
+				return replay, nil
+			}
+			return nil, fmt.Errorf("failed to read replay: %v", err)
+		}
+
+		switch tag {
+		case replayRecordFrame:
+			if _, err := io.ReadFull(gz, b[:]); err != nil {
+				if isTruncated(err) {
+					return replay, nil
+				}
+				return nil, fmt.Errorf("failed to read replay frame: %v", err)
+			}
+			replay.Frames = append(replay.Frames, InputFrame{
+				Forward:  float64(int8(b[1])) / 127,
+				Strafe:   float64(int8(b[2])) / 127,
+				Yaw:      float64(math.Float32frombits(binary.LittleEndian.Uint32(b[3:]))),
+				Pitch:    float64(math.Float32frombits(binary.LittleEndian.Uint32(b[7:]))),
+				Sprint:   b[0]&frameSprint != 0,
+				Crouch:   b[0]&frameCrouch != 0,
+				Jump:     b[0]&frameJump != 0,
+				Interact: b[0]&frameInteract != 0,
+				Voice:    float64(b[11]) / 255,
+			})
+
+		case replayRecordEnd:
+			var ticks uint64
+			if err := readAll(gz, &ticks, &replay.Hash); err != nil {
+				return nil, fmt.Errorf("failed to read replay end: %v", err)
+			}
+			if ticks != uint64(len(replay.Frames)) {
+				return nil, fmt.Errorf("replay is damaged: %d frames, %d expected", len(replay.Frames), ticks)
+			}
+			replay.Complete = true
+			return replay, nil
+
+		default:
+			return nil, fmt.Errorf("replay is damaged: unknown record %d", tag)
+		}
+	}
+}
+
+// readAll читает значения по порядку
+func readAll(r io.Reader, values ...interface{}) error {
+	for _, v := range values {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTruncated сообщает, что поток оборвался (запись не была закрыта)
+func isTruncated(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// NextFrame реализует InputSource
+func (r *Replay) NextFrame(_ *Simulation) (InputFrame, bool) {
+	if r.position >= len(r.Frames) {
+		return InputFrame{}, false
+	}
+	frame := r.Frames[r.position]
+	r.position++
+	return frame, true
+}
+
+// Rewind возвращает воспроизведение к первому кадру
+func (r *Replay) Rewind() {
+	r.position = 0
+}
+
+// VerifyReplay воспроизводит запись без окна и сравнивает итоговое состояние
+// с записанным. Возвращает хэш состояния; для оборванной записи сравнивать не с чем
+func VerifyReplay(replay *Replay) (uint64, error) {
+	sim, err := NewSimulation(replay.Config)
+	if err != nil {
+		return 0, err
+	}
+
+	sim.SetFearProfile(replay.Profile)
+	sim.Start()
+	replay.Rewind()
+	sim.Run(replay, 0)
+
+	hash := sim.StateHash()
+	if replay.Complete && hash != replay.Hash {
+		return hash, fmt.Errorf("replay diverged after %d ticks: state %016x, recorded %016x",
+			sim.Tick(), hash, replay.Hash)
+	}
+	return hash, nil
+}
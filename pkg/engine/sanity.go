@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"math"
+
+	"nightmare/internal/util"
+	"nightmare/pkg/config"
+)
+
+// Рассудок игрока (Player.Sanity, 0..1). Его подтачивают темнота, близость
+// странных объектов, испуги и одиночество вдали от построек и троп, а
+// возвращают поляны и свет. В страшных местах (ProceduralScene.LevelOfFear)
+// рассудок уходит быстрее. Рассудок - часть симуляции; движок показывает,
+// к чему ведет его потеря: искажения, шепот, галлюцинации
+const (
+	sanityDarkDrain       = 1.0 / 1200 // В полной темноте рассудок уходит за 20 минут
+	sanityStrangeDrain    = 1.0 / 300  // Вплотную к странному объекту - за 5 минут
+	sanityStrangeRadius   = 12.0       // Дальше странные объекты не действуют, м
+	sanityIsolationDrain  = 1.0 / 1800 // Вдали от построек и троп - за полчаса
+	sanityIsolationRange  = 40.0       // Дальше этого от построек игрок один, м
+	sanityScareLoss       = 0.05       // Потеря от испуга силы 1
+	sanityLightRestore    = 1.0 / 300  // При полном свете рассудок возвращается за 5 минут
+	sanityClearingRestore = 1.0 / 180  // На поляне - за 3
+
+	// Последствия, которые показывает движок
+	sanityUneasy      = 0.7 // Ниже этого потеря рассудка становится заметна
+	sanityGlitchRate  = 0.1 // Помех изображения в секунду, когда рассудка нет
+	sanityWhisperRate = 0.1 // Шепотов в секунду, когда рассудка нет
+)
+
+// Sanity изменяет рассудок игрока
+type Sanity struct {
+	drainRate   float64 // SanityConfig.DrainRate
+	restoreRate float64 // SanityConfig.RestoreRate
+	losses      float64 // Потери от испугов с прошлого тика
+}
+
+// newSanity создает систему рассудка
+func newSanity(cfg config.SanityConfig) *Sanity {
+	return &Sanity{drainRate: cfg.DrainRate, restoreRate: cfg.RestoreRate}
+}
+
+// Scare отнимает рассудок за испуг силы amount (0..1)
+func (s *Sanity) Scare(amount float64) {
+	s.losses += amount * sanityScareLoss
+}
+
+// Update продвигает рассудок игрока на один тик
+func (s *Sanity) Update(sim *Simulation, deltaTime float64) {
+	player := sim.Player()
+	scene := sim.procedural.GetCurrentScene()
+	eye := player.Position.Add(Vector3{Y: player.EyeHeight() - player.Height/2})
+	light := scene.LightAt(eye)
+
+	drain := (1-light)*sanityDarkDrain + s.strangeNearby(scene, player.Position)*sanityStrangeDrain
+	if s.isolated(scene, player.Position) {
+		drain += sanityIsolationDrain
+	}
+	drain *= 0.5 + scene.LevelOfFear
+
+	// Свет помогает, только когда его больше половины: сумерки уже не успокаивают
+	restore := math.Max(0, light-0.5) * 2 * sanityLightRestore
+	if s.region(scene, player.Position) == "clearing" {
+		restore += sanityClearingRestore
+	}
+
+	change := (restore*s.restoreRate-drain*s.drainRate)*deltaTime - s.losses*s.drainRate
+	player.Sanity = util.Clamp(player.Sanity+change, 0, 1)
+	s.losses = 0
+}
+
+// strangeNearby оценивает близость странных объектов: 1 вплотную к одному
+// объекту, больше - среди нескольких
+func (s *Sanity) strangeNearby(scene *ProceduralScene, position Vector3) float64 {
+	if scene.Index == nil {
+		return 0
+	}
+
+	nearby := 0.0
+	scene.Index.QueryRadius(position, sanityStrangeRadius, func(obj *ProceduralObject) bool {
+		if obj.Type == "strange" {
+			nearby += math.Max(0, 1-horizontalDistance(obj.Position, position)/sanityStrangeRadius)
+		}
+		return true
+	})
+	return nearby
+}
+
+// isolated сообщает, что игрок далеко от построек и не на тропе
+func (s *Sanity) isolated(scene *ProceduralScene, position Vector3) bool {
+	if s.region(scene, position) == "path" {
+		return false
+	}
+	for _, structure := range scene.Structures {
+		if horizontalDistance(structure.Center, position) < sanityIsolationRange {
+			return false
+		}
+	}
+	return true
+}
+
+// region возвращает регион ландшафта под точкой
+func (s *Sanity) region(scene *ProceduralScene, position Vector3) string {
+	terrain := scene.Terrain
+	if terrain == nil || terrain.Regions == nil || !terrain.InBounds(position.X, position.Z) {
+		return ""
+	}
+	x, z := terrain.cellAt(position.X, position.Z)
+	return terrain.Regions[z][x]
+}
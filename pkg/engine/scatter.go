@@ -0,0 +1,371 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+)
+
+// ScatterLayer описывает слой объектов, разбрасываемых по ландшафту
+type ScatterLayer struct {
+	MinRadius float64                    // Расстояние между объектами слоя при максимальной плотности
+	MaxRadius float64                    // Расстояние между объектами слоя при минимальной плотности
+	Footprint float64                    // Радиус, который объект занимает для объектов других слоев
+	Density   func(x, z float64) float64 // Плотность в точке: 0 - объект не ставится, 1 - максимум
+}
+
+// scatterPoint - размещенная точка одного из слоев
+type scatterPoint struct {
+	x, z      float64
+	radius    float64
+	footprint float64
+}
+
+// scatterGrid - равномерная сетка точек для поиска соседей. Точки ячейки
+// связаны в список: heads хранит первую точку ячейки, next - следующую (индекс+1, 0 - конец)
+type scatterGrid struct {
+	minX, minZ float64
+	cellSize   float64
+	cols, rows int
+	heads      []int32
+	next       []int32
+}
+
+func newScatterGrid(minX, minZ, width, height, cellSize float64) *scatterGrid {
+	cols := int(math.Ceil(width / cellSize))
+	rows := int(math.Ceil(height / cellSize))
+
+	return &scatterGrid{
+		minX:     minX,
+		minZ:     minZ,
+		cellSize: cellSize,
+		cols:     cols,
+		rows:     rows,
+		heads:    make([]int32, cols*rows),
+	}
+}
+
+// cellOf возвращает ячейку сетки для мировых координат
+func (g *scatterGrid) cellOf(x, z float64) (int, int) {
+	col := clamp(int((x-g.minX)/g.cellSize), 0, g.cols-1)
+	row := clamp(int((z-g.minZ)/g.cellSize), 0, g.rows-1)
+	return col, row
+}
+
+// insert добавляет точку с индексом idx; индексы добавляются по порядку
+func (g *scatterGrid) insert(x, z float64, idx int) {
+	col, row := g.cellOf(x, z)
+	cell := row*g.cols + col
+	g.next = append(g.next, g.heads[cell])
+	g.heads[cell] = int32(idx + 1)
+}
+
+// ScatterSampler разбрасывает точки по ландшафту методом Poisson-disk (алгоритм Бридсона)
+// с переменным радиусом. Слои, разбрасываемые одним сэмплером, не перекрывают друг друга
+type ScatterSampler struct {
+	terrain      *HeightMap
+	minX, minZ   float64
+	maxX, maxZ   float64
+	occupied     *scatterGrid   // Точки всех слоев (для проверки Footprint)
+	points       []scatterPoint // Точки всех слоев
+	maxFootprint float64
+	rng          *rand.Rand
+}
+
+// Количество кандидатов вокруг активной точки до ее исключения из активного списка.
+// Кандидаты берутся равномерно по окружности со случайным сдвигом, поэтому
+// достаточно меньшего числа попыток, чем в классическом варианте (30)
+const scatterCandidates = 8
+
+// scatterDirections - направления кандидатов вокруг активной точки
+var scatterDirections = func() [scatterCandidates][2]float64 {
+	var dirs [scatterCandidates][2]float64
+	for k := range dirs {
+		angle := float64(k) * 2 * math.Pi / scatterCandidates
+		dirs[k] = [2]float64{math.Cos(angle), math.Sin(angle)}
+	}
+	return dirs
+}()
+
+// NewScatterSampler создает сэмплер для ландшафта с заданным сидом
+func NewScatterSampler(terrain *HeightMap, seed int64) *ScatterSampler {
+	width := float64(terrain.Width)
+	height := float64(terrain.Height)
+
+	return &ScatterSampler{
+		terrain:  terrain,
+		minX:     -width / 2,
+		minZ:     -height / 2,
+		maxX:     width / 2,
+		maxZ:     height / 2,
+		occupied: newScatterGrid(-width/2, -height/2, width, height, 4.0),
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Sample разбрасывает точки слоя и возвращает их мировые координаты (Y = 0).
+// Плотность слоя вычисляется только для кандидатов, не попавших в круги соседей
+func (s *ScatterSampler) Sample(layer ScatterLayer) []Vector3 {
+	// Сетка слоя с ячейкой в максимальный радиус: соседи всегда в окрестности 3x3
+	own := newScatterGrid(s.minX, s.minZ, s.maxX-s.minX, s.maxZ-s.minZ, layer.MaxRadius)
+
+	// Оценка числа точек, чтобы срезы не перевыделялись по ходу
+	capacity := int((s.maxX - s.minX) * (s.maxZ - s.minZ) / (layer.MinRadius * layer.MaxRadius))
+	own.next = make([]int32, 0, capacity)
+	ownPoints := make([]scatterPoint, 0, capacity)
+	result := make([]Vector3, 0, capacity)
+	active := make([]int, 0, capacity)
+
+	// tryAdd добавляет точку, если она помещается, и возвращает радиус кандидата
+	// (0, если до вычисления радиуса дело не дошло)
+	tryAdd := func(x, z float64) (bool, float64) {
+		if x < s.minX || x >= s.maxX || z < s.minZ || z >= s.maxZ {
+			return false, 0
+		}
+
+		// Радиус кандидата еще неизвестен, поэтому сначала отбрасываем кандидатов
+		// в кругах соседей и запоминаем расстояние до ближайшего из них
+		nearest, free := s.nearestInLayer(own, ownPoints, x, z)
+		if !free {
+			return false, 0
+		}
+
+		density := layer.Density(x, z)
+		if density <= 0 {
+			return false, 0
+		}
+
+		// Чем выше плотность, тем ближе объекты друг к другу
+		radius := layer.MaxRadius + (layer.MinRadius-layer.MaxRadius)*math.Min(density, 1)
+		if radius*radius > nearest {
+			return false, radius
+		}
+		if !s.fitsOthers(x, z, layer.Footprint) {
+			return false, 0
+		}
+
+		point := scatterPoint{x: x, z: z, radius: radius, footprint: layer.Footprint}
+
+		own.insert(x, z, len(ownPoints))
+		ownPoints = append(ownPoints, point)
+
+		s.occupied.insert(x, z, len(s.points))
+		s.points = append(s.points, point)
+		s.maxFootprint = math.Max(s.maxFootprint, layer.Footprint)
+
+		active = append(active, len(ownPoints)-1)
+		result = append(result, Vector3{X: x, Y: 0, Z: z})
+		return true, radius
+	}
+
+	// Начальные точки по крупной сетке, чтобы покрыть области, отрезанные водой или постройками
+	step := layer.MaxRadius * 2
+	for z := s.minZ; z < s.maxZ; z += step {
+		for x := s.minX; x < s.maxX; x += step {
+			for attempt := 0; attempt < 3; attempt++ {
+				if added, _ := tryAdd(x+s.rng.Float64()*step, z+s.rng.Float64()*step); added {
+					break
+				}
+			}
+		}
+	}
+
+	// Выращиваем распределение от активных точек. Кандидаты лежат сразу за
+	// радиусом точки: так слой упаковывается плотнее, чем со случайным расстоянием
+	for len(active) > 0 {
+		i := s.rng.Intn(len(active))
+		p := ownPoints[active[i]]
+		dist := p.radius * 1.001
+
+		found := false
+		sinStart, cosStart := math.Sincos(s.rng.Float64() * 2 * math.Pi)
+		for _, dir := range scatterDirections {
+			// Поворачиваем направление на случайный начальный угол
+			dirX := dir[0]*cosStart - dir[1]*sinStart
+			dirZ := dir[0]*sinStart + dir[1]*cosStart
+
+			added, radius := tryAdd(p.x+dirX*dist, p.z+dirZ*dist)
+			if !added && radius > dist {
+				// Там, где реже, кандидату нужно больше места: отодвигаем его на собственный радиус
+				added, _ = tryAdd(p.x+dirX*radius*1.001, p.z+dirZ*radius*1.001)
+			}
+			if added {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			active[i] = active[len(active)-1]
+			active = active[:len(active)-1]
+		}
+	}
+
+	return result
+}
+
+// scatterNeighborhood - ячейки окрестности 3x3, начиная со своей: конфликтующий сосед
+// чаще всего находится в ячейке кандидата, и проверка заканчивается раньше
+var scatterNeighborhood = [9][2]int{{0, 0}, {-1, 0}, {1, 0}, {0, -1}, {0, 1}, {-1, -1}, {1, -1}, {-1, 1}, {1, 1}}
+
+// nearestInLayer возвращает квадрат расстояния до ближайшей точки слоя и false,
+// если кандидат лежит в круге одной из точек. Ячейка сетки не меньше радиуса точек,
+// поэтому точки дальше окрестности 3x3 не влияют на результат
+func (s *ScatterSampler) nearestInLayer(grid *scatterGrid, points []scatterPoint, x, z float64) (float64, bool) {
+	nearest := math.Inf(1)
+
+	col, row := grid.cellOf(x, z)
+	for _, offset := range scatterNeighborhood {
+		c, r := col+offset[0], row+offset[1]
+		if c < 0 || c >= grid.cols || r < 0 || r >= grid.rows {
+			continue
+		}
+
+		for idx := grid.heads[r*grid.cols+c]; idx != 0; idx = grid.next[idx-1] {
+			q := &points[idx-1]
+			dx := q.x - x
+			dz := q.z - z
+			dist := dx*dx + dz*dz
+			if dist < q.radius*q.radius {
+				return 0, false
+			}
+			nearest = min(nearest, dist)
+		}
+	}
+
+	return nearest, true
+}
+
+// fitsOthers проверяет, что объект не перекрывает уже размещенные объекты (любых слоев)
+func (s *ScatterSampler) fitsOthers(x, z, footprint float64) bool {
+	grid := s.occupied
+	span := int(math.Ceil((footprint + s.maxFootprint) / grid.cellSize))
+
+	col, row := grid.cellOf(x, z)
+	for r := max(row-span, 0); r <= min(row+span, grid.rows-1); r++ {
+		for c := max(col-span, 0); c <= min(col+span, grid.cols-1); c++ {
+			for idx := grid.heads[r*grid.cols+c]; idx != 0; idx = grid.next[idx-1] {
+				q := &s.points[idx-1]
+				minDist := footprint + q.footprint
+
+				dx := q.x - x
+				dz := q.z - z
+				if dx*dx+dz*dz < minDist*minDist {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// cellAt возвращает ячейку карты высот для мировых координат
+func (hm *HeightMap) cellAt(x, z float64) (int, int) {
+	gridX := clamp(int(x+float64(hm.Width)/2), 0, hm.Width-1)
+	gridZ := clamp(int(z+float64(hm.Height)/2), 0, hm.Height-1)
+	return gridX, gridZ
+}
+
+// gradientAt возвращает градиент высоты ландшафта (подъем на метр по X и Z) в клетке карты высот
+func (hm *HeightMap) gradientAt(gridX, gridZ int) (float64, float64) {
+	x0, x1 := max(gridX-1, 0), min(gridX+1, hm.Width-1)
+	z0, z1 := max(gridZ-1, 0), min(gridZ+1, hm.Height-1)
+
+	dx := (hm.Data[gridZ][x1] - hm.Data[gridZ][x0]) * terrainHeightScale / float64(max(x1-x0, 1))
+	dz := (hm.Data[z1][gridX] - hm.Data[z0][gridX]) * terrainHeightScale / float64(max(z1-z0, 1))
+	return dx, dz
+}
+
+// slopeAt возвращает уклон ландшафта (подъем на метр) в клетке карты высот
+func (hm *HeightMap) slopeAt(gridX, gridZ int) float64 {
+	dx, dz := hm.gradientAt(gridX, gridZ)
+	return math.Sqrt(dx*dx + dz*dz)
+}
+
+// treeDensityAt - плотность деревьев: гуще в dense_forest, реже на склонах и хребтах
+func (pg *ProceduralGenerator) treeDensityAt(x, z float64) float64 {
+	terrain := pg.currentScene.Terrain
+	gridX, gridZ := terrain.cellAt(x, z)
+
+	elevation := terrain.Data[gridZ][gridX]
+	if terrain.Materials[gridZ][gridX] == 1 || elevation <= 0.3 || elevation >= 0.8 {
+		return 0
+	}
+	if pg.isInsideStructure(x, z, 1.0) || pg.isNearCave(x, z, 1.0) {
+		return 0
+	}
+
+	density := 0.5
+	switch terrain.Regions[gridZ][gridX] {
+	case "dense_forest":
+		density = 1.0
+	case "dark_forest":
+		density = 0.75
+	case "low_forest":
+		density = 0.6
+	case "swamp", "swamp_pit":
+		density = 0.45
+	case "rocky_hills":
+		density = 0.25
+	case "mountains":
+		density = 0.15
+	case "clearing", "path":
+		density = 0.1
+	case "ravine", "pond", "mountain_peak":
+		density = 0.05
+	}
+
+	// Влажная почва благоприятнее для деревьев
+	density *= 0.7 + 0.3*terrain.Humidity[gridZ][gridX]
+
+	// На крутых склонах и хребтах деревья редеют
+	density *= math.Max(0, 1-terrain.slopeAt(gridX, gridZ)/1.2)
+
+	return density
+}
+
+// rockDensityAt - плотность камней: больше в горах, ущельях и на склонах
+func (pg *ProceduralGenerator) rockDensityAt(x, z float64) float64 {
+	terrain := pg.currentScene.Terrain
+	gridX, gridZ := terrain.cellAt(x, z)
+
+	if terrain.Materials[gridZ][gridX] == 1 || terrain.Data[gridZ][gridX] <= 0.2 {
+		return 0
+	}
+	if pg.isInsideStructure(x, z, 1.0) || pg.isNearCave(x, z, 1.0) {
+		return 0
+	}
+
+	density := 0.4
+	switch terrain.Regions[gridZ][gridX] {
+	case "mountains", "mountain_peak", "rocky_hills":
+		density = 1.0
+	case "ravine":
+		density = 0.9
+	case "swamp", "swamp_pit":
+		density = 0.2
+	case "clearing", "path":
+		density = 0.15
+	}
+
+	// Камни чаще встречаются на склонах
+	density *= 0.6 + 0.4*math.Min(terrain.slopeAt(gridX, gridZ), 1)
+
+	return density
+}
+
+// strangeDensityAt - плотность странных объектов: предпочитают зловещие регионы
+func (pg *ProceduralGenerator) strangeDensityAt(x, z float64) float64 {
+	terrain := pg.currentScene.Terrain
+	gridX, gridZ := terrain.cellAt(x, z)
+
+	if terrain.Materials[gridZ][gridX] == 1 || pg.isInsideStructure(x, z, 1.0) || pg.isNearCave(x, z, 1.0) {
+		return 0
+	}
+
+	switch terrain.Regions[gridZ][gridX] {
+	case "swamp", "dense_forest", "ravine", "swamp_pit":
+		return 1.0
+	}
+	return 0.15
+}
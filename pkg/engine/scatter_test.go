@@ -0,0 +1,445 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"nightmare/pkg/config"
+)
+
+// legacyPopulateScene - расстановка объектов до Poisson-disk: случайные точки
+// с проверкой занятой клетки по ключу "%d,%d". Оставлена для сравнения в BenchmarkScatter
+func (pg *ProceduralGenerator) legacyPopulateScene() {
+	if pg.currentScene == nil || pg.currentScene.Terrain == nil {
+		return
+	}
+
+	// Получаем параметры биома
+	biomeParams, ok := pg.biomes[pg.currentScene.BiomeType]
+	if !ok {
+		biomeParams = pg.biomes["dark_forest"] // По умолчанию
+	}
+
+	// Get terrain dimensions
+	terrain := pg.currentScene.Terrain
+	terrainWidth := terrain.Width
+	terrainHeight := terrain.Height
+
+	// Генерируем деревья
+	numTrees := int(biomeParams.TreeDensity) * terrainWidth * terrainHeight / 1000
+
+	// Массив для хранения занятых позиций
+	occupiedPositions := make(map[string]bool)
+
+	for i := 0; i < numTrees; i++ {
+		// Pick a random position on the terrain
+		x := pg.noiseGen.RandomFloat()*float64(terrainWidth) - float64(terrainWidth)/2
+		z := pg.noiseGen.RandomFloat()*float64(terrainHeight) - float64(terrainHeight)/2
+
+		// Find the terrain height at this position
+		terrainX := int(x + float64(terrainWidth)/2)
+		terrainZ := int(z + float64(terrainHeight)/2)
+
+		// Ensure within bounds
+		if terrainX < 0 || terrainX >= terrainWidth || terrainZ < 0 || terrainZ >= terrainHeight {
+			continue
+		}
+
+		elevation := pg.currentScene.Terrain.Data[terrainZ][terrainX]
+		region := pg.currentScene.Terrain.Regions[terrainZ][terrainX]
+
+		// Only place trees at certain elevations and not in water
+		if elevation > 0.3 && elevation < 0.8 && pg.currentScene.Terrain.Materials[terrainZ][terrainX] != 1 {
+			// Check if position is already occupied
+			posKey := fmt.Sprintf("%d,%d", terrainX, terrainZ)
+			if occupiedPositions[posKey] {
+				continue // Skip if position is already occupied
+			}
+
+			// Не ставим объекты внутри построек
+			if pg.isInsideStructure(x, z, 1.0) {
+				continue
+			}
+
+			// Mark position as occupied
+			occupiedPositions[posKey] = true
+
+			// Определяем тип дерева в зависимости от региона и случайности
+			treeType := "pine" // По умолчанию
+
+			// Доступные типы деревьев для биома
+			availableTypes := biomeParams.TreeTypes
+			if len(availableTypes) > 0 {
+				treeType = availableTypes[rand.Intn(len(availableTypes))]
+			}
+
+			// Коррекции на основе региона
+			if region == "swamp" || region == "swamp_pit" {
+				treeType = "dead_tree"
+			} else if region == "dense_forest" {
+				// В густом лесу больше вероятность искривленных деревьев
+				if rand.Float64() < 0.4 {
+					treeType = "twisted_tree"
+				}
+			} else if region == "clearing" || region == "path" {
+				// На полянах и тропах меньше деревьев
+				if rand.Float64() < 0.7 {
+					continue // 70% шанс пропустить дерево
+				}
+
+				// На полянах чаще встречаются низкие деревья и кусты
+				if rand.Float64() < 0.5 {
+					treeType = "small_pine"
+				} else if rand.Float64() < 0.3 {
+					treeType = "bush"
+				}
+			}
+
+			// Различные параметры в зависимости от типа дерева
+			var treeHeight, treeWidth float64
+			var treeMeta map[string]float64
+
+			switch treeType {
+			case "pine":
+				treeHeight = 3.0 + pg.noiseGen.RandomFloat()*2.0
+				treeWidth = 0.8 + pg.noiseGen.RandomFloat()*0.4
+				treeMeta = map[string]float64{
+					"atmosphere.fear":       0.3 + pg.noiseGen.RandomFloat()*0.3,
+					"atmosphere.ominous":    0.2 + pg.noiseGen.RandomFloat()*0.4,
+					"visuals.distorted":     pg.noiseGen.RandomFloat() * 0.5,
+					"visuals.dark":          0.3 + pg.noiseGen.RandomFloat()*0.4,
+					"conditions.silhouette": 0.2 + pg.noiseGen.RandomFloat()*0.7,
+				}
+
+			case "dead_tree":
+				treeHeight = 2.5 + pg.noiseGen.RandomFloat()*1.5
+				treeWidth = 0.6 + pg.noiseGen.RandomFloat()*0.3
+				treeMeta = map[string]float64{
+					"atmosphere.fear":       0.5 + pg.noiseGen.RandomFloat()*0.3,
+					"atmosphere.ominous":    0.4 + pg.noiseGen.RandomFloat()*0.4,
+					"atmosphere.dread":      0.3 + pg.noiseGen.RandomFloat()*0.4,
+					"visuals.distorted":     0.2 + pg.noiseGen.RandomFloat()*0.3,
+					"visuals.dark":          0.5 + pg.noiseGen.RandomFloat()*0.3,
+					"conditions.silhouette": 0.5 + pg.noiseGen.RandomFloat()*0.5,
+				}
+
+			case "twisted_tree":
+				treeHeight = 2.0 + pg.noiseGen.RandomFloat()*2.5
+				treeWidth = 0.7 + pg.noiseGen.RandomFloat()*0.5
+				treeMeta = map[string]float64{
+					"atmosphere.fear":       0.4 + pg.noiseGen.RandomFloat()*0.4,
+					"atmosphere.ominous":    0.5 + pg.noiseGen.RandomFloat()*0.3,
+					"atmosphere.dread":      0.4 + pg.noiseGen.RandomFloat()*0.3,
+					"visuals.distorted":     0.4 + pg.noiseGen.RandomFloat()*0.4,
+					"visuals.twisted":       0.6 + pg.noiseGen.RandomFloat()*0.4,
+					"conditions.silhouette": 0.4 + pg.noiseGen.RandomFloat()*0.4,
+				}
+
+			case "small_pine":
+				treeHeight = 1.5 + pg.noiseGen.RandomFloat()*1.0
+				treeWidth = 0.6 + pg.noiseGen.RandomFloat()*0.3
+				treeMeta = map[string]float64{
+					"atmosphere.fear":       0.2 + pg.noiseGen.RandomFloat()*0.2,
+					"atmosphere.ominous":    0.1 + pg.noiseGen.RandomFloat()*0.3,
+					"visuals.dark":          0.2 + pg.noiseGen.RandomFloat()*0.3,
+					"conditions.silhouette": 0.1 + pg.noiseGen.RandomFloat()*0.5,
+				}
+
+			case "bush":
+				treeHeight = 0.7 + pg.noiseGen.RandomFloat()*0.5
+				treeWidth = 0.8 + pg.noiseGen.RandomFloat()*0.4
+				treeMeta = map[string]float64{
+					"atmosphere.fear": 0.1 + pg.noiseGen.RandomFloat()*0.2,
+					"visuals.dark":    0.2 + pg.noiseGen.RandomFloat()*0.2,
+				}
+			}
+
+			// Создаем дерево с соответствующими параметрами
+			tree := &ProceduralObject{
+				ID:       len(pg.currentScene.Objects) + 1,
+				Type:     "tree",
+				Position: Vector3{X: x, Y: elevation * 20, Z: z}, // Scale elevation
+				Scale:    Vector3{X: treeWidth, Y: treeHeight, Z: treeWidth},
+				Rotation: Vector3{X: 0, Y: pg.noiseGen.RandomFloat() * 2 * math.Pi, Z: 0},
+				Metadata: treeMeta,
+				Seed:     pg.currentScene.Seed + int64(i),
+			}
+
+			// Добавляем случайный наклон для некоторых деревьев
+			if treeType == "twisted_tree" || treeType == "dead_tree" {
+				tree.Rotation.X = (pg.noiseGen.RandomFloat()*2.0 - 1.0) * 0.2 // Наклон до 0.2 радиан
+				tree.Rotation.Z = (pg.noiseGen.RandomFloat()*2.0 - 1.0) * 0.2
+			}
+
+			pg.currentScene.Objects = append(pg.currentScene.Objects, tree)
+		}
+	}
+
+	// Генерируем камни
+	numRocks := int(biomeParams.RockDensity) * terrainWidth * terrainHeight / 1000
+
+	for i := 0; i < numRocks; i++ {
+		// Similar logic as for trees
+		x := pg.noiseGen.RandomFloat()*float64(terrainWidth) - float64(terrainWidth)/2
+		z := pg.noiseGen.RandomFloat()*float64(terrainHeight) - float64(terrainHeight)/2
+
+		terrainX := int(x + float64(terrainWidth)/2)
+		terrainZ := int(z + float64(terrainHeight)/2)
+
+		if terrainX < 0 || terrainX >= terrainWidth || terrainZ < 0 || terrainZ >= terrainHeight {
+			continue
+		}
+
+		elevation := pg.currentScene.Terrain.Data[terrainZ][terrainX]
+		region := pg.currentScene.Terrain.Regions[terrainZ][terrainX]
+
+		// Rocks can be at more places than trees, but not in water
+		if elevation > 0.2 && pg.currentScene.Terrain.Materials[terrainZ][terrainX] != 1 {
+			// Check if position is already occupied
+			posKey := fmt.Sprintf("%d,%d", terrainX, terrainZ)
+			if occupiedPositions[posKey] {
+				continue // Skip if position is already occupied
+			}
+
+			// Не ставим объекты внутри построек
+			if pg.isInsideStructure(x, z, 1.0) {
+				continue
+			}
+
+			// Mark position as occupied
+			occupiedPositions[posKey] = true
+
+			// Определяем тип камня и размер
+			rockSize := 0.5 + pg.noiseGen.RandomFloat()*1.5
+			rockType := "rock"
+
+			// Модификации на основе региона
+			if region == "mountain_peak" || region == "mountains" || region == "rocky_hills" {
+				// Больше и разнообразнее камни в горах
+				rockSize = 1.0 + pg.noiseGen.RandomFloat()*2.0
+
+				if rand.Float64() < 0.3 {
+					rockType = "boulder"
+				}
+			} else if region == "ravine" {
+				// В ущельях больше узких и острых камней
+				rockType = "sharp_rock"
+				rockSize = 0.7 + pg.noiseGen.RandomFloat()*1.2
+			} else if region == "path" || region == "clearing" {
+				// На тропах и полянах меньше камней
+				if rand.Float64() < 0.7 {
+					continue // 70% шанс пропустить камень
+				}
+
+				// Небольшие камни
+				rockSize = 0.3 + pg.noiseGen.RandomFloat()*0.5
+			}
+
+			// Метаданные для камней
+			rockMeta := map[string]float64{
+				"atmosphere.ominous": 0.1 + pg.noiseGen.RandomFloat()*0.3,
+				"visuals.rough":      0.4 + pg.noiseGen.RandomFloat()*0.4,
+				"conditions.shadow":  0.3 + pg.noiseGen.RandomFloat()*0.3,
+			}
+
+			// Специфические метаданные для разных типов камней
+			if rockType == "boulder" {
+				rockMeta["atmosphere.dread"] = 0.2 + pg.noiseGen.RandomFloat()*0.2
+				rockMeta["visuals.dark"] = 0.3 + pg.noiseGen.RandomFloat()*0.3
+			} else if rockType == "sharp_rock" {
+				rockMeta["atmosphere.tension"] = 0.3 + pg.noiseGen.RandomFloat()*0.3
+				rockMeta["visuals.distorted"] = 0.2 + pg.noiseGen.RandomFloat()*0.2
+			}
+
+			// Create a rock object
+			rock := &ProceduralObject{
+				ID:       len(pg.currentScene.Objects) + 1,
+				Type:     "rock",
+				Position: Vector3{X: x, Y: elevation * 20, Z: z},
+				Scale:    Vector3{X: rockSize, Y: rockSize * 0.7, Z: rockSize},
+				Rotation: Vector3{X: pg.noiseGen.RandomFloat() * 0.3, Y: pg.noiseGen.RandomFloat() * 2 * math.Pi, Z: pg.noiseGen.RandomFloat() * 0.3},
+				Metadata: rockMeta,
+				Seed:     pg.currentScene.Seed + int64(i+1000), // Different seed range than trees
+			}
+
+			pg.currentScene.Objects = append(pg.currentScene.Objects, rock)
+		}
+	}
+
+	// Генерируем странные объекты
+	numStrange := int(biomeParams.StrangeDensity * 10)
+
+	for i := 0; i < numStrange; i++ {
+		// Выбираем позицию для странного объекта
+		// Стараемся поместить их в места, которые усилят атмосферу страха
+
+		// Изначально случайная позиция
+		x := pg.noiseGen.RandomFloat()*float64(terrainWidth) - float64(terrainWidth)/2
+		z := pg.noiseGen.RandomFloat()*float64(terrainHeight) - float64(terrainHeight)/2
+
+		// Если возможно, помещаем их в зловещие регионы
+		darkRegions := []string{"swamp", "dense_forest", "ravine", "swamp_pit"}
+
+		// Пытаемся найти подходящий регион
+		attempts := 0
+		maxAttempts := 10
+
+		for attempts < maxAttempts {
+			terrainX := int(x + float64(terrainWidth)/2)
+			terrainZ := int(z + float64(terrainHeight)/2)
+
+			if terrainX >= 0 && terrainX < terrainWidth && terrainZ >= 0 && terrainZ < terrainHeight {
+				region := pg.currentScene.Terrain.Regions[terrainZ][terrainX]
+
+				// Проверяем, подходит ли регион
+				isGoodRegion := false
+				for _, darkRegion := range darkRegions {
+					if region == darkRegion {
+						isGoodRegion = true
+						break
+					}
+				}
+
+				if isGoodRegion {
+					break // Нашли хорошее место
+				}
+			}
+
+			// Пробуем новую случайную позицию
+			x = pg.noiseGen.RandomFloat()*float64(terrainWidth) - float64(terrainWidth)/2
+			z = pg.noiseGen.RandomFloat()*float64(terrainHeight) - float64(terrainHeight)/2
+			attempts++
+		}
+
+		terrainX := int(x + float64(terrainWidth)/2)
+		terrainZ := int(z + float64(terrainHeight)/2)
+
+		// Проверяем, что координаты в пределах ландшафта
+		if terrainX < 0 || terrainX >= terrainWidth || terrainZ < 0 || terrainZ >= terrainHeight {
+			continue
+		}
+
+		elevation := pg.currentScene.Terrain.Data[terrainZ][terrainX]
+
+		// Проверяем, что объект не будет в воде
+		if pg.currentScene.Terrain.Materials[terrainZ][terrainX] == 1 {
+			continue
+		}
+
+		// Check if position is already occupied
+		posKey := fmt.Sprintf("%d,%d", terrainX, terrainZ)
+		if occupiedPositions[posKey] {
+			continue // Skip if position is already occupied
+		}
+
+		// Не ставим объекты внутри построек
+		if pg.isInsideStructure(x, z, 1.0) {
+			continue
+		}
+
+		// Mark position as occupied
+		occupiedPositions[posKey] = true
+
+		// Выбираем тип странного объекта
+		strangeTypes := []string{"obelisk", "strange_tree", "anomaly", "ritual_stones"}
+		strangeType := strangeTypes[rand.Intn(len(strangeTypes))]
+
+		// Параметры объекта в зависимости от типа
+		var strangeSize, strangeHeight float64
+
+		switch strangeType {
+		case "obelisk":
+			strangeSize = 0.5 + pg.noiseGen.RandomFloat()*0.5
+			strangeHeight = 3.0 + pg.noiseGen.RandomFloat()*2.0
+
+		case "strange_tree":
+			strangeSize = 0.8 + pg.noiseGen.RandomFloat()*0.8
+			strangeHeight = 4.0 + pg.noiseGen.RandomFloat()*3.0
+
+		case "anomaly":
+			strangeSize = 1.0 + pg.noiseGen.RandomFloat()*1.5
+			strangeHeight = strangeSize
+
+		case "ritual_stones":
+			strangeSize = 1.2 + pg.noiseGen.RandomFloat()*0.8
+			strangeHeight = 1.5 + pg.noiseGen.RandomFloat()*1.0
+		}
+
+		// Метаданные для странных объектов - высокие значения страха и неестественности
+		strangeMeta := map[string]float64{
+			"atmosphere.fear":       0.7 + pg.noiseGen.RandomFloat()*0.3,
+			"atmosphere.dread":      0.8 + pg.noiseGen.RandomFloat()*0.2,
+			"visuals.distorted":     0.6 + pg.noiseGen.RandomFloat()*0.4,
+			"visuals.twisted":       0.7 + pg.noiseGen.RandomFloat()*0.3,
+			"conditions.silhouette": 0.8 + pg.noiseGen.RandomFloat()*0.2,
+			"conditions.unnatural":  0.9 + pg.noiseGen.RandomFloat()*0.1,
+		}
+
+		// Создаем странный объект
+		strange := &ProceduralObject{
+			ID:       len(pg.currentScene.Objects) + 1,
+			Type:     "strange",
+			Position: Vector3{X: x, Y: elevation * 20, Z: z},
+			Scale:    Vector3{X: strangeSize, Y: strangeHeight, Z: strangeSize},
+			Rotation: Vector3{X: 0, Y: pg.noiseGen.RandomFloat() * 2 * math.Pi, Z: 0},
+			Metadata: strangeMeta,
+			Seed:     pg.currentScene.Seed + int64(i+5000),
+		}
+
+		pg.currentScene.Objects = append(pg.currentScene.Objects, strange)
+	}
+}
+
+// BenchmarkScatter сравнивает расстановку объектов сцены 256x256 с прежней.
+// Кроме времени на сцену сообщает время на объект, потому что способы ставят
+// разное количество объектов. Индекс сцены отключен: прежняя расстановка
+// его не заполняла, а вставка стоит одинаково при любом способе
+func BenchmarkScatter(b *testing.B) {
+	cfg, err := config.LoadConfig("../../config.yaml")
+	if err != nil {
+		b.Fatalf("load config: %v", err)
+	}
+	cfg.Procedural.Seed = 42
+	cfg.Procedural.TerrainSize = 256
+
+	pg, err := NewProceduralGenerator(cfg.Procedural)
+	if err != nil {
+		b.Fatalf("new generator: %v", err)
+	}
+	pg.GenerateInitialWorld()
+	scene := pg.currentScene
+
+	// Постройки остаются, остальные объекты расставляются заново
+	var structureParts []*ProceduralObject
+	for _, obj := range scene.Objects {
+		if obj.StructureID != 0 {
+			structureParts = append(structureParts, obj)
+		}
+	}
+	reset := func() {
+		scene.Objects = append(make([]*ProceduralObject, 0, len(structureParts)), structureParts...)
+		scene.Index = nil
+	}
+
+	run := func(b *testing.B, populate func()) {
+		placed := 0
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			reset()
+			b.StartTimer()
+
+			populate()
+			placed += len(scene.Objects) - len(structureParts)
+		}
+		b.StopTimer()
+
+		b.ReportMetric(float64(placed)/float64(b.N), "objects/op")
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(placed), "ns/object")
+	}
+
+	b.Run("poisson", func(b *testing.B) { run(b, pg.populateScene) })
+	b.Run("legacy", func(b *testing.B) { run(b, pg.legacyPopulateScene) })
+}
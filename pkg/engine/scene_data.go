@@ -9,6 +9,8 @@ type SceneObject struct {
 	Size       float64            // Примерный размер объекта в поле зрения
 	Metadata   map[string]float64 // Метаданные объекта
 	Visibility float64            // Видимость объекта (0.0-1.0)
+
+	Hallucination bool // Объекта нет в сцене, его видит только игрок
 }
 
 // TracedPixel представляет результат трассировки для одного пикселя
@@ -89,6 +91,7 @@ type SceneData struct {
 	ObjectsInView  []*SceneObject     // Objects in view
 	PlayerPosition Vector3            // Current player position
 	ViewDirection  Vector3            // View direction
+	Overlay        []string           // Lines of text drawn over the picture (objectives, story)
 }
 
 // Update NewSceneData to initialize SpecialEffects
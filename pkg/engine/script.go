@@ -0,0 +1,239 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Сценарий ввода - текст, по команде в строке, # - комментарий:
+//
+//	walk X Z      идти к точке мира по прямой
+//	goto X Z      идти к точке мира по найденному пути
+//	turn DEG      повернуться на угол (положительный - вправо)
+//	look DEG      посмотреть вверх (или вниз при отрицательном угле)
+//	wait SEC      стоять на месте
+//	sprint on|off бежать при ходьбе
+//	crouch on|off красться
+//	jump          прыгнуть
+//	interact      осмотреть или тронуть объект перед собой
+//	wander SEC    блуждать (WanderBot); 0 - бесконечно
+//	loop          начать сценарий сначала
+//
+// Команда walk/goto, которая не дошла до цели за разумное время, пропускается
+
+// scriptCommand - одна команда сценария
+type scriptCommand struct {
+	op   string
+	args []float64
+	line int
+}
+
+// Число аргументов команд
+var scriptArity = map[string]int{
+	"walk": 2, "goto": 2, "turn": 1, "look": 1, "wait": 1,
+	"sprint": 1, "crouch": 1, "jump": 0, "interact": 0, "wander": 1, "loop": 0,
+}
+
+// Script проигрывает сценарий ввода. Реализует InputSource
+type Script struct {
+	commands []scriptCommand
+
+	current  int
+	elapsed  float64 // Секунд выполнения текущей команды
+	started  bool    // Текущая команда начата
+	target   Vector3
+	timeout  float64
+	turnLeft float64 // Оставшийся поворот команды turn
+	sprint   bool
+	crouch   bool
+
+	steer  botSteering
+	follow botPathFollower
+	wander *WanderBot
+}
+
+// LoadScript читает сценарий из файла
+func LoadScript(path string) (*Script, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open script: %v", err)
+	}
+	defer file.Close()
+
+	return ParseScript(file)
+}
+
+// ParseScript разбирает сценарий
+func ParseScript(r io.Reader) (*Script, error) {
+	script := &Script{}
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(strings.ToLower(text))
+		if len(fields) == 0 {
+			continue
+		}
+
+		op := fields[0]
+		arity, known := scriptArity[op]
+		if !known {
+			return nil, fmt.Errorf("script line %d: unknown command %q", line, op)
+		}
+		if len(fields)-1 != arity {
+			return nil, fmt.Errorf("script line %d: %s takes %d arguments, got %d", line, op, arity, len(fields)-1)
+		}
+
+		command := scriptCommand{op: op, line: line}
+		for _, field := range fields[1:] {
+			var value float64
+			switch {
+			case field == "on":
+				value = 1
+			case field == "off":
+				value = 0
+			default:
+				v, err := strconv.ParseFloat(field, 64)
+				if err != nil {
+					return nil, fmt.Errorf("script line %d: bad argument %q", line, field)
+				}
+				value = v
+			}
+			command.args = append(command.args, value)
+		}
+		script.commands = append(script.commands, command)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read script: %v", err)
+	}
+	if len(script.commands) == 0 {
+		return nil, fmt.Errorf("script is empty")
+	}
+	return script, nil
+}
+
+// next переходит к следующей команде
+func (s *Script) next() {
+	s.current++
+	s.elapsed = 0
+	s.started = false
+}
+
+// NextFrame реализует InputSource. Ввод заканчивается вместе со сценарием
+func (s *Script) NextFrame(sim *Simulation) (InputFrame, bool) {
+	player := sim.Player()
+	s.steer.init(player)
+	dt := sim.tickDuration
+
+	// Мгновенные команды выполняются в том же тике; ограничиваем их число,
+	// чтобы сценарий из одних мгновенных команд с loop не зациклился
+	for budget := len(s.commands) + 1; budget > 0; budget-- {
+		if s.current >= len(s.commands) {
+			return InputFrame{}, false
+		}
+		command := s.commands[s.current]
+
+		switch command.op {
+		case "sprint":
+			s.sprint = command.args[0] != 0
+			s.next()
+			continue
+		case "crouch":
+			s.crouch = command.args[0] != 0
+			s.next()
+			continue
+		case "loop":
+			s.current = 0
+			s.elapsed = 0
+			s.started = false
+			continue
+		}
+
+		frame, done := s.run(command, sim, player, dt)
+		s.elapsed += dt
+		if done {
+			s.next()
+		}
+
+		if command.op != "wander" && (frame.Forward != 0 || frame.Strafe != 0) {
+			frame.Sprint = s.sprint
+		}
+		frame.Crouch = s.crouch
+		return frame.Quantize(), true
+	}
+
+	return s.steer.idle().Quantize(), true
+}
+
+// run выполняет один тик команды. done = true, если команда завершена
+func (s *Script) run(command scriptCommand, sim *Simulation, player *Player, dt float64) (frame InputFrame, done bool) {
+	first := !s.started
+	s.started = true
+
+	switch command.op {
+	case "walk", "goto":
+		if first {
+			s.target = Vector3{X: command.args[0], Z: command.args[1]}
+			var path []Vector3
+			if command.op == "goto" {
+				path = sim.Navigation().FindPath(NavQuery{From: player.Position, To: s.target})
+			}
+			if path == nil {
+				path = []Vector3{s.target}
+			}
+			s.follow.setPath(path)
+
+			// Втрое дольше, чем идти шагом по прямой, плюс запас на обход
+			s.timeout = 3*horizontalDistance(player.Position, s.target)/player.MoveSpeed + 5
+		}
+		frame, _ = s.follow.step(&s.steer, player, dt)
+		return frame, s.follow.done() || s.elapsed >= s.timeout
+
+	case "turn":
+		if first {
+			s.turnLeft = command.args[0] * math.Pi / 180
+		}
+		step := math.Max(-botTurnSpeed*dt, math.Min(botTurnSpeed*dt, s.turnLeft))
+		s.steer.yaw = wrapAngle(s.steer.yaw + step)
+		s.turnLeft -= step
+		return s.steer.idle(), math.Abs(s.turnLeft) < 1e-9
+
+	case "look":
+		maxPitch := math.Pi/2 - 0.01
+		s.steer.pitch = math.Max(-maxPitch, math.Min(maxPitch, command.args[0]*math.Pi/180))
+		return s.steer.idle(), true
+
+	case "wait":
+		return s.steer.idle(), s.elapsed+dt >= command.args[0]
+
+	case "jump":
+		frame = s.steer.idle()
+		frame.Jump = true
+		return frame, true
+
+	case "interact":
+		frame = s.steer.idle()
+		frame.Interact = true
+		return frame, true
+
+	case "wander":
+		if s.wander == nil {
+			s.wander = NewWanderBot(sim.Seed())
+		}
+		s.wander.steer = s.steer
+		frame, _ = s.wander.NextFrame(sim)
+		s.steer = s.wander.steer
+		return frame, command.args[0] > 0 && s.elapsed+dt >= command.args[0]
+	}
+
+	return s.steer.idle(), true
+}
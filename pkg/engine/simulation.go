@@ -0,0 +1,289 @@
+package engine
+
+import (
+	"hash/fnv"
+
+	"nightmare/pkg/config"
+)
+
+// Simulation - детерминированная часть игры: мир, физика игрока и существа,
+// которые продвигаются фиксированными тиками по кадрам ввода. Ее используют
+// и движок с окном, и headless-режим (воспроизведение записей, боты)
+type Simulation struct {
+	procedural   *ProceduralGenerator
+	physics      *PhysicsSystem
+	sight        *Raytracer // Проверка прямой видимости для восприятия существ
+	navigation   *NavGrid   // Поиск пути для существ и ботов
+	interactions *InteractionSystem
+	ai           config.AIConfig
+	sanityConfig config.SanityConfig
+	objConfig    config.ObjectivesConfig
+	sanity       *Sanity           // nil, если рассудок выключен
+	objectives   *Objectives       // nil, если цели выключены
+	stalker      *Stalker          // nil, если ИИ выключен
+	director     *Director         // nil, если ИИ выключен
+	analyzer     *BehaviorAnalyzer // nil, если ИИ или анализ поведения выключен
+	profile      *FearProfile      // Профиль страха на начало сессии; nil - новый игрок
+	modActions   []Interaction     // Взаимодействия из модов
+	modErrors    []error           // Сломанные файлы и взаимодействия модов, которые пропущены
+	tickDuration float64
+	tick         uint64
+	deaths       int // Сколько раз игрок погиб и вернулся к началу
+}
+
+// NewSimulation создает симуляцию; мир генерируется в Start
+func NewSimulation(cfg *config.Config) (*Simulation, error) {
+	procedural, err := NewProceduralGenerator(cfg.Procedural)
+	if err != nil {
+		return nil, err
+	}
+
+	sight, err := NewRaytracer(cfg.Raytracer)
+	if err != nil {
+		return nil, err
+	}
+
+	modActions, modErrors := LoadModInteractions(cfg.Mods)
+
+	return &Simulation{
+		procedural:   procedural,
+		physics:      NewPhysicsSystem(),
+		sight:        sight,
+		ai:           cfg.AI,
+		sanityConfig: cfg.Sanity,
+		objConfig:    cfg.Objectives,
+		modActions:   modActions,
+		modErrors:    modErrors,
+		tickDuration: 1.0 / SimulationTickRate,
+	}, nil
+}
+
+// Start генерирует мир, ставит в него игрока и существ
+func (s *Simulation) Start() {
+	s.procedural.GenerateInitialWorld()
+	scene := s.procedural.GetCurrentScene()
+	s.physics.SetScene(scene)
+	s.sight.SetScene(scene)
+	s.navigation = NewNavGrid(s.physics, scene)
+	s.tick = 0
+	s.deaths = 0
+
+	s.interactions = newInteractionSystem(s.procedural.Seed())
+	// Сломанное взаимодействие пропускается, как и сломанный файл мода,
+	// и убирается из списка, чтобы перезапуск не сообщал о нем снова
+	actions := s.modActions[:0]
+	for _, interaction := range s.modActions {
+		if err := s.interactions.Add(interaction); err != nil {
+			s.modErrors = append(s.modErrors, err)
+			continue
+		}
+		actions = append(actions, interaction)
+	}
+	s.modActions = actions
+
+	s.sanity = nil
+	if s.sanityConfig.Enabled {
+		s.sanity = newSanity(s.sanityConfig)
+	}
+
+	s.stalker, s.director, s.analyzer = nil, nil, nil
+	if s.ai.Enabled {
+		if s.ai.BehaviorAnalysis {
+			// Сессия пополняет копию: перезапуск начинается с того же профиля
+			profile := s.profile
+			if profile == nil {
+				profile = NewFearProfile()
+			}
+			s.analyzer = newBehaviorAnalyzer(profile.Clone())
+		}
+		s.stalker = newStalker(s, s.ai.Difficulty)
+		s.director = newDirector(s, s.ai.AdaptationRate)
+	}
+
+	// История раскладывается по готовому миру: записки не должны лечь в объекты
+	s.objectives = nil
+	if s.objConfig.Enabled {
+		s.objectives = newObjectives(s, s.objConfig)
+	}
+}
+
+// Step продвигает симуляцию на один тик с данным вводом
+func (s *Simulation) Step(frame InputFrame) {
+	s.physics.GetPlayer().Direction = frame.Direction()
+	s.physics.SetCrouch(frame.Crouch)
+	if frame.Forward != 0 || frame.Strafe != 0 {
+		s.physics.Move(frame.Forward, frame.Strafe, frame.Sprint)
+	}
+	if frame.Jump {
+		s.physics.Jump()
+	}
+	s.interactions.Update(s, frame.Interact, s.tickDuration)
+	if s.objectives != nil {
+		s.objectives.Update(s, frame.Interact, s.tickDuration)
+	}
+
+	s.physics.Update(s.tickDuration)
+	if s.stalker != nil {
+		s.stalker.Update(s, s.tickDuration)
+	}
+	if s.analyzer != nil {
+		s.analyzer.Update(s, s.tickDuration)
+	}
+	if s.director != nil {
+		s.director.Update(s, frame.Voice, s.tickDuration)
+	}
+	if s.sanity != nil {
+		s.sanity.Update(s, s.tickDuration)
+	}
+	// Здоровье кончилось от падения или удара сталкера: игрок возвращается
+	// к началу. Проверка в конце тика, чтобы директор успел заметить урон
+	player := s.physics.GetPlayer()
+	if player.Health <= 0 {
+		s.physics.Respawn()
+		s.deaths++
+	}
+	eye := player.Position.Add(Vector3{Y: player.EyeHeight() - player.Height/2})
+	s.procedural.SetViewer(eye, player.Direction)
+	s.procedural.Update(s.tickDuration)
+	s.tick++
+}
+
+// Run продвигает симуляцию, пока источник дает ввод, но не больше maxTicks
+// тиков (0 - без ограничения). Возвращает число выполненных тиков
+func (s *Simulation) Run(source InputSource, maxTicks uint64) uint64 {
+	start := s.tick
+	for maxTicks == 0 || s.tick-start < maxTicks {
+		frame, ok := source.NextFrame(s)
+		if !ok {
+			break
+		}
+		s.Step(frame)
+	}
+	return s.tick - start
+}
+
+// Tick возвращает число тиков с начала симуляции
+func (s *Simulation) Tick() uint64 {
+	return s.tick
+}
+
+// Deaths возвращает, сколько раз игрок погиб с начала симуляции
+func (s *Simulation) Deaths() int {
+	return s.deaths
+}
+
+// Seed возвращает сид мира
+func (s *Simulation) Seed() int64 {
+	return s.procedural.Seed()
+}
+
+// Procedural возвращает генератор мира
+func (s *Simulation) Procedural() *ProceduralGenerator {
+	return s.procedural
+}
+
+// Physics возвращает физику игрока
+func (s *Simulation) Physics() *PhysicsSystem {
+	return s.physics
+}
+
+// Navigation возвращает навигационную сетку текущего мира
+func (s *Simulation) Navigation() *NavGrid {
+	return s.navigation
+}
+
+// Interactions возвращает систему взаимодействия с объектами
+func (s *Simulation) Interactions() *InteractionSystem {
+	return s.interactions
+}
+
+// Objectives возвращает цели мира или nil, если они выключены
+func (s *Simulation) Objectives() *Objectives {
+	return s.objectives
+}
+
+// ModErrors возвращает ошибки модов, которые симуляция пропустила. Полный
+// список готов после Start
+func (s *Simulation) ModErrors() []error {
+	return s.modErrors
+}
+
+// Stalker возвращает сталкера или nil, если ИИ выключен или ему не нашлось места
+func (s *Simulation) Stalker() *Stalker {
+	return s.stalker
+}
+
+// Director возвращает ИИ-режиссера или nil, если ИИ выключен
+func (s *Simulation) Director() *Director {
+	return s.director
+}
+
+// SetFearProfile задает профиль страха игрока на начало сессии (nil - новый
+// игрок). Вызывается до Start; сам профиль симуляция не меняет
+func (s *Simulation) SetFearProfile(profile *FearProfile) {
+	s.profile = profile
+}
+
+// FearProfile возвращает профиль страха, пополненный за сессию, или nil,
+// если анализ поведения выключен
+func (s *Simulation) FearProfile() *FearProfile {
+	if s.analyzer == nil {
+		return nil
+	}
+	return s.analyzer.Profile()
+}
+
+// frighten пугает игрока: директор учитывает испуг в оценке стресса,
+// испуг отнимает рассудок
+func (s *Simulation) frighten(amount float64) {
+	if s.sanity != nil {
+		s.sanity.Scare(amount)
+	}
+	if s.director != nil {
+		s.director.startle(amount)
+	}
+}
+
+// Player возвращает игрока
+func (s *Simulation) Player() *Player {
+	return s.physics.GetPlayer()
+}
+
+// StateHash возвращает хэш мира и состояния игрока. Две симуляции с одним
+// сидом и одним вводом должны давать одинаковый хэш на одном и том же тике
+func (s *Simulation) StateHash() uint64 {
+	wh := &worldHasher{h: fnv.New64a()}
+	wh.int(int64(s.procedural.WorldHash()))
+	wh.int(int64(s.tick))
+	wh.int(int64(s.deaths))
+
+	player := s.physics.GetPlayer()
+	wh.vector(player.Position)
+	wh.vector(player.Velocity)
+	wh.vector(player.SlideVelocity)
+	wh.float(player.Health)
+	wh.float(player.Stamina)
+	wh.float(player.Sanity)
+	wh.float(player.Height)
+
+	// Положение сталкера - в хэше мира, здесь его решения
+	if s.stalker != nil {
+		wh.int(int64(s.stalker.state))
+		wh.float(s.stalker.awareness)
+		wh.float(s.stalker.yaw)
+	}
+	if s.director != nil {
+		wh.int(int64(s.director.phase))
+		wh.float(s.director.stress)
+		wh.float(s.director.tension)
+		wh.float(s.director.escalation)
+	}
+	if s.objectives != nil {
+		s.objectives.hash(wh)
+	}
+	if s.analyzer != nil {
+		s.analyzer.hash(wh)
+	}
+
+	return wh.h.Sum64()
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+const determinismTicks = 300
+
+// scriptedFrame - ввод, зависящий только от номера тика: ходьба кругами,
+// прыжки и нажатия клавиши действия
+func scriptedFrame(tick uint64) InputFrame {
+	return InputFrame{
+		Forward:  1,
+		Strafe:   math.Sin(float64(tick) * 0.02),
+		Yaw:      float64(tick) * 0.01,
+		Pitch:    -0.1,
+		Sprint:   tick%300 < 120,
+		Jump:     tick%90 == 45,
+		Interact: tick%120 == 60,
+	}.Quantize()
+}
+
+// runSimulation прогоняет симуляцию с кадрами длительностью frameTime(i) через
+// FixedStep, как это делает движок, и возвращает хэш состояния после каждого тика.
+// change позволяет подменить ввод, чтобы проверить обнаружение расхождения
+func runSimulation(t *testing.T, frameTime func(frame int) float64, change func(tick uint64, frame InputFrame) InputFrame) []uint64 {
+	t.Helper()
+
+	sim, err := NewSimulation(determinismConfig(t))
+	if err != nil {
+		t.Fatalf("new simulation: %v", err)
+	}
+	sim.Start()
+
+	clock := NewFixedStep(SimulationTickRate, 0)
+	hashes := make([]uint64, 0, determinismTicks)
+	for frame := 0; len(hashes) < determinismTicks; frame++ {
+		for steps := clock.Advance(frameTime(frame)); steps > 0 && len(hashes) < determinismTicks; steps-- {
+			input := scriptedFrame(sim.Tick())
+			if change != nil {
+				input = change(sim.Tick(), input)
+			}
+			sim.Step(input)
+			hashes = append(hashes, sim.StateHash())
+		}
+	}
+	return hashes
+}
+
+func TestSimulationDeterminism(t *testing.T) {
+	fixed := func(dt float64) func(int) float64 {
+		return func(int) float64 { return dt }
+	}
+
+	reference := runSimulation(t, fixed(1.0/30.0), nil)
+
+	cases := []struct {
+		name      string
+		frameTime func(int) float64
+	}{
+		{"144 FPS", fixed(1.0 / 144.0)},
+		{"20 FPS", fixed(1.0 / 20.0)},
+		// Неровные кадры: то несколько тиков за кадр, то ни одного
+		{"uneven frames", func(frame int) float64 { return []float64{0.004, 0.05, 0.017, 0.1}[frame%4] }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hashes := runSimulation(t, tc.frameTime, nil)
+			if tick := firstDivergence(reference, hashes); tick >= 0 {
+				t.Fatalf("state diverged at tick %d: %016x vs %016x", tick, reference[tick], hashes[tick])
+			}
+		})
+	}
+}
+
+func TestSimulationDivergenceDetected(t *testing.T) {
+	const changedTick = 150
+
+	reference := runSimulation(t, func(int) float64 { return 1.0 / 30.0 }, nil)
+	hashes := runSimulation(t, func(int) float64 { return 1.0 / 144.0 }, func(tick uint64, frame InputFrame) InputFrame {
+		if tick == changedTick {
+			frame.Forward = -frame.Forward
+		}
+		return frame
+	})
+
+	tick := firstDivergence(reference, hashes)
+	if tick < 0 {
+		t.Fatalf("changed input at tick %d was not detected", changedTick)
+	}
+	if tick != changedTick {
+		t.Fatalf("divergence detected at tick %d, want %d", tick, changedTick)
+	}
+}
+
+func TestSimulationRespawn(t *testing.T) {
+	sim, err := NewSimulation(determinismConfig(t))
+	if err != nil {
+		t.Fatalf("new simulation: %v", err)
+	}
+	sim.Start()
+
+	// Уходим от начала и падаем с высоты: удар отнимает больше, чем осталось
+	for tick := uint64(0); tick < 120; tick++ {
+		sim.Step(scriptedFrame(tick))
+	}
+	player := sim.Player()
+	player.Health = 10
+	player.Position.Y += 50
+	player.IsGrounded = false
+	sim.Physics().TakeEvents()
+
+	for tick := 0; tick < 10*SimulationTickRate && sim.Deaths() == 0; tick++ {
+		sim.Step(InputFrame{})
+	}
+	if sim.Deaths() != 1 {
+		t.Fatalf("player died %d times, want 1", sim.Deaths())
+	}
+
+	events := sim.Physics().TakeEvents()
+	if !events.Died || events.Damage < 10 {
+		t.Errorf("events %+v, want a death after a deadly fall", events)
+	}
+	if player.Health != player.MaxHealth || player.Stamina != player.MaxStamina {
+		t.Errorf("respawned with health %.1f and stamina %.1f, want them full", player.Health, player.Stamina)
+	}
+	spawn := sim.Physics().spawn
+	if d := horizontalDistance(player.Position, spawn); d > 1e-9 {
+		t.Errorf("respawned %.2f m away from the spawn point", d)
+	}
+	ground := sim.Procedural().GetCurrentScene().Terrain.HeightAt(spawn.X, spawn.Z)
+	if feet := player.Position.Y - player.Height/2; math.Abs(feet-ground) > 1e-9 {
+		t.Errorf("respawned with feet %.2f m above the ground", feet-ground)
+	}
+}
@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+)
+
+// Проверки долгого прогона без окна
+const (
+	fallThroughTolerance = 0.5  // Насколько ступни могут уйти под поверхность (сглаживание, ступеньки)
+	stuckWindow          = 10.0 // Окно проверки застревания, с
+	stuckDistance        = 1.0  // За окно с вводом движения игрок должен отойти от начальной точки хотя бы на столько
+	stuckInputShare      = 0.8  // Доля тиков окна с вводом движения, при которой смещение проверяется
+)
+
+// SoakReport - итог долгого прогона
+type SoakReport struct {
+	Ticks    uint64  // Выполнено тиков
+	Distance float64 // Пройдено по горизонтали, м
+	Deaths   int     // Сколько раз игрок погиб и вернулся к началу
+	Failure  string  // Что пошло не так; пусто, если прогон прошел
+	Position Vector3 // Позиция игрока в конце прогона или в момент сбоя
+}
+
+// soakMonitor проверяет состояние игрока после каждого тика
+type soakMonitor struct {
+	anchor     Vector3 // Позиция в начале окна застревания
+	windowTick uint64  // Тик начала окна
+	moveTicks  int     // Тиков окна с вводом движения
+	farthest   float64 // Дальше всего от anchor за окно (обход препятствия может вернуть игрока назад)
+	last       Vector3
+}
+
+// RunSoak гоняет симуляцию с источником ввода (обычно ботом) до ticks тиков
+// или до конца ввода и проверяет, что игрок не провалился сквозь землю,
+// не ушел за край карты и не застрял. recorder, если задан, пишет прогон,
+// чтобы сбой можно было воспроизвести (-headless -replay)
+func RunSoak(sim *Simulation, source InputSource, ticks uint64, recorder *ReplayRecorder) (SoakReport, error) {
+	monitor := &soakMonitor{anchor: sim.Player().Position, last: sim.Player().Position, windowTick: sim.Tick()}
+	report := SoakReport{}
+	windowTicks := uint64(stuckWindow * SimulationTickRate)
+
+	for report.Ticks < ticks {
+		frame, ok := source.NextFrame(sim)
+		if !ok {
+			break
+		}
+		if recorder != nil {
+			if err := recorder.Record(frame); err != nil {
+				return report, err
+			}
+		}
+
+		sim.Step(frame)
+		report.Ticks++
+
+		player := sim.Player()
+		report.Distance += horizontalDistance(player.Position, monitor.last)
+		monitor.last = player.Position
+		report.Position = player.Position
+		report.Deaths = sim.Deaths()
+
+		if frame.Forward != 0 || frame.Strafe != 0 {
+			monitor.moveTicks++
+		}
+		monitor.farthest = math.Max(monitor.farthest, horizontalDistance(player.Position, monitor.anchor))
+
+		if failure := monitor.check(sim); failure != "" {
+			report.Failure = failure
+			break
+		}
+
+		if sim.Tick()-monitor.windowTick >= windowTicks {
+			if failure := monitor.checkStuck(windowTicks); failure != "" {
+				report.Failure = failure
+				break
+			}
+			monitor.anchor = player.Position
+			monitor.windowTick = sim.Tick()
+			monitor.moveTicks = 0
+			monitor.farthest = 0
+		}
+	}
+
+	if report.Failure != "" {
+		return report, fmt.Errorf("tick %d at (%.2f, %.2f, %.2f): %s", sim.Tick(),
+			report.Position.X, report.Position.Y, report.Position.Z, report.Failure)
+	}
+	return report, nil
+}
+
+// check ищет провал сквозь землю, выход за карту и здоровье, которое
+// кончилось без возврата к началу
+func (sm *soakMonitor) check(sim *Simulation) string {
+	player := sim.Player()
+	p := player.Position
+	if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.IsNaN(p.Z) || math.IsInf(p.Y, 0) {
+		return "player position is not a number"
+	}
+
+	// Погибший игрок возвращается к началу в том же тике с полным здоровьем
+	if math.IsNaN(player.Health) || player.Health <= 0 || player.Health > player.MaxHealth {
+		return fmt.Sprintf("player health %.1f is out of range (0, %.0f]", player.Health, player.MaxHealth)
+	}
+
+	terrain := sim.Procedural().GetCurrentScene().Terrain
+	if !terrain.InBounds(p.X, p.Z) {
+		return "player left the map"
+	}
+
+	// В пещерах земля ниже поверхности ландшафта, там сравниваем с полом пещеры
+	feetY := p.Y - player.Height/2
+	ground := terrain.HeightAt(p.X, p.Z)
+	if caveGround, _, ok := sim.Procedural().GetCurrentScene().CaveGroundAt(p.X, feetY+player.StepHeight, p.Z); ok {
+		ground = math.Min(ground, caveGround)
+	}
+	if feetY < ground-fallThroughTolerance {
+		return fmt.Sprintf("player fell through the ground: feet %.2f m below it", ground-feetY)
+	}
+	return ""
+}
+
+// checkStuck сообщает о застревании: бот почти все окно пытался идти,
+// а игрок так и не отошел от начальной точки
+func (sm *soakMonitor) checkStuck(windowTicks uint64) string {
+	if float64(sm.moveTicks) < stuckInputShare*float64(windowTicks) {
+		return ""
+	}
+	if sm.farthest < stuckDistance {
+		return fmt.Sprintf("player is stuck: moved %.2f m in %.0f s of walking", sm.farthest, stuckWindow)
+	}
+	return ""
+}
@@ -0,0 +1,387 @@
+package engine
+
+import (
+	"math"
+	"sync"
+)
+
+// spatialCellSize - размер ячейки пространственного индекса (м).
+// Порядка радиуса типичных запросов: физике хватает одной-двух ячеек,
+// анализу окружения - блока 4x4
+const spatialCellSize = 8.0
+
+// spatialCell - координаты ячейки сетки в плоскости XZ
+type spatialCell struct {
+	X, Z int
+}
+
+// spatialEntry запоминает, какие ячейки занимает объект
+type spatialEntry struct {
+	minCell spatialCell
+	maxCell spatialCell
+	radius  float64 // Горизонтальный радиус контура объекта
+}
+
+// SpatialHash - равномерная сетка в плоскости XZ с объектами сцены.
+// Объект попадает во все ячейки, которые перекрывает его горизонтальный контур,
+// поэтому запросы возвращают кандидатов, а точную проверку делает вызывающий код.
+// Индекс обновляется инкрементально через AddObject/RemoveObjectAt/MoveObject сцены
+type SpatialHash struct {
+	cellSize float64
+	cells    map[spatialCell][]*ProceduralObject
+	entries  map[*ProceduralObject]spatialEntry
+
+	// Границы занятой области (только расширяются): ограничивают обход лучом
+	// и вертикальный размер ячеек при отсечении пирамидой видимости
+	hasBounds bool
+	boundsMin spatialCell
+	boundsMax spatialCell
+	minY      float64
+	maxY      float64
+
+	mutex sync.RWMutex
+}
+
+// NewSpatialHash создает пустой индекс с указанным размером ячейки
+func NewSpatialHash(cellSize float64) *SpatialHash {
+	return &SpatialHash{
+		cellSize: cellSize,
+		cells:    make(map[spatialCell][]*ProceduralObject),
+		entries:  make(map[*ProceduralObject]spatialEntry),
+	}
+}
+
+// objectFootprintRadius возвращает горизонтальный радиус, покрывающий
+// коллайдеры и примитивы рейтрейсера объекта
+func objectFootprintRadius(obj *ProceduralObject) float64 {
+	switch obj.Type {
+	case "tree":
+		return obj.Scale.X * 0.8 // Крона шире ствола
+	case "rock":
+		return math.Max(obj.Scale.X, obj.Scale.Z)
+	case "standing_stone":
+		return (obj.Scale.X + obj.Scale.Z) / 4
+	}
+
+	if isBoxObjectType(obj.Type) {
+		return math.Hypot(obj.Scale.X/2, obj.Scale.Z/2)
+	}
+	return obj.Scale.X
+}
+
+// cellOf возвращает ячейку, содержащую точку (x, z)
+func (sh *SpatialHash) cellOf(x, z float64) spatialCell {
+	return spatialCell{
+		X: int(math.Floor(x / sh.cellSize)),
+		Z: int(math.Floor(z / sh.cellSize)),
+	}
+}
+
+// Len возвращает количество объектов в индексе
+func (sh *SpatialHash) Len() int {
+	sh.mutex.RLock()
+	defer sh.mutex.RUnlock()
+
+	return len(sh.entries)
+}
+
+// Insert добавляет объект в индекс
+func (sh *SpatialHash) Insert(obj *ProceduralObject) {
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+
+	sh.insert(obj)
+}
+
+// Remove удаляет объект из индекса
+func (sh *SpatialHash) Remove(obj *ProceduralObject) {
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+
+	sh.remove(obj)
+}
+
+// Update переиндексирует объект после перемещения или изменения размера
+func (sh *SpatialHash) Update(obj *ProceduralObject) {
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+
+	sh.remove(obj)
+	sh.insert(obj)
+}
+
+func (sh *SpatialHash) insert(obj *ProceduralObject) {
+	if _, exists := sh.entries[obj]; exists {
+		return
+	}
+
+	radius := objectFootprintRadius(obj)
+	entry := spatialEntry{
+		minCell: sh.cellOf(obj.Position.X-radius, obj.Position.Z-radius),
+		maxCell: sh.cellOf(obj.Position.X+radius, obj.Position.Z+radius),
+		radius:  radius,
+	}
+	sh.entries[obj] = entry
+
+	for cx := entry.minCell.X; cx <= entry.maxCell.X; cx++ {
+		for cz := entry.minCell.Z; cz <= entry.maxCell.Z; cz++ {
+			cell := spatialCell{X: cx, Z: cz}
+			sh.cells[cell] = append(sh.cells[cell], obj)
+		}
+	}
+
+	// Расширяем границы занятой области
+	bottom := obj.Position.Y - radius
+	top := obj.Position.Y + obj.Scale.Y + radius
+	if !sh.hasBounds {
+		sh.hasBounds = true
+		sh.boundsMin, sh.boundsMax = entry.minCell, entry.maxCell
+		sh.minY, sh.maxY = bottom, top
+		return
+	}
+	sh.boundsMin.X = min(sh.boundsMin.X, entry.minCell.X)
+	sh.boundsMin.Z = min(sh.boundsMin.Z, entry.minCell.Z)
+	sh.boundsMax.X = max(sh.boundsMax.X, entry.maxCell.X)
+	sh.boundsMax.Z = max(sh.boundsMax.Z, entry.maxCell.Z)
+	sh.minY = math.Min(sh.minY, bottom)
+	sh.maxY = math.Max(sh.maxY, top)
+}
+
+func (sh *SpatialHash) remove(obj *ProceduralObject) {
+	entry, exists := sh.entries[obj]
+	if !exists {
+		return
+	}
+	delete(sh.entries, obj)
+
+	for cx := entry.minCell.X; cx <= entry.maxCell.X; cx++ {
+		for cz := entry.minCell.Z; cz <= entry.maxCell.Z; cz++ {
+			cell := spatialCell{X: cx, Z: cz}
+			objects := sh.cells[cell]
+			for i, candidate := range objects {
+				if candidate == obj {
+					objects = append(objects[:i], objects[i+1:]...)
+					break
+				}
+			}
+			if len(objects) == 0 {
+				delete(sh.cells, cell)
+			} else {
+				sh.cells[cell] = objects
+			}
+		}
+	}
+}
+
+// QueryRadius вызывает visit для каждого объекта, контур которого пересекает круг
+// радиуса radius вокруг center в плоскости XZ. Каждый объект посещается один раз.
+// Обход прекращается, если visit вернул false. visit не должен менять индекс
+func (sh *SpatialHash) QueryRadius(center Vector3, radius float64, visit func(obj *ProceduralObject) bool) {
+	sh.mutex.RLock()
+	defer sh.mutex.RUnlock()
+
+	sh.queryCells(center.X-radius, center.Z-radius, center.X+radius, center.Z+radius,
+		func(obj *ProceduralObject, entry spatialEntry) bool {
+			dx := obj.Position.X - center.X
+			dz := obj.Position.Z - center.Z
+			reach := radius + entry.radius
+			if dx*dx+dz*dz > reach*reach {
+				return true
+			}
+			return visit(obj)
+		})
+}
+
+// QueryBox вызывает visit для каждого объекта, контур которого пересекает
+// прямоугольник [min, max] в плоскости XZ (Y игнорируется)
+func (sh *SpatialHash) QueryBox(minCorner, maxCorner Vector3, visit func(obj *ProceduralObject) bool) {
+	sh.mutex.RLock()
+	defer sh.mutex.RUnlock()
+
+	sh.queryCells(minCorner.X, minCorner.Z, maxCorner.X, maxCorner.Z,
+		func(obj *ProceduralObject, entry spatialEntry) bool {
+			if obj.Position.X+entry.radius < minCorner.X || obj.Position.X-entry.radius > maxCorner.X ||
+				obj.Position.Z+entry.radius < minCorner.Z || obj.Position.Z-entry.radius > maxCorner.Z {
+				return true
+			}
+			return visit(obj)
+		})
+}
+
+// queryCells обходит ячейки прямоугольника. Объект, занимающий несколько ячеек,
+// отдается только в первой из них, попавшей в прямоугольник, - без карты посещенных
+func (sh *SpatialHash) queryCells(minX, minZ, maxX, maxZ float64, visit func(obj *ProceduralObject, entry spatialEntry) bool) {
+	from := sh.cellOf(minX, minZ)
+	to := sh.cellOf(maxX, maxZ)
+
+	for cx := from.X; cx <= to.X; cx++ {
+		for cz := from.Z; cz <= to.Z; cz++ {
+			for _, obj := range sh.cells[spatialCell{X: cx, Z: cz}] {
+				entry := sh.entries[obj]
+				if cx != max(entry.minCell.X, from.X) || cz != max(entry.minCell.Z, from.Z) {
+					continue
+				}
+				if !visit(obj, entry) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// QueryFrustum вызывает visit для объектов, чья позиция может попасть в конус
+// видимости (вершина origin, ось forward, косинус половины угла cosHalfFOV)
+// не дальше maxDistance. Ячейки целиком вне конуса отбрасываются, точную
+// проверку позиции делает вызывающий код
+func (sh *SpatialHash) QueryFrustum(origin, forward Vector3, cosHalfFOV, maxDistance float64, visit func(obj *ProceduralObject) bool) {
+	sh.mutex.RLock()
+	defer sh.mutex.RUnlock()
+
+	if !sh.hasBounds {
+		return
+	}
+
+	forward = forward.Normalize()
+	sinHalfFOV := math.Sqrt(math.Max(0, 1-cosHalfFOV*cosHalfFOV))
+
+	// Ячейка - вертикальная колонна в пределах высот объектов; описываем ее сферой
+	halfHeight := (sh.maxY - sh.minY) / 2
+	centerY := sh.minY + halfHeight
+	cellRadius := math.Sqrt(sh.cellSize*sh.cellSize/2 + halfHeight*halfHeight)
+
+	from := sh.cellOf(origin.X-maxDistance, origin.Z-maxDistance)
+	to := sh.cellOf(origin.X+maxDistance, origin.Z+maxDistance)
+
+	for cx := from.X; cx <= to.X; cx++ {
+		for cz := from.Z; cz <= to.Z; cz++ {
+			objects := sh.cells[spatialCell{X: cx, Z: cz}]
+			if len(objects) == 0 {
+				continue
+			}
+
+			center := Vector3{
+				X: (float64(cx) + 0.5) * sh.cellSize,
+				Y: centerY,
+				Z: (float64(cz) + 0.5) * sh.cellSize,
+			}
+			if Vector3Distance(center, origin) > maxDistance+cellRadius ||
+				!sphereIntersectsCone(center, cellRadius, origin, forward, cosHalfFOV, sinHalfFOV) {
+				continue
+			}
+
+			for _, obj := range objects {
+				// Проверяется позиция объекта, поэтому отдаем его только из "домашней" ячейки
+				if sh.cellOf(obj.Position.X, obj.Position.Z) != (spatialCell{X: cx, Z: cz}) {
+					continue
+				}
+				if !visit(obj) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// sphereIntersectsCone проверяет, пересекает ли сфера бесконечный конус
+// (вершина apex, единичная ось axis)
+func sphereIntersectsCone(center Vector3, radius float64, apex, axis Vector3, cosAngle, sinAngle float64) bool {
+	if sinAngle < 0.0001 {
+		return true
+	}
+
+	// Сдвигаем вершину назад так, чтобы расширенный конус касался сферы
+	shiftedApex := apex.Sub(axis.Mul(radius / sinAngle))
+	d := center.Sub(shiftedApex)
+	if axis.Dot(d) < d.Length()*cosAngle {
+		return false
+	}
+
+	// Сфера за вершиной исходного конуса пересекает его только если содержит вершину
+	d = center.Sub(apex)
+	if -axis.Dot(d) >= d.Length()*sinAngle {
+		return d.Length() <= radius
+	}
+	return true
+}
+
+// QueryRay обходит ячейки вдоль луча (DDA по сетке) в порядке удаления и вызывает
+// visit для объектов каждой ячейки с параметром входа луча в ячейку. Ближайшее
+// пересечение можно искать с ранним выходом: как только enter превысил лучшую
+// найденную дистанцию, дальше ближе ничего не будет. Объект, занимающий несколько
+// ячеек, может быть посещен несколько раз
+func (sh *SpatialHash) QueryRay(ray Ray, maxDistance float64, visit func(obj *ProceduralObject, enter float64) bool) {
+	sh.mutex.RLock()
+	defer sh.mutex.RUnlock()
+
+	if !sh.hasBounds {
+		return
+	}
+
+	// Обрезаем луч по занятой области
+	minX := float64(sh.boundsMin.X) * sh.cellSize
+	maxX := float64(sh.boundsMax.X+1) * sh.cellSize
+	minZ := float64(sh.boundsMin.Z) * sh.cellSize
+	maxZ := float64(sh.boundsMax.Z+1) * sh.cellSize
+
+	tMin, tMax := 0.0, maxDistance
+	for _, axis := range [2]struct{ origin, dir, lo, hi float64 }{
+		{ray.Origin.X, ray.Direction.X, minX, maxX},
+		{ray.Origin.Z, ray.Direction.Z, minZ, maxZ},
+	} {
+		if math.Abs(axis.dir) < 1e-12 {
+			if axis.origin < axis.lo || axis.origin > axis.hi {
+				return
+			}
+			continue
+		}
+		t1 := (axis.lo - axis.origin) / axis.dir
+		t2 := (axis.hi - axis.origin) / axis.dir
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tMin = math.Max(tMin, t1)
+		tMax = math.Min(tMax, t2)
+	}
+	if tMin > tMax {
+		return
+	}
+
+	start := ray.Origin.Add(ray.Direction.Mul(tMin))
+	cell := sh.cellOf(start.X, start.Z)
+	cell.X = max(sh.boundsMin.X, min(sh.boundsMax.X, cell.X))
+	cell.Z = max(sh.boundsMin.Z, min(sh.boundsMax.Z, cell.Z))
+
+	stepX, nextX, deltaX := sh.rayAxisSetup(ray.Origin.X, ray.Direction.X, cell.X)
+	stepZ, nextZ, deltaZ := sh.rayAxisSetup(ray.Origin.Z, ray.Direction.Z, cell.Z)
+
+	enter := tMin
+	for enter <= tMax {
+		for _, obj := range sh.cells[cell] {
+			if !visit(obj, enter) {
+				return
+			}
+		}
+
+		if nextX < nextZ {
+			cell.X += stepX
+			enter = nextX
+			nextX += deltaX
+		} else {
+			cell.Z += stepZ
+			enter = nextZ
+			nextZ += deltaZ
+		}
+	}
+}
+
+// rayAxisSetup возвращает шаг по ячейкам, параметр следующей границы ячейки
+// и приращение параметра на одну ячейку вдоль одной оси
+func (sh *SpatialHash) rayAxisSetup(origin, dir float64, cell int) (int, float64, float64) {
+	switch {
+	case dir > 0:
+		return 1, (float64(cell+1)*sh.cellSize - origin) / dir, sh.cellSize / dir
+	case dir < 0:
+		return -1, (float64(cell)*sh.cellSize - origin) / dir, -sh.cellSize / dir
+	}
+	return 0, math.Inf(1), math.Inf(1)
+}
@@ -0,0 +1,605 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+
+	"nightmare/internal/util"
+)
+
+// StalkerState - состояние сталкера
+type StalkerState int
+
+const (
+	StalkerDormant     StalkerState = iota // Спит вдали от игрока
+	StalkerWander                          // Бродит в окрестностях игрока
+	StalkerStalk                           // Крадется за игроком на расстоянии
+	StalkerInvestigate                     // Идет туда, где игрока видел или слышал
+	StalkerChase                           // Гонится за игроком
+	StalkerRetreat                         // Уходит: на него смотрят или он только что напал
+)
+
+var stalkerStateNames = [...]string{"dormant", "wander", "stalk", "investigate", "chase", "retreat"}
+
+func (s StalkerState) String() string {
+	if s < 0 || int(s) >= len(stalkerStateNames) {
+		return "unknown"
+	}
+	return stalkerStateNames[s]
+}
+
+// Тип объекта сцены, которым сталкер представлен в мире
+const stalkerObjectType = "stalker"
+
+// Настройки сталкера, не зависящие от сложности
+const (
+	stalkerHeight    = 2.3  // Выше человека
+	stalkerRadius    = 0.35 // Худой
+	stalkerEyeHeight = 2.1
+	stalkerTurnSpeed = 3.0 // Скорость поворота, рад/с
+
+	stalkerSpawnMin      = 45.0 // Появляется вдали от игрока
+	stalkerSpawnMax      = 60.0
+	stalkerSpawnAttempts = 32
+	stalkerMapMargin     = 4.0 // Отступ целей от края карты
+	stalkerGoalAttempts  = 4   // Сколько случайных целей пробовать за тик
+
+	stalkerWanderMin     = 15.0 // Бродит на таком расстоянии от игрока
+	stalkerWanderMax     = 35.0
+	stalkerSenseRange    = 2.5  // Так близко замечает игрока и спиной
+	stalkerChaseRange    = 7.0  // Ближе этого, уверившись, что это игрок, бросается в погоню
+	stalkerAttackRange   = 1.3  // Дотягивается до игрока
+	stalkerWakeNoise     = 0.6  // Шум, который будит спящего (прыжки, падения, бег по камню)
+	stalkerHearingError  = 4.0  // Ошибка направления на шум на пределе слышимости, м
+	stalkerWatchedAngle  = 0.3  // Игрок смотрит на сталкера, если отклонение взгляда меньше, рад
+	stalkerWatchedTime   = 1.5  // Секунд под взглядом игрока, после которых сталкер уходит
+	stalkerLoseSight     = 8.0  // Секунд без игрока в поле зрения, после которых слежка сменяется поиском
+	stalkerChaseGiveUp   = 4.0  // Секунд погони без игрока в поле зрения
+	stalkerChaseTire     = 25.0 // Самая долгая погоня, с
+	stalkerSearchTime    = 5.0  // Секунд осмотра на месте, где был игрок
+	stalkerRetreatMin    = 25.0 // Уходит на такое расстояние от игрока
+	stalkerRetreatMax    = 40.0
+	stalkerRetreatTime   = 20.0 // Самое долгое отступление, с
+	stalkerRecoverTime   = 45.0 // Секунд после нападения, когда сталкер только крадется
+	stalkerBackOff       = 8.0  // Насколько пятится, если уйти по пути некуда, м
+	stalkerRepathChase   = 0.5  // Как часто пересчитывать путь за движущимся игроком, с
+	stalkerRepath        = 1.5  // То же при слежке и поиске
+	stalkerAwarenessFade = 0.05 // Насколько в секунду забывает игрока, не видя его
+	stalkerThreatRange   = 30.0 // Дальше этого сталкер не пугает
+
+	stalkerSneakDarkness = 2.0  // Крадучись, метр на свету стоит столько метров обхода
+	stalkerSneakView     = 4.0  // То же для метра на виду у игрока (вплотную к нему)
+	stalkerSneakAngle    = 0.8  // Половина угла взгляда игрока, который сталкер обходит, рад
+	stalkerSneakRange    = 30.0 // Дальше этого взгляд игрока не обходит
+)
+
+// stalkerTuning - параметры, которые растут со сложностью (AIConfig.Difficulty)
+type stalkerTuning struct {
+	walkSpeed    float64 // Брожение, м/с
+	stalkSpeed   float64 // Слежка, поиск, отступление
+	chaseSpeed   float64 // Погоня: на средней сложности чуть быстрее шага игрока, бегом можно уйти
+	keepDistance float64 // На таком расстоянии крадется за игроком
+	sightRange   float64 // Дальность зрения днем в ясную погоду, м
+	fieldOfView  float64 // Косинус половины угла зрения
+	hearing      float64 // Множитель радиуса, на котором слышен шум игрока
+	attackDamage float64
+	awareness    float64 // Как быстро уверяется, что видит игрока
+	wakeDelay    float64 // Секунд сна после начала игры
+}
+
+// newStalkerTuning считает параметры для сложности 0..1
+func newStalkerTuning(difficulty float64) stalkerTuning {
+	d := util.Clamp(difficulty, 0, 1)
+	return stalkerTuning{
+		walkSpeed:    util.Lerp(1.2, 2.0, d),
+		stalkSpeed:   util.Lerp(2.0, 3.4, d),
+		chaseSpeed:   util.Lerp(3.6, 6.0, d),
+		keepDistance: util.Lerp(16, 9, d),
+		sightRange:   util.Lerp(20, 40, d),
+		fieldOfView:  math.Cos(util.Lerp(50, 80, d) * math.Pi / 180),
+		hearing:      util.Lerp(0.6, 1.4, d),
+		attackDamage: util.Lerp(15, 35, d),
+		awareness:    util.Lerp(0.5, 1.5, d),
+		wakeDelay:    util.Lerp(90, 30, d),
+	}
+}
+
+// StalkerEvents сообщает, что сделал сталкер за прошедшие тики
+type StalkerEvents struct {
+	StateChanged bool
+	State        StalkerState // Состояние после последней смены
+	Attacked     bool
+	Damage       float64 // Урон игроку
+}
+
+// Stalker - враждебное существо. Спит вдали от игрока, потом бродит рядом,
+// крадется следом, ищет игрока по шуму и последнему месту, где видел,
+// гонится и нападает. Видит игрока в секторе обзора, если линию взгляда
+// не перекрывают земля и объекты; слышит его шаги и прыжки.
+// Сталкер - часть симуляции: его решения детерминированы сидом мира
+type Stalker struct {
+	object *ProceduralObject // Тело в сцене: рендер, коллизии, хэш мира
+	tuning stalkerTuning
+	rng    *rand.Rand
+
+	state     StalkerState
+	stateTime float64 // Секунд в текущем состоянии
+	yaw       float64
+
+	path     []Vector3
+	waypoint int
+	repath   float64 // Секунд до пересчета пути
+	searched float64 // Секунд осмотра на месте
+
+	lastKnown  Vector3 // Где игрок был, когда его видели или слышали
+	awareness  float64 // Уверенность, что рядом игрок (0..1)
+	seesPlayer bool
+	sinceSeen  float64 // Секунд с тех пор, как видел игрока
+	watched    float64 // Секунд подряд под взглядом игрока
+	recover    float64 // Секунд до того, как сталкер снова сможет напасть
+
+	events StalkerEvents
+}
+
+// newStalker ставит спящего сталкера вдали от игрока. nil, если на карте
+// не нашлось места
+func newStalker(sim *Simulation, difficulty float64) *Stalker {
+	scene := sim.procedural.GetCurrentScene()
+	if scene == nil || scene.Terrain == nil {
+		return nil
+	}
+
+	st := &Stalker{
+		tuning: newStalkerTuning(difficulty),
+		rng:    newStream(sim.Seed(), streamStalker),
+		state:  StalkerDormant,
+	}
+
+	player := sim.Player().Position
+	for attempt := 0; attempt < stalkerSpawnAttempts; attempt++ {
+		angle := st.rng.Float64() * 2 * math.Pi
+		distance := stalkerSpawnMin + st.rng.Float64()*(stalkerSpawnMax-stalkerSpawnMin)
+		position, ok := st.clampToMap(sim, Vector3{
+			X: player.X + math.Sin(angle)*distance,
+			Z: player.Z + math.Cos(angle)*distance,
+		})
+		if !ok || !sim.physics.CanStandAt(position.X, position.Z) {
+			continue
+		}
+
+		position.Y = scene.Terrain.HeightAt(position.X, position.Z)
+		st.yaw = st.rng.Float64() * 2 * math.Pi
+		st.object = &ProceduralObject{
+			ID:       scene.NextObjectID(),
+			Type:     stalkerObjectType,
+			Position: position,
+			Scale:    Vector3{X: stalkerRadius, Y: stalkerHeight, Z: stalkerRadius},
+			Rotation: Vector3{Y: st.yaw},
+			Metadata: map[string]float64{
+				"atmosphere.fear":      1.0,
+				"atmosphere.dread":     0.9,
+				"visuals.dark":         0.9,
+				"visuals.twisted":      0.8,
+				"conditions.unnatural": 1.0,
+			},
+			Seed: deriveSeed(sim.Seed(), streamStalker),
+		}
+		scene.AddObject(st.object)
+		return st
+	}
+	return nil
+}
+
+// State возвращает текущее состояние сталкера
+func (st *Stalker) State() StalkerState {
+	return st.state
+}
+
+// Position возвращает позицию ног сталкера
+func (st *Stalker) Position() Vector3 {
+	return st.object.Position
+}
+
+// ObjectID возвращает ID объекта сцены, которым представлен сталкер
+func (st *Stalker) ObjectID() int {
+	return st.object.ID
+}
+
+// SeesPlayer сообщает, видел ли сталкер игрока на последнем тике
+func (st *Stalker) SeesPlayer() bool {
+	return st.seesPlayer
+}
+
+// TakeEvents возвращает накопленные события и сбрасывает их
+func (st *Stalker) TakeEvents() StalkerEvents {
+	events := st.events
+	st.events = StalkerEvents{State: st.state}
+	return events
+}
+
+// Threat - насколько сталкер пугает игрока сейчас (0..1): чем ближе и
+// чем ближе к нападению, тем страшнее. Спящий не пугает
+func (st *Stalker) Threat(player *Player) float64 {
+	var weight float64
+	switch st.state {
+	case StalkerDormant:
+		return 0
+	case StalkerChase:
+		weight = 1
+	case StalkerStalk, StalkerInvestigate:
+		weight = 0.7
+	default:
+		weight = 0.4
+	}
+
+	proximity := 1 - horizontalDistance(st.object.Position, player.Position)/stalkerThreatRange
+	return weight * util.Clamp(proximity, 0, 1)
+}
+
+// Appear переносит сталкера в точку, где игрок может его заметить, и
+// заставляет красться следом: так директор устраивает появление
+func (st *Stalker) Appear(sim *Simulation, position Vector3) {
+	player := sim.Player().Position
+	st.yaw = math.Atan2(player.X-position.X, player.Z-position.Z)
+	st.place(sim, position)
+
+	st.setState(StalkerStalk)
+	st.lastKnown = player
+	st.sinceSeen = 0
+	st.awareness = math.Max(st.awareness, 0.5)
+}
+
+// HoldOff обрывает погоню и не дает сталкеру напасть ближайшие seconds
+// секунд: директор дает игроку передышку
+func (st *Stalker) HoldOff(sim *Simulation, seconds float64) {
+	st.recover = math.Max(st.recover, seconds)
+	if st.state == StalkerChase {
+		st.retreat(sim)
+	}
+}
+
+// Update продвигает сталкера на один тик
+func (st *Stalker) Update(sim *Simulation, deltaTime float64) {
+	st.stateTime += deltaTime
+	st.recover = math.Max(0, st.recover-deltaTime)
+	heard := st.perceive(sim, deltaTime)
+
+	player := sim.Player()
+	distance := horizontalDistance(st.object.Position, player.Position)
+
+	switch st.state {
+	case StalkerDormant:
+		if st.stateTime >= st.tuning.wakeDelay || (heard && player.NoiseEmitted >= stalkerWakeNoise) {
+			st.setState(StalkerWander)
+		}
+
+	case StalkerWander:
+		switch {
+		case st.seesPlayer:
+			st.noticePlayer(sim, distance)
+		case heard:
+			st.investigate(sim)
+		default:
+			if st.move(sim, st.tuning.walkSpeed, deltaTime) {
+				st.wanderGoal(sim)
+			}
+		}
+
+	case StalkerStalk:
+		switch {
+		case st.watched >= stalkerWatchedTime:
+			st.retreat(sim)
+		case st.seesPlayer && st.recover == 0 && (distance < stalkerChaseRange || st.awareness >= 1):
+			st.setState(StalkerChase)
+		case st.sinceSeen > stalkerLoseSight:
+			st.investigate(sim)
+		default:
+			st.stalk(sim, distance, deltaTime)
+		}
+
+	case StalkerInvestigate:
+		if st.seesPlayer {
+			st.noticePlayer(sim, distance)
+			break
+		}
+		st.repath -= deltaTime
+		if heard && st.repath <= 0 {
+			st.setGoal(sim, st.lastKnown)
+			st.repath = stalkerRepath
+			st.searched = 0
+		}
+		if st.move(sim, st.tuning.stalkSpeed, deltaTime) {
+			// На месте осматривается
+			st.yaw = wrapAngle(st.yaw + stalkerTurnSpeed*0.3*deltaTime)
+			st.searched += deltaTime
+			if st.searched >= stalkerSearchTime {
+				st.setState(StalkerWander)
+				st.wanderGoal(sim)
+			}
+		}
+
+	case StalkerChase:
+		switch {
+		case distance <= stalkerAttackRange:
+			st.attack(sim)
+		case st.sinceSeen > stalkerChaseGiveUp:
+			st.investigate(sim)
+		case st.stateTime > stalkerChaseTire:
+			st.retreat(sim)
+		default:
+			st.repath -= deltaTime
+			if st.repath <= 0 {
+				st.repath = stalkerRepathChase
+				if !st.setGoal(sim, player.Position) && distance < 2*stalkerAttackRange {
+					// Пути нет, но игрок рядом (стоит на камне): тянется к нему напрямую
+					st.path, st.waypoint = []Vector3{player.Position}, 0
+				}
+			}
+			st.move(sim, st.tuning.chaseSpeed, deltaTime)
+		}
+
+	case StalkerRetreat:
+		if st.move(sim, st.tuning.stalkSpeed, deltaTime) || st.stateTime > stalkerRetreatTime {
+			st.setState(StalkerWander)
+			st.wanderGoal(sim)
+		}
+	}
+}
+
+// perceive смотрит и слушает. Возвращает true, если сталкер услышал игрока
+func (st *Stalker) perceive(sim *Simulation, deltaTime float64) bool {
+	player := sim.Player()
+	eye := st.object.Position.Add(Vector3{Y: stalkerEyeHeight})
+	head := player.Position.Add(Vector3{Y: player.Height/2 - 0.1})
+	toPlayer := head.Sub(eye)
+	distance := toPlayer.Length()
+
+	st.seesPlayer = false
+	if sightRange := st.sightRange(sim); st.state != StalkerDormant && distance <= sightRange {
+		facing := Vector3{X: math.Sin(st.yaw), Z: math.Cos(st.yaw)}
+		inView := distance < stalkerSenseRange || facing.Dot(horizontalDirection(toPlayer)) >= st.tuning.fieldOfView
+		if inView && sim.sight.Visible(eye, head, st.object.ID) {
+			st.seesPlayer = true
+
+			// Вблизи сталкер быстро уверяется, что это игрок, вдали - медленно
+			gain := st.tuning.awareness * (0.05 + 0.35*(1-distance/sightRange))
+			st.awareness = math.Min(1, st.awareness+gain*deltaTime)
+		}
+	}
+
+	if st.seesPlayer {
+		st.lastKnown = player.Position
+		st.sinceSeen = 0
+	} else {
+		st.sinceSeen += deltaTime
+		st.awareness = math.Max(0, st.awareness-stalkerAwarenessFade*deltaTime)
+	}
+
+	// Игрок смотрит на сталкера: взгляд и линия видимости взаимны
+	if st.seesPlayer && player.Direction.Normalize().Dot(toPlayer.Mul(-1/distance)) >= math.Cos(stalkerWatchedAngle) {
+		st.watched += deltaTime
+	} else {
+		st.watched = 0
+	}
+
+	// Слух: шум игрока слышен в радиусе NoiseRadius, на высокой сложности дальше
+	hearingRadius := player.NoiseRadius() * st.tuning.hearing
+	if hearingRadius <= 0 || distance > hearingRadius {
+		return false
+	}
+	if !st.seesPlayer {
+		// По звуку место угадывается тем хуже, чем он тише
+		spread := distance / hearingRadius * stalkerHearingError
+		st.lastKnown = Vector3{
+			X: player.Position.X + (st.rng.Float64()*2-1)*spread,
+			Y: player.Position.Y,
+			Z: player.Position.Z + (st.rng.Float64()*2-1)*spread,
+		}
+		st.awareness = math.Min(1, st.awareness+player.NoiseEmitted*deltaTime)
+	}
+	return true
+}
+
+// sightRange возвращает дальность зрения с учетом темноты, тумана и того,
+// что игрок пригнулся
+func (st *Stalker) sightRange(sim *Simulation) float64 {
+	scene := sim.procedural.GetCurrentScene()
+	daylight := math.Max(0, -math.Cos(scene.TimeOfDay*2*math.Pi)) // 0 в полночь, 1 в полдень
+
+	sightRange := st.tuning.sightRange * (0.5 + 0.5*daylight)
+	sightRange *= 1 - 0.5*util.Clamp(scene.Weather["fog"], 0, 1)
+	if sim.Player().IsCrouching {
+		sightRange *= 0.6
+	}
+	return sightRange
+}
+
+// setState переключает состояние и сообщает об этом
+func (st *Stalker) setState(state StalkerState) {
+	if st.state == state {
+		return
+	}
+	st.state = state
+	st.stateTime = 0
+	st.repath = 0
+	st.searched = 0
+	st.path = nil
+	st.events.StateChanged = true
+	st.events.State = state
+}
+
+// noticePlayer реагирует на замеченного игрока: вблизи бросается в погоню,
+// иначе начинает красться следом
+func (st *Stalker) noticePlayer(sim *Simulation, distance float64) {
+	if distance < stalkerChaseRange && st.awareness >= 0.5 && st.recover == 0 {
+		st.setState(StalkerChase)
+		return
+	}
+	st.setState(StalkerStalk)
+}
+
+// investigate идет проверить, где игрока видели или слышали последний раз
+func (st *Stalker) investigate(sim *Simulation) {
+	st.setState(StalkerInvestigate)
+	st.setGoal(sim, st.lastKnown)
+	st.repath = stalkerRepath
+}
+
+// stalk держится на расстоянии от игрока: подходит, если тот далеко,
+// и ждет, глядя на него, если близко
+func (st *Stalker) stalk(sim *Simulation, distance, deltaTime float64) {
+	player := sim.Player()
+	keep := st.tuning.keepDistance
+
+	if distance <= keep {
+		st.path = nil
+		st.face(player.Position, deltaTime)
+		return
+	}
+
+	st.repath -= deltaTime
+	if st.repath <= 0 {
+		st.repath = stalkerRepath
+		away := horizontalDirection(st.object.Position.Sub(player.Position))
+		st.setGoal(sim, player.Position.Add(away.Mul(keep)))
+	}
+	st.move(sim, st.tuning.stalkSpeed, deltaTime)
+}
+
+// attack бьет игрока и уходит
+func (st *Stalker) attack(sim *Simulation) {
+	player := sim.Player()
+	damage := math.Min(player.Health, st.tuning.attackDamage)
+	player.Health -= damage
+
+	st.events.Attacked = true
+	st.events.Damage += damage
+	st.awareness = 0.5
+	st.recover = stalkerRecoverTime
+	st.retreat(sim)
+}
+
+// retreat уходит подальше от игрока
+func (st *Stalker) retreat(sim *Simulation) {
+	st.setState(StalkerRetreat)
+
+	// Сначала прочь от игрока, потом, если туда не пройти, в любую сторону
+	player := sim.Player().Position
+	away := math.Atan2(st.object.Position.X-player.X, st.object.Position.Z-player.Z)
+	for attempt := 0; attempt < 2*stalkerGoalAttempts; attempt++ {
+		spread := math.Pi * 2 / 3
+		if attempt >= stalkerGoalAttempts {
+			spread = 2 * math.Pi
+		}
+		angle := away + (st.rng.Float64()-0.5)*spread
+		distance := stalkerRetreatMin + st.rng.Float64()*(stalkerRetreatMax-stalkerRetreatMin)
+		goal, ok := st.clampToMap(sim, Vector3{
+			X: player.X + math.Sin(angle)*distance,
+			Z: player.Z + math.Cos(angle)*distance,
+		})
+		if ok && sim.physics.CanStandAt(goal.X, goal.Z) && st.setGoal(sim, goal) {
+			return
+		}
+	}
+
+	// Пути нет (игрок на узком гребне или в тупике): пятится напрямую
+	goal, _ := st.clampToMap(sim, st.object.Position.Add(Vector3{
+		X: math.Sin(away) * stalkerBackOff,
+		Z: math.Cos(away) * stalkerBackOff,
+	}))
+	st.path, st.waypoint = []Vector3{goal}, 0
+}
+
+// wanderGoal выбирает случайную цель в окрестностях игрока
+func (st *Stalker) wanderGoal(sim *Simulation) {
+	player := sim.Player().Position
+	for attempt := 0; attempt < stalkerGoalAttempts; attempt++ {
+		angle := st.rng.Float64() * 2 * math.Pi
+		distance := stalkerWanderMin + st.rng.Float64()*(stalkerWanderMax-stalkerWanderMin)
+		goal, ok := st.clampToMap(sim, Vector3{
+			X: player.X + math.Sin(angle)*distance,
+			Z: player.Z + math.Cos(angle)*distance,
+		})
+		if ok && sim.physics.CanStandAt(goal.X, goal.Z) && st.setGoal(sim, goal) {
+			return
+		}
+	}
+}
+
+// clampToMap сдвигает точку внутрь карты с отступом от края
+func (st *Stalker) clampToMap(sim *Simulation, p Vector3) (Vector3, bool) {
+	terrain := sim.procedural.GetCurrentScene().Terrain
+	if terrain == nil {
+		return p, false
+	}
+	halfW := float64(terrain.Width)/2 - stalkerMapMargin
+	halfH := float64(terrain.Height)/2 - stalkerMapMargin
+	p.X = util.Clamp(p.X, -halfW, halfW)
+	p.Z = util.Clamp(p.Z, -halfH, halfH)
+	return p, true
+}
+
+// setGoal ищет путь к точке. false, если пути нет. Вне погони сталкер
+// крадется: держится темноты и обходит то, куда смотрит игрок
+func (st *Stalker) setGoal(sim *Simulation, goal Vector3) bool {
+	query := NavQuery{From: st.object.Position, To: goal, Smooth: true}
+	if st.state != StalkerChase {
+		player := sim.Player()
+		query.Cost = CombineCosts(
+			DarknessCost(sim.procedural.GetCurrentScene(), stalkerSneakDarkness),
+			ViewConeCost(player.Position, player.Direction, stalkerSneakAngle, stalkerSneakRange, stalkerSneakView),
+		)
+	}
+	st.path = sim.Navigation().FindPath(query)
+	st.waypoint = 0
+	return st.path != nil
+}
+
+// move ведет сталкера по пути со скоростью speed. Возвращает true, если
+// путь пройден или его нет
+func (st *Stalker) move(sim *Simulation, speed, deltaTime float64) bool {
+	position := st.object.Position
+	step := speed * deltaTime
+
+	for step > 0 && st.waypoint < len(st.path) {
+		target := st.path[st.waypoint]
+		dx, dz := target.X-position.X, target.Z-position.Z
+		distance := math.Hypot(dx, dz)
+		if distance <= step {
+			position.X, position.Z = target.X, target.Z
+			step -= distance
+			st.waypoint++
+			continue
+		}
+
+		position.X += dx / distance * step
+		position.Z += dz / distance * step
+		st.turnTowards(math.Atan2(dx, dz), deltaTime)
+		step = 0
+	}
+
+	if position != st.object.Position {
+		position.Y = sim.physics.groundAt(position.X, position.Y, position.Z).Height
+		st.place(sim, position)
+	}
+	return st.waypoint >= len(st.path)
+}
+
+// face поворачивает сталкера лицом к точке
+func (st *Stalker) face(target Vector3, deltaTime float64) {
+	st.turnTowards(math.Atan2(target.X-st.object.Position.X, target.Z-st.object.Position.Z), deltaTime)
+	st.object.Rotation.Y = st.yaw
+}
+
+// turnTowards поворачивает сталкера к углу yaw с ограниченной скоростью
+func (st *Stalker) turnTowards(yaw, deltaTime float64) {
+	diff := wrapAngle(yaw - st.yaw)
+	step := stalkerTurnSpeed * deltaTime
+	st.yaw = wrapAngle(st.yaw + math.Max(-step, math.Min(step, diff)))
+}
+
+// place переносит тело сталкера; сцена обновляет индекс и коллайдеры
+func (st *Stalker) place(sim *Simulation, position Vector3) {
+	st.object.Rotation.Y = st.yaw
+	sim.procedural.GetCurrentScene().MoveObject(st.object, position)
+}
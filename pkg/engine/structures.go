@@ -0,0 +1,488 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+)
+
+// StructurePrefab описывает шаблон постройки из нескольких объектов
+type StructurePrefab struct {
+	Type           string             // Тип постройки (cabin, shrine, graves, stone_circle, fence)
+	Radius         float64            // Радиус занимаемой области
+	MaxHeightDelta float64            // Максимальный перепад высот под постройкой
+	Regions        []string           // Допустимые регионы (пусто - любые)
+	MinSpacing     float64            // Минимальное расстояние до других построек
+	Frequency      float64            // Количество построек на карту 256x256
+	AlongPath      bool               // Размещать вдоль тропинок из createPaths
+	Metadata       map[string]float64 // Атмосфера постройки
+
+	// build создает объекты постройки в локальных координатах
+	build func(pg *ProceduralGenerator, rng *rand.Rand, structure *PlacedStructure) []*ProceduralObject
+}
+
+// PlacedStructure представляет размещенную в мире постройку
+type PlacedStructure struct {
+	ID        int
+	Type      string
+	Center    Vector3
+	Radius    float64
+	Rotation  float64            // Поворот вокруг оси Y (в радианах)
+	Metadata  map[string]float64 // Атмосфера, на которую реагирует analyzeEnvironment
+	ObjectIDs []int              // Объекты, из которых состоит постройка
+}
+
+// boxObjectTypes - типы объектов, форма которых - ориентированный бокс
+var boxObjectTypes = map[string]bool{
+	"wall":       true,
+	"beam":       true,
+	"fence_post": true,
+	"fence_rail": true,
+	"grave":      true,
+	"shrine":     true,
+	"altar":      true,
+}
+
+// isBoxObjectType проверяет, описывается ли объект боксом
+func isBoxObjectType(objectType string) bool {
+	return boxObjectTypes[objectType]
+}
+
+// structureBoxCollider создает BoxCollider для части постройки.
+// Position объекта - это центр основания, поэтому центр бокса поднят на половину высоты
+func structureBoxCollider(obj *ProceduralObject) *BoxCollider {
+	return &BoxCollider{
+		Center:     obj.Position.Add(Vector3{X: 0, Y: obj.Scale.Y / 2, Z: 0}),
+		HalfExtent: obj.Scale.Mul(0.5),
+		Rotation:   Vector3{X: 0, Y: obj.Rotation.Y, Z: 0},
+	}
+}
+
+// rotateAroundY поворачивает вектор вокруг оси Y (так же, как BoxCollider.localToWorld)
+func rotateAroundY(v Vector3, angle float64) Vector3 {
+	cosA := math.Cos(angle)
+	sinA := math.Sin(angle)
+
+	return Vector3{
+		X: v.X*cosA - v.Z*sinA,
+		Y: v.Y,
+		Z: v.X*sinA + v.Z*cosA,
+	}
+}
+
+// initStructurePrefabs инициализирует шаблоны построек
+func (pg *ProceduralGenerator) initStructurePrefabs() {
+	pg.structurePrefabs = []StructurePrefab{
+		{
+			Type:           "cabin",
+			Radius:         5.0,
+			MaxHeightDelta: 2.0,
+			Regions:        []string{"dark_forest", "dense_forest", "low_forest", "clearing"},
+			MinSpacing:     30.0,
+			Frequency:      3,
+			Metadata: map[string]float64{
+				"atmosphere.fear":    0.5,
+				"atmosphere.dread":   0.6,
+				"atmosphere.ominous": 0.6,
+				"visuals.dark":       0.6,
+				"conditions.shadow":  0.7,
+			},
+			build: buildCabin,
+		},
+		{
+			Type:           "shrine",
+			Radius:         3.0,
+			MaxHeightDelta: 1.5,
+			Regions:        []string{"clearing", "dense_forest", "dark_forest"},
+			MinSpacing:     25.0,
+			Frequency:      2,
+			Metadata: map[string]float64{
+				"atmosphere.fear":      0.6,
+				"atmosphere.ominous":   0.8,
+				"visuals.twisted":      0.4,
+				"conditions.unnatural": 0.7,
+			},
+			build: buildShrine,
+		},
+		{
+			Type:           "graves",
+			Radius:         5.0,
+			MaxHeightDelta: 1.8,
+			Regions:        []string{"clearing"},
+			MinSpacing:     25.0,
+			Frequency:      3,
+			Metadata: map[string]float64{
+				"atmosphere.fear":       0.6,
+				"atmosphere.dread":      0.8,
+				"visuals.dark":          0.5,
+				"conditions.silhouette": 0.5,
+			},
+			build: buildGraves,
+		},
+		{
+			Type:           "stone_circle",
+			Radius:         6.0,
+			MaxHeightDelta: 2.5,
+			Regions:        []string{"clearing", "rocky_hills", "mountains", "dark_forest"},
+			MinSpacing:     40.0,
+			Frequency:      1,
+			Metadata: map[string]float64{
+				"atmosphere.ominous":   0.7,
+				"atmosphere.tension":   0.6,
+				"visuals.twisted":      0.4,
+				"conditions.unnatural": 0.8,
+			},
+			build: buildStoneCircle,
+		},
+		{
+			Type:           "fence",
+			Radius:         8.0,
+			MaxHeightDelta: 3.5,
+			MinSpacing:     15.0,
+			Frequency:      4,
+			AlongPath:      true,
+			Metadata: map[string]float64{
+				"atmosphere.tension": 0.4,
+				"atmosphere.ominous": 0.3,
+				"conditions.shadow":  0.3,
+			},
+			build: buildFence,
+		},
+	}
+}
+
+// placeStructures размещает постройки по правилам шаблонов
+func (pg *ProceduralGenerator) placeStructures() {
+	if pg.currentScene == nil || pg.currentScene.Terrain == nil {
+		return
+	}
+
+	terrain := pg.currentScene.Terrain
+	rng := newStream(pg.currentScene.Seed, streamStructures)
+
+	// Количество построек масштабируется по площади карты
+	areaScale := float64(terrain.Width*terrain.Height) / (256.0 * 256.0)
+
+	for i := range pg.structurePrefabs {
+		prefab := &pg.structurePrefabs[i]
+
+		count := int(math.Round(prefab.Frequency * areaScale))
+		if count == 0 && rng.Float64() < prefab.Frequency*areaScale {
+			count = 1
+		}
+
+		placed := 0
+		for attempt := 0; placed < count && attempt < count*60; attempt++ {
+			center, rotation, ok := pg.findStructureSite(prefab, rng)
+			if !ok {
+				continue
+			}
+
+			pg.buildStructure(prefab, rng, center, rotation)
+			placed++
+		}
+	}
+}
+
+// findStructureSite ищет подходящее место для постройки
+func (pg *ProceduralGenerator) findStructureSite(prefab *StructurePrefab, rng *rand.Rand) (Vector3, float64, bool) {
+	terrain := pg.currentScene.Terrain
+	halfWidth := float64(terrain.Width) / 2.0
+	halfHeight := float64(terrain.Height) / 2.0
+
+	var x, z, rotation float64
+
+	if prefab.AlongPath {
+		// Выбираем точку на тропинке и смещаемся в сторону от нее
+		if len(terrain.Paths) == 0 {
+			return Vector3{}, 0, false
+		}
+
+		path := terrain.Paths[rng.Intn(len(terrain.Paths))]
+		if len(path) < 8 {
+			return Vector3{}, 0, false
+		}
+
+		i := 3 + rng.Intn(len(path)-6)
+		dirX := float64(path[i+3][0] - path[i-3][0])
+		dirZ := float64(path[i+3][1] - path[i-3][1])
+		length := math.Hypot(dirX, dirZ)
+		if length < 0.001 {
+			return Vector3{}, 0, false
+		}
+		dirX /= length
+		dirZ /= length
+
+		side := 1.0
+		if rng.Float64() < 0.5 {
+			side = -1.0
+		}
+		offset := 3.0 * side
+
+		x = float64(path[i][0]) - halfWidth - dirZ*offset
+		z = float64(path[i][1]) - halfHeight + dirX*offset
+
+		// Забор идет вдоль тропинки (локальная ось X постройки)
+		rotation = math.Atan2(dirZ, dirX)
+	} else {
+		x = rng.Float64()*float64(terrain.Width) - halfWidth
+		z = rng.Float64()*float64(terrain.Height) - halfHeight
+		rotation = rng.Float64() * 2 * math.Pi
+	}
+
+	// Постройка должна целиком помещаться на карте
+	if x-prefab.Radius < -halfWidth || x+prefab.Radius >= halfWidth ||
+		z-prefab.Radius < -halfHeight || z+prefab.Radius >= halfHeight {
+		return Vector3{}, 0, false
+	}
+
+	gridX := clamp(int(x+halfWidth), 0, terrain.Width-1)
+	gridZ := clamp(int(z+halfHeight), 0, terrain.Height-1)
+
+	// Не строим в воде
+	if terrain.Materials[gridZ][gridX] == 1 {
+		return Vector3{}, 0, false
+	}
+
+	// Не загораживаем входы в пещеры
+	if pg.isNearCave(x, z, prefab.Radius) {
+		return Vector3{}, 0, false
+	}
+
+	// Проверяем регион
+	if len(prefab.Regions) > 0 {
+		region := terrain.Regions[gridZ][gridX]
+		allowed := false
+		for _, r := range prefab.Regions {
+			if r == region {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return Vector3{}, 0, false
+		}
+	}
+
+	// Проверяем уклон: перепад высот по окружности постройки
+	centerHeight := terrain.HeightAt(x, z)
+	minHeight, maxHeight := centerHeight, centerHeight
+	for i := 0; i < 8; i++ {
+		angle := float64(i) * math.Pi / 4
+		h := terrain.HeightAt(x+math.Cos(angle)*prefab.Radius, z+math.Sin(angle)*prefab.Radius)
+		minHeight = math.Min(minHeight, h)
+		maxHeight = math.Max(maxHeight, h)
+	}
+	if maxHeight-minHeight > prefab.MaxHeightDelta {
+		return Vector3{}, 0, false
+	}
+
+	// Проверяем расстояние до других построек
+	for _, other := range pg.currentScene.Structures {
+		minDist := math.Max(prefab.MinSpacing, prefab.Radius+other.Radius)
+		if math.Hypot(x-other.Center.X, z-other.Center.Z) < minDist {
+			return Vector3{}, 0, false
+		}
+	}
+
+	return Vector3{X: x, Y: centerHeight, Z: z}, rotation, true
+}
+
+// buildStructure создает постройку и добавляет ее объекты в сцену
+func (pg *ProceduralGenerator) buildStructure(prefab *StructurePrefab, rng *rand.Rand, center Vector3, rotation float64) *PlacedStructure {
+	scene := pg.currentScene
+
+	structure := &PlacedStructure{
+		ID:       len(scene.Structures) + 1,
+		Type:     prefab.Type,
+		Center:   center,
+		Radius:   prefab.Radius,
+		Rotation: rotation,
+		Metadata: make(map[string]float64, len(prefab.Metadata)),
+	}
+	for k, v := range prefab.Metadata {
+		structure.Metadata[k] = v
+	}
+
+	for _, part := range prefab.build(pg, rng, structure) {
+		part.ID = scene.NextObjectID()
+		part.StructureID = structure.ID
+		part.Seed = scene.Seed + int64(20000+part.ID)
+
+		// Части постройки наследуют ее атмосферу
+		if part.Metadata == nil {
+			part.Metadata = make(map[string]float64, len(structure.Metadata))
+			for k, v := range structure.Metadata {
+				part.Metadata[k] = v
+			}
+		}
+
+		scene.AddObject(part)
+		structure.ObjectIDs = append(structure.ObjectIDs, part.ID)
+	}
+
+	scene.Structures = append(scene.Structures, structure)
+	return structure
+}
+
+// structurePart создает часть постройки по смещению в локальных координатах постройки
+func (pg *ProceduralGenerator) structurePart(structure *PlacedStructure, objectType string, local, scale Vector3, localYaw float64) *ProceduralObject {
+	offset := rotateAroundY(local, structure.Rotation)
+	x := structure.Center.X + offset.X
+	z := structure.Center.Z + offset.Z
+
+	return &ProceduralObject{
+		Type:     objectType,
+		Position: Vector3{X: x, Y: pg.currentScene.Terrain.HeightAt(x, z) + local.Y, Z: z},
+		Scale:    scale,
+		Rotation: Vector3{X: 0, Y: structure.Rotation + localYaw, Z: 0},
+	}
+}
+
+// isInsideStructure проверяет, попадает ли точка в область какой-либо постройки
+func (pg *ProceduralGenerator) isInsideStructure(x, z, margin float64) bool {
+	for _, structure := range pg.currentScene.Structures {
+		dx := x - structure.Center.X
+		dz := z - structure.Center.Z
+		reach := structure.Radius + margin
+		if dx*dx+dz*dz < reach*reach {
+			return true
+		}
+	}
+	return false
+}
+
+// buildCabin строит полуразрушенную хижину: четыре стены с дверным проемом и упавшая балка
+func buildCabin(pg *ProceduralGenerator, rng *rand.Rand, structure *PlacedStructure) []*ProceduralObject {
+	width := 4.5 + rng.Float64()*1.5
+	depth := 3.5 + rng.Float64()*1.5
+	wallHeight := 2.4
+	thickness := 0.3
+	doorWidth := 1.2
+	frontSegment := (width - doorWidth) / 2
+
+	walls := []struct {
+		local Vector3
+		scale Vector3
+	}{
+		{Vector3{X: 0, Y: 0, Z: depth / 2}, Vector3{X: width, Y: wallHeight, Z: thickness}},                                       // Задняя стена
+		{Vector3{X: -width / 2, Y: 0, Z: 0}, Vector3{X: thickness, Y: wallHeight, Z: depth}},                                      // Левая стена
+		{Vector3{X: width / 2, Y: 0, Z: 0}, Vector3{X: thickness, Y: wallHeight, Z: depth}},                                       // Правая стена
+		{Vector3{X: -(doorWidth + frontSegment) / 2, Y: 0, Z: -depth / 2}, Vector3{X: frontSegment, Y: wallHeight, Z: thickness}}, // Передняя стена слева от двери
+		{Vector3{X: (doorWidth + frontSegment) / 2, Y: 0, Z: -depth / 2}, Vector3{X: frontSegment, Y: wallHeight, Z: thickness}},  // Передняя стена справа от двери
+	}
+
+	parts := make([]*ProceduralObject, 0, len(walls)+1)
+	for i, wall := range walls {
+		scale := wall.scale
+
+		// Разрушение: часть стен обвалилась, одна может отсутствовать совсем
+		roll := rng.Float64()
+		if roll < 0.1 && i > 0 {
+			continue
+		} else if roll < 0.35 {
+			scale.Y *= 0.3 + rng.Float64()*0.3
+		}
+
+		parts = append(parts, pg.structurePart(structure, "wall", wall.local, scale, 0))
+	}
+
+	// Упавшая балка крыши внутри хижины
+	beamLocal := Vector3{X: (rng.Float64() - 0.5) * width * 0.4, Y: 0, Z: (rng.Float64() - 0.5) * depth * 0.4}
+	beam := pg.structurePart(structure, "beam", beamLocal, Vector3{X: width * 0.8, Y: 0.25, Z: 0.25}, (rng.Float64()-0.5)*0.8)
+	beam.Rotation.Z = (rng.Float64() - 0.5) * 0.4
+	parts = append(parts, beam)
+
+	return parts
+}
+
+// buildShrine строит святилище: алтарь между двумя стоячими камнями
+func buildShrine(pg *ProceduralGenerator, rng *rand.Rand, structure *PlacedStructure) []*ProceduralObject {
+	parts := []*ProceduralObject{
+		pg.structurePart(structure, "shrine", Vector3{}, Vector3{X: 1.2, Y: 1.0, Z: 0.8}, 0),
+	}
+
+	for _, side := range []float64{-1, 1} {
+		stoneHeight := 1.6 + rng.Float64()*0.6
+		parts = append(parts, pg.structurePart(structure, "standing_stone",
+			Vector3{X: side * 1.5, Y: 0, Z: 0.5}, Vector3{X: 0.4, Y: stoneHeight, Z: 0.4}, 0))
+	}
+
+	return parts
+}
+
+// buildGraves строит кладбище из нескольких рядов надгробий
+func buildGraves(pg *ProceduralGenerator, rng *rand.Rand, structure *PlacedStructure) []*ProceduralObject {
+	count := 3 + rng.Intn(5)
+	parts := make([]*ProceduralObject, 0, count)
+
+	for i := 0; i < count; i++ {
+		row := i / 3
+		col := i % 3
+
+		local := Vector3{
+			X: float64(col-1)*1.6 + (rng.Float64()-0.5)*0.4,
+			Y: 0,
+			Z: float64(row)*2.2 - 1.5 + (rng.Float64()-0.5)*0.4,
+		}
+		scale := Vector3{X: 0.6, Y: 0.9 + rng.Float64()*0.4, Z: 0.15}
+
+		grave := pg.structurePart(structure, "grave", local, scale, (rng.Float64()-0.5)*0.3)
+
+		// Старые надгробия покосились
+		grave.Rotation.X = (rng.Float64() - 0.5) * 0.3
+		grave.Rotation.Z = (rng.Float64() - 0.5) * 0.3
+
+		parts = append(parts, grave)
+	}
+
+	return parts
+}
+
+// buildStoneCircle строит круг из стоячих камней, иногда с алтарем в центре
+func buildStoneCircle(pg *ProceduralGenerator, rng *rand.Rand, structure *PlacedStructure) []*ProceduralObject {
+	count := 6 + rng.Intn(4)
+	ringRadius := 3.5 + rng.Float64()*1.5
+	parts := make([]*ProceduralObject, 0, count+1)
+
+	for i := 0; i < count; i++ {
+		angle := float64(i) * 2 * math.Pi / float64(count)
+		width := 0.6 + rng.Float64()*0.3
+		local := Vector3{X: math.Cos(angle) * ringRadius, Y: 0, Z: math.Sin(angle) * ringRadius}
+		scale := Vector3{X: width, Y: 1.5 + rng.Float64()*1.5, Z: width}
+
+		parts = append(parts, pg.structurePart(structure, "standing_stone", local, scale, angle))
+	}
+
+	if rng.Float64() < 0.5 {
+		parts = append(parts, pg.structurePart(structure, "altar", Vector3{}, Vector3{X: 1.4, Y: 0.7, Z: 0.9}, 0))
+	}
+
+	return parts
+}
+
+// buildFence строит участок забора вдоль тропинки: столбы и перекладины, часть из которых сломана
+func buildFence(pg *ProceduralGenerator, rng *rand.Rand, structure *PlacedStructure) []*ProceduralObject {
+	const postWidth = 0.15
+	spacing := 2.0
+
+	// Забор не должен выходить за радиус постройки, по которому проверяются
+	// рельеф и расстояние до соседей
+	maxPosts := int((2*structure.Radius-postWidth)/spacing) + 1
+	posts := 5 + rng.Intn(maxPosts-4)
+	length := float64(posts-1) * spacing
+
+	parts := make([]*ProceduralObject, 0, posts*2)
+	for i := 0; i < posts; i++ {
+		localX := -length/2 + float64(i)*spacing
+		parts = append(parts, pg.structurePart(structure, "fence_post",
+			Vector3{X: localX, Y: 0, Z: 0}, Vector3{X: postWidth, Y: 1.2, Z: postWidth}, 0))
+
+		// Перекладина между текущим и следующим столбом
+		if i < posts-1 && rng.Float64() > 0.2 {
+			parts = append(parts, pg.structurePart(structure, "fence_rail",
+				Vector3{X: localX + spacing/2, Y: 0.7, Z: 0}, Vector3{X: spacing, Y: 0.1, Z: 0.08}, 0))
+		}
+	}
+
+	return parts
+}
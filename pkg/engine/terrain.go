@@ -0,0 +1,101 @@
+package engine
+
+import "math"
+
+// terrainHeightScale переводит высоты карты (0..1) в метры мира
+const terrainHeightScale = 20.0
+
+// SurfaceMaterial описывает поверхность под ногами игрока
+type SurfaceMaterial struct {
+	Name        string  // water, mud, dirt, rock, snow
+	SpeedFactor float64 // Множитель скорости ходьбы
+	Hardness    float64 // 0 - мягкая, 1 - твердая (тон шагов)
+	Wetness     float64 // 0 - сухая, 1 - вода (плеск шагов)
+	Loudness    float64 // Множитель шума шагов: снег глушит, вода плещет
+}
+
+// Поверхности ландшафта. Номера материалов карты высот: 1 - вода, 2 - земля, 3 - камень, 4 - снег
+var (
+	surfaceWater = SurfaceMaterial{Name: "water", SpeedFactor: 0.5, Hardness: 0.05, Wetness: 1.0, Loudness: 1.25}
+	surfaceMud   = SurfaceMaterial{Name: "mud", SpeedFactor: 0.7, Hardness: 0.15, Wetness: 0.8, Loudness: 0.9}
+	surfaceDirt  = SurfaceMaterial{Name: "dirt", SpeedFactor: 1.0, Hardness: 0.4, Wetness: 0.2, Loudness: 0.8}
+	surfaceRock  = SurfaceMaterial{Name: "rock", SpeedFactor: 1.0, Hardness: 0.9, Wetness: 0.0, Loudness: 1.1}
+	surfaceSnow  = SurfaceMaterial{Name: "snow", SpeedFactor: 0.8, Hardness: 0.25, Wetness: 0.3, Loudness: 0.6}
+)
+
+// mudHumidity - влажность земли, начиная с которой она считается грязью
+const mudHumidity = 0.7
+
+// InBounds проверяет, лежит ли точка мира над картой высот
+func (hm *HeightMap) InBounds(x, z float64) bool {
+	gridX := x + float64(hm.Width)/2.0
+	gridZ := z + float64(hm.Height)/2.0
+	return gridX >= 0 && gridX < float64(hm.Width) && gridZ >= 0 && gridZ < float64(hm.Height)
+}
+
+// bilinearCell возвращает углы ячейки сетки с точкой (x, z) и веса интерполяции внутри нее.
+// За краем карты используется крайняя ячейка
+func (hm *HeightMap) bilinearCell(x, z float64) (x0, z0, x1, z1 int, wx, wz float64) {
+	gridX := x + float64(hm.Width)/2.0
+	gridZ := z + float64(hm.Height)/2.0
+
+	x0 = clamp(int(math.Floor(gridX)), 0, hm.Width-1)
+	z0 = clamp(int(math.Floor(gridZ)), 0, hm.Height-1)
+	x1 = clamp(x0+1, 0, hm.Width-1)
+	z1 = clamp(z0+1, 0, hm.Height-1)
+
+	wx = math.Max(0, math.Min(1, gridX-float64(x0)))
+	wz = math.Max(0, math.Min(1, gridZ-float64(z0)))
+	return
+}
+
+// HeightAt возвращает интерполированную высоту ландшафта в мировых координатах.
+// Без блокировок: во время игры карта высот не меняется
+func (hm *HeightMap) HeightAt(x, z float64) float64 {
+	x0, z0, x1, z1, wx, wz := hm.bilinearCell(x, z)
+
+	h0 := hm.Data[z0][x0]*(1-wx) + hm.Data[z0][x1]*wx
+	h1 := hm.Data[z1][x0]*(1-wx) + hm.Data[z1][x1]*wx
+
+	return (h0*(1-wz) + h1*wz) * terrainHeightScale
+}
+
+// NormalAt возвращает нормаль ландшафта, посчитанную аналитически
+// по производным той же билинейной интерполяции, что и HeightAt
+func (hm *HeightMap) NormalAt(x, z float64) Vector3 {
+	x0, z0, x1, z1, wx, wz := hm.bilinearCell(x, z)
+
+	h00, h10 := hm.Data[z0][x0], hm.Data[z0][x1]
+	h01, h11 := hm.Data[z1][x0], hm.Data[z1][x1]
+
+	// Частные производные высоты по X и Z (ячейка - 1 метр)
+	dhdx := ((h10-h00)*(1-wz) + (h11-h01)*wz) * terrainHeightScale
+	dhdz := ((h01-h00)*(1-wx) + (h11-h10)*wx) * terrainHeightScale
+
+	return Vector3{X: -dhdx, Y: 1, Z: -dhdz}.Normalize()
+}
+
+// SurfaceAt возвращает материал поверхности в точке: вода, грязь
+// (сырая земля и болота), земля, камень или снег
+func (hm *HeightMap) SurfaceAt(x, z float64) SurfaceMaterial {
+	gridX, gridZ := hm.cellAt(x, z)
+
+	switch hm.Materials[gridZ][gridX] {
+	case 1:
+		return surfaceWater
+	case 3:
+		return surfaceRock
+	case 4:
+		return surfaceSnow
+	}
+
+	region := ""
+	if hm.Regions != nil {
+		region = hm.Regions[gridZ][gridX]
+	}
+	if region == "swamp" || region == "swamp_pit" ||
+		(hm.Humidity != nil && hm.Humidity[gridZ][gridX] >= mudHumidity) {
+		return surfaceMud
+	}
+	return surfaceDirt
+}